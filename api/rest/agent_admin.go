@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func (s *Server) adminAgentAdd(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.AgentAddReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("name", req.Name)
+	ctx.AddCustomLogField("project_id", req.ProjectId)
+
+	res, err := s.AgentService.AdminAdd(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) adminAgentUpdate(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.AgentUpdateReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+	ctx.AddCustomLogField("project_id", req.ProjectId)
+
+	res, err := s.AgentService.AdminUpdate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) adminAgentDelete(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.AgentDeleteReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+
+	res, err := s.AgentService.AdminDelete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) adminAgentList(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.AgentAdminListReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("page", req.Page)
+	ctx.AddCustomLogField("size", req.Size)
+
+	res, err := s.AgentService.AdminList(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
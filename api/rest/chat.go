@@ -1,6 +1,9 @@
 package rest
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
@@ -27,7 +30,7 @@ func (s *Server) agentList(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
 		return nil, err
 	}
 
-	res, err := s.ChatService.AgentList(ctx, req)
+	res, err := s.AgentService.List(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +45,7 @@ func (s *Server) pinAgent(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
 	if req.ProjectId == "" {
 		return nil, errcode.ErrUserPinPjNotExist
 	}
-	err := s.ChatService.PinAgent(ctx, req)
+	err := s.AgentService.Pin(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +60,21 @@ func (s *Server) unpinAgent(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
 	if req.ProjectId == "" {
 		return nil, errcode.ErrUserPinPjNotExist
 	}
-	err := s.ChatService.UnpinAgent(ctx, req)
+	err := s.AgentService.Unpin(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (s *Server) pinAgentOrder(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.AgentPinOrderReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("project_ids", req.ProjectIds)
+
+	err := s.AgentService.SetPinOrder(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -123,6 +140,109 @@ func (s *Server) chatCompletionsJsonMode(ctx *reqctx.ReqCtx, c *gin.Context) (an
 	return res, nil
 }
 
+func (s *Server) chatCompletionsStream(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatCompletionsReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+
+	res, err := s.ChatService.CompletionsStream(ctx, req, true, chatStreamEmitter(c))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// chatStreamEmitter starts the server-sent events response and returns the emitter writing the events
+func chatStreamEmitter(c *gin.Context) entity.ChatStreamEmitter {
+	// answers may take longer than the server write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	return func(event string, data any) {
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+}
+
+func (s *Server) chatRegenerate(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatRegenerateReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+	ctx.AddCustomLogField("index", req.Index)
+
+	res, err := s.ChatService.Regenerate(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatRegenerateStream(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatRegenerateReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+	ctx.AddCustomLogField("index", req.Index)
+
+	res, err := s.ChatService.RegenerateStream(ctx, req, true, chatStreamEmitter(c))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatEdit(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatEditReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+	ctx.AddCustomLogField("index", req.Index)
+
+	res, err := s.ChatService.Edit(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatEditStream(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatEditReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+	ctx.AddCustomLogField("index", req.Index)
+
+	res, err := s.ChatService.EditStream(ctx, req, true, chatStreamEmitter(c))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatSwitchBranch(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatSwitchBranchReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+	ctx.AddCustomLogField("index", req.Index)
+
+	res, err := s.ChatService.SwitchBranch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (s *Server) chatUpdate(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
 	req := &entity.ChatUpdateReq{}
 	if err := c.ShouldBindJSON(req); err != nil {
@@ -138,6 +258,136 @@ func (s *Server) chatUpdate(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
 	return res, nil
 }
 
+func (s *Server) chatSearch(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatSearchReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("q", req.Q)
+	ctx.AddCustomLogField("page", req.Page)
+	ctx.AddCustomLogField("size", req.Size)
+
+	res, err := s.ChatService.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatShare(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatShareReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+
+	res, err := s.ShareService.Share(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatShareRevoke(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatShareRevokeReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("share_id", req.ShareID)
+
+	res, err := s.ShareService.Revoke(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatShareList(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatShareListReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+
+	res, err := s.ShareService.List(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatShareView(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatShareViewReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("share_id", req.ShareID)
+
+	res, err := s.ShareService.View(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatExport(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatExportReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+	ctx.AddCustomLogField("format", req.Format)
+
+	res, err := s.ChatService.Export(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatExportAll(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatExportAllReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("format", req.Format)
+
+	res, err := s.ChatService.ExportAll(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatPinAgent(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatPinAgentReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+	ctx.AddCustomLogField("project_id", req.ProjectId)
+
+	res, err := s.ChatService.PinAgent(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) chatUnpinAgent(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatUnpinAgentReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+
+	res, err := s.ChatService.UnpinAgent(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (s *Server) chatDelete(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
 	req := &entity.ChatDeleteReq{}
 	if err := c.ShouldBindJSON(req); err != nil {
@@ -164,3 +414,19 @@ func (s *Server) chatDeleteAll(ctx *reqctx.ReqCtx, c *gin.Context) (any, error)
 	}
 	return res, nil
 }
+
+func (s *Server) chatFeedback(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatFeedbackReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("id", req.ID)
+	ctx.AddCustomLogField("index", req.Index)
+	ctx.AddCustomLogField("rating", req.Rating)
+
+	res, err := s.FeedbackService.Rate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func (s *Server) adminChatUsageReport(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatUsageReportReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("period", req.Period)
+	ctx.AddCustomLogField("group_by", req.GroupBy)
+
+	res, err := s.LLMUsageService.AdminReport(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) adminChatFeedbackAnalytics(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatFeedbackAnalyticsReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("group_by", req.GroupBy)
+	ctx.AddCustomLogField("project_id", req.ProjectId)
+	ctx.AddCustomLogField("agent_id", req.AgentID)
+
+	res, err := s.FeedbackService.AdminAnalytics(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) adminChatFeedbackExport(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatFeedbackExportReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("limit", req.Limit)
+	ctx.AddCustomLogField("project_id", req.ProjectId)
+	ctx.AddCustomLogField("agent_id", req.AgentID)
+
+	res, err := s.FeedbackService.AdminExport(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) adminChatCachePurge(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.ChatCachePurgeReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("project_id", req.ProjectId)
+
+	res, err := s.ChatService.AdminPurgeCache(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func (s *Server) adminPromptAdd(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.PromptTemplateAddReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("name", req.Name)
+
+	res, err := s.PromptService.AdminAdd(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) adminPromptUpdate(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.PromptTemplateUpdateReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("name", req.Name)
+
+	res, err := s.PromptService.AdminUpdate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) adminPromptSetActive(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.PromptTemplateSetActiveReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("name", req.Name)
+	ctx.AddCustomLogField("version", req.Version)
+	ctx.AddCustomLogField("is_active", req.IsActive)
+
+	res, err := s.PromptService.AdminSetActive(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) adminPromptList(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	req := &entity.PromptTemplateListReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		return nil, err
+	}
+	ctx.AddCustomLogField("name", req.Name)
+
+	res, err := s.PromptService.AdminList(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -11,10 +11,14 @@ func (s *Server) dataPumpNewTokens(ctx *reqctx.ReqCtx, c *gin.Context) (res any,
 	if err = c.ShouldBindQuery(req); err != nil {
 		return nil, err
 	}
-	if res, err = s.CoreAPI.PumpDataService.NewTokens(ctx.Ctx, req); err != nil {
+	vo, err := s.CoreAPI.PumpDataService.NewTokens(ctx.Ctx, req)
+	if err != nil {
 		return nil, err
 	}
-	return res, nil
+	// the results are shared by the pump data cache
+	copied := *vo
+	copied.ChartURL = s.CoreAPI.ChartService.NewTokensChartURL(ctx, vo)
+	return &copied, nil
 }
 
 func (s *Server) dataPumpLaunchTime(ctx *reqctx.ReqCtx, c *gin.Context) (res any, err error) {
@@ -22,10 +26,14 @@ func (s *Server) dataPumpLaunchTime(ctx *reqctx.ReqCtx, c *gin.Context) (res any
 	if err = c.ShouldBindQuery(req); err != nil {
 		return nil, err
 	}
-	if res, err = s.CoreAPI.PumpDataService.LaunchTime(ctx.Ctx, req); err != nil {
+	vo, err := s.CoreAPI.PumpDataService.LaunchTime(ctx.Ctx, req)
+	if err != nil {
 		return nil, err
 	}
-	return res, nil
+	// the results are shared by the pump data cache
+	copied := *vo
+	copied.ChartURL = s.CoreAPI.ChartService.LaunchTimeChartURL(ctx, vo)
+	return &copied, nil
 }
 
 func (s *Server) dataPumpTransactions(ctx *reqctx.ReqCtx, c *gin.Context) (res any, err error) {
@@ -33,10 +41,14 @@ func (s *Server) dataPumpTransactions(ctx *reqctx.ReqCtx, c *gin.Context) (res a
 	if err = c.ShouldBindQuery(req); err != nil {
 		return nil, err
 	}
-	if res, err = s.CoreAPI.PumpDataService.Transactions(ctx.Ctx, req); err != nil {
+	vo, err := s.CoreAPI.PumpDataService.Transactions(ctx.Ctx, req)
+	if err != nil {
 		return nil, err
 	}
-	return res, nil
+	// the results are shared by the pump data cache
+	copied := *vo
+	copied.ChartURL = s.CoreAPI.ChartService.TransactionsChartURL(ctx, vo)
+	return &copied, nil
 }
 
 func (s *Server) dataPumpTopTraders(ctx *reqctx.ReqCtx, c *gin.Context) (res any, err error) {
@@ -0,0 +1,11 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func (s *Server) adminDataPumpCacheStats(ctx *reqctx.ReqCtx, c *gin.Context) (any, error) {
+	return s.CoreAPI.PumpDataService.CacheStats(), nil
+}
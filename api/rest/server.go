@@ -2,6 +2,7 @@ package rest
 
 import (
 	"fmt"
+	"mime"
 	"net"
 	"net/http"
 	"os"
@@ -173,6 +174,11 @@ func (s *Server) init() error {
 			g.GET("/list", s.apiHandlerWrap(s.agentList, apiNeedAuth()))
 			g.POST("/pin", s.apiHandlerWrap(s.pinAgent, apiNeedAuth()))
 			g.POST("/unpin", s.apiHandlerWrap(s.unpinAgent, apiNeedAuth()))
+			g.POST("/pin/order", s.apiHandlerWrap(s.pinAgentOrder, apiNeedAuth()))
+			g.POST("/add", s.apiHandlerWrap(s.adminAgentAdd, apiNeedAdmin()))
+			g.POST("/update", s.apiHandlerWrap(s.adminAgentUpdate, apiNeedAdmin()))
+			g.POST("/delete", s.apiHandlerWrap(s.adminAgentDelete, apiNeedAdmin()))
+			g.GET("/list-edit", s.apiHandlerWrap(s.adminAgentList, apiNeedAdmin()))
 		}
 
 		{
@@ -180,11 +186,39 @@ func (s *Server) init() error {
 			g.POST("/create", s.apiHandlerWrap(s.chatCreate, apiNeedAuth()))
 			g.GET("/list", s.apiHandlerWrap(s.chatList, apiNeedAuth()))
 			g.GET("/history", s.apiHandlerWrap(s.chatHistory, apiNeedAuth()))
+			g.GET("/search", s.apiHandlerWrap(s.chatSearch, apiNeedAuth()))
+			g.GET("/export", s.apiHandlerWrap(s.chatExport, apiNeedAuth(), apiFileResponse()))
+			g.GET("/export-all", s.apiHandlerWrap(s.chatExportAll, apiNeedAuth(), apiFileResponse()))
+			g.POST("/share", s.apiHandlerWrap(s.chatShare, apiNeedAuth()))
+			g.POST("/share/revoke", s.apiHandlerWrap(s.chatShareRevoke, apiNeedAuth()))
+			g.GET("/share/list", s.apiHandlerWrap(s.chatShareList, apiNeedAuth()))
+			g.GET("/share/view", s.apiHandlerWrap(s.chatShareView))
 			// chat
 			g.POST("/completions", s.apiHandlerWrap(s.chatCompletionsJsonMode, apiNeedAuth()))
+			g.POST("/completions/stream", s.apiHandlerWrap(s.chatCompletionsStream, apiNeedAuth(), apiStreamResponse()))
+			g.POST("/regenerate", s.apiHandlerWrap(s.chatRegenerate, apiNeedAuth()))
+			g.POST("/regenerate/stream", s.apiHandlerWrap(s.chatRegenerateStream, apiNeedAuth(), apiStreamResponse()))
+			g.POST("/edit", s.apiHandlerWrap(s.chatEdit, apiNeedAuth()))
+			g.POST("/edit/stream", s.apiHandlerWrap(s.chatEditStream, apiNeedAuth(), apiStreamResponse()))
+			g.POST("/branch/switch", s.apiHandlerWrap(s.chatSwitchBranch, apiNeedAuth()))
 			g.POST("/update", s.apiHandlerWrap(s.chatUpdate, apiNeedAuth()))
+			g.POST("/agent/pin", s.apiHandlerWrap(s.chatPinAgent, apiNeedAuth()))
+			g.POST("/agent/unpin", s.apiHandlerWrap(s.chatUnpinAgent, apiNeedAuth()))
 			g.POST("/delete", s.apiHandlerWrap(s.chatDelete, apiNeedAuth()))
 			g.POST("/delete-all", s.apiHandlerWrap(s.chatDeleteAll, apiNeedAuth()))
+			g.POST("/feedback", s.apiHandlerWrap(s.chatFeedback, apiNeedAuth()))
+			g.GET("/usage/report", s.apiHandlerWrap(s.adminChatUsageReport, apiNeedAdmin()))
+			g.GET("/feedback/analytics", s.apiHandlerWrap(s.adminChatFeedbackAnalytics, apiNeedAdmin()))
+			g.GET("/feedback/export", s.apiHandlerWrap(s.adminChatFeedbackExport, apiNeedAdmin()))
+			g.POST("/cache/purge", s.apiHandlerWrap(s.adminChatCachePurge, apiNeedAdmin()))
+		}
+
+		{
+			g := v.Group("/prompt")
+			g.POST("/add", s.apiHandlerWrap(s.adminPromptAdd, apiNeedAdmin()))
+			g.POST("/update", s.apiHandlerWrap(s.adminPromptUpdate, apiNeedAdmin()))
+			g.POST("/set-active", s.apiHandlerWrap(s.adminPromptSetActive, apiNeedAdmin()))
+			g.GET("/list", s.apiHandlerWrap(s.adminPromptList, apiNeedAdmin()))
 		}
 
 		{
@@ -199,6 +233,7 @@ func (s *Server) init() error {
 			g.GET("/trader/profit-distribution", s.apiHandlerWrap(s.dataPumpTraderProfitDistribution, apiNeedAuth()))
 			g.GET("/trader/trades", s.apiHandlerWrap(s.dataPumpTraderTrades, apiNeedAuth()))
 			g.GET("/trader/detail", s.apiHandlerWrap(s.dataPumpTraderDetail, apiNeedAuth()))
+			g.GET("/cache/stats", s.apiHandlerWrap(s.adminDataPumpCacheStats, apiNeedAdmin()))
 		}
 
 		{
@@ -256,10 +291,17 @@ func (s *Server) generateRequestContext(c *gin.Context) *reqctx.ReqCtx {
 	return ctx
 }
 
+const (
+	sseEventDone  = "done"
+	sseEventError = "error"
+)
+
 type apiConfig struct {
 	needAuth       bool
 	needAdmin      bool
 	customResponse func(c *gin.Context)
+	streamResponse bool
+	fileResponse   bool
 }
 
 type apiConfigOption func(*apiConfig)
@@ -283,6 +325,21 @@ func apiCustomResponse(customResponse func(c *gin.Context)) apiConfigOption {
 	}
 }
 
+// apiStreamResponse marks a server-sent events api, the handler writes intermediate events itself
+// and the result (or the error) is sent as the last event
+func apiStreamResponse() apiConfigOption {
+	return func(c *apiConfig) {
+		c.streamResponse = true
+	}
+}
+
+// apiFileResponse sends the *entity.Attachment returned by the handler as a download, errors are still json
+func apiFileResponse() apiConfigOption {
+	return func(c *apiConfig) {
+		c.fileResponse = true
+	}
+}
+
 func newAPIConfig(opts ...apiConfigOption) apiConfig {
 	apiCfg := &apiConfig{
 		needAuth:  false,
@@ -366,7 +423,13 @@ func (s *Server) apiHandlerWrap(handler func(ctx *reqctx.ReqCtx, c *gin.Context)
 		}
 
 		if err != nil {
-			if cfg.customResponse == nil {
+			if cfg.streamResponse && c.Writer.Written() {
+				s.failStreamWithErr(ctx, c, err)
+			} else if cfg.streamResponse {
+				// nothing streamed yet, reply with a plain json error
+				c.Writer.Header().Del("Content-Type")
+				s.failResponseWithErr(ctx, c, err)
+			} else if cfg.customResponse == nil {
 				s.failResponseWithErr(ctx, c, err)
 			} else {
 				cfg.customResponse(c)
@@ -379,7 +442,11 @@ func (s *Server) apiHandlerWrap(handler func(ctx *reqctx.ReqCtx, c *gin.Context)
 		ctx.CombineCustomLogFields(logFields)
 		ctx.Logger.WithFields(logFields).Info("API request")
 
-		if cfg.customResponse == nil {
+		if cfg.streamResponse {
+			s.successStreamWithData(c, res)
+		} else if cfg.fileResponse {
+			s.successFileResponse(c, res.(*entity.Attachment))
+		} else if cfg.customResponse == nil {
 			s.successResponseWithData(c, res)
 		} else {
 			cfg.customResponse(c)
@@ -414,3 +481,34 @@ func (s *Server) successResponseWithData(c *gin.Context, data any) {
 	}
 	c.JSON(http.StatusOK, res)
 }
+
+func (s *Server) successFileResponse(c *gin.Context, file *entity.Attachment) {
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
+
+func (s *Server) failStreamWithErr(ctx *reqctx.ReqCtx, c *gin.Context, err error) {
+	code := errcode.DecodeError(err)
+	msg := err.Error()
+
+	ctx.AddCustomLogField("err_code", code)
+	ctx.AddCustomLogField("err_msg", msg)
+
+	c.SSEvent(sseEventError, gin.H{
+		"code":    code,
+		"message": msg,
+	})
+	c.Writer.Flush()
+}
+
+func (s *Server) successStreamWithData(c *gin.Context, data any) {
+	res := gin.H{
+		"code": 0,
+	}
+	if data != nil {
+		res["data"] = data
+	}
+	c.SSEvent(sseEventDone, res)
+	c.Writer.Flush()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+)
+
+// LoadKeyFile reads a hex private key, the "address|private key" lines of accounts.txt are accepted too
+func LoadKeyFile(path string) (*ecdsa.PrivateKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(raw)), "\n")
+	if _, key, ok := strings.Cut(line, "|"); ok {
+		line = key
+	}
+	key, err := ethcrypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(line), "0x"))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid private key")
+	}
+	return key, nil
+}
+
+// SignMessage signs the message the way wallets do (EIP-191 personal_sign), the way the server verifies it
+func SignMessage(key *ecdsa.PrivateKey, message string) (string, error) {
+	hash := ethcrypto.Keccak256([]byte("\x19Ethereum Signed Message:\n" + strconv.Itoa(len(message)) + message))
+	signature, err := ethcrypto.Sign(hash, key)
+	if err != nil {
+		return "", err
+	}
+	// wallets send v as 27/28
+	signature[64] += 27
+	return hexutil.Encode(signature), nil
+}
+
+// LoginWithKey signs the login message of a fresh nonce with the key
+func LoginWithKey(ctx context.Context, c *Client, key *ecdsa.PrivateKey) (*entity.UserLoginRes, error) {
+	addr := ethcrypto.PubkeyToAddress(key.PublicKey).Hex()
+	nonce, err := c.Nonce(ctx, addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get nonce")
+	}
+	message := fmt.Sprintf(config.WalletSignatureMessageTemplate, nonce.Nonce)
+	signature, err := SignMessage(key, message)
+	if err != nil {
+		return nil, err
+	}
+	return c.Login(ctx, &entity.UserLoginReq{
+		Type: model.UserAuthTypeWallet,
+		UserLoginByWallet: entity.UserLoginByWallet{
+			Addr:      addr,
+			Signature: signature,
+			Nonce:     nonce.Nonce,
+			Message:   message,
+		},
+	})
+}
@@ -1,56 +1,256 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/wyt-labs/wyt-core/internal/pkg/config"
-	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/c-bata/go-prompt"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+)
+
+const (
+	listSize = 20
+	// project names offered by the completer
+	maxProjectNames = 1000
+	projectPageSize = 100
 )
 
+var commands = []prompt.Suggest{
+	{Text: "/new", Description: "start a chat, optionally with the agent of a project id"},
+	{Text: "/list", Description: "list your chats"},
+	{Text: "/use", Description: "switch to a chat by its # in /list or its id"},
+	{Text: "/history", Description: "show the messages of the current chat"},
+	{Text: "/help", Description: "show the commands"},
+	{Text: "/exit", Description: "quit"},
+}
+
+// Chat is a terminal session: the window being used and the project names for completion
 type Chat struct {
-	chatgptDriver *extension.ChatgptDriver
-	HistoryMsgs   []*extension.ChatgptMsg
-}
-
-func NewChat() (*Chat, error) {
-	chatgptDriver, err := extension.NewChatgptDriver(&extension.ChatgptConfig{
-		Endpoint:        "https://sme.openai.azure.com/",
-		APIKey:          "b19a2b2fdae04ff2ae0f6cdc4792eb86",
-		Model:           "gpt4-turbo",
-		Temperature:     0.7,
-		PresencePenalty: 0,
-	}, nil, nil)
+	client    *Client
+	out       io.Writer
+	projectId string
+
+	windowId string
+	// windows of the last /list, /use accepts their position
+	windows []*model.ChatWindow
+
+	lock         sync.RWMutex
+	projectNames []string
+}
+
+func NewChat(client *Client, out io.Writer, projectId string) *Chat {
+	return &Chat{
+		client:    client,
+		out:       out,
+		projectId: projectId,
+	}
+}
+
+// LoadProjectNames fetches the published project names in the background, the completer uses what has arrived
+func (c *Chat) LoadProjectNames(ctx context.Context) {
+	go func() {
+		for page := uint64(1); page*projectPageSize <= maxProjectNames; page++ {
+			res, err := c.client.ProjectList(ctx, &entity.ProjectListReq{Page: page, Size: projectPageSize})
+			if err != nil {
+				return
+			}
+			names := make([]string, 0, len(res.List))
+			for _, project := range res.List {
+				if project.Name != "" {
+					names = append(names, project.Name)
+				}
+			}
+			c.lock.Lock()
+			c.projectNames = append(c.projectNames, names...)
+			c.lock.Unlock()
+			if len(res.List) < projectPageSize {
+				return
+			}
+		}
+	}()
+}
+
+func (c *Chat) Completer(d prompt.Document) []prompt.Suggest {
+	if strings.HasPrefix(d.TextBeforeCursor(), "/") {
+		if strings.Contains(d.TextBeforeCursor(), " ") {
+			return nil
+		}
+		return prompt.FilterHasPrefix(commands, d.GetWordBeforeCursor(), true)
+	}
+	word := d.GetWordBeforeCursor()
+	if word == "" {
+		return nil
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	suggests := make([]prompt.Suggest, 0, len(c.projectNames))
+	for _, name := range c.projectNames {
+		suggests = append(suggests, prompt.Suggest{Text: name, Description: "project"})
+	}
+	return prompt.FilterHasPrefix(suggests, word, true)
+}
+
+func (c *Chat) Executor(in string) {
+	in = strings.TrimSpace(in)
+	if in == "" {
+		return
+	}
+	if err := c.execute(context.Background(), in); err != nil {
+		fmt.Fprintln(c.out, "Something went wrong:", err.Error())
+	}
+}
+
+func (c *Chat) execute(ctx context.Context, in string) error {
+	if !strings.HasPrefix(in, "/") {
+		return c.send(ctx, in)
+	}
+	cmd, arg, _ := strings.Cut(in, " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case "/new":
+		return c.create(ctx, arg)
+	case "/list":
+		return c.list(ctx)
+	case "/use":
+		return c.use(ctx, arg)
+	case "/history":
+		return c.history(ctx)
+	case "/help":
+		for _, command := range commands {
+			fmt.Fprintf(c.out, "%-10s %s\n", command.Text, command.Description)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown command %s, see /help", cmd)
+}
+
+func (c *Chat) create(ctx context.Context, projectId string) error {
+	if projectId == "" {
+		projectId = c.projectId
+	}
+	res, err := c.client.ChatCreate(ctx, projectId)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &Chat{
-		chatgptDriver: chatgptDriver,
-	}, nil
+	c.windowId = res.ID.Hex()
+	fmt.Fprintf(c.out, "Chat %s created.\n", c.windowId)
+	return nil
 }
 
-func (c *Chat) Executor(msg string) {
-	res, err := func() (string, error) {
-		c.HistoryMsgs = append(c.HistoryMsgs, &extension.ChatgptMsg{
-			Role:    "user",
-			Content: msg,
-		})
+func (c *Chat) list(ctx context.Context) error {
+	res, err := c.client.ChatList(ctx, 1, listSize)
+	if err != nil {
+		return err
+	}
+	c.windows = res.List
+	renderWindows(c.out, res.List)
+	if res.Total > int64(len(res.List)) {
+		fmt.Fprintf(c.out, "%d of %d chats shown.\n", len(res.List), res.Total)
+	}
+	return nil
+}
 
-		allMsgs := []*extension.ChatgptMsg{{
-			Role:    "system",
-			Content: config.ChatPrompt,
-		}}
-		historyMsgsSize := len(c.HistoryMsgs)
-		if historyMsgsSize <= 5 {
-			allMsgs = append(allMsgs, c.HistoryMsgs...)
-		} else if historyMsgsSize > 5 {
-			allMsgs = append(allMsgs, c.HistoryMsgs[historyMsgsSize-5:historyMsgsSize]...)
+func (c *Chat) use(ctx context.Context, arg string) error {
+	if arg == "" {
+		return fmt.Errorf("usage: /use <# or id>")
+	}
+	id := arg
+	if n, err := strconv.Atoi(arg); err == nil {
+		if n < 1 || n > len(c.windows) {
+			return fmt.Errorf("no chat #%d, run /list first", n)
 		}
+		id = c.windows[n-1].ID.Hex()
+	}
+	history, err := c.client.ChatHistory(ctx, id)
+	if err != nil {
+		return err
+	}
+	c.windowId = id
+	fmt.Fprintf(c.out, "Using chat %s: %s (%d messages).\n", id, history.Title, history.MsgNum)
+	return nil
+}
 
-		return c.chatgptDriver.ChatCompletions(allMsgs)
-	}()
+func (c *Chat) history(ctx context.Context) error {
+	if c.windowId == "" {
+		return fmt.Errorf("no chat in use, see /new and /use")
+	}
+	history, err := c.client.ChatHistory(ctx, c.windowId)
 	if err != nil {
-		fmt.Println("Something went wrong:", err.Error())
-	} else {
-		fmt.Println(res)
+		return err
+	}
+	renderHistory(c.out, history)
+	return nil
+}
+
+// send asks the question in the current chat, a new one is started when there is none
+func (c *Chat) send(ctx context.Context, msg string) error {
+	if c.windowId == "" {
+		if err := c.create(ctx, ""); err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	text := &streamText{}
+	res, err := c.client.ChatCompletionsStream(ctx, &entity.ChatCompletionsReq{
+		ID:   c.windowId,
+		Type: model.ChatMsgRoleUser,
+		Msg:  msg,
+	}, func(event string, data json.RawMessage) {
+		c.printEvent(event, data, text)
+	})
+	if text.Printed() {
+		fmt.Fprint(c.out, "\n\n")
+	}
+	if err != nil {
+		return err
+	}
+	renderMsg(c.out, res.Msg, text.Printed())
+	fmt.Fprintf(c.out, "(%s)\n", time.Since(start).Round(100*time.Millisecond))
+	return nil
+}
+
+func (c *Chat) printEvent(event string, data json.RawMessage, text *streamText) {
+	switch event {
+	case entity.ChatStreamEventAgent:
+		var agent entity.ChatStreamAgent
+		if json.Unmarshal(data, &agent) == nil {
+			fmt.Fprintf(c.out, "· agent: %s\n", agent.Name)
+		}
+	case entity.ChatStreamEventIntent:
+		var intent entity.ChatStreamIntent
+		if json.Unmarshal(data, &intent) == nil && intent.Intention != "" {
+			fmt.Fprintf(c.out, "· intent: %s %s %s\n", intent.Intention, intent.View, strings.Join(intent.IntentKeys, ", "))
+		}
+	case entity.ChatStreamEventProjectFetch:
+		var fetch entity.ChatStreamProjectFetch
+		if json.Unmarshal(data, &fetch) == nil {
+			fmt.Fprintf(c.out, "· fetching %s: %s\n", fetch.View, strings.Join(fetch.ProjectKeys, ", "))
+		}
+	case entity.ChatStreamEventFunctionCall:
+		var call entity.ChatStreamFunctionCall
+		if json.Unmarshal(data, &call) == nil {
+			fmt.Fprintf(c.out, "· calling %s %s\n", call.Name, call.Arguments)
+		}
+	case entity.ChatStreamEventToken:
+		var token entity.ChatStreamToken
+		if json.Unmarshal(data, &token) == nil {
+			fmt.Fprint(c.out, text.Add(token.Content))
+		}
+	}
+}
+
+// LivePrefix shows the chat in use in the prompt
+func (c *Chat) LivePrefix() (string, bool) {
+	if c.windowId == "" {
+		return "", false
 	}
+	return fmt.Sprintf("[%s] >>> ", c.windowId[max(0, len(c.windowId)-6):]), true
 }
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/crypto"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+)
+
+const testToken = "test-token"
+
+func writeData(w http.ResponseWriter, data any) {
+	_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": data})
+}
+
+func writeEvent(w http.ResponseWriter, event string, data any) {
+	raw, _ := json.Marshal(data)
+	_, _ = fmt.Fprintf(w, "event:%s\ndata:%s\n\n", event, raw)
+}
+
+func newMockServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/user/nonce", func(w http.ResponseWriter, r *http.Request) {
+		writeData(w, &entity.UserNonceRes{Nonce: "nonce-" + r.URL.Query().Get("addr")})
+	})
+	mux.HandleFunc("/api/v1/user/login", func(w http.ResponseWriter, r *http.Request) {
+		req := &entity.UserLoginReq{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(req))
+		assert.Equal(t, "nonce-"+req.Addr, req.Nonce)
+		assert.Equal(t, fmt.Sprintf(config.WalletSignatureMessageTemplate, req.Nonce), req.Message)
+		if err := crypto.VerifyETHSignature(req.Addr, req.Message, req.Signature); err != nil {
+			_ = json.NewEncoder(w).Encode(map[string]any{"code": 10001, "message": err.Error()})
+			return
+		}
+		writeData(w, &entity.UserLoginRes{Token: testToken})
+	})
+	mux.HandleFunc("/api/v1/chat/completions/stream", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(config.JWTTokenHeaderKey) != testToken {
+			_ = json.NewEncoder(w).Encode(map[string]any{"code": 10002, "message": "token invalid"})
+			return
+		}
+		req := &entity.ChatCompletionsReq{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(req))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if req.Msg == "fail" {
+			writeEvent(w, entity.ChatStreamEventAgent, &entity.ChatStreamAgent{Name: "WYT Agent"})
+			writeEvent(w, sseEventError, map[string]any{"code": 10402, "message": "chat failed"})
+			return
+		}
+		writeEvent(w, entity.ChatStreamEventAgent, &entity.ChatStreamAgent{Name: "WYT Agent"})
+		for _, token := range []string{`{"intention":"","content":"DeFi is \"decentral`, `ized\" fin`, `ance\u00`, `21"}`} {
+			writeEvent(w, entity.ChatStreamEventToken, &entity.ChatStreamToken{Content: token})
+		}
+		writeEvent(w, sseEventDone, map[string]any{"code": 0, "data": &entity.ChatCompletionsRes{Msg: &entity.ChatMsg{
+			Index: 1,
+			Role:  model.ChatMsgRoleAssistant,
+			Content: &model.ChatContentAssistant{
+				Type: model.ChatContentAssistantTypeGeneral,
+				GeneralAnswer: &model.ChatContentAssistantGeneralAnswerRes{
+					GeneralAnswer: model.ChatContentAssistantGeneralAnswer{Content: `DeFi is "decentralized" finance!`},
+				},
+			},
+		}}})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestLoginWithKey(t *testing.T) {
+	server := newMockServer(t)
+	defer server.Close()
+
+	key, err := ethcrypto.GenerateKey()
+	assert.Nil(t, err)
+	client := NewClient(server.URL + "/")
+	res, err := LoginWithKey(context.Background(), client, key)
+	assert.Nil(t, err)
+	assert.Equal(t, testToken, res.Token)
+	assert.Equal(t, testToken, client.Token)
+}
+
+func TestClient_ChatCompletionsStream(t *testing.T) {
+	server := newMockServer(t)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	_, err := client.ChatCompletionsStream(context.Background(), &entity.ChatCompletionsReq{Msg: "hi"}, nil)
+	assert.Equal(t, &apiError{Code: 10002, Message: "token invalid"}, err)
+
+	client.Token = testToken
+	var events []string
+	_, err = client.ChatCompletionsStream(context.Background(), &entity.ChatCompletionsReq{Msg: "fail"}, func(event string, data json.RawMessage) {
+		events = append(events, event)
+	})
+	assert.Equal(t, &apiError{Code: 10402, Message: "chat failed"}, err)
+	assert.Equal(t, []string{entity.ChatStreamEventAgent}, events)
+
+	// the streamed content is shown once, the final answer adds nothing
+	var out bytes.Buffer
+	c := NewChat(client, &out, "")
+	c.windowId = "window"
+	assert.Nil(t, c.send(context.Background(), "What is DeFi?"))
+	assert.Contains(t, out.String(), "· agent: WYT Agent\n")
+	assert.Equal(t, 1, strings.Count(out.String(), `DeFi is "decentralized" finance!`))
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := ": comment\nevent:token\ndata:{\"content\":\"a\"}\n\ndata: line1\ndata: line2\n\nevent:done\ndata:{}"
+	var got []string
+	err := readEvents(strings.NewReader(stream), func(event string, data []byte) error {
+		got = append(got, event+"="+string(data))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`token={"content":"a"}`, "message=line1\nline2", "done={}"}, got)
+}
+
+func TestStreamText(t *testing.T) {
+	s := &streamText{}
+	var shown []string
+	for _, token := range []string{`{"intent_keys":["a"],`, `"content"`, `: "line\`, `n2 \u4f`, `60\\ done`, `"`, `, "view":"x"}`} {
+		shown = append(shown, s.Add(token))
+	}
+	assert.Equal(t, []string{"", "", "line", "\n2 ", "你\\ done", "", ""}, shown)
+
+	// plain text answers are shown as they come
+	s = &streamText{}
+	assert.Equal(t, "Hello", s.Add("Hello"))
+	assert.Equal(t, " world", s.Add(" world"))
+	assert.True(t, s.Printed())
+}
+
+func TestRenderMsg(t *testing.T) {
+	var out bytes.Buffer
+	renderMsg(&out, &entity.ChatMsg{
+		Role: model.ChatMsgRoleAssistant,
+		Content: &model.ChatContentAssistant{
+			Type: model.ChatContentAssistantTypeProjectInfo,
+			ProjectInfo: &model.ChatContentAssistantProjectInfoRes{
+				View: model.ChatContentAssistantProjectInfoViewOverview,
+				Project: model.ChatContentAssistantProjectInfo{
+					Overview: &model.ChatContentAssistantProjectOverview{
+						Name:        "Bitcoin",
+						TokenSymbol: "BTC",
+						TokenPrice:  65000.5,
+						Description: strings.Repeat("long ", 20),
+					},
+				},
+			},
+		},
+	}, false)
+	assert.Contains(t, out.String(), "== Overview ==")
+	assert.Regexp(t, `Token Symbol\s+BTC`, out.String())
+	assert.Regexp(t, `Token Price\s+65000.5`, out.String())
+	assert.Contains(t, out.String(), "long long...\n")
+
+	out.Reset()
+	renderMsg(&out, &entity.ChatMsg{Role: model.ChatMsgRoleUser, Content: map[string]any{"content": "hello"}}, false)
+	assert.Equal(t, "hello\n\n", out.String())
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+)
+
+const (
+	apiPrefix = "/api/v1"
+
+	sseEventDone  = "done"
+	sseEventError = "error"
+)
+
+// apiError is the {code, message} body of a failed api call
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("api error %d: %s", e.Code, e.Message)
+}
+
+type apiResponse struct {
+	apiError
+	Data json.RawMessage `json:"data"`
+}
+
+// Client calls the wyt-core rest api, Token is sent with every request once logged in
+type Client struct {
+	BaseURL    string
+	Token      string
+	IsZH       bool
+	HTTPClient *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, method string, path string, query url.Values, body any) (*http.Request, error) {
+	u := c.BaseURL + apiPrefix + path
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set(config.JWTTokenHeaderKey, c.Token)
+	}
+	if c.IsZH {
+		req.Header.Set(config.IsZHLangHeaderKey, "true")
+	}
+	return req, nil
+}
+
+// call sends the request and decodes the data of the response into res (when not nil)
+func (c *Client) call(ctx context.Context, method string, path string, query url.Values, body any, res any) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeResponse(resp, res)
+}
+
+func decodeResponse(resp *http.Response, res any) error {
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var apiRes apiResponse
+	if err := json.Unmarshal(raw, &apiRes); err != nil {
+		return errors.Errorf("unexpected response, http status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	return decodeData(&apiRes, res)
+}
+
+func decodeData(apiRes *apiResponse, res any) error {
+	if apiRes.Code != 0 {
+		return &apiRes.apiError
+	}
+	if res == nil || len(apiRes.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(apiRes.Data, res)
+}
+
+func (c *Client) Nonce(ctx context.Context, addr string) (*entity.UserNonceRes, error) {
+	res := &entity.UserNonceRes{}
+	if err := c.call(ctx, http.MethodGet, "/user/nonce", url.Values{"addr": {addr}}, nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) Login(ctx context.Context, req *entity.UserLoginReq) (*entity.UserLoginRes, error) {
+	res := &entity.UserLoginRes{}
+	if err := c.call(ctx, http.MethodPost, "/user/login", nil, req, res); err != nil {
+		return nil, err
+	}
+	c.Token = res.Token
+	return res, nil
+}
+
+// DevLogin logs in without signature, only served by dev builds
+func (c *Client) DevLogin(ctx context.Context, addr string) (*entity.UserLoginRes, error) {
+	res := &entity.UserLoginRes{}
+	if err := c.call(ctx, http.MethodPost, "/user/dev-login", nil, &entity.UserDevLoginReq{Addr: addr}, res); err != nil {
+		return nil, err
+	}
+	c.Token = res.Token
+	return res, nil
+}
+
+func (c *Client) ChatCreate(ctx context.Context, projectId string) (*entity.ChatCreateRes, error) {
+	res := &entity.ChatCreateRes{}
+	if err := c.call(ctx, http.MethodPost, "/chat/create", nil, &entity.ChatCreateReq{ProjectId: projectId}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ChatList(ctx context.Context, page uint64, size uint64) (*entity.ChatListRes, error) {
+	res := &entity.ChatListRes{}
+	query := url.Values{
+		"page": {strconv.FormatUint(page, 10)},
+		"size": {strconv.FormatUint(size, 10)},
+	}
+	if err := c.call(ctx, http.MethodGet, "/chat/list", query, nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ChatHistory(ctx context.Context, id string) (*entity.ChatHistoryRes, error) {
+	res := &entity.ChatHistoryRes{}
+	if err := c.call(ctx, http.MethodGet, "/chat/history", url.Values{"id": {id}}, nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ProjectList(ctx context.Context, req *entity.ProjectListReq) (*entity.ProjectListRes, error) {
+	res := &entity.ProjectListRes{}
+	if err := c.call(ctx, http.MethodPost, "/project/list-view", nil, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ChatCompletionsStream asks the question and passes the intermediate events (raw json data) to onEvent,
+// the final answer is returned once the server sends it
+func (c *Client) ChatCompletionsStream(ctx context.Context, req *entity.ChatCompletionsReq, onEvent func(event string, data json.RawMessage)) (*entity.ChatCompletionsRes, error) {
+	httpReq, err := c.newRequest(ctx, http.MethodPost, "/chat/completions/stream", nil, req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// errors raised before anything is streamed come back as plain json
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		res := &entity.ChatCompletionsRes{}
+		if err := decodeResponse(resp, res); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	var res *entity.ChatCompletionsRes
+	err = readEvents(resp.Body, func(event string, data []byte) error {
+		switch event {
+		case sseEventDone:
+			var apiRes apiResponse
+			if err := json.Unmarshal(data, &apiRes); err != nil {
+				return errors.Errorf("unexpected done event: %s", data)
+			}
+			res = &entity.ChatCompletionsRes{}
+			return decodeData(&apiRes, res)
+		case sseEventError:
+			apiErr := &apiError{}
+			if err := json.Unmarshal(data, apiErr); err != nil {
+				return errors.Errorf("unexpected error event: %s", data)
+			}
+			return apiErr
+		default:
+			if onEvent != nil {
+				onEvent(event, data)
+			}
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.New("stream closed before the answer was sent")
+	}
+	return res, nil
+}
+
+// readEvents parses a server-sent events stream, multi-line data is joined with '\n'
+func readEvents(r io.Reader, handle func(event string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var event string
+	var data []string
+	dispatch := func() error {
+		defer func() {
+			event = ""
+			data = nil
+		}()
+		if data == nil {
+			return nil
+		}
+		if event == "" {
+			event = "message"
+		}
+		return handle(event, []byte(strings.Join(data, "\n")))
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "error reading stream")
+	}
+	return dispatch()
+}
@@ -1,33 +1,89 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/c-bata/go-prompt"
+	"github.com/urfave/cli/v2"
 )
 
-func completer(d prompt.Document) []prompt.Suggest {
-	s := []prompt.Suggest{
-		{Text: "Show me some info about ", Description: ""},
-		{Text: "Compare projects between ", Description: ""},
+func main() {
+	app := cli.NewApp()
+	app.Name = "wyt-chat"
+	app.Usage = "chat with wyt from the terminal"
+	app.HideVersion = true
+	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:    "server",
+			Usage:   "address of the wyt-core http server",
+			Value:   "http://127.0.0.1:8080",
+			EnvVars: []string{"WYT_SERVER"},
+		},
+		&cli.StringFlag{
+			Name:    "key",
+			Usage:   "file with the hex private key of the wallet to log in with",
+			EnvVars: []string{"WYT_KEY_FILE"},
+		},
+		&cli.StringFlag{
+			Name:  "dev-addr",
+			Usage: "wallet address to log in with the dev-login api of dev builds, no signature needed",
+		},
+		&cli.StringFlag{
+			Name:  "project",
+			Usage: "project id of the agent of the new chats, the agent router picks one when empty",
+		},
+		&cli.BoolFlag{
+			Name:  "zh",
+			Usage: "answer in chinese",
+		},
+	}
+	app.Action = run
+	if err := app.Run(os.Args); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
-	return prompt.FilterHasPrefix(s, d.GetWordBeforeCursor(), true)
 }
 
-func main() {
-	fmt.Print("Welcome to wyt-chat, I'm ChatGPt-based ai assistant, you can ask me questions about web3 projects.\n")
-	fmt.Println("Please use `exit` or `Ctrl-D` to exit this program.")
+func run(cliCtx *cli.Context) error {
+	ctx := context.Background()
+	client := NewClient(cliCtx.String("server"))
+	client.IsZH = cliCtx.Bool("zh")
 
-	c, err := NewChat()
-	if err != nil {
-		panic(err)
+	switch {
+	case cliCtx.String("key") != "":
+		key, err := LoadKeyFile(cliCtx.String("key"))
+		if err != nil {
+			return err
+		}
+		if _, err := LoginWithKey(ctx, client, key); err != nil {
+			return fmt.Errorf("login failed: %w", err)
+		}
+	case cliCtx.String("dev-addr") != "":
+		if _, err := client.DevLogin(ctx, cliCtx.String("dev-addr")); err != nil {
+			return fmt.Errorf("dev login failed: %w", err)
+		}
+	default:
+		return fmt.Errorf("use --key or --dev-addr to log in")
 	}
+
+	c := NewChat(client, os.Stdout, cliCtx.String("project"))
+	c.LoadProjectNames(ctx)
+
+	fmt.Println("Welcome to wyt-chat, you can ask me questions about web3 projects.")
+	fmt.Println("Type /help for the commands, `/exit` or `Ctrl-D` to exit this program.")
 	p := prompt.New(
 		c.Executor,
-		completer,
+		c.Completer,
 		prompt.OptionTitle("wyt-chat"),
 		prompt.OptionPrefix(">>> "),
+		prompt.OptionLivePrefix(c.LivePrefix),
 		prompt.OptionInputTextColor(prompt.Yellow),
+		prompt.OptionSetExitCheckerOnInput(func(in string, breakline bool) bool {
+			return breakline && (in == "/exit" || in == "exit")
+		}),
 	)
 	p.Run()
+	return nil
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/chatexport"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+)
+
+const (
+	timeLayout = "2006-01-02 15:04"
+	// longer table cells are cut, the terminal is not wide enough for descriptions
+	maxCellWidth = 48
+)
+
+// decodeMsg restores the stored message from the api message, its content is typed by the role
+func decodeMsg(msg *entity.ChatMsg) (*model.ChatMsg, error) {
+	raw, err := json.Marshal(msg.Content)
+	if err != nil {
+		return nil, err
+	}
+	res := &model.ChatMsg{
+		Timestamp: msg.Timestamp,
+		Role:      msg.Role,
+	}
+	var content any
+	switch msg.Role {
+	case model.ChatMsgRoleUser:
+		res.ContentUser = &model.ChatContentUser{}
+		content = res.ContentUser
+	case model.ChatMsgRoleUserBuiltin:
+		res.ContentUserBuiltin = &model.ChatContentUserBuiltin{}
+		content = res.ContentUserBuiltin
+	case model.ChatMsgRoleSystem:
+		res.ContentSystem = &model.ChatContentSystem{}
+		content = res.ContentSystem
+	case model.ChatMsgRoleAssistant:
+		res.ContentAssistant = &model.ChatContentAssistant{}
+		content = res.ContentAssistant
+	default:
+		return nil, fmt.Errorf("unknown message role: %s", msg.Role)
+	}
+	if err := json.Unmarshal(raw, content); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// renderMsg prints the message with its cards as tables, skipText leaves out the plain text already streamed
+func renderMsg(w io.Writer, msg *entity.ChatMsg, skipText bool) {
+	m, err := decodeMsg(msg)
+	if err != nil {
+		// still show something readable
+		raw, _ := json.MarshalIndent(msg.Content, "", "  ")
+		fmt.Fprintf(w, "%s\n", raw)
+		return
+	}
+	for _, block := range chatexport.MsgBlocks(m) {
+		if skipText && block.Heading == "" && block.Table == nil && block.Link == "" {
+			continue
+		}
+		renderBlock(w, block)
+	}
+}
+
+func renderBlock(w io.Writer, block *chatexport.Block) {
+	if block.Heading != "" {
+		fmt.Fprintf(w, "== %s ==\n", block.Heading)
+	}
+	switch {
+	case block.Table != nil:
+		renderTable(w, block.Table)
+	case block.Link != "":
+		fmt.Fprintf(w, "%s\n", block.Link)
+	case block.Text != "":
+		fmt.Fprintf(w, "%s\n", strings.TrimSpace(block.Text))
+	}
+	fmt.Fprintln(w)
+}
+
+func renderTable(w io.Writer, table *chatexport.Table) {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	writeRow := func(cells []string) {
+		for i, cell := range cells {
+			if i != 0 {
+				_, _ = io.WriteString(tw, "\t")
+			}
+			_, _ = io.WriteString(tw, cutCell(cell))
+		}
+		_, _ = io.WriteString(tw, "\n")
+	}
+	if len(table.Header) != 0 {
+		writeRow(table.Header)
+		underline := make([]string, len(table.Header))
+		for i, h := range table.Header {
+			underline[i] = strings.Repeat("-", utf8.RuneCountInString(cutCell(h)))
+		}
+		writeRow(underline)
+	}
+	for _, row := range table.Rows {
+		writeRow(row)
+	}
+	_ = tw.Flush()
+	_, _ = w.Write(buf.Bytes())
+}
+
+func cutCell(cell string) string {
+	cell = strings.Join(strings.Fields(cell), " ")
+	if utf8.RuneCountInString(cell) <= maxCellWidth {
+		return cell
+	}
+	return strings.TrimSpace(string([]rune(cell)[:maxCellWidth-3])) + "..."
+}
+
+func renderWindows(w io.Writer, list []*model.ChatWindow) {
+	if len(list) == 0 {
+		fmt.Fprintln(w, "No chats yet, use /new to start one.")
+		return
+	}
+	table := &chatexport.Table{Header: []string{"#", "ID", "Title", "Messages", "Updated"}}
+	for i, window := range list {
+		table.Rows = append(table.Rows, []string{
+			fmt.Sprint(i + 1),
+			window.ID.Hex(),
+			window.Title,
+			fmt.Sprint(window.MsgNum),
+			time.Time(window.UpdateTime).Local().Format(timeLayout),
+		})
+	}
+	renderTable(w, table)
+}
+
+func renderHistory(w io.Writer, history *entity.ChatHistoryRes) {
+	fmt.Fprintf(w, "# %s\n\n", history.Title)
+	for _, msg := range history.Msgs {
+		fmt.Fprintf(w, "[%s] %s\n", roleLabel(msg.Role), time.Time(msg.Timestamp).Local().Format(timeLayout))
+		renderMsg(w, msg, false)
+	}
+}
+
+func roleLabel(role model.ChatMsgRole) string {
+	switch role {
+	case model.ChatMsgRoleUser, model.ChatMsgRoleUserBuiltin:
+		return "you"
+	case model.ChatMsgRoleAssistant:
+		return "wyt"
+	}
+	return role
+}
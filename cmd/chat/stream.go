@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// streamText turns the streamed tokens into readable text. Structured answers are streamed as json,
+// only the "content" string of the answer is shown, as far as it has arrived; other answers are shown as is.
+type streamText struct {
+	buf     strings.Builder
+	printed int
+}
+
+// Add appends the token and returns the text that has not been shown yet
+func (s *streamText) Add(token string) string {
+	s.buf.WriteString(token)
+	text := s.text()
+	if len(text) <= s.printed {
+		return ""
+	}
+	res := text[s.printed:]
+	s.printed = len(text)
+	return res
+}
+
+// Printed reports whether any text has been shown
+func (s *streamText) Printed() bool {
+	return s.printed != 0
+}
+
+func (s *streamText) text() string {
+	raw := s.buf.String()
+	if !strings.HasPrefix(strings.TrimSpace(raw), "{") {
+		return raw
+	}
+	return partialJSONString(raw, "content")
+}
+
+// partialJSONString decodes the string value of key from the beginning of a json object,
+// the value may be cut anywhere
+func partialJSONString(raw string, key string) string {
+	i := strings.Index(raw, `"`+key+`"`)
+	if i < 0 {
+		return ""
+	}
+	rest := strings.TrimLeft(raw[i+len(key)+2:], " \t\r\n")
+	if !strings.HasPrefix(rest, ":") {
+		return ""
+	}
+	rest = strings.TrimLeft(rest[1:], " \t\r\n")
+	if !strings.HasPrefix(rest, `"`) {
+		return ""
+	}
+	rest = rest[1:]
+
+	end := len(rest)
+	for j := 0; j < len(rest); j++ {
+		if rest[j] == '\\' {
+			j++
+			continue
+		}
+		if rest[j] == '"' {
+			end = j
+			break
+		}
+	}
+	value := trimPartialEscape(rest[:end])
+	var res string
+	for value != "" {
+		if err := json.Unmarshal([]byte(`"`+value+`"`), &res); err == nil {
+			return res
+		}
+		// a cut \u escape or utf-8 sequence, wait for the next token
+		value = value[:len(value)-1]
+	}
+	return ""
+}
+
+// trimPartialEscape drops a trailing escape sequence that has not fully arrived
+func trimPartialEscape(value string) string {
+	i := strings.LastIndex(value, `\`)
+	if i < 0 {
+		return value
+	}
+	// count the backslashes in the run ending at i, an even run is escaped backslashes
+	n := 0
+	for j := i; j >= 0 && value[j] == '\\'; j-- {
+		n++
+	}
+	if n%2 == 0 {
+		return value
+	}
+	tail := value[i+1:]
+	if tail == "" || (tail[0] == 'u' && len(tail) < 5) {
+		return value[:i]
+	}
+	return value
+}
@@ -0,0 +1,122 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/wyt-labs/wyt-core/internal/coreapi"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/intenteval"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+)
+
+// Command is the offline evaluation commands
+var Command = &cli.Command{
+	Name:        "eval",
+	Usage:       "The offline evaluation commands",
+	Subcommands: subCommands,
+}
+
+var subCommands = []*cli.Command{
+	{
+		Name:  "intent",
+		Usage: "Score the intent classification of a golden dataset with the configured llm provider, the wyt ai backend is not evaluated",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "dataset",
+				Usage:    "json array of cases: id, category, question, intention, view, keys and tool call arguments",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "baseline",
+				Usage: "report of a previous run to compare with, the run fails on regressions",
+			},
+			&cli.BoolFlag{
+				Name:  "update-baseline",
+				Usage: "write the report of this run to the baseline file",
+			},
+			&cli.StringFlag{
+				Name:  "prompt",
+				Usage: "file with the system prompt to evaluate, the built-in structured output prompt by default",
+			},
+			&cli.StringFlag{
+				Name:  "agent",
+				Usage: "agent (project id) whose tools are offered to the model",
+			},
+		},
+		Action: intent,
+	},
+}
+
+func intent(ctx *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Println("load config failed:", err)
+		os.Exit(1)
+		return nil
+	}
+	// provider only: the wyt ai backend runs the tools itself and does not report how it classified
+	// the question, so it cannot be scored here
+	cfg.AIBackend.AIEnv = ""
+	driver, err := extension.NewChatgptDriver(coreapi.ChatgptConfig(cfg), &base.Component{Config: cfg}, nil)
+	if err != nil {
+		fmt.Println("create llm driver failed:", err)
+		os.Exit(1)
+		return nil
+	}
+
+	cases, err := intenteval.LoadDataset(ctx.String("dataset"))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+		return nil
+	}
+	// the built-in prompt of the driver when empty
+	var prompt string
+	if p := ctx.String("prompt"); p != "" {
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			fmt.Println("read prompt failed:", err)
+			os.Exit(1)
+			return nil
+		}
+		prompt = string(raw)
+	}
+
+	runner := &intenteval.Runner{
+		Classifier:   driver,
+		SystemPrompt: prompt,
+		AgentID:      ctx.String("agent"),
+	}
+	fmt.Printf("llm provider: %s (the wyt ai backend is not evaluated)\n", driver.Provider().Name())
+	report := runner.Run(ctx.Context, cases)
+	intenteval.WriteReport(os.Stdout, report)
+
+	baselinePath := ctx.String("baseline")
+	if baselinePath == "" {
+		return nil
+	}
+	if ctx.Bool("update-baseline") {
+		if err := intenteval.SaveBaseline(baselinePath, report); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("baseline updated:", baselinePath)
+		return nil
+	}
+	baseline, err := intenteval.LoadBaseline(baselinePath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+		return nil
+	}
+	diff := intenteval.NewDiff(baseline, report)
+	intenteval.WriteDiff(os.Stdout, diff)
+	if len(diff.Regressions) != 0 {
+		os.Exit(1)
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/wyt-labs/wyt-core/cmd/core/cmd"
 	configcmd "github.com/wyt-labs/wyt-core/cmd/core/cmd/config"
+	evalcmd "github.com/wyt-labs/wyt-core/cmd/core/cmd/eval"
 	"github.com/wyt-labs/wyt-core/internal/pkg/config"
 )
 
@@ -36,6 +37,7 @@ func main() {
 			},
 		},
 		configcmd.Command,
+		evalcmd.Command,
 	}
 
 	if err := app.Run(os.Args); err != nil {
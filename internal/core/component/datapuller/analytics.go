@@ -0,0 +1,46 @@
+package datapuller
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/wyt-labs/wyt-core/internal/core/component/datapuller/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+)
+
+// AnalyticsSource runs the pump data queries, every backend lays the results out as the metabase cards
+// so the rows are decoded the same way
+type AnalyticsSource interface {
+	DailyLaunchedTokenInfo(duration int, timezone string) (*model.DatasetQueryResults, error)
+	LaunchedTokenTimeDistribution(duration int, timezone string) (*model.DatasetQueryResults, error)
+	DailyTradeCounts(duration int, timezone string) (*model.DatasetQueryResults, error)
+	TraderOverview(trader string) (*model.DatasetQueryResults, error)
+	TraderOverviewV2(trader string, tz string, days int) (*model.DatasetQueryResults, error)
+	TraderTxTimeDistribution(trader string, duration int, timezone string) (*model.DatasetQueryResults, error)
+	TraderProfitTokenDistribution(trader string, duration int, timezone string) (*model.DatasetQueryResults, error)
+	TraderProfitDistribution(trader string, duration int, timezone string) (*model.DatasetQueryResults, error)
+	TopTrader(duration int, winRatio float32) (*model.DatasetQueryResults, error)
+}
+
+var (
+	_ AnalyticsSource = (*MetabaseDataSource)(nil)
+	_ AnalyticsSource = (*SQLDataSource)(nil)
+)
+
+// NewAnalyticsSource picks the backend configured in config.Backends.PumpDataSource
+func NewAnalyticsSource(baseComponent *base.Component, metabaseDataSource *MetabaseDataSource) (AnalyticsSource, error) {
+	switch source := strings.ToLower(baseComponent.Config.Backends.PumpDataSource); source {
+	case "", config.PumpDataSourceMetabase:
+		return metabaseDataSource, nil
+	case config.PumpDataSourceSQL:
+		sqlDataSource, err := NewSQLDataSource(baseComponent)
+		if err != nil {
+			return nil, err
+		}
+		return sqlDataSource, nil
+	default:
+		return nil, errors.Errorf("unsupported pump data source: %s", source)
+	}
+}
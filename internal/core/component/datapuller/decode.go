@@ -0,0 +1,171 @@
+package datapuller
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/wyt-labs/wyt-core/internal/core/component/datapuller/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+)
+
+// ErrSchemaDrift is returned when the columns of a card are not the ones its rows are decoded into,
+// usually because the card was edited in metabase
+var ErrSchemaDrift = errors.New("metabase schema drift")
+
+// base types of the number columns, see https://www.metabase.com/docs/latest/data-modeling/field-types
+var numberBaseTypes = []string{"type/Number", "type/Integer", "type/BigInteger", "type/Float", "type/Decimal"}
+
+type columnField struct {
+	field    int
+	column   string
+	index    int
+	nullable bool
+}
+
+// DecodeRows maps the rows of the results to T by column name. The fields are tagged with their column:
+//
+//	TotalNetProfit float64 `metabase:"total_net_profit"`
+//	Tag            string  `metabase:"tag,nullable"`
+//
+// missing columns and columns of another type are reported as ErrSchemaDrift, null values are refused
+// unless the field is nullable
+func DecodeRows[T any](ret *model.DatasetQueryResults) ([]*T, error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		return nil, errors.Errorf("cannot decode rows into %s", typ)
+	}
+	columns := make(map[string]int, len(ret.Data.Cols))
+	for i, col := range ret.Data.Cols {
+		columns[col.Name] = i
+	}
+
+	var fields []columnField
+	var missing, changed []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, ok := field.Tag.Lookup("metabase")
+		if !ok || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		index, ok := columns[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		if !compatible(field.Type.Kind(), ret.Data.Cols[index].BaseType) {
+			changed = append(changed, fmt.Sprintf("%s is %s", name, ret.Data.Cols[index].BaseType))
+			continue
+		}
+		fields = append(fields, columnField{field: i, column: name, index: index, nullable: opts == "nullable"})
+	}
+	if len(missing) != 0 || len(changed) != 0 {
+		var problems []string
+		if len(missing) != 0 {
+			problems = append(problems, "missing columns: "+strings.Join(missing, ", "))
+		}
+		if len(changed) != 0 {
+			problems = append(problems, "changed columns: "+strings.Join(changed, ", "))
+		}
+		return nil, errors.Wrap(ErrSchemaDrift, strings.Join(problems, "; "))
+	}
+
+	res := make([]*T, 0, len(ret.Data.Rows))
+	for rowIndex, row := range ret.Data.Rows {
+		item := new(T)
+		value := reflect.ValueOf(item).Elem()
+		for _, f := range fields {
+			if f.index >= len(row) {
+				return nil, errors.Errorf("row %d has no column %s", rowIndex, f.column)
+			}
+			if err := setField(value.Field(f.field), row[f.index], f.nullable); err != nil {
+				return nil, errors.Wrapf(err, "row %d, column %s", rowIndex, f.column)
+			}
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+// compatible tells whether the column can be decoded into the field, the columns without base type are
+// checked value by value
+func compatible(kind reflect.Kind, baseType string) bool {
+	if baseType == "" {
+		return true
+	}
+	isNumber := slices.Contains(numberBaseTypes, baseType)
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return isNumber
+	case reflect.Bool:
+		return baseType == "type/Boolean"
+	}
+	// numbers can be read as text
+	return true
+}
+
+func setField(field reflect.Value, v any, nullable bool) error {
+	if v == nil {
+		if nullable {
+			return nil
+		}
+		return errors.New("value is null")
+	}
+	switch field.Kind() {
+	case reflect.String:
+		switch v := v.(type) {
+		case string:
+			field.SetString(v)
+		case float64:
+			field.SetString(strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			return errors.Wrapf(ErrSchemaDrift, "cannot read %T as text", v)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := v.(float64)
+		if !ok {
+			return errors.Wrapf(ErrSchemaDrift, "cannot read %T as integer", v)
+		}
+		field.SetInt(int64(f))
+	case reflect.Float32, reflect.Float64:
+		f, ok := v.(float64)
+		if !ok {
+			return errors.Wrapf(ErrSchemaDrift, "cannot read %T as number", v)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return errors.Wrapf(ErrSchemaDrift, "cannot read %T as boolean", v)
+		}
+		field.SetBool(b)
+	default:
+		return errors.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// decodeCardRows is DecodeRows for the results of a card, a schema drift is logged as an alert since the
+// card has to be fixed in metabase
+func decodeCardRows[T any](baseComponent *base.Component, card string, ret *model.DatasetQueryResults) ([]*T, error) {
+	rows, err := DecodeRows[T](ret)
+	if err != nil {
+		logger := baseComponent.Logger.WithFields(logrus.Fields{
+			"card": card,
+			"err":  err,
+		})
+		if errors.Is(err, ErrSchemaDrift) {
+			logger.WithField("alert", "metabase_schema_drift").Error("Metabase card columns changed")
+		} else {
+			logger.Error("Failed to decode metabase card rows")
+		}
+		return nil, errors.Wrapf(err, "metabase card %s", card)
+	}
+	return rows, nil
+}
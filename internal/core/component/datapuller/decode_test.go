@@ -0,0 +1,153 @@
+package datapuller
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/core/component/datapuller/model"
+)
+
+type testRow struct {
+	Date  string  `metabase:"date"`
+	Count int64   `metabase:"count"`
+	Ratio float64 `metabase:"ratio,nullable"`
+	Note  string
+}
+
+func results(t *testing.T, raw string) *model.DatasetQueryResults {
+	ret := &model.DatasetQueryResults{}
+	assert.Nil(t, json.Unmarshal([]byte(raw), ret))
+	return ret
+}
+
+func TestDecodeRows(t *testing.T) {
+	// the order of the columns does not matter
+	rows, err := DecodeRows[testRow](results(t, `{"data":{
+		"cols":[{"name":"ratio","base_type":"type/Float"},{"name":"extra"},{"name":"count","base_type":"type/BigInteger"},{"name":"date","base_type":"type/Date"}],
+		"rows":[[0.5,"x",3,"2024-05-01"],[null,"y",4,"2024-05-02"]]}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []*testRow{{Date: "2024-05-01", Count: 3, Ratio: 0.5}, {Date: "2024-05-02", Count: 4}}, rows)
+
+	_, err = DecodeRows[testRow](results(t, `{"data":{"cols":[{"name":"ratio"},{"name":"day"}],"rows":[]}}`))
+	assert.True(t, errors.Is(err, ErrSchemaDrift))
+	assert.ErrorContains(t, err, "missing columns: date, count")
+
+	_, err = DecodeRows[testRow](results(t, `{"data":{"cols":[{"name":"date"},{"name":"count","base_type":"type/Text"},{"name":"ratio"}],"rows":[]}}`))
+	assert.True(t, errors.Is(err, ErrSchemaDrift))
+	assert.ErrorContains(t, err, "changed columns: count is type/Text")
+
+	// without base type the values are checked
+	_, err = DecodeRows[testRow](results(t, `{"data":{"cols":[{"name":"date"},{"name":"count"},{"name":"ratio"}],"rows":[["2024-05-01","3",null]]}}`))
+	assert.True(t, errors.Is(err, ErrSchemaDrift))
+	assert.ErrorContains(t, err, "row 0, column count: cannot read string as integer")
+
+	_, err = DecodeRows[testRow](results(t, `{"data":{"cols":[{"name":"date"},{"name":"count"},{"name":"ratio"}],"rows":[["2024-05-01",null,null]]}}`))
+	assert.False(t, errors.Is(err, ErrSchemaDrift))
+	assert.ErrorContains(t, err, "row 0, column count: value is null")
+}
+
+// mockMetabaseCard serves the results of testdata/metabase/<card>.json. The fixtures lay the columns out as the
+// positional decoders used to read the cards, the names of the columns they did not read are placeholders.
+func mockMetabaseCard(t *testing.T, card string) *MetabaseDataSource {
+	raw, err := os.ReadFile(filepath.Join("testdata", "metabase", card+".json"))
+	assert.Nil(t, err)
+	m, _ := newMockMetabase(t, string(raw))
+	return m
+}
+
+func TestPumpDataService_DecodeCards(t *testing.T) {
+	ctx := context.Background()
+	req := &model.CommonPumpDataQuery{Address: "abc", Duration: 7, Timezone: "CST"}
+	overview := &model.TraderOverviewInfoV2{
+		TotalNetProfit:     10,
+		ProfitRatio:        2,
+		NetProfitWinRatio:  0.8,
+		TradedTokenCount:   4,
+		AvgSolCostPerToken: 1.25,
+		TotalCost:          5,
+		AvgTipPerToken:     0.02,
+		AvgFeePerToken:     0.01,
+		TokenCreateCount:   2,
+	}
+	tests := []struct {
+		card  string
+		query func(pd *PumpDataService) (any, error)
+		want  any
+	}{
+		{CardDailyLaunchedTokens, func(pd *PumpDataService) (any, error) { return pd.NewTokens(ctx, req) }, &model.NewTokensVO{Rows: []*model.DailyTokensData{
+			{Date: "2024-09-20T00:00:00+08:00", TotalCount: 20, P2RCount: 5, P2RRatio: 0.25},
+			{Date: "2024-09-21T00:00:00+08:00", TotalCount: 10, P2RCount: 1, P2RRatio: 0.1},
+		}}},
+		{CardLaunchedTokenTimeDistribution, func(pd *PumpDataService) (any, error) { return pd.LaunchTime(ctx, req) }, &model.LaunchTimeVO{Rows: []*model.LaunchTimeData{
+			{TimeRange: "[00:00~00:30)", LaunchedCount: 766},
+			{TimeRange: "[00:30~01:00)", LaunchedCount: 686},
+		}}},
+		{CardDailyTradeCounts, func(pd *PumpDataService) (any, error) { return pd.Transactions(ctx, req) }, &model.TransactionsVO{Rows: []*model.TradeCountData{
+			{Date: "2024-09-13T00:00:00+08:00", TradeCount: 1462284},
+		}}},
+		{CardTopTraders, func(pd *PumpDataService) (any, error) { return pd.TopTraders(ctx, req) }, &model.TopTradersVO{Rows: []*model.TopTraderData{
+			{Trader: "74tYkMYmwnmi44PQo6L6QpkxmdNTdX5AZaiKMrMAncwW", TotalNetProfit: 12.5, NetProfitWinRatio: 0.75, GrossProfitWinRatio: 0.8, TotalTxCount: 1200},
+		}}},
+		{CardTraderOverview, func(pd *PumpDataService) (any, error) { return pd.TraderOverview(ctx, req) }, &model.TraderOverviewVO{Info: overview}},
+		{CardTraderOverviewV2, func(pd *PumpDataService) (any, error) { return pd.TraderOverviewV2(ctx, req) }, &model.TraderOverviewVO{Info: overview}},
+		{CardTraderProfitDistribution, func(pd *PumpDataService) (any, error) { return pd.TraderProfit(ctx, req) }, &model.TraderProfitVO{Rows: []*model.TraderProfitData{
+			{Date: "2024-09-15T00:00:00+08:00", NetProfit: -0.110840788, GrossProfit: -0.099578228},
+		}}},
+		{CardTraderProfitTokenDistribution, func(pd *PumpDataService) (any, error) { return pd.TraderProfitDistribution(ctx, req) }, &model.ProfitDistributionVO{Rows: []*model.ProfitDistributionData{
+			{ProfitMarginBucket: "< -100%", TokenCount: 67},
+			{ProfitMarginBucket: "-100% ~ -50%", TokenCount: 1},
+		}}},
+		{CardTraderTxTimeDistribution, func(pd *PumpDataService) (any, error) { return pd.TraderTrades(ctx, req) }, &model.TraderTradesVO{Rows: []*model.TraderTradesData{
+			{TimeRange: "[00:00~00:30)", TxCount: 4},
+			{TimeRange: "[00:30~01:00)", TxCount: 0},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.card, func(t *testing.T) {
+			m := mockMetabaseCard(t, tt.card)
+			res, err := tt.query(NewPumpDataService(m.baseComponent, m))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestPumpDataService_DecodeColumnNames(t *testing.T) {
+	ctx := context.Background()
+	req := &model.CommonPumpDataQuery{Address: "abc"}
+	m, _ := newMockMetabase(t, `{"data":{
+		"cols":[{"name":"Created_Tokens","base_type":"type/BigInteger"},{"name":"total_net_profit"},{"name":"net_profit_win_ratio"},
+			{"name":"traded_token_count"},{"name":"avg_sol_cost_per_token"},{"name":"avg_fee_per_token"},{"name":"avg_tip_per_token"}],
+		"rows":[[2,10,0.8,4,1.25,0.01,0.02]]}}`)
+	pd := NewPumpDataService(m.baseComponent, m)
+
+	// the v2 card naming a column otherwise is a schema drift until the name is mapped
+	_, err := pd.TraderOverviewV2(ctx, req)
+	assert.True(t, errors.Is(err, ErrSchemaDrift))
+	assert.ErrorContains(t, err, "missing columns: created_token_count")
+
+	card := m.cards[CardTraderOverviewV2]
+	card.ColumnNames = map[string]string{"created_tokens": "created_token_count"}
+	m.cards[CardTraderOverviewV2] = card
+	pd = NewPumpDataService(m.baseComponent, m)
+	res, err := pd.TraderOverviewV2(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), res.Info.TokenCreateCount)
+}
+
+func TestPumpDataService_DecodeErrors(t *testing.T) {
+	m, _ := newMockMetabase(t, `{"data":{
+		"cols":[{"name":"day"},{"name":"net_profit"},{"name":"gross_profit"}],
+		"rows":[["2024-09-15T00:00:00+08:00","-0.11",-0.09]]}}`)
+	pd := NewPumpDataService(m.baseComponent, m)
+
+	// the error names the column of the value
+	_, err := pd.TraderProfit(context.Background(), &model.CommonPumpDataQuery{Address: "abc"})
+	assert.ErrorContains(t, err, "row 0, column net_profit: cannot read string as number")
+}
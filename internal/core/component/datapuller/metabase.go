@@ -3,25 +3,61 @@ package datapuller
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/pkg/errors"
+
 	"github.com/wyt-labs/wyt-core/internal/core/component/datapuller/model"
 	"github.com/wyt-labs/wyt-core/internal/core/component/httpclient"
 	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
 	"github.com/wyt-labs/wyt-core/pkg/basic"
 	"github.com/wyt-labs/wyt-core/pkg/cache"
 )
 
 func init() {
-	basic.RegisterComponents(NewMetabaseDataSource, NewPumpDataService)
+	basic.RegisterComponents(NewMetabaseDataSource, NewAnalyticsSource, NewPumpDataService)
+}
+
+// logical names of the pump data queries, the metabase cards (see config.Backends.MetabaseCards) and the
+// sql files are registered under them
+const (
+	CardDailyLaunchedTokens           = "daily_launched_tokens"
+	CardLaunchedTokenTimeDistribution = "launched_token_time_distribution"
+	CardDailyTradeCounts              = "daily_trade_counts"
+	CardTraderOverview                = "trader_overview"
+	CardTraderOverviewV2              = "trader_overview_v2"
+	CardTraderTxTimeDistribution      = "trader_tx_time_distribution"
+	CardTraderProfitTokenDistribution = "trader_profit_token_distribution"
+	CardTraderProfitDistribution      = "trader_profit_distribution"
+	CardTopTraders                    = "top_traders"
+)
+
+// cardNames are the queries every analytics source has to run
+var cardNames = []string{
+	CardDailyLaunchedTokens,
+	CardLaunchedTokenTimeDistribution,
+	CardDailyTradeCounts,
+	CardTraderOverview,
+	CardTraderOverviewV2,
+	CardTraderTxTimeDistribution,
+	CardTraderProfitTokenDistribution,
+	CardTraderProfitDistribution,
+	CardTopTraders,
 }
 
 type MetabaseDataSource struct {
 	baseComponent *base.Component
 	apiClient     *httpclient.Client
+	cards         map[string]config.MetabaseCard
 }
 
 func NewMetabaseDataSource(baseComponent *base.Component) (*MetabaseDataSource, error) {
+	cards := baseComponent.Config.Backends.MetabaseCards
+	if err := ValidateMetabaseCards(cards); err != nil {
+		return nil, errors.Wrap(err, "invalid metabase cards")
+	}
 	client, err := httpclient.NewHttpClient(
 		httpclient.WithBaseURL(baseComponent.Config.Backends.MetabaseURL),
 	)
@@ -32,6 +68,7 @@ func NewMetabaseDataSource(baseComponent *base.Component) (*MetabaseDataSource,
 	return &MetabaseDataSource{
 		baseComponent: baseComponent,
 		apiClient:     client,
+		cards:         cards,
 	}, nil
 }
 
@@ -64,75 +101,98 @@ func (m *MetabaseDataSource) Auth(username, pwd string) (string, error) {
 	return token, nil
 }
 
-// DailyLaunchedTokenInfo
-// 过去duration天, 代币创建以及上Raydium的数量,UTC或者CST时间
-func (m *MetabaseDataSource) DailyLaunchedTokenInfo(duration int, timezone string) (*model.DatasetQueryResults, error) {
-	token, err := m.Auth(
-		m.baseComponent.Config.Backends.MetabaseUserName,
-		m.baseComponent.Config.Backends.MetabasePassword,
-	)
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to auth metabase")
-		return nil, err
-	}
-	if duration < 7 {
-		duration = 7
-	}
-	if timezone == "" {
-		timezone = "UTC"
+// ValidateMetabaseCards checks that every card of the pump data queries is registered with a card id
+// and the parameter ids of all its template tags
+func ValidateMetabaseCards(cards map[string]config.MetabaseCard) error {
+	for _, name := range cardNames {
+		card, ok := cards[name]
+		if !ok {
+			return errors.Errorf("card %s is missing", name)
+		}
+		if len(card.Variants) == 0 {
+			return errors.Errorf("card %s has no variant", name)
+		}
+		if card.DefaultVariant != "" {
+			if _, ok := card.Variants[strings.ToLower(card.DefaultVariant)]; !ok {
+				return errors.Errorf("card %s: default variant %s is missing", name, card.DefaultVariant)
+			}
+		}
+		for variantName, variant := range card.Variants {
+			if variant.CardID <= 0 {
+				return errors.Errorf("card %s[%s]: card_id is missing", name, variantName)
+			}
+			for _, param := range card.Params {
+				if param.Name == "" || param.Type == "" {
+					return errors.Errorf("card %s: parameters need a name and a type", name)
+				}
+				if variant.ParamIDs[param.Name] == "" {
+					return errors.Errorf("card %s[%s]: id of parameter %s is missing", name, variantName, param.Name)
+				}
+			}
+		}
 	}
-	headers := map[string]string{
-		"Content-Type":       "application/json",
-		"X-Metabase-Session": token,
+	return nil
+}
+
+type metabaseQueryParam struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Value  any    `json:"value"`
+	Target []any  `json:"target"`
+}
+
+type metabaseQuery struct {
+	IgnoreCache       bool                  `json:"ignore_cache"`
+	CollectionPreview bool                  `json:"collection_preview"`
+	Parameters        []*metabaseQueryParam `json:"parameters"`
+}
+
+// buildQuery fills the parameters of the card variant with the values of its template tags
+func buildQuery(name string, card config.MetabaseCard, variant config.MetabaseCardVariant, params map[string]any) (*metabaseQuery, error) {
+	query := &metabaseQuery{}
+	for _, param := range card.Params {
+		value, ok := params[param.Name]
+		if !ok {
+			return nil, errors.Errorf("metabase card %s: parameter %s is missing", name, param.Name)
+		}
+		var paramValue any = fmt.Sprint(value)
+		if strings.HasPrefix(param.Type, "number") {
+			// numbers are sent as a list
+			paramValue = []string{fmt.Sprint(value)}
+		}
+		query.Parameters = append(query.Parameters, &metabaseQueryParam{
+			ID:     variant.ParamIDs[param.Name],
+			Type:   param.Type,
+			Value:  paramValue,
+			Target: []any{"variable", []string{"template-tag", param.Name}},
+		})
+	}
+	return query, nil
+}
+
+// Query runs the card registered as name, variant picks the card of a timezone or a period (the default
+// one when empty) and params are the values of its template tags
+func (m *MetabaseDataSource) Query(name string, variant string, params map[string]any) (*model.DatasetQueryResults, error) {
+	card, ok := m.cards[name]
+	if !ok {
+		return nil, errors.Errorf("unknown metabase card: %s", name)
 	}
-	var id string
-	if timezone == "UTC" {
-		id = "bb79b15e-1245-4166-96f0-c6baaa71567c"
-	} else if timezone == "CST" {
-		id = "a3066d17-b2fc-4d12-bf4a-92f81ab71d66"
+	if variant == "" {
+		variant = card.DefaultVariant
 	}
-	plStr := fmt.Sprintf(`
-	{
-		"ignore_cache": false,
-		"collection_preview": false,
-		"parameters": [
-			{
-				"id": "%s",
-				"type": "number/=",
-				"value": [
-					"%d"
-				],
-			"target": [
-				"variable",
-				[
-					"template-tag",
-					"days"
-				]
-			]
-			}
-		]
-	}`, id, duration)
-	payload := strings.NewReader(plStr)
-	var resp []byte
-	if timezone == "UTC" || timezone == "" {
-		resp, err = m.apiClient.PostV2("/api/card/106/query", payload, headers, nil)
-	} else if timezone == "CST" {
-		resp, err = m.apiClient.PostV2("/api/card/115/query", payload, headers, nil)
+	cardVariant, ok := card.Variants[strings.ToLower(variant)]
+	if !ok {
+		return nil, errors.Errorf("metabase card %s has no variant %s", name, variant)
 	}
+	query, err := buildQuery(name, card, cardVariant, params)
 	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to create token")
 		return nil, err
 	}
-	var ret model.DatasetQueryResults
-	if err := json.Unmarshal(resp, &ret); err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to unmarshal create token response")
+	payload, err := json.Marshal(query)
+	if err != nil {
 		return nil, err
 	}
-	return &ret, nil
-}
 
-// 过去duration天,新Token发射的时间分布（按半小时）,UTC或者CST时间
-func (m *MetabaseDataSource) LaunchedTokenTimeDistribution(duration int, timezone string) (*model.DatasetQueryResults, error) {
 	token, err := m.Auth(
 		m.baseComponent.Config.Backends.MetabaseUserName,
 		m.baseComponent.Config.Backends.MetabasePassword,
@@ -141,542 +201,129 @@ func (m *MetabaseDataSource) LaunchedTokenTimeDistribution(duration int, timezon
 		m.baseComponent.Logger.WithField("err", err).Error("failed to auth metabase")
 		return nil, err
 	}
-	if duration < 7 {
-		duration = 7
-	}
-	if timezone == "" {
-		timezone = "UTC"
-	}
 	headers := map[string]string{
 		"Content-Type":       "application/json",
 		"X-Metabase-Session": token,
 	}
-	var id string
-	if timezone == "UTC" {
-		id = "4afba805-4047-477a-b1aa-399e06f5f5e4"
-	} else if timezone == "CST" {
-		id = "a9330250-fca8-46b2-88ed-0fef40ef981e"
-	}
-	plStr := fmt.Sprintf(`
-	{
-		"ignore_cache": false,
-		"collection_preview": false,
-		"parameters": [
-			{
-				"id": "%s",
-				"type": "number/=",
-				"value": [
-					"%d"
-				],
-			"target": [
-				"variable",
-				[
-					"template-tag",
-					"days"
-				]
-			]
-			}
-		]
-	}`, id, duration)
-	payload := strings.NewReader(plStr)
-	var resp []byte
-	if timezone == "UTC" || timezone == "" {
-		resp, err = m.apiClient.PostV2("/api/card/107/query", payload, headers, nil)
-	} else if timezone == "CST" {
-		resp, err = m.apiClient.PostV2("/api/card/114/query", payload, headers, nil)
-	}
+	logger := m.baseComponent.Logger.WithField("card", name).WithField("card_id", cardVariant.CardID)
+	resp, err := m.apiClient.PostV2(fmt.Sprintf("/api/card/%d/query", cardVariant.CardID), strings.NewReader(string(payload)), headers, nil)
 	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to launched token time distribution")
+		logger.WithField("err", err).Error("failed to query metabase card")
 		return nil, err
 	}
 	var ret model.DatasetQueryResults
 	if err := json.Unmarshal(resp, &ret); err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to unmarshal launched token time distribution response")
+		logger.WithField("err", err).Error("failed to unmarshal metabase card response")
+		return nil, err
+	}
+	renameColumns(card.ColumnNames, &ret)
+	if err := checkColumns(name, card.Columns, &ret); err != nil {
+		logger.WithField("err", err).Error("metabase card returned unexpected columns")
 		return nil, err
 	}
 	return &ret, nil
 }
 
-// 过去duration天,每日交易量,UTC或者CST时间
-func (m *MetabaseDataSource) DailyTradeCounts(duration int, timezone string) (*model.DatasetQueryResults, error) {
-	token, err := m.Auth(
-		m.baseComponent.Config.Backends.MetabaseUserName,
-		m.baseComponent.Config.Backends.MetabasePassword,
-	)
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to auth metabase")
-		return nil, err
-	}
-	if duration < 7 {
-		duration = 7
-	}
-	if timezone == "" {
-		timezone = "UTC"
+// renameColumns gives the columns of the card the names the rows are decoded with
+func renameColumns(names map[string]string, ret *model.DatasetQueryResults) {
+	if len(names) == 0 {
+		return
+	}
+	lower := make(map[string]string, len(names))
+	for from, to := range names {
+		lower[strings.ToLower(from)] = to
+	}
+	for i, col := range ret.Data.Cols {
+		if to, ok := lower[strings.ToLower(col.Name)]; ok {
+			ret.Data.Cols[i].Name = to
+		}
 	}
-	headers := map[string]string{
-		"Content-Type":       "application/json",
-		"X-Metabase-Session": token,
+}
+
+// checkColumns reports the expected columns missing from the results as ErrSchemaDrift
+func checkColumns(name string, expected []string, ret *model.DatasetQueryResults) error {
+	var missing []string
+	for _, col := range expected {
+		if !slices.ContainsFunc(ret.Data.Cols, func(item model.DatasetQueryResultsCol) bool {
+			return item.Name == col
+		}) {
+			missing = append(missing, col)
+		}
 	}
-	var id string
-	if timezone == "UTC" {
-		id = "59c5518a-8697-4d92-8531-4ba301e6ab85"
-	} else if timezone == "CST" {
-		id = "59c5518a-8697-4d92-8531-4ba301e6ab85"
+	if len(missing) != 0 {
+		return errors.Wrapf(ErrSchemaDrift, "metabase card %s is missing columns: %s", name, strings.Join(missing, ", "))
 	}
-	plStr := fmt.Sprintf(`
-	{
-		"ignore_cache": false,
-		"collection_preview": false,
-		"parameters": [
-			{
-				"id": "%s",
-				"type": "number/=",
-				"value": [
-					"%d"
-				],
-			"target": [
-				"variable",
-				[
-					"template-tag",
-					"days"
-				]
-			]
-			}
-		]
-	}`, id, duration)
-	payload := strings.NewReader(plStr)
-	var resp []byte
-	if timezone == "UTC" || timezone == "" {
-		resp, err = m.apiClient.PostV2("/api/card/108/query", payload, headers, nil)
-	} else if timezone == "CST" {
-		resp, err = m.apiClient.PostV2("/api/card/116/query", payload, headers, nil)
+	return nil
+}
+
+// timezoneVariant is the variant of the cards per timezone, the default one when empty
+func timezoneVariant(timezone string) string {
+	return strings.ToLower(timezone)
+}
+
+// DailyLaunchedTokenInfo
+// 过去duration天, 代币创建以及上Raydium的数量,UTC或者CST时间
+func (m *MetabaseDataSource) DailyLaunchedTokenInfo(duration int, timezone string) (*model.DatasetQueryResults, error) {
+	if duration < 7 {
+		duration = 7
 	}
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to daily trade counts")
-		return nil, err
+	return m.Query(CardDailyLaunchedTokens, timezoneVariant(timezone), map[string]any{"days": duration})
+}
+
+// 过去duration天,新Token发射的时间分布（按半小时）,UTC或者CST时间
+func (m *MetabaseDataSource) LaunchedTokenTimeDistribution(duration int, timezone string) (*model.DatasetQueryResults, error) {
+	if duration < 7 {
+		duration = 7
 	}
-	var ret model.DatasetQueryResults
-	if err := json.Unmarshal(resp, &ret); err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to unmarshal daily trade counts response")
-		return nil, err
+	return m.Query(CardLaunchedTokenTimeDistribution, timezoneVariant(timezone), map[string]any{"days": duration})
+}
+
+// 过去duration天,每日交易量,UTC或者CST时间
+func (m *MetabaseDataSource) DailyTradeCounts(duration int, timezone string) (*model.DatasetQueryResults, error) {
+	if duration < 7 {
+		duration = 7
 	}
-	return &ret, nil
+	return m.Query(CardDailyTradeCounts, timezoneVariant(timezone), map[string]any{"days": duration})
 }
 
 // trader总览信息, 74tYkMYmwnmi44PQo6L6QpkxmdNTdX5AZaiKMrMAncwW
 func (m *MetabaseDataSource) TraderOverview(trader string) (*model.DatasetQueryResults, error) {
-	token, err := m.Auth(
-		m.baseComponent.Config.Backends.MetabaseUserName,
-		m.baseComponent.Config.Backends.MetabasePassword,
-	)
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to auth metabase")
-		return nil, err
-	}
-	headers := map[string]string{
-		"Content-Type":       "application/json",
-		"X-Metabase-Session": token,
-	}
-	id := "cd81bc1d-4a11-4375-84ea-e018c5d9ddef"
-	plStr := fmt.Sprintf(`
-		{
-			"ignore_cache": false,
-			"collection_preview": false,
-			"parameters": [
-				{
-					"id": "%s",
-					"type": "category",
-					"value": "%s",
-					"target": [
-						"variable",
-						[
-							"template-tag",
-							"trader"
-						]
-					]
-				}
-			]
-		}`, id, trader)
-	payload := strings.NewReader(plStr)
-	resp, err := m.apiClient.PostV2("/api/card/110/query", payload, headers, nil)
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to trader overview")
-		return nil, err
-	}
-	var ret model.DatasetQueryResults
-	if err := json.Unmarshal(resp, &ret); err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to unmarshal trader overview response")
-		return nil, err
-	}
-	return &ret, nil
+	return m.Query(CardTraderOverview, "", map[string]any{"trader": trader})
 }
 
 // trader V2总览信息, 9YqDWbEpKME1pjM91FYDMYfuTbFCqmWT8GLitfe19Ngr
 func (m *MetabaseDataSource) TraderOverviewV2(trader string, tz string, days int) (*model.DatasetQueryResults, error) {
-	token, err := m.Auth(
-		m.baseComponent.Config.Backends.MetabaseUserName,
-		m.baseComponent.Config.Backends.MetabasePassword,
-	)
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to auth metabase")
-		return nil, err
-	}
-	headers := map[string]string{
-		"Content-Type":       "application/json",
-		"X-Metabase-Session": token,
-	}
 	if tz == "" {
 		tz = "CST"
 	}
-	plStr := fmt.Sprintf(`{
-	"ignore_cache": false,
-	"collection_preview": false,
-	"parameters": [
-		{
-		"id": "3332d084-956d-489e-87f2-e634c4d0e42d",
-		"type": "category",
-		"value": "%s",
-		"target": [
-			"variable",
-			[
-			"template-tag",
-			"trader"
-			]
-		]
-		},
-		{
-		"id": "d988ac7f-ecb0-40d7-8123-5410e5598ebc",
-		"type": "category",
-		"value": "%s",
-		"target": [
-			"variable",
-			[
-			"template-tag",
-			"tz"
-			]
-		]
-		},
-		{
-		"id": "a9218770-8bb2-474d-ab33-11ffdc7e2052",
-		"type": "number/=",
-		"value": [
-			"%d"
-		],
-		"target": [
-			"variable",
-			[
-			"template-tag",
-			"days"
-			]
-		]
-		}
-	]
-	}`, trader, tz, days)
-	payload := strings.NewReader(plStr)
-	resp, err := m.apiClient.PostV2("/api/card/140/query", payload, headers, nil)
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to trader overview")
-		return nil, err
-	}
-	var ret model.DatasetQueryResults
-	if err := json.Unmarshal(resp, &ret); err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to unmarshal trader overview response")
-		return nil, err
-	}
-	return &ret, nil
+	// the timezone is a parameter, not a variant
+	return m.Query(CardTraderOverviewV2, "", map[string]any{"trader": trader, "tz": tz, "days": days})
 }
 
 // Trader出手时间分布, 74tYkMYmwnmi44PQo6L6QpkxmdNTdX5AZaiKMrMAncwW, EPCwxro6Nf3PEtMxR638qwyyccH7F7dbH2RuXY6HVN8s
 // 过去duration天, trader的交易时间分布, UTC或者CST时间
 func (m *MetabaseDataSource) TraderTxTimeDistribution(trader string, duration int, timezone string) (*model.DatasetQueryResults, error) {
-	token, err := m.Auth(
-		m.baseComponent.Config.Backends.MetabaseUserName,
-		m.baseComponent.Config.Backends.MetabasePassword,
-	)
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to auth metabase")
-		return nil, err
-	}
-	headers := map[string]string{
-		"Content-Type":       "application/json",
-		"X-Metabase-Session": token,
-	}
-	var id string
-	var id2 string
-	if timezone == "UTC" {
-		id = "b8f94534-7734-4e40-b8e4-212e0d876cda"
-		id2 = "e164924c-5361-411d-82de-34de55cd3e67"
-	} else if timezone == "CST" {
-		id = "b8f94534-7734-4e40-b8e4-212e0d876cda"
-		id2 = "e164924c-5361-411d-82de-34de55cd3e67"
-	}
-	plStr := fmt.Sprintf(`
-	{
-		"ignore_cache": false,
-		"collection_preview": false,
-		"parameters": [
-			{
-			"id": "%s",
-			"type": "category",
-			"value": "%s",
-			"target": [
-				"variable",
-				[
-				"template-tag",
-				"trader"
-				]
-			]
-			},
-			{
-			"id": "%s",
-			"type": "number/=",
-			"value": [
-				"%d"
-			],
-			"target": [
-				"variable",
-				[
-				"template-tag",
-				"days"
-				]
-			]
-			}
-		]
-	}`, id, trader, id2, duration)
-	payload := strings.NewReader(plStr)
-	var resp []byte
-	if timezone == "UTC" || timezone == "" {
-		resp, err = m.apiClient.PostV2("/api/card/112/query", payload, headers, nil)
-	} else if timezone == "CST" {
-		resp, err = m.apiClient.PostV2("/api/card/120/query", payload, headers, nil)
-	}
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to launched token time distribution")
-		return nil, err
-	}
-	var ret model.DatasetQueryResults
-	if err := json.Unmarshal(resp, &ret); err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to unmarshal launched token time distribution response")
-		return nil, err
-	}
-	return &ret, nil
+	return m.Query(CardTraderTxTimeDistribution, timezoneVariant(timezone), map[string]any{"trader": trader, "days": duration})
 }
 
 // Trader利润分布, 74tYkMYmwnmi44PQo6L6QpkxmdNTdX5AZaiKMrMAncwW, EPCwxro6Nf3PEtMxR638qwyyccH7F7dbH2RuXY6HVN8s
 // 过去duration天, Trader利润分布, UTC或者CST时间
 func (m *MetabaseDataSource) TraderProfitTokenDistribution(trader string, duration int, timezone string) (*model.DatasetQueryResults, error) {
-	token, err := m.Auth(
-		m.baseComponent.Config.Backends.MetabaseUserName,
-		m.baseComponent.Config.Backends.MetabasePassword,
-	)
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to auth metabase")
-		return nil, err
-	}
-	headers := map[string]string{
-		"Content-Type":       "application/json",
-		"X-Metabase-Session": token,
-	}
-	var id string
-	var id2 string
-	if timezone == "UTC" {
-		id = "586e6393-9ff0-4eac-9918-d10984d441c7"
-		id2 = "43a557d5-fbc8-4cd7-b8e4-101564b47695"
-	} else if timezone == "CST" {
-		id = "596faff0-6ef4-42cf-be21-b854f2db58af"
-		id2 = "208313ef-a20b-427c-a4ea-9d154e053aed"
-	}
-	plStr := fmt.Sprintf(`
-	{
-		"ignore_cache": false,
-		"collection_preview": false,
-		"parameters": [
-			{
-			"id": "%s",
-			"type": "category",
-			"value": "%s",
-			"target": [
-				"variable",
-				[
-				"template-tag",
-				"trader"
-				]
-			]
-			},
-			{
-			"id": "%s",
-			"type": "number/=",
-			"value": [
-				"%d"
-			],
-			"target": [
-				"variable",
-				[
-				"template-tag",
-				"days"
-				]
-			]
-			}
-		]
-	}`, id, trader, id2, duration)
-	payload := strings.NewReader(plStr)
-	var resp []byte
-	if timezone == "UTC" || timezone == "" {
-		resp, err = m.apiClient.PostV2("/api/card/113/query", payload, headers, nil)
-	} else if timezone == "CST" {
-		resp, err = m.apiClient.PostV2("/api/card/119/query", payload, headers, nil)
-	}
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to get trader profit token distribution")
-		return nil, err
-	}
-	var ret model.DatasetQueryResults
-	if err := json.Unmarshal(resp, &ret); err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to unmarshal trader profit token distribution response")
-		return nil, err
-	}
-	return &ret, nil
+	return m.Query(CardTraderProfitTokenDistribution, timezoneVariant(timezone), map[string]any{"trader": trader, "days": duration})
 }
 
 // Trader近7日收益分布, 74tYkMYmwnmi44PQo6L6QpkxmdNTdX5AZaiKMrMAncwW, EPCwxro6Nf3PEtMxR638qwyyccH7F7dbH2RuXY6HVN8s, 8i57XsS3E4iuw2qy2cPbKDWnW4pwx6yaBc7N7UQzG3MJ
 // 过去duration天, Trader近7日收益分布, UTC或者CST时间
 func (m *MetabaseDataSource) TraderProfitDistribution(trader string, duration int, timezone string) (*model.DatasetQueryResults, error) {
-	token, err := m.Auth(
-		m.baseComponent.Config.Backends.MetabaseUserName,
-		m.baseComponent.Config.Backends.MetabasePassword,
-	)
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to auth metabase")
-		return nil, err
-	}
-	headers := map[string]string{
-		"Content-Type":       "application/json",
-		"X-Metabase-Session": token,
-	}
-	if timezone == "" {
-		timezone = "CST"
-	}
-	var id string
-	var id2 string
-	if timezone == "UTC" {
-		id = "90848092-e63d-4b0a-8e07-265fd413e2f2"
-		id2 = "a8098d1f-6d88-4e64-8b3f-4ee5a0e92994"
-	} else if timezone == "CST" {
-		id = "f4bba41b-2de9-4fa1-9651-96d696aa221e"
-		id2 = "158050e1-75eb-4729-8567-1214ba6ff071"
-	}
-	plStr := fmt.Sprintf(`
-	{
-		"ignore_cache": false,
-		"collection_preview": false,
-		"parameters": [
-			{
-				"id": "%s",
-				"type": "category",
-				"value": "%s",
-				"target": [
-					"variable",
-					[
-						"template-tag",
-						"trader"
-					]
-				]
-			},
-			{
-				"id": "%s",
-				"type": "number/=",
-				"value": [
-					"%d"
-				],
-				"target": [
-					"variable",
-					[
-						"template-tag",
-						"days"
-					]
-				]
-			}
-		]
-	}`, id, trader, id2, duration)
-	payload := strings.NewReader(plStr)
-	var resp []byte
-	if timezone == "UTC" || timezone == "" {
-		resp, err = m.apiClient.PostV2("/api/card/111/query", payload, headers, nil)
-	} else if timezone == "CST" {
-		resp, err = m.apiClient.PostV2("/api/card/118/query", payload, headers, nil)
-	}
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to get trader profit distribution")
-		return nil, err
-	}
-	var ret model.DatasetQueryResults
-	if err := json.Unmarshal(resp, &ret); err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to unmarshal trader profit distribution response")
-		return nil, err
-	}
-	return &ret, nil
+	return m.Query(CardTraderProfitDistribution, timezoneVariant(timezone), map[string]any{"trader": trader, "days": duration})
 }
 
 // Top Traders
 func (m *MetabaseDataSource) TopTrader(duration int, winRatio float32) (*model.DatasetQueryResults, error) {
-	token, err := m.Auth(
-		m.baseComponent.Config.Backends.MetabaseUserName,
-		m.baseComponent.Config.Backends.MetabasePassword,
-	)
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to auth metabase")
-		return nil, err
+	// 1 天 unless 7 or 30
+	variant := "1d"
+	if duration == 7 || duration == 30 {
+		variant = fmt.Sprintf("%dd", duration)
 	}
-	headers := map[string]string{
-		"Content-Type":       "application/json",
-		"X-Metabase-Session": token,
-	}
-	var id string
-	if duration == 30 {
-		id = "04f74b13-e4df-4836-b94b-72c1170dffcd"
-	} else if duration == 7 {
-		id = "6f67544d-f340-4dd4-91ce-56a225d95a25"
-	} else { // 1 天
-		id = "ec83280c-ba44-4968-9899-4a37d4f8a318"
-	}
-	plStr := fmt.Sprintf(`
-	{
-		"ignore_cache": false,
-		"collection_preview": false,
-		"parameters": [
-			{
-			"id": "%s",
-			"type": "number/=",
-			"value": [
-				"%v"
-			],
-			"target": [
-				"variable",
-				[
-					"template-tag",
-					"win_ratio"
-				]
-			]
-			}
-		]
-	}`, id, winRatio)
-	payload := strings.NewReader(plStr)
-	var resp []byte
-	if duration == 30 {
-		resp, err = m.apiClient.PostV2("/api/card/125/query", payload, headers, nil)
-	} else if duration == 7 {
-		resp, err = m.apiClient.PostV2("/api/card/124/query", payload, headers, nil)
-	} else { // 1 天
-		resp, err = m.apiClient.PostV2("/api/card/131/query", payload, headers, nil)
-	}
-	if err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to get trader profit distribution")
-		return nil, err
-	}
-	var ret model.DatasetQueryResults
-	if err := json.Unmarshal(resp, &ret); err != nil {
-		m.baseComponent.Logger.WithField("err", err).Error("failed to unmarshal trader profit distribution response")
-		return nil, err
-	}
-	return &ret, nil
+	return m.Query(CardTopTraders, variant, map[string]any{"win_ratio": winRatio})
 }
@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
 )
 
 func TestMetabaseDataSource_Auth(t *testing.T) {
@@ -222,3 +225,91 @@ func TestMetabaseDataSource_TopTrader(t *testing.T) {
 func TestMetabaseDataSource_Common(t *testing.T) {
 
 }
+
+// newMockMetabase serves the session and card apis, the queries are recorded by card path
+func newMockMetabase(t *testing.T, rows string) (*MetabaseDataSource, map[string]*metabaseQuery) {
+	queries := make(map[string]*metabaseQuery)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/session" {
+			_, _ = io.WriteString(w, `{"id":"session"}`)
+			return
+		}
+		assert.Equal(t, "session", r.Header.Get("X-Metabase-Session"))
+		query := &metabaseQuery{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(query))
+		queries[r.URL.Path] = query
+		_, _ = io.WriteString(w, rows)
+	}))
+	t.Cleanup(server.Close)
+
+	baseComponent := base.NewMockBaseComponent(t)
+	baseComponent.Config.Backends.MetabaseURL = server.URL
+	m, err := NewMetabaseDataSource(baseComponent)
+	assert.Nil(t, err)
+	return m, queries
+}
+
+func TestMetabaseDataSource_Query(t *testing.T) {
+	m, queries := newMockMetabase(t, `{"data":{"cols":[{"name":"day"},{"name":"total"}],"rows":[["2024-05-01T00:00:00Z",3]]}}`)
+	// every card gets the same rows, the parameters are checked here and the columns below
+	for name, card := range m.cards {
+		card.Columns = nil
+		m.cards[name] = card
+	}
+
+	ret, err := m.DailyLaunchedTokenInfo(3, "CST")
+	assert.Nil(t, err)
+	assert.Len(t, ret.Data.Rows, 1)
+	query := queries["/api/card/115/query"]
+	assert.NotNil(t, query)
+	assert.Equal(t, &metabaseQueryParam{
+		ID:     "a3066d17-b2fc-4d12-bf4a-92f81ab71d66",
+		Type:   config.MetabaseParamTypeNumber,
+		Value:  []any{"7"},
+		Target: []any{"variable", []any{"template-tag", "days"}},
+	}, query.Parameters[0])
+
+	// default variant, category parameters are plain values
+	_, err = m.TraderProfitDistribution("abc", 7, "")
+	assert.Nil(t, err)
+	query = queries["/api/card/118/query"]
+	assert.NotNil(t, query)
+	assert.Equal(t, "abc", query.Parameters[0].Value)
+
+	_, err = m.TopTrader(30, 0.5)
+	assert.Nil(t, err)
+	assert.Equal(t, []any{"0.5"}, queries["/api/card/125/query"].Parameters[0].Value)
+
+	_, err = m.DailyTradeCounts(7, "PST")
+	assert.ErrorContains(t, err, "has no variant pst")
+
+	// the card was rebuilt with other columns, the columns not decoded do not matter
+	card := m.cards[CardDailyLaunchedTokens]
+	card.Columns = []string{"day", "total_count", "p2r_count"}
+	m.cards[CardDailyLaunchedTokens] = card
+	_, err = m.DailyLaunchedTokenInfo(7, "UTC")
+	assert.True(t, errors.Is(err, ErrSchemaDrift))
+	assert.ErrorContains(t, err, "daily_launched_tokens is missing columns: total_count, p2r_count")
+	card.Columns = []string{"day"}
+	m.cards[CardDailyLaunchedTokens] = card
+	_, err = m.DailyLaunchedTokenInfo(7, "UTC")
+	assert.Nil(t, err)
+}
+
+func TestValidateMetabaseCards(t *testing.T) {
+	assert.Nil(t, ValidateMetabaseCards(config.DefaultMetabaseCards()))
+
+	cards := config.DefaultMetabaseCards()
+	delete(cards, CardTopTraders)
+	assert.ErrorContains(t, ValidateMetabaseCards(cards), "card top_traders is missing")
+
+	cards = config.DefaultMetabaseCards()
+	cards[CardTraderOverviewV2].Variants["all"].ParamIDs["tz"] = ""
+	assert.ErrorContains(t, ValidateMetabaseCards(cards), "id of parameter tz is missing")
+
+	cards = config.DefaultMetabaseCards()
+	card := cards[CardDailyTradeCounts]
+	card.DefaultVariant = "pst"
+	cards[CardDailyTradeCounts] = card
+	assert.ErrorContains(t, ValidateMetabaseCards(cards), "default variant pst is missing")
+}
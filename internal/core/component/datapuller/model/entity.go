@@ -123,6 +123,8 @@ type CommonPumpDataQuery struct {
 
 type NewTokensVO struct {
 	Rows []*DailyTokensData `json:"rows"`
+	// chart of the rows, empty when charts are disabled
+	ChartURL string `json:"chart_url,omitempty"`
 }
 
 type DailyTokensData struct {
@@ -133,7 +135,8 @@ type DailyTokensData struct {
 }
 
 type LaunchTimeVO struct {
-	Rows []*LaunchTimeData `json:"rows"`
+	Rows     []*LaunchTimeData `json:"rows"`
+	ChartURL string            `json:"chart_url,omitempty"`
 }
 
 type LaunchTimeData struct {
@@ -142,7 +145,8 @@ type LaunchTimeData struct {
 }
 
 type TransactionsVO struct {
-	Rows []*TradeCountData `json:"rows"`
+	Rows     []*TradeCountData `json:"rows"`
+	ChartURL string            `json:"chart_url,omitempty"`
 }
 
 type TradeCountData struct {
@@ -249,6 +253,20 @@ type Trader struct {
 	Address string `json:"address"`
 }
 
+type QueryCacheStats struct {
+	Query     string `json:"query"`
+	Hits      uint64 `json:"hits"`
+	StaleHits uint64 `json:"stale_hits"`
+	Misses    uint64 `json:"misses"`
+	Refreshes uint64 `json:"refreshes"`
+	Errors    uint64 `json:"errors"`
+}
+
+type QueryCacheStatsVO struct {
+	Enable  bool               `json:"enable"`
+	Queries []*QueryCacheStats `json:"queries"`
+}
+
 type GetSupportedChainsReq struct {
 	ChainId int `json:"chainId" form:"chainId"`
 }
@@ -11,18 +11,28 @@ import (
 )
 
 type PumpDataService struct {
-	BaseComponent      *base.Component
-	metabaseDataSource *MetabaseDataSource
+	BaseComponent   *base.Component
+	analyticsSource AnalyticsSource
+	cache           *queryCache
 }
 
-func NewPumpDataService(baseComponent *base.Component, metabaseDataSource *MetabaseDataSource) *PumpDataService {
+func NewPumpDataService(baseComponent *base.Component, analyticsSource AnalyticsSource) *PumpDataService {
 	return &PumpDataService{
-		BaseComponent:      baseComponent,
-		metabaseDataSource: metabaseDataSource,
+		BaseComponent:   baseComponent,
+		analyticsSource: analyticsSource,
+		cache:           newQueryCache(baseComponent),
 	}
 }
 
-func (pd *PumpDataService) NewTokens(ctx context.Context, req *model.CommonPumpDataQuery) (*model.NewTokensVO, error) {
+// dailyTokensRow is a row of the daily launched tokens card, day is an RFC 3339 time
+type dailyTokensRow struct {
+	Day        string  `metabase:"day"`
+	TotalCount int64   `metabase:"total_count"`
+	P2RCount   int64   `metabase:"p2r_count"`
+	P2RRatio   float64 `metabase:"p2r_ratio"`
+}
+
+func (pd *PumpDataService) newTokens(ctx context.Context, req *model.CommonPumpDataQuery) (*model.NewTokensVO, error) {
 	if "testdata" == req.Source {
 		return &model.NewTokensVO{
 			Rows: []*model.DailyTokensData{
@@ -41,48 +51,40 @@ func (pd *PumpDataService) NewTokens(ctx context.Context, req *model.CommonPumpD
 			},
 		}, nil
 	}
-	metaRes, err := pd.metabaseDataSource.DailyLaunchedTokenInfo(req.Duration, req.Timezone)
+	metaRes, err := pd.analyticsSource.DailyLaunchedTokenInfo(req.Duration, req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	metaRows, err := decodeCardRows[dailyTokensRow](pd.BaseComponent, CardDailyLaunchedTokens, metaRes)
 	if err != nil {
 		return nil, err
 	}
 
 	res := &model.NewTokensVO{
-		Rows: make([]*model.DailyTokensData, 0),
+		Rows: make([]*model.DailyTokensData, 0, len(metaRows)),
 	}
-	for _, row := range metaRes.Data.Rows {
-		dateStr, ok := row[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid date format: %s", row[0])
-		}
-		date, err := time.Parse(time.RFC3339, dateStr)
+	for _, row := range metaRows {
+		date, err := time.Parse(time.RFC3339, row.Day)
 		if err != nil {
 			return nil, fmt.Errorf("invalid date format: %v", err)
 		}
-		totalCount, ok := row[1].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid total count format: %s", row[1])
-		}
-		p2rCount, ok := row[2].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid p2r count format: %s", row[2])
-		}
-		p2rRatio, ok := row[3].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid p2r ratio format: %s", row[3])
-		}
-		dailyTokensData := &model.DailyTokensData{
+		res.Rows = append(res.Rows, &model.DailyTokensData{
 			Date:       date.Format(time.RFC3339),
-			TotalCount: int64(totalCount),
-			P2RCount:   int64(p2rCount),
-			P2RRatio:   p2rRatio,
-		}
-		res.Rows = append(res.Rows, dailyTokensData)
+			TotalCount: row.TotalCount,
+			P2RCount:   row.P2RCount,
+			P2RRatio:   row.P2RRatio,
+		})
 	}
 
 	return res, nil
 }
 
-func (pd *PumpDataService) LaunchTime(ctx context.Context, req *model.CommonPumpDataQuery) (*model.LaunchTimeVO, error) {
+type launchTimeRow struct {
+	TimeRange     string `metabase:"time_range"`
+	LaunchedCount int64  `metabase:"launched_count"`
+}
+
+func (pd *PumpDataService) launchTime(ctx context.Context, req *model.CommonPumpDataQuery) (*model.LaunchTimeVO, error) {
 	if "testdata" == req.Source {
 		return &model.LaunchTimeVO{
 			Rows: []*model.LaunchTimeData{
@@ -98,35 +100,35 @@ func (pd *PumpDataService) LaunchTime(ctx context.Context, req *model.CommonPump
 		}, nil
 	}
 
-	metaRes, err := pd.metabaseDataSource.LaunchedTokenTimeDistribution(req.Duration, req.Timezone)
+	metaRes, err := pd.analyticsSource.LaunchedTokenTimeDistribution(req.Duration, req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	metaRows, err := decodeCardRows[launchTimeRow](pd.BaseComponent, CardLaunchedTokenTimeDistribution, metaRes)
 	if err != nil {
 		return nil, err
 	}
 
 	res := &model.LaunchTimeVO{
-		Rows: make([]*model.LaunchTimeData, 0),
+		Rows: make([]*model.LaunchTimeData, 0, len(metaRows)),
 	}
-
-	for _, row := range metaRes.Data.Rows {
-		timeRange, ok := row[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid time range format: %s", row[0])
-		}
-		launchedCount, ok := row[1].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid launched count format: %s", row[1])
-		}
-		launchTimeData := &model.LaunchTimeData{
-			TimeRange:     timeRange,
-			LaunchedCount: int64(launchedCount),
-		}
-		res.Rows = append(res.Rows, launchTimeData)
+	for _, row := range metaRows {
+		res.Rows = append(res.Rows, &model.LaunchTimeData{
+			TimeRange:     row.TimeRange,
+			LaunchedCount: row.LaunchedCount,
+		})
 	}
 
 	return res, nil
 }
 
-func (pd *PumpDataService) Transactions(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TransactionsVO, error) {
+// tradeCountRow is a row of the daily trade counts card, day is an RFC 3339 time
+type tradeCountRow struct {
+	Day        string `metabase:"day"`
+	TradeCount int64  `metabase:"trade_count"`
+}
+
+func (pd *PumpDataService) transactions(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TransactionsVO, error) {
 	duration := req.Duration
 	timezone := req.Timezone
 	source := req.Source
@@ -146,36 +148,39 @@ func (pd *PumpDataService) Transactions(ctx context.Context, req *model.CommonPu
 		}, nil
 	}
 
-	results, err := pd.metabaseDataSource.DailyTradeCounts(duration, timezone)
+	results, err := pd.analyticsSource.DailyTradeCounts(duration, timezone)
+	if err != nil {
+		return nil, err
+	}
+	metaRows, err := decodeCardRows[tradeCountRow](pd.BaseComponent, CardDailyTradeCounts, results)
 	if err != nil {
 		return nil, err
 	}
 
-	rows := make([]*model.TradeCountData, len(results.Data.Rows))
-	for i, row := range results.Data.Rows {
-		dateStr, ok := row[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid date format: %s", row[0])
-		}
-		date, err := time.Parse(time.RFC3339, dateStr)
+	rows := make([]*model.TradeCountData, len(metaRows))
+	for i, row := range metaRows {
+		date, err := time.Parse(time.RFC3339, row.Day)
 		if err != nil {
 			return nil, fmt.Errorf("invalid date format: %v", err)
 		}
-		tradeCount, ok := row[1].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid trade count format")
-		}
-
 		rows[i] = &model.TradeCountData{
 			Date:       date.Format(time.RFC3339),
-			TradeCount: int64(tradeCount),
+			TradeCount: row.TradeCount,
 		}
 	}
 
 	return &model.TransactionsVO{Rows: rows}, nil
 }
 
-func (pd *PumpDataService) TopTraders(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TopTradersVO, error) {
+type topTraderRow struct {
+	Trader              string  `metabase:"trader"`
+	TotalNetProfit      float64 `metabase:"total_net_profit"`
+	NetProfitWinRatio   float64 `metabase:"net_profit_win_ratio"`
+	GrossProfitWinRatio float64 `metabase:"gross_profit_win_ratio"`
+	TotalTxCount        int64   `metabase:"total_tx_count"`
+}
+
+func (pd *PumpDataService) topTraders(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TopTradersVO, error) {
 	duration := req.Duration
 	maxWinRate := req.MaxWinRate
 	source := req.Source
@@ -192,44 +197,23 @@ func (pd *PumpDataService) TopTraders(ctx context.Context, req *model.CommonPump
 		return getTopTradersTestData(), nil
 	}
 
-	results, err := pd.metabaseDataSource.TopTrader(duration, float32(maxWinRate))
+	results, err := pd.analyticsSource.TopTrader(duration, float32(maxWinRate))
+	if err != nil {
+		return nil, err
+	}
+	metaRows, err := decodeCardRows[topTraderRow](pd.BaseComponent, CardTopTraders, results)
 	if err != nil {
 		return nil, err
 	}
 
-	rows := make([]*model.TopTraderData, len(results.Data.Rows))
-	for i, row := range results.Data.Rows {
-		trader, ok := row[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid trader format: %v", row[0])
-		}
-
-		totalNetProfit, ok := row[1].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid total net profit format: %v", row[1])
-		}
-
-		netProfitWinRatio, ok := row[2].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid net profit win ratio format: %v", row[2])
-		}
-
-		grossProfitWinRatio, ok := row[3].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid gross profit win ratio format: %v", row[3])
-		}
-
-		totalTxCount, ok := row[4].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid total transaction count format: %v", row[4])
-		}
-
+	rows := make([]*model.TopTraderData, len(metaRows))
+	for i, row := range metaRows {
 		rows[i] = &model.TopTraderData{
-			Trader:              trader,
-			TotalNetProfit:      totalNetProfit,
-			NetProfitWinRatio:   netProfitWinRatio,
-			GrossProfitWinRatio: grossProfitWinRatio,
-			TotalTxCount:        int64(totalTxCount),
+			Trader:              row.Trader,
+			TotalNetProfit:      row.TotalNetProfit,
+			NetProfitWinRatio:   row.NetProfitWinRatio,
+			GrossProfitWinRatio: row.GrossProfitWinRatio,
+			TotalTxCount:        row.TotalTxCount,
 		}
 	}
 
@@ -286,7 +270,7 @@ func getTraderInfoTestData() *model.TraderInfoVO {
 	}
 }
 
-func (pd *PumpDataService) TraderOverview(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderOverviewVO, error) {
+func (pd *PumpDataService) traderOverview(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderOverviewVO, error) {
 	address := req.Address
 	source := req.Source
 
@@ -294,229 +278,80 @@ func (pd *PumpDataService) TraderOverview(ctx context.Context, req *model.Common
 		return getTraderOverviewTestData(), nil
 	}
 
-	metaRes, err := pd.metabaseDataSource.TraderOverview(address)
+	metaRes, err := pd.analyticsSource.TraderOverview(address)
 	if err != nil {
 		return nil, err
 	}
 
-	// Map results to TraderOverviewInfo
+	metaRows, err := decodeCardRows[traderOverviewRow](pd.BaseComponent, CardTraderOverview, metaRes)
+	if err != nil {
+		return nil, err
+	}
 	var res *model.TraderOverviewInfoV2
-	if len(metaRes.Data.Rows) > 0 {
-		metaInfo := metaRes.Data.Rows[0]
-
-		totalNetProfit, ok := metaInfo[0].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid total net profit format: %v", metaInfo[0])
-		}
-
-		netProfitWinRatio, ok := metaInfo[1].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid net profit win ratio format: %v", metaInfo[1])
-		}
-
-		// grossProfitWinRatio, ok := metaInfo[2].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid gross profit win ratio format: %v", metaInfo[2])
-		// }
-
-		tradedTokenCount, ok := metaInfo[3].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid traded token count format: %v", metaInfo[3])
-		}
-
-		// totalTxCount, ok := metaInfo[4].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid total transaction count format: %v", metaInfo[4])
-		// }
-
-		// successTxCount, ok := metaInfo[5].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid success transaction count format: %v", metaInfo[5])
-		// }
-
-		// revertedTxCount, ok := metaInfo[6].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid reverted transaction count format: %v", metaInfo[6])
-		// }
-
-		// tradedTokenCountPercentage, ok := metaInfo[7].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid traded token count percentage format: %v", metaInfo[7])
-		// }
-
-		// snipedTokenCount, ok := metaInfo[8].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid sniped token count format: %v", metaInfo[8])
-		// }
-
-		// snipedTokenCountPercentage, ok := metaInfo[9].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid sniped token count percentage format: %v", metaInfo[9])
-		// }
-
-		// totalGrossProfit, ok := metaInfo[10].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid total gross profit format: %v", metaInfo[10])
-		// }
-
-		avgSolCostPerToken, ok := metaInfo[11].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid average solution cost per token format: %v", metaInfo[11])
-		}
-
-		// totalGasFee, ok := metaInfo[12].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid total gas fee format: %v", metaInfo[12])
-		// }
-
-		// totalTip, ok := metaInfo[13].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid total tip format: %v", metaInfo[13])
-		// }
-
-		// totalCommission, ok := metaInfo[14].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid total commission format: %v", metaInfo[14])
-		// }
-
-		avgFeePerToken, ok := metaInfo[15].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid average fee per token format: %v", metaInfo[15])
-		}
-
-		avgTipPerToken, ok := metaInfo[16].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid average tip per token format: %v", metaInfo[16])
-		}
-
-		// avgBuyCountPerToken, ok := metaInfo[17].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid average buy count per token format: %v", metaInfo[17])
-		// }
-
-		// avgSellCountPerToken, ok := metaInfo[18].(float64)
-		// if !ok {
-		// 	return nil, fmt.Errorf("invalid average sell count per token format: %v", metaInfo[18])
-		// }
-
-		createdTokens, ok := metaInfo[19].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid create token number: %v", metaInfo[19])
-		}
-
-		totalCost := avgSolCostPerToken * tradedTokenCount
-		res = &model.TraderOverviewInfoV2{
-			TotalNetProfit:     totalNetProfit,
-			ProfitRatio:        totalNetProfit / totalCost,
-			NetProfitWinRatio:  netProfitWinRatio,
-			TradedTokenCount:   int64(tradedTokenCount),
-			AvgSolCostPerToken: avgSolCostPerToken,
-			TotalCost:          totalCost,
-			AvgTipPerToken:     avgTipPerToken,
-			AvgFeePerToken:     avgFeePerToken,
-			TokenCreateCount:   int64(createdTokens),
-		}
-
-		// res = &model.TraderOverviewInfo{
-		// 	TotalNetProfit:             totalNetProfit,
-		// 	NetProfitWinRatio:          netProfitWinRatio,
-		// 	GrossProfitWinRatio:        grossProfitWinRatio,
-		// 	TradedTokenCount:           int64(tradedTokenCount),
-		// 	TotalTxCount:               int64(totalTxCount),
-		// 	SuccessTxCount:             int64(successTxCount),
-		// 	RevertedTxCount:            int64(revertedTxCount),
-		// 	TradedTokenCountPercentage: tradedTokenCountPercentage,
-		// 	SnipedTokenCount:           int64(snipedTokenCount),
-		// 	SnipedTokenCountPercentage: snipedTokenCountPercentage,
-		// 	TotalGrossProfit:           totalGrossProfit,
-		// 	AvgSolCostPerToken:         avgSolCostPerToken,
-		// 	TotalGasFee:                totalGasFee,
-		// 	TotalTip:                   totalTip,
-		// 	TotalCommission:            totalCommission,
-		// 	AvgFeePerToken:             avgFeePerToken,
-		// 	AvgTipPerToken:             avgTipPerToken,
-		// 	AvgBuyCountPerToken:        avgBuyCountPerToken,
-		// 	AvgSellCountPerToken:       avgSellCountPerToken,
-		// }
+	if len(metaRows) != 0 {
+		res = metaRows[0].info()
 	}
-
 	return &model.TraderOverviewVO{Info: res}, nil
 }
 
-func (pd *PumpDataService) TraderOverviewV2(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderOverviewVO, error) {
+func (pd *PumpDataService) traderOverviewV2(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderOverviewVO, error) {
 	address := req.Address
 	source := req.Source
 
 	if source == "testdata" {
 		return getTraderOverviewTestData(), nil
 	}
-	if req.Duration == 0 {
-		req.Duration = 7
+	// the request is shared by the queries of the trader detail, it is not changed
+	duration := req.Duration
+	if duration == 0 {
+		duration = 7
 	}
-	if req.Timezone == "" {
-		req.Timezone = "CST"
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "CST"
 	}
 
-	metaRes, err := pd.metabaseDataSource.TraderOverviewV2(address, req.Timezone, req.Duration)
+	metaRes, err := pd.analyticsSource.TraderOverviewV2(address, timezone, duration)
 	if err != nil {
 		return nil, err
 	}
 
-	// Map results to TraderOverviewInfo
+	metaRows, err := decodeCardRows[traderOverviewRow](pd.BaseComponent, CardTraderOverviewV2, metaRes)
+	if err != nil {
+		return nil, err
+	}
 	var res *model.TraderOverviewInfoV2
-	if len(metaRes.Data.Rows) > 0 {
-		metaInfo := metaRes.Data.Rows[0]
-
-		totalNetProfit, ok := metaInfo[0].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid total net profit format: %v", metaInfo[0])
-		}
-
-		netProfitWinRatio, ok := metaInfo[3].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid net profit win ratio format: %v", metaInfo[1])
-		}
-
-		tradedTokenCount, ok := metaInfo[5].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid traded token count format: %v", metaInfo[3])
-		}
-
-		avgSolCostPerToken, ok := metaInfo[13].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid average solution cost per token format: %v", metaInfo[11])
-		}
-
-		avgFeePerToken, ok := metaInfo[17].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid average fee per token format: %v", metaInfo[15])
-		}
-
-		avgTipPerToken, ok := metaInfo[18].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid average tip per token format: %v", metaInfo[16])
-		}
+	if len(metaRows) != 0 {
+		res = metaRows[0].info()
+	}
+	return &model.TraderOverviewVO{Info: res}, nil
+}
 
-		createdTokens, ok := metaInfo[21].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid create token number: %v", metaInfo[19])
-		}
+// traderOverviewRow holds the columns of the trader overview cards (v1 and v2) the overview is computed from,
+// the v2 columns named otherwise are mapped with config.MetabaseCard.ColumnNames
+type traderOverviewRow struct {
+	TotalNetProfit     float64 `metabase:"total_net_profit"`
+	NetProfitWinRatio  float64 `metabase:"net_profit_win_ratio"`
+	TradedTokenCount   int64   `metabase:"traded_token_count"`
+	AvgSolCostPerToken float64 `metabase:"avg_sol_cost_per_token"`
+	AvgFeePerToken     float64 `metabase:"avg_fee_per_token"`
+	AvgTipPerToken     float64 `metabase:"avg_tip_per_token"`
+	CreatedTokenCount  int64   `metabase:"created_token_count"`
+}
 
-		totalCost := avgSolCostPerToken * tradedTokenCount
-		res = &model.TraderOverviewInfoV2{
-			TotalNetProfit:     totalNetProfit,
-			ProfitRatio:        totalNetProfit / totalCost,
-			NetProfitWinRatio:  netProfitWinRatio,
-			TradedTokenCount:   int64(tradedTokenCount),
-			AvgSolCostPerToken: avgSolCostPerToken,
-			TotalCost:          totalCost,
-			AvgTipPerToken:     avgTipPerToken,
-			AvgFeePerToken:     avgFeePerToken,
-			TokenCreateCount:   int64(createdTokens),
-		}
+func (row traderOverviewRow) info() *model.TraderOverviewInfoV2 {
+	totalCost := row.AvgSolCostPerToken * float64(row.TradedTokenCount)
+	return &model.TraderOverviewInfoV2{
+		TotalNetProfit:     row.TotalNetProfit,
+		ProfitRatio:        row.TotalNetProfit / totalCost,
+		NetProfitWinRatio:  row.NetProfitWinRatio,
+		TradedTokenCount:   row.TradedTokenCount,
+		AvgSolCostPerToken: row.AvgSolCostPerToken,
+		TotalCost:          totalCost,
+		AvgTipPerToken:     row.AvgTipPerToken,
+		AvgFeePerToken:     row.AvgFeePerToken,
+		TokenCreateCount:   row.CreatedTokenCount,
 	}
-
-	return &model.TraderOverviewVO{Info: res}, nil
 }
 
 func getTraderOverviewTestData() *model.TraderOverviewVO {
@@ -532,7 +367,13 @@ func getTraderOverviewTestData() *model.TraderOverviewVO {
 	}
 }
 
-func (pd *PumpDataService) TraderProfit(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderProfitVO, error) {
+type traderProfitRow struct {
+	Day         string  `metabase:"day"`
+	NetProfit   float64 `metabase:"net_profit"`
+	GrossProfit float64 `metabase:"gross_profit"`
+}
+
+func (pd *PumpDataService) traderProfit(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderProfitVO, error) {
 	address := req.Address
 	duration := req.Duration
 	if duration == 0 {
@@ -548,30 +389,21 @@ func (pd *PumpDataService) TraderProfit(ctx context.Context, req *model.CommonPu
 		return getTraderProfitTestData(), nil
 	}
 
-	results, err := pd.metabaseDataSource.TraderProfitDistribution(address, duration, timezone)
+	results, err := pd.analyticsSource.TraderProfitDistribution(address, duration, timezone)
+	if err != nil {
+		return nil, err
+	}
+	metaRows, err := decodeCardRows[traderProfitRow](pd.BaseComponent, CardTraderProfitDistribution, results)
 	if err != nil {
 		return nil, err
 	}
 
-	rows := make([]*model.TraderProfitData, len(results.Data.Rows))
-	for i, row := range results.Data.Rows {
-		date, ok := row[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid date format: %v", row[2])
-		}
-		netProfit, ok := row[1].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid net profit format: %v", row[0])
-		}
-		grossProfit, ok := row[2].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid gross profit format: %v", row[1])
-		}
-
+	rows := make([]*model.TraderProfitData, len(metaRows))
+	for i, row := range metaRows {
 		rows[i] = &model.TraderProfitData{
-			NetProfit:   netProfit,
-			GrossProfit: grossProfit,
-			Date:        date,
+			NetProfit:   row.NetProfit,
+			GrossProfit: row.GrossProfit,
+			Date:        row.Day,
 		}
 	}
 
@@ -595,7 +427,12 @@ func getTraderProfitTestData() *model.TraderProfitVO {
 	}
 }
 
-func (pd *PumpDataService) TraderProfitDistribution(ctx context.Context, req *model.CommonPumpDataQuery) (*model.ProfitDistributionVO, error) {
+type profitDistributionRow struct {
+	ProfitMarginBucket string `metabase:"profit_margin_bucket"`
+	TokenCount         int64  `metabase:"token_count"`
+}
+
+func (pd *PumpDataService) traderProfitDistribution(ctx context.Context, req *model.CommonPumpDataQuery) (*model.ProfitDistributionVO, error) {
 	address := req.Address
 	duration := req.Duration
 	if duration == 0 {
@@ -611,25 +448,20 @@ func (pd *PumpDataService) TraderProfitDistribution(ctx context.Context, req *mo
 		return getProfitDistributionTestData(), nil
 	}
 
-	results, err := pd.metabaseDataSource.TraderProfitTokenDistribution(address, duration, timezone)
+	results, err := pd.analyticsSource.TraderProfitTokenDistribution(address, duration, timezone)
+	if err != nil {
+		return nil, err
+	}
+	metaRows, err := decodeCardRows[profitDistributionRow](pd.BaseComponent, CardTraderProfitTokenDistribution, results)
 	if err != nil {
 		return nil, err
 	}
 
-	rows := make([]*model.ProfitDistributionData, len(results.Data.Rows))
-	for i, row := range results.Data.Rows {
-		profitMarginBucket, ok := row[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid profit margin bucket format: %v", row[0])
-		}
-		tokenCount, ok := row[1].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid token count format: %v", row[1])
-		}
-
+	rows := make([]*model.ProfitDistributionData, len(metaRows))
+	for i, row := range metaRows {
 		rows[i] = &model.ProfitDistributionData{
-			ProfitMarginBucket: profitMarginBucket,
-			TokenCount:         int64(tokenCount),
+			ProfitMarginBucket: row.ProfitMarginBucket,
+			TokenCount:         row.TokenCount,
 		}
 	}
 
@@ -651,7 +483,12 @@ func getProfitDistributionTestData() *model.ProfitDistributionVO {
 	}
 }
 
-func (pd *PumpDataService) TraderTrades(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderTradesVO, error) {
+type traderTradesRow struct {
+	TimeRange string `metabase:"time_range"`
+	TxCount   int64  `metabase:"tx_count"`
+}
+
+func (pd *PumpDataService) traderTrades(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderTradesVO, error) {
 	address := req.Address
 	duration := req.Duration
 	if duration == 0 {
@@ -667,25 +504,20 @@ func (pd *PumpDataService) TraderTrades(ctx context.Context, req *model.CommonPu
 		return getTraderTradesTestData(), nil
 	}
 
-	results, err := pd.metabaseDataSource.TraderTxTimeDistribution(address, duration, timezone)
+	results, err := pd.analyticsSource.TraderTxTimeDistribution(address, duration, timezone)
+	if err != nil {
+		return nil, err
+	}
+	metaRows, err := decodeCardRows[traderTradesRow](pd.BaseComponent, CardTraderTxTimeDistribution, results)
 	if err != nil {
 		return nil, err
 	}
 
-	rows := make([]*model.TraderTradesData, len(results.Data.Rows))
-	for i, row := range results.Data.Rows {
-		timeRange, ok := row[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid time range format: %v", row[0])
-		}
-		txCount, ok := row[1].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid transaction count format: %v", row[1])
-		}
-
+	rows := make([]*model.TraderTradesData, len(metaRows))
+	for i, row := range metaRows {
 		rows[i] = &model.TraderTradesData{
-			TimeRange: timeRange,
-			TxCount:   int64(txCount),
+			TimeRange: row.TimeRange,
+			TxCount:   row.TxCount,
 		}
 	}
 
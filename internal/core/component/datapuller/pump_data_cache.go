@@ -0,0 +1,246 @@
+package datapuller
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/wyt-labs/wyt-core/internal/core/component/datapuller/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/pkg/cache"
+)
+
+const queryCacheNamespace = "pump_data"
+
+// defaultQueryCacheTTLs follow how often the cards change: the daily statistics are refreshed by
+// metabase every few minutes, the trader queries are cheaper to keep shorter
+var defaultQueryCacheTTLs = map[string]time.Duration{
+	CardDailyLaunchedTokens:           10 * time.Minute,
+	CardLaunchedTokenTimeDistribution: 10 * time.Minute,
+	CardDailyTradeCounts:              10 * time.Minute,
+	CardTopTraders:                    5 * time.Minute,
+	CardTraderOverview:                5 * time.Minute,
+	CardTraderOverviewV2:              5 * time.Minute,
+	CardTraderTxTimeDistribution:      5 * time.Minute,
+	CardTraderProfitTokenDistribution: 5 * time.Minute,
+	CardTraderProfitDistribution:      5 * time.Minute,
+}
+
+type queryCacheEntry struct {
+	value any
+	// served as stale after this time, until the memcache drops it
+	freshUntil time.Time
+}
+
+type queryCacheCounters struct {
+	hits      atomic.Uint64
+	staleHits atomic.Uint64
+	misses    atomic.Uint64
+	refreshes atomic.Uint64
+	errors    atomic.Uint64
+}
+
+// queryCache keeps the results of the pump data queries by query name and parameters. The concurrent
+// fetches of the same results are made once, and the expired results are served while they are refreshed.
+type queryCache struct {
+	baseComponent *base.Component
+	group         singleflight.Group
+	// keys being refreshed in the background
+	refreshing sync.Map
+
+	lock     sync.Mutex
+	counters map[string]*queryCacheCounters
+}
+
+func newQueryCache(baseComponent *base.Component) *queryCache {
+	return &queryCache{
+		baseComponent: baseComponent,
+		counters:      make(map[string]*queryCacheCounters),
+	}
+}
+
+// ttl of the query: the configured one, then the configured default, then the built-in one
+func (c *queryCache) ttl(query string) time.Duration {
+	cfg := c.baseComponent.Config.Extension.PumpDataCache
+	if ttl, ok := cfg.TTLs[query]; ok {
+		return ttl.ToDuration()
+	}
+	if cfg.DefaultTTL != 0 {
+		return cfg.DefaultTTL.ToDuration()
+	}
+	return defaultQueryCacheTTLs[query]
+}
+
+func (c *queryCache) countersOf(query string) *queryCacheCounters {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	counters, ok := c.counters[query]
+	if !ok {
+		counters = &queryCacheCounters{}
+		c.counters[query] = counters
+	}
+	return counters
+}
+
+// cachedQuery returns the cached results of the query, fetch loads them on a miss and refreshes them in
+// the background once expired. The results are shared, they must not be changed.
+func cachedQuery[T any](ctx context.Context, c *queryCache, query string, params any, fetch func(ctx context.Context) (T, error)) (T, error) {
+	cfg := c.baseComponent.Config.Extension.PumpDataCache
+	ttl := c.ttl(query)
+	if !cfg.Enable || ttl <= 0 {
+		return fetch(ctx)
+	}
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return fetch(ctx)
+	}
+	key := query + ":" + string(rawParams)
+	counters := c.countersOf(query)
+	get := func() (*queryCacheEntry, bool) {
+		return cache.GetFromMemCache[*queryCacheEntry](c.baseComponent.MemCache, queryCacheNamespace, key)
+	}
+	load := func(ctx context.Context) func() (any, error) {
+		return func() (any, error) {
+			// the callers that missed just before the last fetch ended find its results
+			if entry, ok := get(); ok && time.Now().Before(entry.freshUntil) {
+				return entry.value, nil
+			}
+			res, err := fetch(ctx)
+			if err != nil {
+				counters.errors.Add(1)
+				return nil, err
+			}
+			cache.PutToMemCacheWithTTL(c.baseComponent.MemCache, queryCacheNamespace, key, &queryCacheEntry{
+				value:      res,
+				freshUntil: time.Now().Add(ttl),
+			}, ttl+cfg.StaleTTL.ToDuration())
+			return res, nil
+		}
+	}
+
+	if entry, ok := get(); ok {
+		if time.Now().Before(entry.freshUntil) {
+			counters.hits.Add(1)
+		} else {
+			counters.staleHits.Add(1)
+			c.refresh(query, key, load(context.WithoutCancel(ctx)), counters)
+		}
+		return entry.value.(T), nil
+	}
+
+	counters.misses.Add(1)
+	res, err, _ := c.group.Do(key, load(ctx))
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return res.(T), nil
+}
+
+// refresh reloads the stale results once, the callers keep getting the stale ones meanwhile
+func (c *queryCache) refresh(query string, key string, load func() (any, error), counters *queryCacheCounters) {
+	if _, loaded := c.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	ch := c.group.DoChan(key, load)
+	go func() {
+		defer c.refreshing.Delete(key)
+		res := <-ch
+		if res.Err != nil {
+			c.baseComponent.Logger.WithFields(logrus.Fields{
+				"query": query,
+				"err":   res.Err,
+			}).Warn("Failed to refresh pump data, the stale results are kept")
+			return
+		}
+		counters.refreshes.Add(1)
+	}()
+}
+
+// Stats returns the counters of the queries by name
+func (c *queryCache) Stats() *model.QueryCacheStatsVO {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	res := &model.QueryCacheStatsVO{
+		Enable:  c.baseComponent.Config.Extension.PumpDataCache.Enable,
+		Queries: make([]*model.QueryCacheStats, 0, len(c.counters)),
+	}
+	for query, counters := range c.counters {
+		res.Queries = append(res.Queries, &model.QueryCacheStats{
+			Query:     query,
+			Hits:      counters.hits.Load(),
+			StaleHits: counters.staleHits.Load(),
+			Misses:    counters.misses.Load(),
+			Refreshes: counters.refreshes.Load(),
+			Errors:    counters.errors.Load(),
+		})
+	}
+	sort.Slice(res.Queries, func(i, j int) bool {
+		return res.Queries[i].Query < res.Queries[j].Query
+	})
+	return res
+}
+
+func (pd *PumpDataService) CacheStats() *model.QueryCacheStatsVO {
+	return pd.cache.Stats()
+}
+
+func (pd *PumpDataService) NewTokens(ctx context.Context, req *model.CommonPumpDataQuery) (*model.NewTokensVO, error) {
+	return cachedQuery(ctx, pd.cache, CardDailyLaunchedTokens, req, func(ctx context.Context) (*model.NewTokensVO, error) {
+		return pd.newTokens(ctx, req)
+	})
+}
+
+func (pd *PumpDataService) LaunchTime(ctx context.Context, req *model.CommonPumpDataQuery) (*model.LaunchTimeVO, error) {
+	return cachedQuery(ctx, pd.cache, CardLaunchedTokenTimeDistribution, req, func(ctx context.Context) (*model.LaunchTimeVO, error) {
+		return pd.launchTime(ctx, req)
+	})
+}
+
+func (pd *PumpDataService) Transactions(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TransactionsVO, error) {
+	return cachedQuery(ctx, pd.cache, CardDailyTradeCounts, req, func(ctx context.Context) (*model.TransactionsVO, error) {
+		return pd.transactions(ctx, req)
+	})
+}
+
+func (pd *PumpDataService) TopTraders(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TopTradersVO, error) {
+	return cachedQuery(ctx, pd.cache, CardTopTraders, req, func(ctx context.Context) (*model.TopTradersVO, error) {
+		return pd.topTraders(ctx, req)
+	})
+}
+
+func (pd *PumpDataService) TraderOverview(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderOverviewVO, error) {
+	return cachedQuery(ctx, pd.cache, CardTraderOverview, req, func(ctx context.Context) (*model.TraderOverviewVO, error) {
+		return pd.traderOverview(ctx, req)
+	})
+}
+
+func (pd *PumpDataService) TraderOverviewV2(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderOverviewVO, error) {
+	return cachedQuery(ctx, pd.cache, CardTraderOverviewV2, req, func(ctx context.Context) (*model.TraderOverviewVO, error) {
+		return pd.traderOverviewV2(ctx, req)
+	})
+}
+
+func (pd *PumpDataService) TraderProfit(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderProfitVO, error) {
+	return cachedQuery(ctx, pd.cache, CardTraderProfitDistribution, req, func(ctx context.Context) (*model.TraderProfitVO, error) {
+		return pd.traderProfit(ctx, req)
+	})
+}
+
+func (pd *PumpDataService) TraderProfitDistribution(ctx context.Context, req *model.CommonPumpDataQuery) (*model.ProfitDistributionVO, error) {
+	return cachedQuery(ctx, pd.cache, CardTraderProfitTokenDistribution, req, func(ctx context.Context) (*model.ProfitDistributionVO, error) {
+		return pd.traderProfitDistribution(ctx, req)
+	})
+}
+
+func (pd *PumpDataService) TraderTrades(ctx context.Context, req *model.CommonPumpDataQuery) (*model.TraderTradesVO, error) {
+	return cachedQuery(ctx, pd.cache, CardTraderTxTimeDistribution, req, func(ctx context.Context) (*model.TraderTradesVO, error) {
+		return pd.traderTrades(ctx, req)
+	})
+}
@@ -0,0 +1,157 @@
+package datapuller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/core/component/datapuller/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+)
+
+func newTestQueryCache(t *testing.T, ttl time.Duration, staleTTL time.Duration) *queryCache {
+	baseComponent := base.NewMockBaseComponent(t)
+	baseComponent.Config.Extension.PumpDataCache = config.PumpDataCache{
+		Enable:   true,
+		TTLs:     map[string]config.Duration{CardDailyLaunchedTokens: config.Duration(ttl)},
+		StaleTTL: config.Duration(staleTTL),
+	}
+	return newQueryCache(baseComponent)
+}
+
+func TestQueryCache_HitMiss(t *testing.T) {
+	c := newTestQueryCache(t, time.Minute, 0)
+	var fetches atomic.Int32
+	fetch := func(ctx context.Context) (*model.NewTokensVO, error) {
+		fetches.Add(1)
+		return &model.NewTokensVO{Rows: []*model.DailyTokensData{{TotalCount: 3}}}, nil
+	}
+	ctx := context.Background()
+
+	res, err := cachedQuery(ctx, c, CardDailyLaunchedTokens, &model.CommonPumpDataQuery{Duration: 7}, fetch)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), res.Rows[0].TotalCount)
+	_, err = cachedQuery(ctx, c, CardDailyLaunchedTokens, &model.CommonPumpDataQuery{Duration: 7}, fetch)
+	assert.Nil(t, err)
+	// other parameters are another entry
+	_, err = cachedQuery(ctx, c, CardDailyLaunchedTokens, &model.CommonPumpDataQuery{Duration: 3}, fetch)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// errors are not cached
+	failure := errors.New("metabase is down")
+	for i := 0; i < 2; i++ {
+		_, err = cachedQuery(ctx, c, CardDailyLaunchedTokens, &model.CommonPumpDataQuery{Duration: 1}, func(ctx context.Context) (*model.NewTokensVO, error) {
+			return nil, failure
+		})
+		assert.ErrorIs(t, err, failure)
+	}
+
+	assert.Equal(t, &model.QueryCacheStatsVO{
+		Enable: true,
+		Queries: []*model.QueryCacheStats{
+			{Query: CardDailyLaunchedTokens, Hits: 1, Misses: 4, Errors: 2},
+		},
+	}, c.Stats())
+}
+
+func TestQueryCache_Stale(t *testing.T) {
+	c := newTestQueryCache(t, 20*time.Millisecond, time.Minute)
+	var fetches atomic.Int32
+	refreshed := make(chan struct{}, 1)
+	fetch := func(ctx context.Context) (*model.NewTokensVO, error) {
+		n := fetches.Add(1)
+		if n > 1 {
+			defer func() { refreshed <- struct{}{} }()
+		}
+		return &model.NewTokensVO{Rows: []*model.DailyTokensData{{TotalCount: int64(n)}}}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	req := &model.CommonPumpDataQuery{Duration: 7}
+
+	_, err := cachedQuery(ctx, c, CardDailyLaunchedTokens, req, fetch)
+	assert.Nil(t, err)
+	time.Sleep(30 * time.Millisecond)
+
+	// the stale results are served while refreshed, even once the request is done
+	res, err := cachedQuery(ctx, c, CardDailyLaunchedTokens, req, fetch)
+	cancel()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), res.Rows[0].TotalCount)
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("stale results were not refreshed")
+	}
+	assert.Eventually(t, func() bool {
+		res, err := cachedQuery(context.Background(), c, CardDailyLaunchedTokens, req, fetch)
+		return err == nil && res.Rows[0].TotalCount == 2
+	}, time.Second, 5*time.Millisecond)
+
+	stats := c.Stats().Queries[0]
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Refreshes)
+	assert.GreaterOrEqual(t, stats.StaleHits, uint64(1))
+}
+
+func TestQueryCache_Collapse(t *testing.T) {
+	c := newTestQueryCache(t, time.Minute, 0)
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (*model.NewTokensVO, error) {
+		fetches.Add(1)
+		<-release
+		return &model.NewTokensVO{}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cachedQuery(context.Background(), c, CardDailyLaunchedTokens, &model.CommonPumpDataQuery{Duration: 7}, fetch)
+			assert.Nil(t, err)
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		queries := c.Stats().Queries
+		return len(queries) == 1 && queries[0].Misses == 10
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), fetches.Load())
+}
+
+func TestQueryCache_Disabled(t *testing.T) {
+	c := newTestQueryCache(t, time.Minute, 0)
+	c.baseComponent.Config.Extension.PumpDataCache.Enable = false
+	var fetches atomic.Int32
+	fetch := func(ctx context.Context) (*model.NewTokensVO, error) {
+		fetches.Add(1)
+		return &model.NewTokensVO{}, nil
+	}
+	for i := 0; i < 2; i++ {
+		_, err := cachedQuery(context.Background(), c, CardDailyLaunchedTokens, &model.CommonPumpDataQuery{}, fetch)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(2), fetches.Load())
+	assert.Empty(t, c.Stats().Queries)
+}
+
+func TestQueryCache_TTL(t *testing.T) {
+	c := newTestQueryCache(t, time.Minute, 0)
+	assert.Equal(t, time.Minute, c.ttl(CardDailyLaunchedTokens))
+	assert.Equal(t, defaultQueryCacheTTLs[CardTopTraders], c.ttl(CardTopTraders))
+
+	// the configured default replaces the built-in ttls, not the configured ones
+	c.baseComponent.Config.Extension.PumpDataCache.DefaultTTL = config.Duration(30 * time.Second)
+	assert.Equal(t, time.Minute, c.ttl(CardDailyLaunchedTokens))
+	assert.Equal(t, 30*time.Second, c.ttl(CardTopTraders))
+	assert.Equal(t, 30*time.Second, c.ttl("unknown"))
+}
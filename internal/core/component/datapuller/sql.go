@@ -0,0 +1,459 @@
+package datapuller
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/wyt-labs/wyt-core/internal/core/component/datapuller/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+)
+
+// sqlFiles are the pump data queries by dialect, sql/<dialect>/<query name>.v<version>.sql
+//
+//go:embed sql
+var sqlFiles embed.FS
+
+// the dialects with a linked and tested driver, there are no clickhouse queries as no clickhouse driver is
+// linked to run them
+const (
+	SQLDialectPostgres = "postgres"
+	SQLDialectSQLite   = "sqlite"
+)
+
+type sqlDialect struct {
+	// $1, $2... instead of ?
+	numberedPlaceholders bool
+	// the times are stored as unix seconds
+	unixTime bool
+}
+
+var sqlDialects = map[string]sqlDialect{
+	SQLDialectPostgres: {numberedPlaceholders: true},
+	SQLDialectSQLite:   {unixTime: true},
+}
+
+// dialects of the usual drivers, for the configs without dialect
+var sqlDriverDialects = map[string]string{
+	"postgres": SQLDialectPostgres,
+	"pgx":      SQLDialectPostgres,
+	"sqlite3":  SQLDialectSQLite,
+	"sqlite":   SQLDialectSQLite,
+}
+
+// sqlTimezones are the timezones of the daily queries, the ones the metabase cards have a variant for
+var sqlTimezones = map[string]*time.Location{
+	"utc": time.UTC,
+	"cst": time.FixedZone("CST", 8*60*60),
+}
+
+var (
+	sqlFileName = regexp.MustCompile(`^([a-z0-9_]+)\.v([0-9]+)\.sql$`)
+	sqlParam    = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+)
+
+// sqlQuery is a sql file ready to run, its {{name}} parameters are replaced by the placeholders of the
+// dialect and params are their names in the order of the arguments
+type sqlQuery struct {
+	name    string
+	version int
+	text    string
+	params  []string
+}
+
+// loadSQLQueries picks the sql file of every pump data query for the dialect, the version pinned in
+// versions or the latest one
+func loadSQLQueries(files fs.FS, dialectName string, versions map[string]int) (map[string]*sqlQuery, error) {
+	dialect, ok := sqlDialects[dialectName]
+	if !ok {
+		return nil, errors.Errorf("unsupported sql dialect: %s", dialectName)
+	}
+	dir := path.Join("sql", dialectName)
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the %s queries", dialectName)
+	}
+	available := make(map[string]map[int]string)
+	for _, entry := range entries {
+		matches := sqlFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			return nil, errors.Errorf("%s: sql files are named <query>.v<version>.sql", path.Join(dir, entry.Name()))
+		}
+		version, _ := strconv.Atoi(matches[2])
+		if available[matches[1]] == nil {
+			available[matches[1]] = make(map[int]string)
+		}
+		available[matches[1]][version] = path.Join(dir, entry.Name())
+	}
+	for name := range versions {
+		if _, ok := available[name]; !ok {
+			return nil, errors.Errorf("version pinned for unknown query %s", name)
+		}
+	}
+
+	queries := make(map[string]*sqlQuery, len(cardNames))
+	for _, name := range cardNames {
+		byVersion, ok := available[name]
+		if !ok {
+			return nil, errors.Errorf("query %s has no %s sql file", name, dialectName)
+		}
+		version, pinned := versions[name]
+		if !pinned {
+			for v := range byVersion {
+				version = max(version, v)
+			}
+		}
+		file, ok := byVersion[version]
+		if !ok {
+			return nil, errors.Errorf("query %s has no version %d", name, version)
+		}
+		raw, err := fs.ReadFile(files, file)
+		if err != nil {
+			return nil, err
+		}
+		query := &sqlQuery{name: name, version: version}
+		query.text, query.params = bindSQLParams(dialect, string(raw))
+		queries[name] = query
+	}
+	return queries, nil
+}
+
+// bindSQLParams replaces the {{name}} parameters by the placeholders of the dialect
+func bindSQLParams(dialect sqlDialect, text string) (string, []string) {
+	var params []string
+	positions := make(map[string]int)
+	text = sqlParam.ReplaceAllStringFunc(text, func(tag string) string {
+		name := sqlParam.FindStringSubmatch(tag)[1]
+		if !dialect.numberedPlaceholders {
+			params = append(params, name)
+			return "?"
+		}
+		position, ok := positions[name]
+		if !ok {
+			params = append(params, name)
+			position = len(params)
+			positions[name] = position
+		}
+		return "$" + strconv.Itoa(position)
+	})
+	return text, params
+}
+
+// SQLDataSource runs the pump data queries on the analytics database, the results are laid out as the
+// metabase ones
+type SQLDataSource struct {
+	baseComponent *base.Component
+	db            *sql.DB
+	dialect       sqlDialect
+	queries       map[string]*sqlQuery
+	timeout       time.Duration
+	now           func() time.Time
+}
+
+func NewSQLDataSource(baseComponent *base.Component) (*SQLDataSource, error) {
+	cfg := baseComponent.Config.Backends.PumpDataSQL
+	dialectName := strings.ToLower(cfg.Dialect)
+	if dialectName == "" {
+		dialectName = sqlDriverDialects[cfg.Driver]
+	}
+	queries, err := loadSQLQueries(sqlFiles, dialectName, cfg.Versions)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid pump data sql queries")
+	}
+	db, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the pump data database")
+	}
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	s := &SQLDataSource{
+		baseComponent: baseComponent,
+		db:            db,
+		dialect:       sqlDialects[dialectName],
+		queries:       queries,
+		timeout:       cfg.QueryTimeout.ToDuration(),
+		now:           time.Now,
+	}
+	baseComponent.RegisterLifecycleHook(s)
+	return s, nil
+}
+
+func (s *SQLDataSource) Start() error {
+	ctx, cancel := s.context()
+	defer cancel()
+	if err := s.db.PingContext(ctx); err != nil {
+		return errors.Wrap(err, "failed to connect the pump data database")
+	}
+	return nil
+}
+
+func (s *SQLDataSource) Stop() error {
+	return s.db.Close()
+}
+
+func (s *SQLDataSource) context() (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), s.timeout)
+}
+
+// Query runs the sql file of the query, params are the values of its {{name}} parameters
+func (s *SQLDataSource) Query(name string, params map[string]any) (*model.DatasetQueryResults, error) {
+	query, ok := s.queries[name]
+	if !ok {
+		return nil, errors.Errorf("unknown pump data query: %s", name)
+	}
+	args := make([]any, 0, len(query.params))
+	for _, param := range query.params {
+		value, ok := params[param]
+		if !ok {
+			return nil, errors.Errorf("pump data query %s: parameter %s is missing", name, param)
+		}
+		if t, ok := value.(time.Time); ok && s.dialect.unixTime {
+			value = t.Unix()
+		}
+		args = append(args, value)
+	}
+
+	logger := s.baseComponent.Logger.WithFields(logrus.Fields{
+		"query":   name,
+		"version": query.version,
+	})
+	ctx, cancel := s.context()
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, query.text, args...)
+	if err != nil {
+		logger.WithField("err", err).Error("failed to run pump data query")
+		return nil, errors.Wrapf(err, "pump data query %s", name)
+	}
+	defer rows.Close()
+	ret, err := readSQLRows(rows)
+	if err != nil {
+		logger.WithField("err", err).Error("failed to read pump data query rows")
+		return nil, errors.Wrapf(err, "pump data query %s", name)
+	}
+	return ret, nil
+}
+
+// readSQLRows converts the rows as metabase returns them: numbers are float64, times are RFC3339 strings
+func readSQLRows(rows *sql.Rows) (*model.DatasetQueryResults, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	ret := &model.DatasetQueryResults{Status: "completed"}
+	for _, columnType := range columnTypes {
+		ret.Data.Cols = append(ret.Data.Cols, model.DatasetQueryResultsCol{
+			Name:        columnType.Name(),
+			DisplayName: columnType.Name(),
+			BaseType:    sqlBaseType(columnType.DatabaseTypeName()),
+		})
+	}
+	ret.Data.Rows = make([][]any, 0)
+	for rows.Next() {
+		values := make([]any, len(columnTypes))
+		dest := make([]any, len(columnTypes))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, value := range values {
+			if values[i], err = sqlValue(value, ret.Data.Cols[i].BaseType); err != nil {
+				return nil, errors.Wrapf(err, "column %s", ret.Data.Cols[i].Name)
+			}
+		}
+		ret.Data.Rows = append(ret.Data.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	ret.RowCount = int64(len(ret.Data.Rows))
+	return ret, nil
+}
+
+// sqlBaseType maps the database type of a column to a metabase base type, empty when unknown (sqlite
+// has no type for the computed columns)
+func sqlBaseType(databaseType string) string {
+	databaseType = strings.ToUpper(databaseType)
+	switch {
+	case databaseType == "":
+		return ""
+	case strings.Contains(databaseType, "BOOL"):
+		return "type/Boolean"
+	case strings.Contains(databaseType, "INT"):
+		return "type/Integer"
+	case strings.Contains(databaseType, "FLOAT"), strings.Contains(databaseType, "DOUBLE"), strings.Contains(databaseType, "REAL"):
+		return "type/Float"
+	case strings.Contains(databaseType, "NUMERIC"), strings.Contains(databaseType, "DECIMAL"):
+		return "type/Decimal"
+	case strings.Contains(databaseType, "TIME"), strings.Contains(databaseType, "DATE"):
+		return "type/DateTime"
+	case strings.Contains(databaseType, "CHAR"), strings.Contains(databaseType, "TEXT"), strings.Contains(databaseType, "STRING"):
+		return "type/Text"
+	}
+	return ""
+}
+
+func sqlValue(value any, baseType string) (any, error) {
+	isNumber := baseType == "type/Integer" || baseType == "type/Float" || baseType == "type/Decimal"
+	switch v := value.(type) {
+	case nil, bool, float64:
+		return v, nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case []byte:
+		value = string(v)
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32:
+		return rv.Float(), nil
+	case reflect.String:
+		if isNumber {
+			return strconv.ParseFloat(rv.String(), 64)
+		}
+		return rv.String(), nil
+	}
+	// decimals of the drivers
+	if isNumber {
+		return strconv.ParseFloat(fmt.Sprint(value), 64)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// dailyParams are the parameters of the queries over the last days in the timezone, today included
+func (s *SQLDataSource) dailyParams(duration int, timezone string) (map[string]any, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, ok := sqlTimezones[strings.ToLower(timezone)]
+	if !ok {
+		return nil, errors.Errorf("unsupported timezone: %s", timezone)
+	}
+	now := s.now().In(loc)
+	_, offset := now.Zone()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	return map[string]any{
+		"days":      duration,
+		"since":     today.AddDate(0, 0, 1-duration),
+		"tz_offset": offset,
+		// the days are returned as RFC3339 strings like the ones of metabase
+		"tz_suffix": today.Format("Z07:00"),
+	}, nil
+}
+
+func (s *SQLDataSource) traderParams(trader string, duration int, timezone string) (map[string]any, error) {
+	params, err := s.dailyParams(duration, timezone)
+	if err != nil {
+		return nil, err
+	}
+	params["trader"] = trader
+	return params, nil
+}
+
+func (s *SQLDataSource) DailyLaunchedTokenInfo(duration int, timezone string) (*model.DatasetQueryResults, error) {
+	if duration < 7 {
+		duration = 7
+	}
+	params, err := s.dailyParams(duration, timezone)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(CardDailyLaunchedTokens, params)
+}
+
+func (s *SQLDataSource) LaunchedTokenTimeDistribution(duration int, timezone string) (*model.DatasetQueryResults, error) {
+	if duration < 7 {
+		duration = 7
+	}
+	params, err := s.dailyParams(duration, timezone)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(CardLaunchedTokenTimeDistribution, params)
+}
+
+func (s *SQLDataSource) DailyTradeCounts(duration int, timezone string) (*model.DatasetQueryResults, error) {
+	if duration < 7 {
+		duration = 7
+	}
+	params, err := s.dailyParams(duration, timezone)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(CardDailyTradeCounts, params)
+}
+
+func (s *SQLDataSource) TraderOverview(trader string) (*model.DatasetQueryResults, error) {
+	return s.Query(CardTraderOverview, map[string]any{"trader": trader})
+}
+
+func (s *SQLDataSource) TraderOverviewV2(trader string, tz string, days int) (*model.DatasetQueryResults, error) {
+	if tz == "" {
+		tz = "CST"
+	}
+	params, err := s.traderParams(trader, days, tz)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(CardTraderOverviewV2, params)
+}
+
+func (s *SQLDataSource) TraderTxTimeDistribution(trader string, duration int, timezone string) (*model.DatasetQueryResults, error) {
+	params, err := s.traderParams(trader, duration, timezone)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(CardTraderTxTimeDistribution, params)
+}
+
+func (s *SQLDataSource) TraderProfitTokenDistribution(trader string, duration int, timezone string) (*model.DatasetQueryResults, error) {
+	params, err := s.traderParams(trader, duration, timezone)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(CardTraderProfitTokenDistribution, params)
+}
+
+func (s *SQLDataSource) TraderProfitDistribution(trader string, duration int, timezone string) (*model.DatasetQueryResults, error) {
+	if timezone == "" {
+		timezone = "CST"
+	}
+	params, err := s.traderParams(trader, duration, timezone)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(CardTraderProfitDistribution, params)
+}
+
+func (s *SQLDataSource) TopTrader(duration int, winRatio float32) (*model.DatasetQueryResults, error) {
+	// the periods of the metabase cards
+	if duration != 7 && duration != 30 {
+		duration = 1
+	}
+	return s.Query(CardTopTraders, map[string]any{
+		"days":      duration,
+		"since":     s.now().Add(-time.Duration(duration) * 24 * time.Hour),
+		"win_ratio": float64(winRatio),
+	})
+}
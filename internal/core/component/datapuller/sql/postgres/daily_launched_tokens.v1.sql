@@ -0,0 +1,13 @@
+-- tokens launched per day and how many of them migrated to raydium
+SELECT to_char(local_day, 'YYYY-MM-DD') || 'T00:00:00' || {{tz_suffix}} AS day,
+       count(*) AS total_count,
+       count(migrated_at) AS p2r_count,
+       count(migrated_at)::double precision / count(*) AS p2r_ratio
+FROM (
+    SELECT ((created_at AT TIME ZONE 'UTC') + make_interval(secs => {{tz_offset}}))::date AS local_day,
+           migrated_at
+    FROM pump_tokens
+    WHERE created_at >= {{since}}
+) launched
+GROUP BY local_day
+ORDER BY local_day
@@ -0,0 +1,10 @@
+-- swaps per day
+SELECT to_char(local_day, 'YYYY-MM-DD') || 'T00:00:00' || {{tz_suffix}} AS day,
+       count(*) AS trade_count
+FROM (
+    SELECT ((block_time AT TIME ZONE 'UTC') + make_interval(secs => {{tz_offset}}))::date AS local_day
+    FROM pump_trades
+    WHERE block_time >= {{since}}
+) trades
+GROUP BY local_day
+ORDER BY local_day
@@ -0,0 +1,12 @@
+-- tokens launched per half hour of the day
+SELECT '[' || lpad((bucket / 2)::text, 2, '0') || ':' || lpad((bucket % 2 * 30)::text, 2, '0')
+           || '~' || lpad(((bucket + 1) / 2)::text, 2, '0') || ':' || lpad(((bucket + 1) % 2 * 30)::text, 2, '0')
+           || ')' AS time_range,
+       count(*) AS launched_count
+FROM (
+    SELECT (extract(epoch FROM created_at)::bigint + {{tz_offset}}) % 86400 / 1800 AS bucket
+    FROM pump_tokens
+    WHERE created_at >= {{since}}
+) launched
+GROUP BY bucket
+ORDER BY bucket
@@ -0,0 +1,12 @@
+-- profits of the trader per day
+SELECT to_char(local_day, 'YYYY-MM-DD') || 'T00:00:00' || {{tz_suffix}} AS day,
+       sum(CASE WHEN is_buy THEN -sol_amount ELSE sol_amount END) - sum(fee) - sum(tip) AS net_profit,
+       sum(CASE WHEN is_buy THEN -sol_amount ELSE sol_amount END) AS gross_profit
+FROM (
+    SELECT ((block_time AT TIME ZONE 'UTC') + make_interval(secs => {{tz_offset}}))::date AS local_day,
+           is_buy, sol_amount, fee, tip
+    FROM pump_trades
+    WHERE trader = {{trader}} AND block_time >= {{since}}
+) trades
+GROUP BY local_day
+ORDER BY local_day
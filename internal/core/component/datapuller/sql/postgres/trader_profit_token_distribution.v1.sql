@@ -0,0 +1,22 @@
+-- tokens bought by the trader by net profit margin
+WITH tokens AS (
+    SELECT (sum(CASE WHEN is_buy THEN 0 ELSE sol_amount END) - sum(CASE WHEN is_buy THEN sol_amount ELSE 0 END) - sum(fee) - sum(tip))
+               / sum(CASE WHEN is_buy THEN sol_amount ELSE 0 END) AS margin
+    FROM pump_trades
+    WHERE trader = {{trader}} AND block_time >= {{since}}
+    GROUP BY mint
+    HAVING sum(CASE WHEN is_buy THEN sol_amount ELSE 0 END) > 0
+)
+SELECT CASE
+           WHEN margin < -1 THEN '< -100%'
+           WHEN margin < -0.5 THEN '-100% ~ -50%'
+           WHEN margin < 0 THEN '-50% ~ 0%'
+           WHEN margin < 0.5 THEN '0% ~ 50%'
+           WHEN margin < 1 THEN '50% ~ 100%'
+           WHEN margin < 5 THEN '100% ~ 500%'
+           ELSE '> 500%'
+       END AS profit_margin_bucket,
+       count(*) AS token_count
+FROM tokens
+GROUP BY profit_margin_bucket
+ORDER BY min(margin)
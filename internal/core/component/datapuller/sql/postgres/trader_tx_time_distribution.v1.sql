@@ -0,0 +1,12 @@
+-- swaps of the trader per half hour of the day
+SELECT '[' || lpad((bucket / 2)::text, 2, '0') || ':' || lpad((bucket % 2 * 30)::text, 2, '0')
+           || '~' || lpad(((bucket + 1) / 2)::text, 2, '0') || ':' || lpad(((bucket + 1) % 2 * 30)::text, 2, '0')
+           || ')' AS time_range,
+       count(*) AS tx_count
+FROM (
+    SELECT (extract(epoch FROM block_time)::bigint + {{tz_offset}}) % 86400 / 1800 AS bucket
+    FROM pump_trades
+    WHERE trader = {{trader}} AND block_time >= {{since}}
+) trades
+GROUP BY bucket
+ORDER BY bucket
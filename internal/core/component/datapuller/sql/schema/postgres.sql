@@ -0,0 +1,27 @@
+-- tables read by the pump data queries, filled by the pump.fun indexer
+
+-- tokens launched on pump.fun
+CREATE TABLE IF NOT EXISTS pump_tokens (
+    mint        TEXT PRIMARY KEY,
+    creator     TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    -- null until the token migrates to raydium
+    migrated_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS pump_tokens_created_at ON pump_tokens (created_at);
+CREATE INDEX IF NOT EXISTS pump_tokens_creator ON pump_tokens (creator);
+
+-- swaps on the bonding curves, the amounts are in SOL
+CREATE TABLE IF NOT EXISTS pump_trades (
+    signature  TEXT NOT NULL,
+    trader     TEXT NOT NULL,
+    mint       TEXT NOT NULL,
+    is_buy     BOOLEAN NOT NULL,
+    -- SOL paid by a buy or received by a sell
+    sol_amount DOUBLE PRECISION NOT NULL,
+    fee        DOUBLE PRECISION NOT NULL DEFAULT 0,
+    tip        DOUBLE PRECISION NOT NULL DEFAULT 0,
+    block_time TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS pump_trades_block_time ON pump_trades (block_time);
+CREATE INDEX IF NOT EXISTS pump_trades_trader ON pump_trades (trader, block_time);
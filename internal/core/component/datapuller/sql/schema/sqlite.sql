@@ -0,0 +1,27 @@
+-- tables read by the pump data queries, the times are unix seconds
+
+-- tokens launched on pump.fun
+CREATE TABLE IF NOT EXISTS pump_tokens (
+    mint        TEXT PRIMARY KEY,
+    creator     TEXT NOT NULL,
+    created_at  INTEGER NOT NULL,
+    -- null until the token migrates to raydium
+    migrated_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS pump_tokens_created_at ON pump_tokens (created_at);
+CREATE INDEX IF NOT EXISTS pump_tokens_creator ON pump_tokens (creator);
+
+-- swaps on the bonding curves, the amounts are in SOL
+CREATE TABLE IF NOT EXISTS pump_trades (
+    signature  TEXT NOT NULL,
+    trader     TEXT NOT NULL,
+    mint       TEXT NOT NULL,
+    is_buy     INTEGER NOT NULL,
+    -- SOL paid by a buy or received by a sell
+    sol_amount REAL NOT NULL,
+    fee        REAL NOT NULL DEFAULT 0,
+    tip        REAL NOT NULL DEFAULT 0,
+    block_time INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS pump_trades_block_time ON pump_trades (block_time);
+CREATE INDEX IF NOT EXISTS pump_trades_trader ON pump_trades (trader, block_time);
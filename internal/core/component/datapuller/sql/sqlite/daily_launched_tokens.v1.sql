@@ -0,0 +1,13 @@
+-- tokens launched per day and how many of them migrated to raydium
+SELECT local_day || 'T00:00:00' || {{tz_suffix}} AS day,
+       count(*) AS total_count,
+       count(migrated_at) AS p2r_count,
+       CAST(count(migrated_at) AS REAL) / count(*) AS p2r_ratio
+FROM (
+    SELECT date(created_at + {{tz_offset}}, 'unixepoch') AS local_day,
+           migrated_at
+    FROM pump_tokens
+    WHERE created_at >= {{since}}
+) launched
+GROUP BY local_day
+ORDER BY local_day
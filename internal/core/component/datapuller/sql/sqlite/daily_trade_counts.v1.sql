@@ -0,0 +1,10 @@
+-- swaps per day
+SELECT local_day || 'T00:00:00' || {{tz_suffix}} AS day,
+       count(*) AS trade_count
+FROM (
+    SELECT date(block_time + {{tz_offset}}, 'unixepoch') AS local_day
+    FROM pump_trades
+    WHERE block_time >= {{since}}
+) trades
+GROUP BY local_day
+ORDER BY local_day
@@ -0,0 +1,10 @@
+-- tokens launched per half hour of the day
+SELECT printf('[%02d:%02d~%02d:%02d)', bucket / 2, bucket % 2 * 30, (bucket + 1) / 2, (bucket + 1) % 2 * 30) AS time_range,
+       count(*) AS launched_count
+FROM (
+    SELECT (created_at + {{tz_offset}}) % 86400 / 1800 AS bucket
+    FROM pump_tokens
+    WHERE created_at >= {{since}}
+) launched
+GROUP BY bucket
+ORDER BY bucket
@@ -0,0 +1,23 @@
+-- most profitable traders of the period whose net profit win ratio is at most win_ratio
+WITH tokens AS (
+    SELECT trader,
+           sum(CASE WHEN is_buy THEN -sol_amount ELSE sol_amount END) AS gross_profit,
+           sum(fee) + sum(tip) AS costs,
+           count(*) AS tx_count
+    FROM pump_trades
+    WHERE block_time >= {{since}}
+    GROUP BY trader, mint
+), traders AS (
+    SELECT trader,
+           sum(gross_profit - costs) AS total_net_profit,
+           avg(CASE WHEN gross_profit - costs > 0 THEN 1.0 ELSE 0.0 END) AS net_profit_win_ratio,
+           avg(CASE WHEN gross_profit > 0 THEN 1.0 ELSE 0.0 END) AS gross_profit_win_ratio,
+           sum(tx_count) AS total_tx_count
+    FROM tokens
+    GROUP BY trader
+)
+SELECT trader, total_net_profit, net_profit_win_ratio, gross_profit_win_ratio, total_tx_count
+FROM traders
+WHERE net_profit_win_ratio <= {{win_ratio}}
+ORDER BY total_net_profit DESC
+LIMIT 100
@@ -0,0 +1,23 @@
+-- profits of the trader over all the tokens it traded, no row when it never traded
+WITH tokens AS (
+    SELECT mint,
+           sum(CASE WHEN is_buy THEN sol_amount ELSE 0 END) AS cost,
+           sum(CASE WHEN is_buy THEN 0 ELSE sol_amount END) AS revenue,
+           sum(fee) AS fees,
+           sum(tip) AS tips
+    FROM pump_trades
+    WHERE trader = {{trader}}
+    GROUP BY mint
+)
+SELECT *
+FROM (
+    SELECT coalesce(sum(revenue - cost - fees - tips), 0.0) AS total_net_profit,
+           coalesce(avg(CASE WHEN revenue - cost - fees - tips > 0 THEN 1.0 ELSE 0.0 END), 0.0) AS net_profit_win_ratio,
+           count(*) AS traded_token_count,
+           coalesce(avg(cost), 0.0) AS avg_sol_cost_per_token,
+           coalesce(avg(fees), 0.0) AS avg_fee_per_token,
+           coalesce(avg(tips), 0.0) AS avg_tip_per_token,
+           (SELECT count(*) FROM pump_tokens WHERE creator = {{trader}}) AS created_token_count
+    FROM tokens
+) overview
+WHERE traded_token_count > 0
@@ -0,0 +1,10 @@
+-- swaps of the trader per half hour of the day
+SELECT printf('[%02d:%02d~%02d:%02d)', bucket / 2, bucket % 2 * 30, (bucket + 1) / 2, (bucket + 1) % 2 * 30) AS time_range,
+       count(*) AS tx_count
+FROM (
+    SELECT (block_time + {{tz_offset}}) % 86400 / 1800 AS bucket
+    FROM pump_trades
+    WHERE trader = {{trader}} AND block_time >= {{since}}
+) trades
+GROUP BY bucket
+ORDER BY bucket
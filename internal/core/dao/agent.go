@@ -0,0 +1,119 @@
+package dao
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	agentCollectionName = "agent"
+)
+
+type AgentDao struct {
+	baseComponent *base.Component
+	db            *DB
+	collection    *mongo.Collection
+}
+
+func NewAgentDao(baseComponent *base.Component, db *DB) *AgentDao {
+	d := &AgentDao{
+		baseComponent: baseComponent,
+		db:            db,
+	}
+	baseComponent.RegisterLifecycleHook(d)
+	return d
+}
+
+func (d *AgentDao) Start() error {
+	d.collection = d.db.DB.Collection(agentCollectionName)
+
+	// the index used to allow several agents per project, the deleted agents keep their project id
+	if err := d.db.dropIndex(d.collection, "_project_id"); err != nil {
+		return err
+	}
+	if err := d.db.createUniqueIndexWhere(d.collection, []string{"project_id"}, bson.M{"is_deleted": false}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *AgentDao) Stop() error {
+	return nil
+}
+
+func (d *AgentDao) Add(ctx *reqctx.ReqCtx, e *model.Agent) error {
+	var err error
+	e.BaseModel, err = model.NewBaseModel(ctx.Caller)
+	if err != nil {
+		return err
+	}
+	e.ID, err = d.db.insert(d.collection, ctx, e)
+	if mongo.IsDuplicateKeyError(err) {
+		return errcode.ErrAgentAlreadyExists
+	}
+	return err
+}
+
+func (d *AgentDao) Update(ctx *reqctx.ReqCtx, e *model.Agent) error {
+	e.UpdateTime = model.JSONTime(time.Now())
+	err := d.db.update(d.collection, ctx, e.ID, e)
+	if mongo.IsDuplicateKeyError(err) {
+		return errcode.ErrAgentAlreadyExists
+	}
+	return err
+}
+
+func (d *AgentDao) Delete(ctx *reqctx.ReqCtx, id string) error {
+	return d.db.delete(d.collection, ctx, id)
+}
+
+func (d *AgentDao) Query(ctx *reqctx.ReqCtx, id string) (*model.Agent, error) {
+	var res model.Agent
+	if err := d.db.queryByID(d.collection, ctx, id, &res); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errcode.ErrAgentNotExist
+		}
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (d *AgentDao) QueryByProjectId(ctx *reqctx.ReqCtx, projectId string) (*model.Agent, error) {
+	var res model.Agent
+	if err := d.db.queryByFilter(d.collection, ctx, bson.M{"project_id": projectId, "is_deleted": false}, &res); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errcode.ErrAgentNotExist
+		}
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (d *AgentDao) ExistsByProjectId(ctx *reqctx.ReqCtx, projectId string) (bool, error) {
+	cnt, err := d.collection.CountDocuments(ctx.Ctx, bson.M{"project_id": projectId, "is_deleted": false})
+	if err != nil {
+		return false, err
+	}
+	return cnt != 0, nil
+}
+
+// CountAll counts the agents including the deleted ones, 0 means the catalog has never been filled
+func (d *AgentDao) CountAll(ctx *reqctx.ReqCtx) (int64, error) {
+	return d.collection.CountDocuments(ctx.Ctx, bson.M{})
+}
+
+func (d *AgentDao) List(ctx *reqctx.ReqCtx, page uint64, size uint64, filter any, sort map[string]bool) ([]*model.Agent, int64, error) {
+	var res []*model.Agent
+	total, err := d.db.pageList(d.collection, ctx, page, size, filter, sort, &res)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res, total, nil
+}
@@ -19,9 +19,15 @@ import (
 )
 
 func init() {
-	basic.RegisterComponents(NewDB, NewUserDao, NewProjectDao, NewFileSystemDao, NewMiscDao, NewSystemCacheDao, NewChatDao, NewWebsiteDao, NewUserPluginDao)
+	basic.RegisterComponents(NewDB, NewUserDao, NewProjectDao, NewFileSystemDao, NewMiscDao, NewSystemCacheDao, NewChatDao, NewWebsiteDao, NewUserPluginDao, NewProjectChunkDao, NewLLMUsageDao, NewPromptDao, NewChatFeedbackDao, NewAgentDao, NewChatShareDao)
 }
 
+// mongodb error codes of DropOne that mean there is nothing to drop
+const (
+	mongoErrCodeNamespaceNotFound = 26
+	mongoErrCodeIndexNotFound     = 27
+)
+
 var authMechanisms = []string{
 	"SCRAM-SHA-256",
 	"SCRAM-SHA-1",
@@ -150,6 +156,57 @@ func (d *DB) createIndexes(collection *mongo.Collection, isUnique bool, fields [
 	return nil
 }
 
+// createUniqueIndexWhere makes the fields unique together among the documents matching filter, e.g. the ones not deleted
+func (d *DB) createUniqueIndexWhere(collection *mongo.Collection, fields []string, filter bson.M) error {
+	name := "_" + strings.Join(fields, "_") + "_unique"
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: 1})
+	}
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: keys,
+		Options: options.Index().
+			SetName(name).
+			SetUnique(true).
+			SetPartialFilterExpression(filter),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to create mongodb unique index, collection: %v, fields: %v", collection.Name(), fields)
+	}
+	return nil
+}
+
+// dropIndex drops the index by name, a missing index or collection is not an error
+func (d *DB) dropIndex(collection *mongo.Collection, name string) error {
+	_, err := collection.Indexes().DropOne(context.Background(), name)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.Code == mongoErrCodeNamespaceNotFound || cmdErr.Code == mongoErrCodeIndexNotFound) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to drop mongodb index, collection: %v, index: %v", collection.Name(), name)
+	}
+	return nil
+}
+
+// createTextIndex creates the text index of the collection, mongodb allows only one per collection.
+// With the language "none" the words are neither stemmed nor dropped as stop words.
+func (d *DB) createTextIndex(collection *mongo.Collection, fields []string, language string) error {
+	name := "_text_" + language
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: "text"})
+	}
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetName(name).SetDefaultLanguage(language),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to create mongodb text index, collection: %v, fields: %v", collection.Name(), fields)
+	}
+	return nil
+}
+
 func (d *DB) insert(collection *mongo.Collection, ctx *reqctx.ReqCtx, v any) (primitive.ObjectID, error) {
 	res, err := collection.InsertOne(ctx.Ctx, v)
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/wyt-labs/wyt-core/internal/core/model"
 	"github.com/wyt-labs/wyt-core/internal/pkg/base"
@@ -18,6 +19,13 @@ const (
 	chatHistoryCollectionName = "chat_history"
 )
 
+// ChatHistorySearchFields are the message texts searched by ChatHistorySearch
+var ChatHistorySearchFields = []string{
+	"msg.content_user.content",
+	"msg.content_assistant.tips",
+	"msg.content_assistant.general_answer.general_answer.content",
+}
+
 type ChatDao struct {
 	baseComponent         *base.Component
 	db                    *DB
@@ -38,7 +46,14 @@ func (d *ChatDao) Start() error {
 	d.chatWindowCollection = d.db.DB.Collection(chatWindowCollectionName)
 	d.chatHistoryCollection = d.db.DB.Collection(chatHistoryCollectionName)
 
-	if err := d.db.createIndexes(d.chatHistoryCollection, false, []string{"window_id", "index"}); err != nil {
+	if err := d.db.createIndexes(d.chatHistoryCollection, false, []string{"window_id", "index", "creator"}); err != nil {
+		return err
+	}
+	// the english text index of the previous versions stemmed the words and dropped english stop words
+	if err := d.db.dropIndex(d.chatHistoryCollection, "_text"); err != nil {
+		return err
+	}
+	if err := d.db.createTextIndex(d.chatHistoryCollection, ChatHistorySearchFields, "none"); err != nil {
 		return err
 	}
 	return nil
@@ -90,6 +105,40 @@ func (d *ChatDao) ChatWindowUpdate(ctx *reqctx.ReqCtx, e *model.ChatWindow) erro
 	return nil
 }
 
+// ChatWindowSetGeneratedTitle sets the title unless the user has set one, false is returned when the title is kept
+func (d *ChatDao) ChatWindowSetGeneratedTitle(ctx *reqctx.ReqCtx, id primitive.ObjectID, title string) (bool, error) {
+	now := model.JSONTime(time.Now())
+	res, err := d.chatWindowCollection.UpdateOne(ctx.Ctx, bson.M{
+		"_id":          id,
+		"is_deleted":   false,
+		"title_is_set": false,
+	}, bson.M{"$set": bson.M{
+		"title":       title,
+		"update_time": &now,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount != 0, nil
+}
+
+// ChatWindowSetSummary replaces the conversation memory summary unless it moved past fromIndex in the meantime,
+// false is returned when the summary is kept
+func (d *ChatDao) ChatWindowSetSummary(ctx *reqctx.ReqCtx, id primitive.ObjectID, fromIndex uint64, summary string, summaryIndex uint64) (bool, error) {
+	res, err := d.chatWindowCollection.UpdateOne(ctx.Ctx, bson.M{
+		"_id":           id,
+		"is_deleted":    false,
+		"summary_index": fromIndex,
+	}, bson.M{"$set": bson.M{
+		"summary":       summary,
+		"summary_index": summaryIndex,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount != 0, nil
+}
+
 func (d *ChatDao) ChatWindowDelete(ctx *reqctx.ReqCtx, id string) error {
 	if err := d.db.delete(d.chatWindowCollection, ctx, id); err != nil {
 		return err
@@ -117,6 +166,9 @@ func (d *ChatDao) ChatHistoryAdd(ctx *reqctx.ReqCtx, e *model.ChatHistory) error
 func (d *ChatDao) ChatHistoryQuery(ctx *reqctx.ReqCtx, windowID primitive.ObjectID, index uint64) (*model.ChatHistory, error) {
 	var res model.ChatHistory
 	if err := d.db.queryByFilter(d.chatHistoryCollection, ctx, bson.M{"window_id": windowID, "index": index}, &res); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errcode.ErrChatMsgNotExist
+		}
 		return nil, err
 	}
 	return &res, nil
@@ -130,3 +182,60 @@ func (d *ChatDao) ChatHistoryList(ctx *reqctx.ReqCtx, page uint64, size uint64,
 	}
 	return res, total, nil
 }
+
+// ChatHistoryBranches returns the index and parent of the messages of the windows, without their content
+func (d *ChatDao) ChatHistoryBranches(ctx *reqctx.ReqCtx, windowIDs []primitive.ObjectID) ([]*model.ChatHistory, error) {
+	opts := options.Find().SetProjection(bson.M{"window_id": 1, "index": 1, "parent_index": 1})
+	cursor, err := d.chatHistoryCollection.Find(ctx.Ctx, bson.M{
+		"is_deleted": false,
+		"window_id":  bson.M{"$in": windowIDs},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var res []*model.ChatHistory
+	if err := cursor.All(ctx.Ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ChatHistoryListSince returns the window messages with index >= fromIndex in index order
+func (d *ChatDao) ChatHistoryListSince(ctx *reqctx.ReqCtx, windowID primitive.ObjectID, fromIndex uint64) ([]*model.ChatHistory, error) {
+	res, _, err := d.ChatHistoryList(ctx, 0, 0, bson.M{
+		"is_deleted": false,
+		"window_id":  windowID,
+		"index":      bson.M{"$gte": fromIndex},
+	}, map[string]bool{"index": true})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ChatHistorySearch runs the search of the filter, the best matches first when it is a text search ($text included),
+// the newest first otherwise
+func (d *ChatDao) ChatHistorySearch(ctx *reqctx.ReqCtx, page uint64, size uint64, filter bson.M) ([]*model.ChatHistorySearchHit, int64, error) {
+	total, err := d.chatHistoryCollection.CountDocuments(ctx.Ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "create_time", Value: -1}})
+	if _, ok := filter["$text"]; ok {
+		score := bson.M{"$meta": "textScore"}
+		opts.SetProjection(bson.M{"score": score}).
+			SetSort(bson.D{{Key: "score", Value: score}, {Key: "create_time", Value: -1}})
+	}
+	if page != 0 && size != 0 {
+		opts.SetSkip(int64((page - 1) * size)).SetLimit(int64(size))
+	}
+	cursor, err := d.chatHistoryCollection.Find(ctx.Ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	var res []*model.ChatHistorySearchHit
+	if err := cursor.All(ctx.Ctx, &res); err != nil {
+		return nil, 0, err
+	}
+	return res, total, nil
+}
@@ -0,0 +1,153 @@
+package dao
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	chatFeedbackCollectionName = "chat_feedback"
+)
+
+// chatFeedbackCountStages sums the ratings of the grouped feedbacks
+var chatFeedbackCountStages = bson.M{
+	"up":    bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$rating", model.ChatFeedbackRatingUp}}, 1, 0}}},
+	"down":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$rating", model.ChatFeedbackRatingDown}}, 1, 0}}},
+	"total": bson.M{"$sum": 1},
+}
+
+type ChatFeedbackDao struct {
+	baseComponent *base.Component
+	db            *DB
+	collection    *mongo.Collection
+}
+
+func NewChatFeedbackDao(baseComponent *base.Component, db *DB) *ChatFeedbackDao {
+	d := &ChatFeedbackDao{
+		baseComponent: baseComponent,
+		db:            db,
+	}
+	baseComponent.RegisterLifecycleHook(d)
+	return d
+}
+
+func (d *ChatFeedbackDao) Start() error {
+	d.collection = d.db.DB.Collection(chatFeedbackCollectionName)
+
+	if err := d.db.createIndexes(d.collection, false, []string{"window_id", "index"}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *ChatFeedbackDao) Stop() error {
+	return nil
+}
+
+// Upsert saves the feedback of a message, rating the message again replaces the previous feedback
+func (d *ChatFeedbackDao) Upsert(ctx *reqctx.ReqCtx, e *model.ChatFeedback) error {
+	var err error
+	e.BaseModel, err = model.NewBaseModel(ctx.Caller)
+	if err != nil {
+		return err
+	}
+	_, err = d.collection.UpdateOne(ctx.Ctx, bson.M{
+		"window_id":  e.WindowID,
+		"index":      e.Index,
+		"is_deleted": false,
+	}, bson.M{
+		"$setOnInsert": bson.M{
+			"create_time": &e.CreateTime,
+			"delete_time": &e.DeleteTime,
+			"creator":     e.Creator,
+		},
+		"$set": bson.M{
+			"update_time":    &e.UpdateTime,
+			"rating":         e.Rating,
+			"comment":        e.Comment,
+			"project_id":     e.ProjectId,
+			"agent_id":       e.AgentID,
+			"intention":      e.Intention,
+			"view":           e.View,
+			"fc_type":        e.FCType,
+			"prompt_name":    e.PromptName,
+			"prompt_version": e.PromptVersion,
+		},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+// ListByWindows returns the feedbacks of the windows in index order
+func (d *ChatFeedbackDao) ListByWindows(ctx *reqctx.ReqCtx, windowIDs []primitive.ObjectID) ([]*model.ChatFeedback, error) {
+	var res []*model.ChatFeedback
+	_, err := d.db.pageList(d.collection, ctx, 0, 0, bson.M{
+		"is_deleted": false,
+		"window_id":  bson.M{"$in": windowIDs},
+	}, map[string]bool{"index": true}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Stats counts the ratings of the feedbacks matching filter by groupBy fields, the biggest groups first
+func (d *ChatFeedbackDao) Stats(ctx *reqctx.ReqCtx, filter bson.M, groupBy []string) ([]*model.ChatFeedbackStat, error) {
+	key := bson.M{}
+	for _, field := range groupBy {
+		key[field] = "$" + field
+	}
+	group := bson.M{"_id": key}
+	for k, v := range chatFeedbackCountStages {
+		group[k] = v
+	}
+	cursor, err := d.collection.Aggregate(ctx.Ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: group}},
+		{{Key: "$replaceWith", Value: bson.M{"$mergeObjects": bson.A{"$_id", "$$ROOT"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "total", Value: -1}, {Key: "down", Value: -1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = cursor.Close(ctx.Ctx)
+	}()
+	var res []*model.ChatFeedbackStat
+	if err := cursor.All(ctx.Ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// WorstWindows returns the windows with the most thumbs-down among the feedbacks matching filter
+func (d *ChatFeedbackDao) WorstWindows(ctx *reqctx.ReqCtx, filter bson.M, limit int64) ([]*model.ChatFeedbackWindowStat, error) {
+	group := bson.M{"_id": "$window_id"}
+	for k, v := range chatFeedbackCountStages {
+		group[k] = v
+	}
+	cursor, err := d.collection.Aggregate(ctx.Ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: group}},
+		{{Key: "$match", Value: bson.M{"down": bson.M{"$gt": 0}}}},
+		{{Key: "$addFields", Value: bson.M{"window_id": "$_id"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "down", Value: -1}, {Key: "up", Value: 1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = cursor.Close(ctx.Ctx)
+	}()
+	var res []*model.ChatFeedbackWindowStat
+	if err := cursor.All(ctx.Ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,90 @@
+package dao
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	chatShareCollectionName = "chat_share"
+)
+
+type ChatShareDao struct {
+	baseComponent *base.Component
+	db            *DB
+	collection    *mongo.Collection
+}
+
+func NewChatShareDao(baseComponent *base.Component, db *DB) *ChatShareDao {
+	d := &ChatShareDao{
+		baseComponent: baseComponent,
+		db:            db,
+	}
+	baseComponent.RegisterLifecycleHook(d)
+	return d
+}
+
+func (d *ChatShareDao) Start() error {
+	d.collection = d.db.DB.Collection(chatShareCollectionName)
+
+	if err := d.db.createIndexes(d.collection, true, []string{"share_id"}); err != nil {
+		return err
+	}
+	if err := d.db.createIndexes(d.collection, false, []string{"window_id"}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *ChatShareDao) Stop() error {
+	return nil
+}
+
+func (d *ChatShareDao) Add(ctx *reqctx.ReqCtx, e *model.ChatShare) error {
+	var err error
+	e.BaseModel, err = model.NewBaseModel(ctx.Caller)
+	if err != nil {
+		return err
+	}
+	e.ID, err = d.db.insert(d.collection, ctx, e)
+	return err
+}
+
+// QueryByShareID returns the share unless it has been revoked
+func (d *ChatShareDao) QueryByShareID(ctx *reqctx.ReqCtx, shareID string) (*model.ChatShare, error) {
+	var res model.ChatShare
+	if err := d.db.queryByFilter(d.collection, ctx, bson.M{"share_id": shareID, "is_deleted": false}, &res); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errcode.ErrChatShareNotExist
+		}
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ListByWindow returns the shares of the window that have not been revoked, newest first
+func (d *ChatShareDao) ListByWindow(ctx *reqctx.ReqCtx, windowID primitive.ObjectID) ([]*model.ChatShare, error) {
+	var res []*model.ChatShare
+	if _, err := d.db.pageList(d.collection, ctx, 0, 0, bson.M{
+		"window_id":  windowID,
+		"is_deleted": false,
+	}, map[string]bool{"create_time": false}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (d *ChatShareDao) Revoke(ctx *reqctx.ReqCtx, id string) error {
+	return d.db.delete(d.collection, ctx, id)
+}
+
+// RevokeByWindow revokes all the shares of the window, e.g. when it is deleted
+func (d *ChatShareDao) RevokeByWindow(ctx *reqctx.ReqCtx, windowID primitive.ObjectID) error {
+	return d.db.deleteByFilter(d.collection, ctx, bson.M{"window_id": windowID})
+}
@@ -0,0 +1,138 @@
+package dao
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	llmUsageCollectionName     = "llm_usage"
+	llmUsageStatCollectionName = "llm_usage_stat"
+)
+
+type LLMUsageDao struct {
+	baseComponent          *base.Component
+	db                     *DB
+	llmUsageCollection     *mongo.Collection
+	llmUsageStatCollection *mongo.Collection
+}
+
+func NewLLMUsageDao(baseComponent *base.Component, db *DB) *LLMUsageDao {
+	d := &LLMUsageDao{
+		baseComponent: baseComponent,
+		db:            db,
+	}
+	baseComponent.RegisterLifecycleHook(d)
+	return d
+}
+
+func (d *LLMUsageDao) Start() error {
+	d.llmUsageCollection = d.db.DB.Collection(llmUsageCollectionName)
+	d.llmUsageStatCollection = d.db.DB.Collection(llmUsageStatCollectionName)
+
+	if err := d.db.createIndexes(d.llmUsageCollection, false, []string{"creator", "window_id"}); err != nil {
+		return err
+	}
+	if err := d.db.createIndexes(d.llmUsageStatCollection, false, []string{"user_id", "date"}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *LLMUsageDao) Stop() error {
+	return nil
+}
+
+// Add records the usage of a call and adds it to the daily and monthly stats of the user and project
+func (d *LLMUsageDao) Add(ctx *reqctx.ReqCtx, e *model.LLMUsage) error {
+	var err error
+	e.BaseModel, err = model.NewBaseModel(ctx.Caller)
+	if err != nil {
+		return err
+	}
+	e.ID, err = d.db.insert(d.llmUsageCollection, ctx, e)
+	if err != nil {
+		return err
+	}
+
+	opts := options.Update().SetUpsert(true)
+	for _, period := range []model.LLMUsagePeriod{model.LLMUsagePeriodDay, model.LLMUsagePeriodMonth} {
+		date := model.LLMUsagePeriodDate(period, time.Time(e.CreateTime))
+		// the id identifies the stat so concurrent calls increase the same document
+		id := fmt.Sprintf("%s/%s/%s/%s", period, date, e.Creator.Hex(), e.ProjectId)
+		_, err := d.llmUsageStatCollection.UpdateByID(ctx.Ctx, id, bson.M{
+			"$setOnInsert": bson.M{
+				"user_id": e.Creator,
+				"period":  period,
+				"date":    date,
+			},
+			// set on every call since the stats of the current period were written as agent_id
+			"$set": bson.M{
+				"project_id": e.ProjectId,
+			},
+			"$inc": bson.M{
+				"calls":             1,
+				"prompt_tokens":     e.PromptTokens,
+				"completion_tokens": e.CompletionTokens,
+				"total_tokens":      e.TotalTokens,
+			},
+		}, opts)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UserTotalTokens returns the tokens used by the user with all projects in the period date
+func (d *LLMUsageDao) UserTotalTokens(ctx *reqctx.ReqCtx, userID primitive.ObjectID, period model.LLMUsagePeriod, date string) (int64, error) {
+	res, err := d.Stats(ctx, bson.M{"user_id": userID, "period": period, "date": date}, "")
+	if err != nil {
+		return 0, err
+	}
+	if len(res) == 0 {
+		return 0, nil
+	}
+	return res[0].TotalTokens, nil
+}
+
+// Stats sums the stats matching filter by date and groupBy (user_id, project_id or empty to sum all of them),
+// newest dates and biggest consumers first
+func (d *LLMUsageDao) Stats(ctx *reqctx.ReqCtx, filter bson.M, groupBy string) ([]*model.LLMUsageStat, error) {
+	key := bson.M{"period": "$period", "date": "$date"}
+	if groupBy != "" {
+		key[groupBy] = "$" + groupBy
+	}
+	cursor, err := d.llmUsageStatCollection.Aggregate(ctx.Ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":               key,
+			"calls":             bson.M{"$sum": "$calls"},
+			"prompt_tokens":     bson.M{"$sum": "$prompt_tokens"},
+			"completion_tokens": bson.M{"$sum": "$completion_tokens"},
+			"total_tokens":      bson.M{"$sum": "$total_tokens"},
+		}}},
+		{{Key: "$replaceWith", Value: bson.M{"$mergeObjects": bson.A{"$_id", "$$ROOT"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: -1}, {Key: "total_tokens", Value: -1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = cursor.Close(ctx.Ctx)
+	}()
+	var res []*model.LLMUsageStat
+	if err := cursor.All(ctx.Ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,75 @@
+package dao
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	projectChunkCollectionName = "project_chunk"
+)
+
+type ProjectChunkDao struct {
+	baseComponent *base.Component
+	db            *DB
+	collection    *mongo.Collection
+}
+
+func NewProjectChunkDao(baseComponent *base.Component, db *DB) *ProjectChunkDao {
+	d := &ProjectChunkDao{
+		baseComponent: baseComponent,
+		db:            db,
+	}
+	baseComponent.RegisterLifecycleHook(d)
+	return d
+}
+
+func (d *ProjectChunkDao) Start() error {
+	d.collection = d.db.DB.Collection(projectChunkCollectionName)
+
+	if err := d.db.createIndexes(d.collection, false, []string{"project_id"}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *ProjectChunkDao) Stop() error {
+	return nil
+}
+
+// Replace swaps all chunks of the project, chunks are derived from the project and are removed for good
+func (d *ProjectChunkDao) Replace(ctx *reqctx.ReqCtx, projectID primitive.ObjectID, chunks []*model.ProjectChunk) error {
+	if _, err := d.collection.DeleteMany(ctx.Ctx, bson.M{"project_id": projectID}); err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+	docs := make([]any, len(chunks))
+	for i, chunk := range chunks {
+		var err error
+		chunk.BaseModel, err = model.NewBaseModel(ctx.Caller)
+		if err != nil {
+			return err
+		}
+		chunk.ID = primitive.NewObjectID()
+		docs[i] = chunk
+	}
+	_, err := d.collection.InsertMany(ctx.Ctx, docs)
+	return err
+}
+
+// ListAll returns the chunks embedded with embeddingModel
+func (d *ProjectChunkDao) ListAll(ctx *reqctx.ReqCtx, embeddingModel string) ([]*model.ProjectChunk, error) {
+	var res []*model.ProjectChunk
+	_, err := d.db.pageList(d.collection, ctx, 0, 0, bson.M{"embedding_model": embeddingModel}, map[string]bool{"project_id": true}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
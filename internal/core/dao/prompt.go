@@ -0,0 +1,140 @@
+package dao
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	promptTemplateCollectionName = "prompt_template"
+)
+
+type PromptDao struct {
+	baseComponent *base.Component
+	db            *DB
+	collection    *mongo.Collection
+}
+
+func NewPromptDao(baseComponent *base.Component, db *DB) *PromptDao {
+	d := &PromptDao{
+		baseComponent: baseComponent,
+		db:            db,
+	}
+	baseComponent.RegisterLifecycleHook(d)
+	return d
+}
+
+func (d *PromptDao) Start() error {
+	d.collection = d.db.DB.Collection(promptTemplateCollectionName)
+
+	if err := d.db.createIndexes(d.collection, false, []string{"name", "is_active"}); err != nil {
+		return err
+	}
+	// two admins editing a template at once would otherwise both add the next version
+	if err := d.db.createUniqueIndexWhere(d.collection, []string{"name", "version"}, bson.M{"is_deleted": false}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *PromptDao) Stop() error {
+	return nil
+}
+
+// Add inserts a template version, ErrPromptTemplateVersionExists is returned when the version was added meanwhile
+func (d *PromptDao) Add(ctx *reqctx.ReqCtx, e *model.PromptTemplate) error {
+	var err error
+	e.BaseModel, err = model.NewBaseModel(ctx.Caller)
+	if err != nil {
+		return err
+	}
+	e.ID, err = d.db.insert(d.collection, ctx, e)
+	if mongo.IsDuplicateKeyError(err) {
+		return errcode.ErrPromptTemplateVersionExists
+	}
+	return err
+}
+
+func (d *PromptDao) ExistsByName(ctx *reqctx.ReqCtx, name string) (bool, error) {
+	cnt, err := d.collection.CountDocuments(ctx.Ctx, bson.M{"name": name, "is_deleted": false})
+	if err != nil {
+		return false, err
+	}
+	return cnt != 0, nil
+}
+
+// Latest returns the highest version of the template
+func (d *PromptDao) Latest(ctx *reqctx.ReqCtx, name string) (*model.PromptTemplate, error) {
+	var res model.PromptTemplate
+	err := d.collection.FindOne(ctx.Ctx, bson.M{"name": name, "is_deleted": false}, options.FindOne().SetSort(bson.M{"version": -1})).Decode(&res)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errcode.ErrPromptTemplateNotExist
+		}
+		return nil, err
+	}
+	return &res, nil
+}
+
+// QueryVersion returns a version of the template
+func (d *PromptDao) QueryVersion(ctx *reqctx.ReqCtx, name string, version uint64) (*model.PromptTemplate, error) {
+	var res model.PromptTemplate
+	if err := d.db.queryByFilter(d.collection, ctx, bson.M{"name": name, "version": version, "is_deleted": false}, &res); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errcode.ErrPromptTemplateNotExist
+		}
+		return nil, err
+	}
+	return &res, nil
+}
+
+// SetActive makes the version the only active one of the template, or deactivates all versions when isActive is false
+func (d *PromptDao) SetActive(ctx *reqctx.ReqCtx, name string, version uint64, isActive bool) error {
+	now := model.JSONTime(time.Now())
+	if _, err := d.collection.UpdateMany(ctx.Ctx, bson.M{"name": name, "is_active": true}, bson.M{"$set": bson.M{
+		"is_active":   false,
+		"update_time": &now,
+	}}); err != nil {
+		return err
+	}
+	if !isActive {
+		return nil
+	}
+	res, err := d.collection.UpdateOne(ctx.Ctx, bson.M{"name": name, "version": version, "is_deleted": false}, bson.M{"$set": bson.M{
+		"is_active":   true,
+		"update_time": &now,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errcode.ErrPromptTemplateNotExist
+	}
+	return nil
+}
+
+func (d *PromptDao) List(ctx *reqctx.ReqCtx, page uint64, size uint64, filter any, sort map[string]bool) ([]*model.PromptTemplate, int64, error) {
+	var res []*model.PromptTemplate
+	total, err := d.db.pageList(d.collection, ctx, page, size, filter, sort, &res)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res, total, nil
+}
+
+// ListActive returns the active version of every template
+func (d *PromptDao) ListActive(ctx *reqctx.ReqCtx) ([]*model.PromptTemplate, error) {
+	res, _, err := d.List(ctx, 0, 0, bson.M{"is_active": true, "is_deleted": false}, map[string]bool{"name": true})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
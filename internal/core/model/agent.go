@@ -0,0 +1,43 @@
+package model
+
+type AgentVisibility = string
+
+const (
+	// AgentVisibilityPublic agents are listed to every user
+	AgentVisibilityPublic AgentVisibility = "public"
+	// AgentVisibilityInternal agents are only listed to managers and admins, e.g. while being tuned
+	AgentVisibilityInternal AgentVisibility = "internal"
+	// AgentVisibilityHidden agents are not listed and cannot be chatted with
+	AgentVisibilityHidden AgentVisibility = "hidden"
+)
+
+// Agent is an assistant of the catalog, backed by a project of the wyt ai backend
+type Agent struct {
+	BaseModel `bson:"inline"`
+
+	Name        string `json:"name" bson:"name"`
+	Description string `json:"description" bson:"description"`
+	Icon        string `json:"icon" bson:"icon"`
+	// ProjectId of the backing project, chat windows and pins refer to the agent with it
+	ProjectId string `json:"project_id" bson:"project_id"`
+	// Prompt adds the instructions of the agent to the system prompt, the assigned template or the built-in one
+	Prompt string `json:"prompt" bson:"prompt"`
+	// EnabledTools restricts the tools offered to the model, all tools allowed by the tool policies when empty
+	EnabledTools []string        `json:"enabled_tools" bson:"enabled_tools"`
+	Visibility   AgentVisibility `json:"visibility" bson:"visibility"`
+	// DefaultPinned agents are pinned for the users who did not unpin them
+	DefaultPinned bool `json:"default_pinned" bson:"default_pinned"`
+	// Order sorts the catalog, ascending
+	Order int `json:"order" bson:"order"`
+}
+
+// VisibleTo checks if the agent is listed to the role
+func (a *Agent) VisibleTo(role UserRole) bool {
+	switch a.Visibility {
+	case AgentVisibilityPublic:
+		return true
+	case AgentVisibilityInternal:
+		return role == UserRoleManager || role == UserRoleAdmin
+	}
+	return false
+}
@@ -190,6 +190,11 @@ type ChatContentAssistant struct {
 	TopTrader         *ChatContentAssistantTopTraderRes      `json:"top_trader" bson:"top_trader"`
 	TraderOverview    *ChatContentAssistantTraderOverviewRes `json:"trader_overview" bson:"trader_overview"`
 	Uniswap           *ChatContentAssistantUniswapRes        `json:"uniswap" bson:"uniswap"`
+	// registered tools without a dedicated field
+	Tool *ChatContentAssistantToolRes `json:"tool,omitempty" bson:"tool,omitempty"`
+
+	// image of the quantitative answers (pump.fun data, comparisons), hosted in the file system
+	ChartURL string `json:"chart_url,omitempty" bson:"chart_url,omitempty"`
 }
 
 type ChatContentAssistantSwapRes struct {
@@ -214,6 +219,13 @@ type FuncCallingRet struct {
 
 	// RemoteFunctionResult store the result executed by remote function
 	RemoteFunctionResult map[string]any `json:"remote_function_result" bson:"remote_function_result"`
+	// ToolResult stores the result of registered tools without a dedicated field
+	ToolResult any `json:"tool_result,omitempty" bson:"tool_result,omitempty"`
+}
+
+type ChatContentAssistantToolRes struct {
+	View ChatContentAssistantView `json:"view" bson:"view"`
+	Tool ChatContentAssistantInfo `json:"tool" bson:"tool"`
 }
 
 type ChatContentAssistantTraderOverviewRes struct {
@@ -334,6 +346,23 @@ type ChatWindow struct {
 	MsgNum uint64 `json:"msg_num" bson:"msg_num"`
 
 	ProjectId string `json:"project_id" bson:"project_id"`
+	// all messages are answered by the agent of ProjectId, otherwise it is the fallback of the agent router
+	AgentPinned bool `json:"agent_pinned" bson:"agent_pinned"`
+
+	// index of the last message of the active branch, the next question follows it.
+	// nil for the windows written before branching, their last message is MsgNum-1
+	Head *int64 `json:"head,omitempty" bson:"head,omitempty"`
+
+	// llm summary of the history messages before SummaryIndex, used as conversation memory
+	Summary      string `json:"-" bson:"summary"`
+	SummaryIndex uint64 `json:"-" bson:"summary_index"`
+}
+
+func (w *ChatWindow) HeadIndex() int64 {
+	if w.Head != nil {
+		return *w.Head
+	}
+	return int64(w.MsgNum) - 1
 }
 
 type ChatHistory struct {
@@ -342,4 +371,25 @@ type ChatHistory struct {
 	Index     uint64             `json:"index" bson:"index"`
 	Msg       ChatMsg            `json:"msg" bson:"msg"`
 	ProjectId string             `json:"project_id" bson:"project_id"`
+	// index of the previous message of the branch, -1 for the first message of the window.
+	// nil for the messages written before branching, they follow Index-1
+	ParentIndex *int64 `json:"parent_index,omitempty" bson:"parent_index,omitempty"`
+	// agent backed by ProjectId that answered the turn, zero for projects outside the agent catalog
+	AgentID primitive.ObjectID `json:"agent_id,omitempty" bson:"agent_id,omitempty"`
+	// prompt template version the turn was answered with, empty for the built-in prompt
+	PromptName    string `json:"prompt_name,omitempty" bson:"prompt_name,omitempty"`
+	PromptVersion uint64 `json:"prompt_version,omitempty" bson:"prompt_version,omitempty"`
+}
+
+func (h *ChatHistory) Parent() int64 {
+	if h.ParentIndex != nil {
+		return *h.ParentIndex
+	}
+	return int64(h.Index) - 1
+}
+
+// ChatHistorySearchHit is a message matched by the text search with its relevance
+type ChatHistorySearchHit struct {
+	ChatHistory `bson:"inline"`
+	Score       float64 `json:"score" bson:"score"`
 }
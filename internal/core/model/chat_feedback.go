@@ -0,0 +1,81 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ChatFeedbackRating = string
+
+const (
+	ChatFeedbackRatingUp   ChatFeedbackRating = "up"
+	ChatFeedbackRatingDown ChatFeedbackRating = "down"
+)
+
+// ChatFeedback is the rating of an answer given by the window owner (the creator)
+type ChatFeedback struct {
+	BaseModel `bson:"inline"`
+
+	WindowID primitive.ObjectID `json:"window_id" bson:"window_id"`
+	// index of the rated message in the window
+	Index   uint64             `json:"index" bson:"index"`
+	Rating  ChatFeedbackRating `json:"rating" bson:"rating"`
+	Comment string             `json:"comment" bson:"comment"`
+
+	// copied from the rated message so the analytics do not need to load the histories
+	ProjectId     string             `json:"project_id" bson:"project_id"`
+	AgentID       primitive.ObjectID `json:"agent_id,omitempty" bson:"agent_id,omitempty"`
+	Intention     string             `json:"intention" bson:"intention"`
+	View          string             `json:"view" bson:"view"`
+	FCType        string             `json:"fc_type" bson:"fc_type"`
+	PromptName    string             `json:"prompt_name" bson:"prompt_name"`
+	PromptVersion uint64             `json:"prompt_version" bson:"prompt_version"`
+}
+
+// ChatFeedbackStat counts the ratings of one group of answers, the group fields not used are empty
+type ChatFeedbackStat struct {
+	Intention     string  `json:"intention,omitempty" bson:"intention"`
+	View          string  `json:"view,omitempty" bson:"view"`
+	FCType        string  `json:"fc_type,omitempty" bson:"fc_type"`
+	PromptName    string  `json:"prompt_name,omitempty" bson:"prompt_name"`
+	PromptVersion uint64  `json:"prompt_version,omitempty" bson:"prompt_version"`
+	Up            int64   `json:"up" bson:"up"`
+	Down          int64   `json:"down" bson:"down"`
+	Total         int64   `json:"total" bson:"total"`
+	UpRatio       float64 `json:"up_ratio" bson:"-"`
+}
+
+// ChatFeedbackWindowStat counts the ratings of the answers of a window
+type ChatFeedbackWindowStat struct {
+	WindowID primitive.ObjectID `json:"window_id" bson:"window_id"`
+	Up       int64              `json:"up" bson:"up"`
+	Down     int64              `json:"down" bson:"down"`
+}
+
+// Facets returns the view and the function calling type of the answer, both are empty for plain answers
+func (c *ChatContentAssistant) Facets() (view ChatContentAssistantView, fcType FuncCallingType) {
+	switch {
+	case c.ProjectInfo != nil:
+		return c.ProjectInfo.View, ""
+	case c.GeneralAnswer != nil:
+		return c.GeneralAnswer.View, ""
+	case c.ProjectCompare != nil:
+		return c.ProjectCompare.View, ""
+	case c.SwapInfo != nil:
+		return c.SwapInfo.View, c.SwapInfo.Swap.FuncCallingRet.FCType
+	case c.DailyNewToken != nil:
+		return c.DailyNewToken.View, c.DailyNewToken.NewToken.FuncCallingRet.FCType
+	case c.TokenLaunchedTime != nil:
+		return c.TokenLaunchedTime.View, c.TokenLaunchedTime.LaunchedTimeDistribution.FuncCallingRet.FCType
+	case c.TokenSwapCount != nil:
+		return c.TokenSwapCount.View, c.TokenSwapCount.TokenSwapCount.FuncCallingRet.FCType
+	case c.TopTrader != nil:
+		return c.TopTrader.View, c.TopTrader.TopTrader.FuncCallingRet.FCType
+	case c.TraderOverview != nil:
+		return c.TraderOverview.View, c.TraderOverview.TraderOverview.FuncCallingRet.FCType
+	case c.Uniswap != nil:
+		return c.Uniswap.View, c.Uniswap.Uniswap.FuncCallingRet.FCType
+	case c.Tool != nil:
+		return c.Tool.View, c.Tool.Tool.FuncCallingRet.FCType
+	}
+	return "", ""
+}
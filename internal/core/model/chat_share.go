@@ -0,0 +1,18 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChatShare is a read-only copy of a chat window taken when the owner (the creator) shared it,
+// revoking the share soft deletes it
+type ChatShare struct {
+	BaseModel `bson:"inline"`
+
+	// public id of the share, random so it cannot be guessed from the window id
+	ShareID   string             `json:"share_id" bson:"share_id"`
+	WindowID  primitive.ObjectID `json:"window_id" bson:"window_id"`
+	Title     string             `json:"title" bson:"title"`
+	ProjectId string             `json:"project_id" bson:"project_id"`
+	Msgs      []*ChatHistory     `json:"msgs" bson:"msgs"`
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LLMUsage is the token usage of one model call made while answering a user (the creator)
+type LLMUsage struct {
+	BaseModel `bson:"inline"`
+
+	WindowID primitive.ObjectID `json:"window_id" bson:"window_id"`
+	// project the question was asked about, see ChatHistory.ProjectId
+	ProjectId        string `json:"project_id" bson:"project_id"`
+	Model            string `json:"model" bson:"model"`
+	PromptTokens     int64  `json:"prompt_tokens" bson:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens" bson:"completion_tokens"`
+	TotalTokens      int64  `json:"total_tokens" bson:"total_tokens"`
+}
+
+type LLMUsagePeriod = string
+
+const (
+	LLMUsagePeriodDay   LLMUsagePeriod = "day"
+	LLMUsagePeriodMonth LLMUsagePeriod = "month"
+)
+
+// LLMUsagePeriodDate returns the date of the period in UTC, 2006-01-02 for days and 2006-01 for months
+func LLMUsagePeriodDate(period LLMUsagePeriod, t time.Time) string {
+	if period == LLMUsagePeriodMonth {
+		return t.UTC().Format("2006-01")
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+// LLMUsageStat aggregates the usage of a user with a project over a day or a month
+type LLMUsageStat struct {
+	UserID           primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ProjectId        string             `json:"project_id" bson:"project_id"`
+	Period           LLMUsagePeriod     `json:"period" bson:"period"`
+	Date             string             `json:"date" bson:"date"`
+	Calls            int64              `json:"calls" bson:"calls"`
+	PromptTokens     int64              `json:"prompt_tokens" bson:"prompt_tokens"`
+	CompletionTokens int64              `json:"completion_tokens" bson:"completion_tokens"`
+	TotalTokens      int64              `json:"total_tokens" bson:"total_tokens"`
+}
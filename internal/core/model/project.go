@@ -518,3 +518,25 @@ func (p *Project) CalculateDerivedData(cfg *config.Config) {
 	p.calculateFundingInfo(cfg)
 	p.calculateExchanges()
 }
+
+type ProjectChunkSection = string
+
+const (
+	ProjectChunkSectionDescription ProjectChunkSection = "description"
+	ProjectChunkSectionTokenomics  ProjectChunkSection = "tokenomics"
+	ProjectChunkSectionTeam        ProjectChunkSection = "team"
+	ProjectChunkSectionFunding     ProjectChunkSection = "funding"
+)
+
+// ProjectChunk is a piece of published project text with its embedding, used to answer questions with our own data
+type ProjectChunk struct {
+	BaseModel `bson:"inline"`
+
+	ProjectID   primitive.ObjectID  `json:"project_id" bson:"project_id"`
+	ProjectName string              `json:"project_name" bson:"project_name"`
+	Section     ProjectChunkSection `json:"section" bson:"section"`
+	Content     string              `json:"content" bson:"content"`
+
+	Embedding      []float32 `json:"-" bson:"embedding"`
+	EmbeddingModel string    `json:"-" bson:"embedding_model"`
+}
@@ -0,0 +1,32 @@
+package model
+
+// PromptTemplate is a version of the system prompt (and response schema) sent with chat questions.
+// Content is a text/template rendered with PromptVars. Every edit adds a version of the same name,
+// at most one version of a name is active, and the active templates offered to an agent are assigned
+// to chat windows by weight.
+type PromptTemplate struct {
+	BaseModel `bson:"inline"`
+
+	Name    string `json:"name" bson:"name"`
+	Version uint64 `json:"version" bson:"version"`
+	Content string `json:"content" bson:"content"`
+	// json_schema object (name, schema, strict) of the answer, empty keeps the default schema
+	Schema map[string]any `json:"schema" bson:"schema"`
+	// agents (project ids) the template is offered to, empty offers it to the agents without their own template
+	AgentIDs []string `json:"agent_ids" bson:"agent_ids"`
+	Weight   uint64   `json:"weight" bson:"weight"`
+	IsActive bool     `json:"is_active" bson:"is_active"`
+	Remark   string   `json:"remark" bson:"remark"`
+	// the wyt ai backend cannot take a custom prompt or schema, so a template only applies to the questions
+	// it answers when it opts to have them answered by the model instead, otherwise those questions skip it
+	BypassBackend bool `json:"bypass_backend" bson:"bypass_backend"`
+}
+
+// PromptVars are the variables available in PromptTemplate.Content
+type PromptVars struct {
+	// today in UTC, 2006-01-02
+	Date    string
+	AgentID string
+	// en or zh, the language of the user interface
+	Language string
+}
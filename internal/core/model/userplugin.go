@@ -1,19 +1,31 @@
 package model
 
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserPlugin is the pin of an agent by a user
 type UserPlugin struct {
 	BaseModel `bson:"inline"`
 
-	UserId      string `json:"user_id" bson:"user_id"`
-	ProjectId   string `json:"project_id" bson:"project_id"`
-	ProjectName string `json:"project_name" bson:"project_name"`
+	UserId      string             `json:"user_id" bson:"user_id"`
+	AgentID     primitive.ObjectID `json:"agent_id" bson:"agent_id,omitempty"`
+	ProjectId   string             `json:"project_id" bson:"project_id"`
+	ProjectName string             `json:"project_name" bson:"project_name"`
 
 	PinStatus int `json:"pin_status" bson:"pin_status"`
+	// PinOrder sorts the pinned agents of the user, ascending
+	PinOrder int `json:"pin_order" bson:"pin_order"`
 }
 
 type UserPluginDto struct {
 	UserId      string `json:"user_id" bson:"user_id"`
+	AgentID     string `json:"agent_id" bson:"agent_id"`
 	ProjectId   string `json:"project_id" bson:"project_id"`
 	ProjectName string `json:"project_name" bson:"project_name"`
+	Description string `json:"description" bson:"description"`
+	Icon        string `json:"icon" bson:"icon"`
 	PinStatus   int    `json:"pin_status" bson:"pin_status"`
+	PinOrder    int    `json:"pin_order" bson:"pin_order"`
 	PinTime     int64  `json:"pin_time" bson:"pin_time"`
 }
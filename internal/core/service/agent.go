@@ -0,0 +1,319 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/dao"
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	agentUnpinned = 0
+	agentPinned   = 1
+)
+
+// agentStore keeps the agent catalog, implemented by dao.AgentDao
+type agentStore interface {
+	Add(ctx *reqctx.ReqCtx, e *model.Agent) error
+	Update(ctx *reqctx.ReqCtx, e *model.Agent) error
+	Delete(ctx *reqctx.ReqCtx, id string) error
+	Query(ctx *reqctx.ReqCtx, id string) (*model.Agent, error)
+	QueryByProjectId(ctx *reqctx.ReqCtx, projectId string) (*model.Agent, error)
+	ExistsByProjectId(ctx *reqctx.ReqCtx, projectId string) (bool, error)
+	CountAll(ctx *reqctx.ReqCtx) (int64, error)
+	List(ctx *reqctx.ReqCtx, page uint64, size uint64, filter any, sort map[string]bool) ([]*model.Agent, int64, error)
+}
+
+// AgentService manages the agent catalog and the agents pinned by the users
+type AgentService struct {
+	baseComponent *base.Component
+	agentDao      agentStore
+	userPluginDao *dao.UserPluginDao
+}
+
+func NewAgentService(baseComponent *base.Component, agentDao *dao.AgentDao, userPluginDao *dao.UserPluginDao) *AgentService {
+	s := &AgentService{
+		baseComponent: baseComponent,
+		agentDao:      agentDao,
+		userPluginDao: userPluginDao,
+	}
+	baseComponent.RegisterLifecycleHook(s)
+	return s
+}
+
+// Start fills an empty catalog with the agents of the wyt ai backend projects, which used to be built in
+func (s *AgentService) Start() error {
+	ctx := s.baseComponent.BackgroundContext()
+	cnt, err := s.agentDao.CountAll(ctx)
+	if err != nil {
+		return err
+	}
+	if cnt != 0 {
+		return nil
+	}
+	for _, agent := range defaultAgents(s.baseComponent.Config) {
+		if err := s.agentDao.Add(ctx, agent); err != nil {
+			return err
+		}
+		s.baseComponent.Logger.WithField("agent", agent.Name).Info("Default agent added")
+	}
+	return nil
+}
+
+func (s *AgentService) Stop() error {
+	return nil
+}
+
+// Lookup returns the agent backed by the project, nil when the project is not in the catalog
+func (s *AgentService) Lookup(ctx *reqctx.ReqCtx, projectId string) (*model.Agent, error) {
+	agent, err := s.agentDao.QueryByProjectId(ctx, projectId)
+	if err != nil {
+		if err == errcode.ErrAgentNotExist {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !agent.VisibleTo(ctx.CallerRole) {
+		return nil, errcode.ErrAgentNotExist
+	}
+	return agent, nil
+}
+
+// List returns the agents visible to the caller, the pinned ones first in the order chosen by the caller
+func (s *AgentService) List(ctx *reqctx.ReqCtx, req *entity.AgentListReq) (*entity.AgentListRes, error) {
+	list, err := s.userAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.AgentListRes{
+		List:  list,
+		Total: int64(len(list)),
+	}, nil
+}
+
+func (s *AgentService) Pin(ctx *reqctx.ReqCtx, req *entity.AgentPinReq) error {
+	agent, err := s.visibleAgent(ctx, req.ProjectId)
+	if err != nil {
+		return err
+	}
+	pins, err := s.userPins(ctx)
+	if err != nil {
+		return err
+	}
+	pin, ok := lo.Find(pins, func(item *model.UserPlugin) bool {
+		return item.ProjectId == req.ProjectId
+	})
+	if ok && pin.PinStatus == agentPinned {
+		return nil
+	}
+	return s.savePin(ctx, pin, agent, agentPinned, nextPinOrder(pins))
+}
+
+func (s *AgentService) Unpin(ctx *reqctx.ReqCtx, req *entity.AgentUnPinReq) error {
+	pins, err := s.userPins(ctx)
+	if err != nil {
+		return err
+	}
+	pin, ok := lo.Find(pins, func(item *model.UserPlugin) bool {
+		return item.ProjectId == req.ProjectId
+	})
+	if ok {
+		pin.PinStatus = agentUnpinned
+		return s.userPluginDao.UpdateUserPin(ctx, pin)
+	}
+	// the agent may be pinned by default, the record keeps it unpinned
+	agent, err := s.visibleAgent(ctx, req.ProjectId)
+	if err != nil {
+		return err
+	}
+	return s.savePin(ctx, nil, agent, agentUnpinned, 0)
+}
+
+func (s *AgentService) SetPinOrder(ctx *reqctx.ReqCtx, req *entity.AgentPinOrderReq) error {
+	if len(lo.Uniq(req.ProjectIds)) != len(req.ProjectIds) {
+		return errcode.ErrRequestParameter.Wrap("project_ids cannot contain duplicates")
+	}
+	agents, err := s.visibleAgents(ctx)
+	if err != nil {
+		return err
+	}
+	pins, err := s.userPins(ctx)
+	if err != nil {
+		return err
+	}
+	agentMap := lo.KeyBy(agents, func(item *model.Agent) string {
+		return item.ProjectId
+	})
+	for _, projectId := range req.ProjectIds {
+		if _, ok := agentMap[projectId]; !ok {
+			return errcode.ErrAgentNotExist.Wrap(projectId)
+		}
+	}
+
+	order := append([]string{}, req.ProjectIds...)
+	for _, dto := range buildAgentList(ctx.Caller, agents, pins) {
+		if dto.PinStatus == agentPinned && !lo.Contains(order, dto.ProjectId) {
+			order = append(order, dto.ProjectId)
+		}
+	}
+	pinMap := lo.KeyBy(pins, func(item *model.UserPlugin) string {
+		return item.ProjectId
+	})
+	for i, projectId := range order {
+		pin := pinMap[projectId]
+		if pin != nil && pin.PinStatus == agentPinned && pin.PinOrder == i {
+			continue
+		}
+		if err := s.savePin(ctx, pin, agentMap[projectId], agentPinned, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// savePin updates the pin of the agent, pin is nil when the user has no record of the agent yet
+func (s *AgentService) savePin(ctx *reqctx.ReqCtx, pin *model.UserPlugin, agent *model.Agent, status int, order int) error {
+	if pin != nil {
+		pin.AgentID = agent.ID
+		pin.ProjectName = agent.Name
+		pin.PinStatus = status
+		pin.PinOrder = order
+		return s.userPluginDao.UpdateUserPin(ctx, pin)
+	}
+	bm, err := model.NewBaseModel(ctx.Caller)
+	if err != nil {
+		return err
+	}
+	return s.userPluginDao.InsertUserPin(ctx, &model.UserPlugin{
+		BaseModel:   bm,
+		UserId:      ctx.Caller,
+		AgentID:     agent.ID,
+		ProjectId:   agent.ProjectId,
+		ProjectName: agent.Name,
+		PinStatus:   status,
+		PinOrder:    order,
+	})
+}
+
+func (s *AgentService) userAgents(ctx *reqctx.ReqCtx) ([]*model.UserPluginDto, error) {
+	agents, err := s.visibleAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pins, err := s.userPins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return buildAgentList(ctx.Caller, agents, pins), nil
+}
+
+func (s *AgentService) visibleAgents(ctx *reqctx.ReqCtx) ([]*model.Agent, error) {
+	agents, _, err := s.agentDao.List(ctx, 0, 0, bson.M{"is_deleted": false}, map[string]bool{"order": true})
+	if err != nil {
+		return nil, err
+	}
+	return lo.Filter(agents, func(item *model.Agent, _ int) bool {
+		return item.VisibleTo(ctx.CallerRole)
+	}), nil
+}
+
+func (s *AgentService) visibleAgent(ctx *reqctx.ReqCtx, projectId string) (*model.Agent, error) {
+	agent, err := s.Lookup(ctx, projectId)
+	if err != nil {
+		return nil, err
+	}
+	if agent == nil {
+		return nil, errcode.ErrAgentNotExist
+	}
+	return agent, nil
+}
+
+func (s *AgentService) userPins(ctx *reqctx.ReqCtx) ([]*model.UserPlugin, error) {
+	if _, err := primitive.ObjectIDFromHex(ctx.Caller); err != nil {
+		return nil, err
+	}
+	pins, _, err := s.userPluginDao.UserPluginPinList(ctx, 0, 0, bson.M{
+		"is_deleted": false,
+		"user_id":    ctx.Caller,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+// buildAgentList merges the catalog (in catalog order) with the pins of the user, the agents without pin record
+// use their default pin status
+func buildAgentList(userId string, agents []*model.Agent, pins []*model.UserPlugin) []*model.UserPluginDto {
+	pinMap := lo.KeyBy(pins, func(item *model.UserPlugin) string {
+		return item.ProjectId
+	})
+	res := make([]*model.UserPluginDto, 0, len(agents))
+	for _, agent := range agents {
+		dto := &model.UserPluginDto{
+			UserId:      userId,
+			AgentID:     agent.ID.Hex(),
+			ProjectId:   agent.ProjectId,
+			ProjectName: agent.Name,
+			Description: agent.Description,
+			Icon:        agent.Icon,
+			PinStatus:   lo.Ternary(agent.DefaultPinned, agentPinned, agentUnpinned),
+		}
+		if pin, ok := pinMap[agent.ProjectId]; ok {
+			dto.PinStatus = pin.PinStatus
+			dto.PinOrder = pin.PinOrder
+			dto.PinTime = time.Time(pin.UpdateTime).Unix()
+		}
+		res = append(res, dto)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].PinStatus != res[j].PinStatus {
+			return res[i].PinStatus == agentPinned
+		}
+		return res[i].PinStatus == agentPinned && res[i].PinOrder < res[j].PinOrder
+	})
+	return res
+}
+
+func nextPinOrder(pins []*model.UserPlugin) int {
+	next := 0
+	for _, pin := range pins {
+		if pin.PinStatus == agentPinned && pin.PinOrder >= next {
+			next = pin.PinOrder + 1
+		}
+	}
+	return next
+}
+
+// defaultAgents are the agents served before the catalog existed
+func defaultAgents(cfg *config.Config) []*model.Agent {
+	var agents []*model.Agent
+	if cfg.AIBackend.ProjectId != "" {
+		agents = append(agents, &model.Agent{
+			Name:          "WYT Agent",
+			ProjectId:     cfg.AIBackend.ProjectId,
+			Visibility:    model.AgentVisibilityPublic,
+			DefaultPinned: true,
+			Order:         0,
+		})
+	}
+	if cfg.AIBackend.UniProjectId != "" {
+		agents = append(agents, &model.Agent{
+			Name:       "Uniswap",
+			ProjectId:  cfg.AIBackend.UniProjectId,
+			Visibility: model.AgentVisibilityPublic,
+			Order:      1,
+		})
+	}
+	return agents
+}
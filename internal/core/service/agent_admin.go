@@ -0,0 +1,113 @@
+package service
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func (s *AgentService) AdminAdd(ctx *reqctx.ReqCtx, req *entity.AgentAddReq) (*entity.AgentAddRes, error) {
+	if err := checkAgent(req); err != nil {
+		return nil, err
+	}
+	exist, err := s.agentDao.ExistsByProjectId(ctx, req.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+	if exist {
+		return nil, errcode.ErrAgentAlreadyExists
+	}
+
+	agent := &model.Agent{}
+	fillAgent(agent, req)
+	if err := s.agentDao.Add(ctx, agent); err != nil {
+		return nil, err
+	}
+	return &entity.AgentAddRes{
+		ID: agent.ID,
+	}, nil
+}
+
+func (s *AgentService) AdminUpdate(ctx *reqctx.ReqCtx, req *entity.AgentUpdateReq) (*entity.AgentUpdateRes, error) {
+	if err := checkAgent(&req.AgentAddReq); err != nil {
+		return nil, err
+	}
+	agent, err := s.agentDao.Query(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if agent.ProjectId != req.ProjectId {
+		exist, err := s.agentDao.ExistsByProjectId(ctx, req.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			return nil, errcode.ErrAgentAlreadyExists
+		}
+	}
+
+	fillAgent(agent, &req.AgentAddReq)
+	if err := s.agentDao.Update(ctx, agent); err != nil {
+		return nil, err
+	}
+	return &entity.AgentUpdateRes{}, nil
+}
+
+func (s *AgentService) AdminDelete(ctx *reqctx.ReqCtx, req *entity.AgentDeleteReq) (*entity.AgentDeleteRes, error) {
+	if _, err := s.agentDao.Query(ctx, req.ID); err != nil {
+		return nil, err
+	}
+	if err := s.agentDao.Delete(ctx, req.ID); err != nil {
+		return nil, err
+	}
+	return &entity.AgentDeleteRes{}, nil
+}
+
+func (s *AgentService) AdminList(ctx *reqctx.ReqCtx, req *entity.AgentAdminListReq) (*entity.AgentAdminListRes, error) {
+	list, total, err := s.agentDao.List(ctx, req.Page, req.Size, bson.M{"is_deleted": false}, map[string]bool{"order": true})
+	if err != nil {
+		return nil, err
+	}
+	return &entity.AgentAdminListRes{
+		List:  list,
+		Total: total,
+	}, nil
+}
+
+func fillAgent(agent *model.Agent, req *entity.AgentAddReq) {
+	agent.Name = req.Name
+	agent.Description = req.Description
+	agent.Icon = req.Icon
+	agent.ProjectId = req.ProjectId
+	agent.Prompt = req.Prompt
+	agent.EnabledTools = req.EnabledTools
+	agent.Visibility = req.Visibility
+	agent.DefaultPinned = req.DefaultPinned
+	agent.Order = req.Order
+}
+
+func checkAgent(req *entity.AgentAddReq) error {
+	if req.Name == "" {
+		return errcode.ErrRequestParameter.Wrap("name cannot be empty")
+	}
+	if req.ProjectId == "" {
+		return errcode.ErrUserPinPjNotExist
+	}
+	switch req.Visibility {
+	case "":
+		req.Visibility = model.AgentVisibilityPublic
+	case model.AgentVisibilityPublic, model.AgentVisibilityInternal, model.AgentVisibilityHidden:
+	default:
+		return errcode.ErrRequestParameter.Wrap("visibility must be public, internal or hidden")
+	}
+	for _, name := range req.EnabledTools {
+		if _, ok := extension.LookupTool(name); !ok {
+			return errcode.ErrRequestParameter.Wrap("unknown tool: " + name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+)
+
+func TestBuildAgentList(t *testing.T) {
+	agents := []*model.Agent{
+		{BaseModel: model.BaseModel{ID: primitive.NewObjectID()}, Name: "WYT Agent", ProjectId: "wyt", DefaultPinned: true},
+		{BaseModel: model.BaseModel{ID: primitive.NewObjectID()}, Name: "Uniswap", ProjectId: "uni"},
+		{BaseModel: model.BaseModel{ID: primitive.NewObjectID()}, Name: "Pump", ProjectId: "pump"},
+		{BaseModel: model.BaseModel{ID: primitive.NewObjectID()}, Name: "Okx", ProjectId: "okx"},
+	}
+
+	// without pins only the default pinned agents are pinned
+	list := buildAgentList("u1", agents, nil)
+	assert.Equal(t, []string{"wyt", "uni", "pump", "okx"}, agentProjectIds(list))
+	assert.Equal(t, agentPinned, list[0].PinStatus)
+	assert.Equal(t, agentUnpinned, list[1].PinStatus)
+	assert.Equal(t, "u1", list[0].UserId)
+	assert.Equal(t, agents[0].ID.Hex(), list[0].AgentID)
+
+	list = buildAgentList("u1", agents, []*model.UserPlugin{
+		{ProjectId: "wyt", PinStatus: agentUnpinned},
+		{ProjectId: "okx", PinStatus: agentPinned, PinOrder: 0},
+		{ProjectId: "uni", PinStatus: agentPinned, PinOrder: 1},
+		// no longer in the catalog
+		{ProjectId: "removed", PinStatus: agentPinned, PinOrder: 2},
+	})
+	assert.Equal(t, []string{"okx", "uni", "wyt", "pump"}, agentProjectIds(list))
+	assert.Equal(t, agentUnpinned, list[2].PinStatus)
+}
+
+func TestNextPinOrder(t *testing.T) {
+	assert.Equal(t, 0, nextPinOrder(nil))
+	assert.Equal(t, 3, nextPinOrder([]*model.UserPlugin{
+		{PinStatus: agentPinned, PinOrder: 0},
+		{PinStatus: agentPinned, PinOrder: 2},
+		{PinStatus: agentUnpinned, PinOrder: 5},
+	}))
+}
+
+func TestCheckAgent(t *testing.T) {
+	req := &entity.AgentAddReq{Name: "Pump", ProjectId: "pump", EnabledTools: []string{"top_traders"}}
+	assert.Nil(t, checkAgent(req))
+	assert.Equal(t, model.AgentVisibilityPublic, req.Visibility)
+
+	assert.Equal(t, errcode.ErrUserPinPjNotExist, checkAgent(&entity.AgentAddReq{Name: "Pump"}))
+	assert.NotNil(t, checkAgent(&entity.AgentAddReq{ProjectId: "pump"}))
+	assert.NotNil(t, checkAgent(&entity.AgentAddReq{Name: "Pump", ProjectId: "pump", Visibility: "secret"}))
+	assert.NotNil(t, checkAgent(&entity.AgentAddReq{Name: "Pump", ProjectId: "pump", EnabledTools: []string{"unknown"}}))
+}
+
+func TestDefaultAgents(t *testing.T) {
+	cfg := &config.Config{}
+	assert.Empty(t, defaultAgents(cfg))
+
+	cfg.AIBackend.ProjectId = "wyt"
+	cfg.AIBackend.UniProjectId = "uni"
+	agents := defaultAgents(cfg)
+	assert.Len(t, agents, 2)
+	assert.Equal(t, "wyt", agents[0].ProjectId)
+	assert.True(t, agents[0].DefaultPinned)
+	assert.Equal(t, "uni", agents[1].ProjectId)
+	assert.False(t, agents[1].DefaultPinned)
+}
+
+func TestAgentVisibleTo(t *testing.T) {
+	public := &model.Agent{Visibility: model.AgentVisibilityPublic}
+	internal := &model.Agent{Visibility: model.AgentVisibilityInternal}
+	hidden := &model.Agent{Visibility: model.AgentVisibilityHidden}
+	assert.True(t, public.VisibleTo(model.UserRoleMember))
+	assert.False(t, internal.VisibleTo(model.UserRoleMember))
+	assert.True(t, internal.VisibleTo(model.UserRoleManager))
+	assert.False(t, hidden.VisibleTo(model.UserRoleAdmin))
+}
+
+func agentProjectIds(list []*model.UserPluginDto) []string {
+	var ids []string
+	for _, item := range list {
+		ids = append(ids, item.ProjectId)
+	}
+	return ids
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	pumpmodel "github.com/wyt-labs/wyt-core/internal/core/component/datapuller/model"
+	"github.com/wyt-labs/wyt-core/internal/core/dao"
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/chart"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/pkg/cache"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+// chartCacheNamespace maps the chart ids to their url, the charts already uploaded are not uploaded again
+const chartCacheNamespace = "chart_url"
+
+// ChartService renders the charts of the quantitative answers and hosts them in the file system
+type ChartService struct {
+	baseComponent *base.Component
+	fileSystemDao *dao.FileSystemDao
+}
+
+func NewChartService(baseComponent *base.Component, fileSystemDao *dao.FileSystemDao) *ChartService {
+	return &ChartService{
+		baseComponent: baseComponent,
+		fileSystemDao: fileSystemDao,
+	}
+}
+
+func (s *ChartService) enabled() bool {
+	return s != nil && s.baseComponent.Config.Extension.Chart.Enable
+}
+
+func (s *ChartService) format() chart.Format {
+	if format := s.baseComponent.Config.Extension.Chart.Format; chart.ValidFormat(format) {
+		return format
+	}
+	return chart.FormatPNG
+}
+
+// Publish renders the chart and returns its url, the same chart is stored once
+func (s *ChartService) Publish(ctx *reqctx.ReqCtx, spec *chart.Spec) (string, error) {
+	format := s.format()
+	id := chart.ID(spec, format)
+	if url, ok := cache.GetFromMemCache[string](s.baseComponent.MemCache, chartCacheNamespace, id); ok {
+		return url, nil
+	}
+	var buf bytes.Buffer
+	if err := chart.Render(&buf, spec, format); err != nil {
+		return "", err
+	}
+	bucket := entity.BucketTypeChart.String()
+	if err := s.fileSystemDao.UploadWithID(ctx, id, bucket, id+"."+format, &buf, spec.Title); err != nil {
+		return "", err
+	}
+	url := s.generateFileURL(bucket, id)
+	cache.PutToMemCache(s.baseComponent.MemCache, chartCacheNamespace, id, url)
+	return url, nil
+}
+
+// publish is Publish for the charts sent next to data: nothing is published when charts are disabled or
+// there is nothing to draw, and failures only lose the chart
+func (s *ChartService) publish(ctx *reqctx.ReqCtx, spec *chart.Spec) string {
+	if !s.enabled() || spec == nil {
+		return ""
+	}
+	url, err := s.Publish(ctx, spec)
+	if err != nil {
+		ctx.Logger.WithFields(logrus.Fields{
+			"err":   err,
+			"chart": spec.Title,
+		}).Warn("Failed to publish chart")
+		return ""
+	}
+	return url
+}
+
+func (s *ChartService) generateFileURL(bucketName string, id string) string {
+	return fmt.Sprintf("http://%s/api/v1/fs/files/%s/%s", s.baseComponent.Config.App.AccessDomain, bucketName, id)
+}
+
+func (s *ChartService) NewTokensChartURL(ctx *reqctx.ReqCtx, vo *pumpmodel.NewTokensVO) string {
+	return s.publish(ctx, newTokensChart(vo))
+}
+
+func (s *ChartService) LaunchTimeChartURL(ctx *reqctx.ReqCtx, vo *pumpmodel.LaunchTimeVO) string {
+	return s.publish(ctx, launchTimeChart(vo))
+}
+
+func (s *ChartService) TransactionsChartURL(ctx *reqctx.ReqCtx, vo *pumpmodel.TransactionsVO) string {
+	return s.publish(ctx, transactionsChart(vo))
+}
+
+// MetricsCompareChartURL draws a line per project, names maps the project ids to the legend
+func (s *ChartService) MetricsCompareChartURL(ctx *reqctx.ReqCtx, metricsType entity.MetricsType, res *entity.ProjectMetricsCompareRes, names map[string]string) string {
+	return s.publish(ctx, metricsCompareChart(metricsType, res, names))
+}
+
+// AttachChatChart adds the chart url to the quantitative chat answers
+func (s *ChartService) AttachChatChart(ctx *reqctx.ReqCtx, content *model.ChatContentAssistant) {
+	if !s.enabled() || content == nil {
+		return
+	}
+	content.ChartURL = s.publish(ctx, chatChart(content))
+}
+
+func chatChart(content *model.ChatContentAssistant) *chart.Spec {
+	switch {
+	case content.DailyNewToken != nil:
+		return newTokensChart(content.DailyNewToken.NewToken.FuncCallingRet.NewTokenResult.DailyNewToken)
+	case content.TokenLaunchedTime != nil:
+		return launchTimeChart(content.TokenLaunchedTime.LaunchedTimeDistribution.FuncCallingRet.TokenLTD.LaunchTimeDt)
+	case content.TokenSwapCount != nil:
+		return transactionsChart(content.TokenSwapCount.TokenSwapCount.FuncCallingRet.TokenSwapCount.TxCounts)
+	case content.TraderOverview != nil:
+		return profitDistributionChart(content.TraderOverview.TraderOverview.FuncCallingRet.TraderOverview.TraderDetails)
+	case content.ProjectCompare != nil:
+		return marketCapChart(content.ProjectCompare.Projects)
+	}
+	return nil
+}
+
+func newTokensChart(vo *pumpmodel.NewTokensVO) *chart.Spec {
+	if vo == nil || len(vo.Rows) == 0 {
+		return nil
+	}
+	spec := &chart.Spec{
+		Kind:   chart.KindLine,
+		Title:  "Daily New Tokens",
+		Series: []chart.Series{{Name: "Launched"}, {Name: "Migrated to Raydium"}},
+	}
+	for _, row := range vo.Rows {
+		spec.Labels = append(spec.Labels, chartDateLabel(row.Date))
+		spec.Series[0].Values = append(spec.Series[0].Values, float64(row.TotalCount))
+		spec.Series[1].Values = append(spec.Series[1].Values, float64(row.P2RCount))
+	}
+	if len(vo.Rows) == 1 {
+		// one day is not a line
+		spec.Kind = chart.KindBar
+		spec.Series = spec.Series[:1]
+	}
+	return spec
+}
+
+func launchTimeChart(vo *pumpmodel.LaunchTimeVO) *chart.Spec {
+	if vo == nil || len(vo.Rows) == 0 {
+		return nil
+	}
+	spec := &chart.Spec{
+		Kind:   chart.KindHistogram,
+		Title:  "Token Launched Time Distribution",
+		Series: []chart.Series{{Name: "Launched"}},
+	}
+	for _, row := range vo.Rows {
+		spec.Labels = append(spec.Labels, row.TimeRange)
+		spec.Series[0].Values = append(spec.Series[0].Values, float64(row.LaunchedCount))
+	}
+	return spec
+}
+
+func transactionsChart(vo *pumpmodel.TransactionsVO) *chart.Spec {
+	if vo == nil || len(vo.Rows) == 0 {
+		return nil
+	}
+	spec := &chart.Spec{
+		Kind:   chart.KindBar,
+		Title:  "Daily Token Swap Count",
+		Series: []chart.Series{{Name: "Swaps"}},
+	}
+	for _, row := range vo.Rows {
+		spec.Labels = append(spec.Labels, chartDateLabel(row.Date))
+		spec.Series[0].Values = append(spec.Series[0].Values, float64(row.TradeCount))
+	}
+	return spec
+}
+
+func profitDistributionChart(vo *pumpmodel.TraderDetailVO) *chart.Spec {
+	if vo == nil || len(vo.ProfitDistribution) == 0 {
+		return nil
+	}
+	spec := &chart.Spec{
+		Kind:   chart.KindPie,
+		Title:  "Profit Distribution of the Traded Tokens",
+		Series: []chart.Series{{Name: "Tokens"}},
+	}
+	var total int64
+	for _, row := range vo.ProfitDistribution {
+		spec.Labels = append(spec.Labels, row.ProfitMarginBucket)
+		spec.Series[0].Values = append(spec.Series[0].Values, float64(row.TokenCount))
+		total += row.TokenCount
+	}
+	if total == 0 {
+		return nil
+	}
+	return spec
+}
+
+// marketCapChart compares the market caps of the projects, nil when less than two are known
+func marketCapChart(projects []model.ChatContentAssistantProjectInfo) *chart.Spec {
+	spec := &chart.Spec{
+		Kind:   chart.KindBar,
+		Title:  "Market Cap",
+		Series: []chart.Series{{Name: "Market Cap"}},
+	}
+	for _, project := range projects {
+		if project.Overview == nil || project.Overview.TokenMarketCap == 0 {
+			continue
+		}
+		spec.Labels = append(spec.Labels, project.Overview.Name)
+		spec.Series[0].Values = append(spec.Series[0].Values, float64(project.Overview.TokenMarketCap))
+	}
+	if len(spec.Labels) < 2 {
+		return nil
+	}
+	return spec
+}
+
+func metricsCompareChart(metricsType entity.MetricsType, res *entity.ProjectMetricsCompareRes, names map[string]string) *chart.Spec {
+	if res == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(res.Metrics))
+	for id := range res.Metrics {
+		ids = append(ids, id)
+	}
+	// stable legend
+	sort.Slice(ids, func(i, j int) bool {
+		return names[ids[i]] < names[ids[j]] || (names[ids[i]] == names[ids[j]] && ids[i] < ids[j])
+	})
+
+	spec := &chart.Spec{
+		Kind:  chart.KindLine,
+		Title: metricsType,
+	}
+	hasValue := false
+	for _, id := range ids {
+		metrics := res.Metrics[id]
+		if len(spec.Times) < len(metrics) {
+			spec.Times = spec.Times[:0]
+			for _, m := range metrics {
+				spec.Times = append(spec.Times, time.Unix(int64(m.Timestamp), 0).UTC())
+			}
+		}
+		name := names[id]
+		if name == "" {
+			name = id
+		}
+		series := chart.Series{Name: name}
+		for _, m := range metrics {
+			var v uint64
+			switch metricsType {
+			case entity.MetricsTypeCirculatingMarketCap:
+				v = m.CirculatingMarketCap
+			case entity.MetricsTypeFullyDilutedValue:
+				v = m.FullyDilutedValue
+			case entity.MetricsTypeActiveAddresses:
+				v = m.ActiveAddresses
+			}
+			hasValue = hasValue || v != 0
+			series.Values = append(series.Values, float64(v))
+		}
+		spec.Series = append(spec.Series, series)
+	}
+	if !hasValue || len(spec.Times) < 2 {
+		return nil
+	}
+	return spec
+}
+
+// chartDateLabel shortens the dates of the rows to month-day
+func chartDateLabel(date string) string {
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.Format("01-02")
+		}
+	}
+	return date
+}
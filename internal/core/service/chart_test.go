@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	pumpmodel "github.com/wyt-labs/wyt-core/internal/core/component/datapuller/model"
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/chart"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/pkg/cache"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func TestChartSpecs(t *testing.T) {
+	spec := newTokensChart(&pumpmodel.NewTokensVO{Rows: []*pumpmodel.DailyTokensData{
+		{Date: "2024-05-01T00:00:00Z", TotalCount: 10, P2RCount: 1},
+		{Date: "2024-05-02T00:00:00Z", TotalCount: 12, P2RCount: 3},
+	}})
+	assert.Equal(t, chart.KindLine, spec.Kind)
+	assert.Equal(t, []string{"05-01", "05-02"}, spec.Labels)
+	assert.Equal(t, []float64{1, 3}, spec.Series[1].Values)
+
+	spec = launchTimeChart(&pumpmodel.LaunchTimeVO{Rows: []*pumpmodel.LaunchTimeData{{TimeRange: "00-04", LaunchedCount: 7}}})
+	assert.Equal(t, chart.KindHistogram, spec.Kind)
+	assert.Equal(t, []float64{7}, spec.Series[0].Values)
+
+	spec = transactionsChart(&pumpmodel.TransactionsVO{Rows: []*pumpmodel.TradeCountData{{Date: "2024-05-01", TradeCount: 5}}})
+	assert.Equal(t, chart.KindBar, spec.Kind)
+	assert.Equal(t, []string{"05-01"}, spec.Labels)
+
+	// nothing to draw
+	assert.Nil(t, newTokensChart(&pumpmodel.NewTokensVO{}))
+	assert.Nil(t, profitDistributionChart(&pumpmodel.TraderDetailVO{ProfitDistribution: []*pumpmodel.ProfitDistributionData{{ProfitMarginBucket: "0-10%"}}}))
+	assert.Nil(t, marketCapChart([]model.ChatContentAssistantProjectInfo{{Overview: &model.ChatContentAssistantProjectOverview{Name: "BTC", TokenMarketCap: 1}}}))
+
+	res := &entity.ProjectMetricsCompareRes{Metrics: map[string][]entity.ProjectMetrics{
+		"b": {{Timestamp: 1714521600, ActiveAddresses: 5}, {Timestamp: 1714608000, ActiveAddresses: 6}},
+		"a": {{Timestamp: 1714521600}, {Timestamp: 1714608000}},
+	}}
+	spec = metricsCompareChart(entity.MetricsTypeActiveAddresses, res, map[string]string{"a": "Arbitrum", "b": "Base"})
+	assert.Equal(t, []string{"Arbitrum", "Base"}, []string{spec.Series[0].Name, spec.Series[1].Name})
+	assert.Equal(t, []float64{5, 6}, spec.Series[1].Values)
+	assert.Equal(t, time.Unix(1714608000, 0).UTC(), spec.Times[1])
+	assert.Nil(t, metricsCompareChart(entity.MetricsTypeCirculatingMarketCap, res, nil))
+}
+
+func TestChartService_AttachChatChart(t *testing.T) {
+	ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, "")
+	content := &model.ChatContentAssistant{
+		TokenSwapCount: &model.ChatContentAssistantTokenSwapCountRes{
+			TokenSwapCount: model.ChatContentAssistantInfo{FuncCallingRet: model.FuncCallingRet{
+				TokenSwapCount: model.DailyTokenSwapCountsFuncCallingResult{TxCounts: &pumpmodel.TransactionsVO{Rows: []*pumpmodel.TradeCountData{
+					{Date: "2024-05-01", TradeCount: 5},
+				}}},
+			}},
+		},
+	}
+
+	// no chart service, or charts disabled
+	var s *ChartService
+	s.AttachChatChart(ctx, content)
+	assert.Empty(t, content.ChartURL)
+	s = NewChartService(base.NewMockBaseComponent(t), nil)
+	s.AttachChatChart(ctx, content)
+	assert.Empty(t, content.ChartURL)
+
+	// the chart is already uploaded
+	s.baseComponent.Config.Extension.Chart.Enable = true
+	s.baseComponent.Config.App.AccessDomain = "wyt.test"
+	id := chart.ID(chatChart(content), chart.FormatPNG)
+	cache.PutToMemCache(s.baseComponent.MemCache, chartCacheNamespace, id, s.generateFileURL(entity.BucketTypeChart.String(), id))
+	s.AttachChatChart(ctx, content)
+	assert.Equal(t, "http://wyt.test/api/v1/fs/files/chart/"+id, content.ChartURL)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -18,6 +19,7 @@ import (
 	"github.com/wyt-labs/wyt-core/internal/pkg/base"
 	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
 	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/internal/pkg/guardrail"
 	"github.com/wyt-labs/wyt-core/pkg/extension"
 	"github.com/wyt-labs/wyt-core/pkg/reqctx"
 )
@@ -27,6 +29,7 @@ const (
 	projectNotFoundErrMsg = "Sorry, the project you are looking for cannot be found. Please double-check the project details and try again."
 	intentionErrMsg       = "Apologies, we couldn't understand the command. Please ensure the command is valid and try again."
 	traderNotFoundErrMsg  = "Trader not found. You can give me a solana address of another trader."
+	injectionErrMsg       = "Sorry, your question looks like an attempt to change how the assistant works and was not sent. Please rephrase it."
 )
 
 // const (
@@ -51,14 +54,40 @@ const (
 // 	chatCompareTokenRegEx5        = "(?i)Which is more valuable, (\\w+) or (\\w+)?"
 // )
 
+// chatStore keeps the chat windows and their messages, implemented by dao.ChatDao
+type chatStore interface {
+	ChatWindowAdd(ctx *reqctx.ReqCtx, e *model.ChatWindow) error
+	ChatWindowQuery(ctx *reqctx.ReqCtx, id string) (*model.ChatWindow, error)
+	ChatWindowList(ctx *reqctx.ReqCtx, page uint64, size uint64, filter any, sort map[string]bool) ([]*model.ChatWindow, int64, error)
+	ChatWindowUpdate(ctx *reqctx.ReqCtx, e *model.ChatWindow) error
+	ChatWindowSetGeneratedTitle(ctx *reqctx.ReqCtx, id primitive.ObjectID, title string) (bool, error)
+	ChatWindowSetSummary(ctx *reqctx.ReqCtx, id primitive.ObjectID, fromIndex uint64, summary string, summaryIndex uint64) (bool, error)
+	ChatWindowDelete(ctx *reqctx.ReqCtx, id string) error
+	ChatHistoryAdd(ctx *reqctx.ReqCtx, e *model.ChatHistory) error
+	ChatHistoryQuery(ctx *reqctx.ReqCtx, windowID primitive.ObjectID, index uint64) (*model.ChatHistory, error)
+	ChatHistoryList(ctx *reqctx.ReqCtx, page uint64, size uint64, filter any, sort map[string]bool) ([]*model.ChatHistory, int64, error)
+	ChatHistoryBranches(ctx *reqctx.ReqCtx, windowIDs []primitive.ObjectID) ([]*model.ChatHistory, error)
+	ChatHistoryListSince(ctx *reqctx.ReqCtx, windowID primitive.ObjectID, fromIndex uint64) ([]*model.ChatHistory, error)
+	ChatHistorySearch(ctx *reqctx.ReqCtx, page uint64, size uint64, filter bson.M) ([]*model.ChatHistorySearchHit, int64, error)
+}
+
 type ChatService struct {
 	baseComponent    *base.Component
 	projectDao       *dao.ProjectDao
 	miscDao          *dao.MiscDao
-	chatDao          *dao.ChatDao
-	userPluginDao    *dao.UserPluginDao
+	chatDao          chatStore
+	chatShareDao     chatShareStore
 	chatgptDriver    *extension.ChatgptDriver
 	marketDatasource *datasource.Market
+	projectIndex     *ProjectIndexService
+	llmUsageService  *LLMUsageService
+	promptService    *PromptService
+	agentService     *AgentService
+	chartService     *ChartService
+	// nil when the guardrail is disabled
+	guard *guardrail.Guard
+	// windows whose conversation memory is being summarized
+	summarizing sync.Map
 }
 
 func NewChatService(
@@ -66,18 +95,33 @@ func NewChatService(
 	projectDao *dao.ProjectDao,
 	miscDao *dao.MiscDao,
 	chatDao *dao.ChatDao,
+	chatShareDao *dao.ChatShareDao,
 	chatgptDriver *extension.ChatgptDriver,
 	marketDatasource *datasource.Market,
-	userPluginDao *dao.UserPluginDao,
+	projectIndex *ProjectIndexService,
+	llmUsageService *LLMUsageService,
+	promptService *PromptService,
+	agentService *AgentService,
+	chartService *ChartService,
 ) (*ChatService, error) {
+	guard, err := guardrail.New(baseComponent.Config.Extension.Guardrail)
+	if err != nil {
+		return nil, err
+	}
 	return &ChatService{
 		baseComponent:    baseComponent,
 		projectDao:       projectDao,
 		miscDao:          miscDao,
 		chatDao:          chatDao,
+		chatShareDao:     chatShareDao,
 		chatgptDriver:    chatgptDriver,
 		marketDatasource: marketDatasource,
-		userPluginDao:    userPluginDao,
+		projectIndex:     projectIndex,
+		llmUsageService:  llmUsageService,
+		promptService:    promptService,
+		agentService:     agentService,
+		chartService:     chartService,
+		guard:            guard,
 	}, nil
 }
 
@@ -131,45 +175,70 @@ func (s *ChatService) History(ctx *reqctx.ReqCtx, req *entity.ChatHistoryReq) (*
 		return nil, errcode.ErrAccountPermission
 	}
 
-	res, total, err := s.chatDao.ChatHistoryList(ctx, req.Page, req.Size, bson.M{
-		"is_deleted": false,
-		"window_id":  w.ID,
-	}, map[string]bool{"index": false})
+	branch, children, err := chatBranchMsgs(ctx, s.chatDao, w, req.Branch)
 	if err != nil {
 		return nil, err
 	}
+	head := int64(-1)
+	if len(branch) != 0 {
+		head = int64(branch[len(branch)-1].Index)
+	}
+	// newest first
 	var msgs []*entity.ChatMsg
-	for _, element := range res {
-		var content any
-		switch element.Msg.Role {
-		case model.ChatMsgRoleUserBuiltin:
-			content = element.Msg.ContentUserBuiltin
-		case model.ChatMsgRoleUser:
-			content = element.Msg.ContentUser
-		case model.ChatMsgRoleSystem:
-			content = element.Msg.ContentSystem
-		case model.ChatMsgRoleAssistant:
-			content = element.Msg.ContentAssistant
+	for _, element := range pageSlice(lo.Reverse(branch), req.Page, req.Size) {
+		msg := chatHistoryToMsg(element)
+		if siblings := children[element.Parent()]; len(siblings) > 1 {
+			msg.Siblings = siblings
 		}
-
-		msgs = append(msgs, &entity.ChatMsg{
-			Index:     element.Index,
-			Timestamp: element.CreateTime,
-			Role:      element.Msg.Role,
-			Content:   content,
-			ProjectId: element.ProjectId,
-		})
+		msgs = append(msgs, msg)
 	}
 	pjId := w.ProjectId
 	return &entity.ChatHistoryRes{
 		Title:      w.Title,
 		ProjectId:  pjId,
 		CreateTime: w.CreateTime,
-		MsgNum:     uint64(total),
+		MsgNum:     uint64(len(branch)),
 		Msgs:       msgs,
+		Head:       head,
 	}, nil
 }
 
+// pageSlice pages the list like the dao lists, page starts from 1 and a zero page or size returns everything
+func pageSlice[T any](list []T, page uint64, size uint64) []T {
+	if page == 0 || size == 0 {
+		return list
+	}
+	start := (page - 1) * size
+	if start >= uint64(len(list)) {
+		return nil
+	}
+	return list[start:min(start+size, uint64(len(list)))]
+}
+
+func chatHistoryToMsg(h *model.ChatHistory) *entity.ChatMsg {
+	var content any
+	switch h.Msg.Role {
+	case model.ChatMsgRoleUserBuiltin:
+		content = h.Msg.ContentUserBuiltin
+	case model.ChatMsgRoleUser:
+		content = h.Msg.ContentUser
+	case model.ChatMsgRoleSystem:
+		content = h.Msg.ContentSystem
+	case model.ChatMsgRoleAssistant:
+		content = h.Msg.ContentAssistant
+	}
+
+	return &entity.ChatMsg{
+		Index:       h.Index,
+		Timestamp:   h.CreateTime,
+		Role:        h.Msg.Role,
+		Content:     content,
+		ProjectId:   h.ProjectId,
+		AgentID:     lo.Ternary(h.AgentID.IsZero(), "", h.AgentID.Hex()),
+		ParentIndex: h.Parent(),
+	}
+}
+
 func (s *ChatService) convertProjectInfo(ctx *reqctx.ReqCtx, projects []*model.Project, view string) ([]model.ChatContentAssistantProjectInfo, string, error) {
 	res := lo.Map(projects, func(item *model.Project, index int) model.ChatContentAssistantProjectInfo {
 		return model.ChatContentAssistantProjectInfo{
@@ -371,28 +440,64 @@ func (s *ChatService) fetchProjectDataByKey(ctx *reqctx.ReqCtx, projectKeys []st
 }
 
 func (s *ChatService) Completions(ctx *reqctx.ReqCtx, req *entity.ChatCompletionsReq, useStructuredOutput bool) (*entity.ChatCompletionsRes, error) {
+	return s.completions(ctx, req, useStructuredOutput, nil, nil)
+}
+
+// CompletionsStream works like Completions but reports progress (intent, project lookups, function calls
+// and model output) through emitter while the answer is being produced, the persisted history is identical
+func (s *ChatService) CompletionsStream(ctx *reqctx.ReqCtx, req *entity.ChatCompletionsReq, useStructuredOutput bool, emitter entity.ChatStreamEmitter) (*entity.ChatCompletionsRes, error) {
+	return s.completions(ctx, req, useStructuredOutput, emitter, nil)
+}
+
+func (s *ChatService) completions(ctx *reqctx.ReqCtx, req *entity.ChatCompletionsReq, useStructuredOutput bool, emitter entity.ChatStreamEmitter, opts *chatTurnOpts) (*entity.ChatCompletionsRes, error) {
+	emit := func(event string, data any) {
+		if emitter != nil {
+			emitter(event, data)
+		}
+	}
 	w, err := s.chatDao.ChatWindowQuery(ctx, req.ID)
 	if err != nil {
 		return nil, err
 	}
-	pjId := w.ProjectId
-	s.baseComponent.Logger.WithFields(logrus.Fields{"window id:": req.ID}).Info("project id:", pjId)
-	if req.ProjectId != "" && pjId != "" && req.ProjectId != pjId {
-		return nil, errcode.ErrPjWinIdErr
+	if w.Creator.Hex() != ctx.Caller {
+		return nil, errcode.ErrAccountPermission
 	}
-	if req.ProjectId == "" && pjId != "" {
-		req.ProjectId = pjId
+	turn, err := s.chatTurn(ctx, w, req, opts)
+	if err != nil {
+		return nil, err
 	}
-	if pjId == "" && req.ProjectId != "" {
-		pjId = req.ProjectId
+	// 默认是WYT的项目
+	pjId, routable, err := chatProjectId(w, req.ProjectId, s.baseComponent.Config.AIBackend.ProjectId)
+	if err != nil {
+		return nil, err
 	}
-	if pjId == "" {
-		// 默认是WYT的项目
-		pjId = s.baseComponent.Config.AIBackend.ProjectId
+	// redacted question of the user and the refusal of the injection check, answered as a system message
+	var question string
+	var injectionErr error
+	if req.Type == model.ChatMsgRoleUser {
+		if err := s.llmUsageService.CheckQuota(ctx); err != nil {
+			return nil, err
+		}
+		// every model call below is accounted to the caller, window and project
+		ctx.Ctx = extension.WithUsageListener(ctx.Ctx, func(modelName string, usage extension.LLMUsage) {
+			s.llmUsageService.Record(ctx, w.ID, pjId, modelName, usage)
+		})
+		question, pjId, injectionErr = s.guardQuestion(ctx, req.Msg, pjId, routable)
 	}
-
-	if w.Creator.Hex() != ctx.Caller {
-		return nil, errcode.ErrAccountPermission
+	s.baseComponent.Logger.WithFields(logrus.Fields{"window id:": req.ID}).Info("project id:", pjId)
+	// nil for projects outside the agent catalog, they are answered without agent settings
+	agent, err := s.agentService.Lookup(ctx, pjId)
+	if err != nil {
+		return nil, err
+	}
+	var agentID primitive.ObjectID
+	if agent != nil {
+		agentID = agent.ID
+		emit(entity.ChatStreamEventAgent, &entity.ChatStreamAgent{
+			AgentID:   agent.ID.Hex(),
+			ProjectId: agent.ProjectId,
+			Name:      agent.Name,
+		})
 	}
 
 	now := time.Now()
@@ -405,16 +510,18 @@ func (s *ChatService) Completions(ctx *reqctx.ReqCtx, req *entity.ChatCompletion
 	aiMsg := &model.ChatMsg{
 		Timestamp: model.JSONTime(now),
 	}
+	// prompt template the question is answered with, nil for the built-in prompt
+	var promptTmpl *model.PromptTemplate
+	// turns to fold into the conversation memory summary once the answer is stored
+	var memoryOverflow []chatMemoryTurn
 	switch req.Type {
 	case model.ChatMsgRoleUser:
 		// ai msg
 		aiMsg.ContentAssistant = &model.ChatContentAssistant{}
 		aiMsg.Role = model.ChatMsgRoleAssistant
-		// 本次问答结果
-		var chatAIAnalyticalResult model.ChatAIAnalyticalResult
 		// user msg content
 		userMsg.ContentUser = &model.ChatContentUser{
-			Content: req.Msg,
+			Content: question,
 		}
 		// 将本次user msg添加到user msg list
 		w.LastUserMsgs = append(w.LastUserMsgs, *userMsg)
@@ -423,290 +530,40 @@ func (s *ChatService) Completions(ctx *reqctx.ReqCtx, req *entity.ChatCompletion
 		}
 
 		err = func() error {
-			questMsgs := make([]*extension.ChatgptMsg, 0)
+			if injectionErr != nil {
+				return injectionErr
+			}
+			// previous turns first, the question is always the last message
+			var questMsgs []*extension.ChatgptMsg
+			questMsgs, memoryOverflow = s.chatMemory(ctx, w, turn.parent)
+			s.redactMemory(ctx, questMsgs)
 			for _, msg := range w.LastUserMsgs {
 				questMsgs = append(questMsgs, &extension.ChatgptMsg{
 					Role:    msg.Role,
 					Content: msg.ContentUser.Content,
 				})
 			}
-			var retStr string
-			var fcRet *model.FuncCallingRet
-
-			if useStructuredOutput {
-				// always call this
-				retStr, fcRet, err = s.chatgptDriver.DocsFaq(ctx.Ctx, questMsgs, pjId)
-			} else {
-				retStr, fcRet, err = s.chatgptDriver.ChatWithStructuredOutput(questMsgs)
-			}
-			if err != nil {
-				ctx.AddCustomLogField("ai_err", err)
-				if strings.Contains(err.Error(), "not found") {
-					return errors.New(traderNotFoundErrMsg)
+			var prompt string
+			prompt, promptTmpl = s.promptService.Apply(ctx, pjId, w.ID.Hex(), useStructuredOutput && s.chatgptDriver.HasBackend())
+			if promptTmpl != nil {
+				ctx.PutValue(chatPromptTemplateKey{}, promptTmpl)
+				if promptTmpl.BypassBackend {
+					ctx.Ctx = extension.WithBypassBackend(ctx.Ctx)
 				}
-				return errors.New(networkErrMsg)
-			}
-			if retStr != "" {
-				if err := json.Unmarshal([]byte(retStr), &chatAIAnalyticalResult); err != nil {
-					ctx.AddCustomLogField("ai_err", err)
-					return errors.New(intentionErrMsg)
+				ctx.Ctx = extension.WithSystemPrompt(ctx.Ctx, prompt)
+				if len(promptTmpl.Schema) != 0 {
+					ctx.Ctx = extension.WithResponseSchema(ctx.Ctx, promptTmpl.Schema)
 				}
-				ctx.AddCustomLogField("ai_analytical", retStr)
-				if (chatAIAnalyticalResult.Intention != model.ChatAIAnalyticalIntentionSearch) &&
-					(chatAIAnalyticalResult.Intention != model.ChatAIAnalyticalIntentionCompare) {
-					aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeGeneral
-					aiMsg.ContentAssistant.Fill = chatAIAnalyticalResult.Fill
-					aiMsg.ContentAssistant.Tips = "Here is the answer:"
-					aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeGeneral
-					aiMsg.ContentAssistant.GeneralAnswer = &model.ChatContentAssistantGeneralAnswerRes{
-						View: model.ChatContentAssistantProjectInfoViewGeneralAnswer,
-						GeneralAnswer: model.ChatContentAssistantGeneralAnswer{
-							ID:      primitive.NewObjectID(),
-							Content: chatAIAnalyticalResult.Content,
-						},
-					}
-				} else {
-					isGeneralFaq := false
-					datas, view, err := s.fetchProjectDataByKey(ctx, chatAIAnalyticalResult.IntentKeys, chatAIAnalyticalResult.View)
-					if err != nil {
-						ctx.AddCustomLogField("ai_err", err)
-						if chatAIAnalyticalResult.Content != "" {
-							isGeneralFaq = true
-						}
-					}
-					chatAIAnalyticalResult.View = view
-					aiMsg.ContentAssistant.ProjectKeys = chatAIAnalyticalResult.IntentKeys
-
-					switch chatAIAnalyticalResult.Intention {
-					case model.ChatAIAnalyticalIntentionSearch:
-						if len(chatAIAnalyticalResult.IntentKeys) == 0 {
-							return errors.New("the intention is not clear, and AI cannot analyze it. Please provide a more detailed description")
-						}
-						part := view
-						if view == "overview" {
-							part = "some info"
-						}
-						aiMsg.ContentAssistant.Tips = fmt.Sprintf("Here is %s about %s:", part, chatAIAnalyticalResult.IntentKeys[0])
-						if isGeneralFaq {
-							aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeGeneral
-							aiMsg.ContentAssistant.GeneralAnswer = &model.ChatContentAssistantGeneralAnswerRes{
-								View: model.ChatContentAssistantProjectInfoViewGeneralAnswer,
-								GeneralAnswer: model.ChatContentAssistantGeneralAnswer{
-									ID:      primitive.NewObjectID(),
-									Content: chatAIAnalyticalResult.Content,
-								},
-							}
-						} else {
-							aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeProjectInfo
-							aiMsg.ContentAssistant.ProjectInfo = &model.ChatContentAssistantProjectInfoRes{
-								View:    chatAIAnalyticalResult.View,
-								Project: datas[0],
-							}
-						}
-					case model.ChatAIAnalyticalIntentionCompare:
-						if len(chatAIAnalyticalResult.IntentKeys) < 2 {
-							return errors.New("Sorry, I didn't understand what you meant. Can you provide me with more information?")
-						}
-						if view == "overview" {
-							aiMsg.ContentAssistant.Tips = fmt.Sprintf("Here is a brief comparison about %s and %s:", chatAIAnalyticalResult.IntentKeys[0], chatAIAnalyticalResult.IntentKeys[1])
-						} else {
-							aiMsg.ContentAssistant.Tips = fmt.Sprintf("Here is %s about comparison of %s and %s:", view, chatAIAnalyticalResult.IntentKeys[0], chatAIAnalyticalResult.IntentKeys[1])
-						}
-						aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeProjectCompare
-						aiMsg.ContentAssistant.ProjectCompare = &model.ChatContentAssistantProjectCompareRes{
-							View:     chatAIAnalyticalResult.View,
-							Projects: datas,
-						}
-					default:
-						aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeGeneral
-						aiMsg.ContentAssistant.GeneralAnswer = &model.ChatContentAssistantGeneralAnswerRes{
-							View: model.ChatContentAssistantProjectInfoViewGeneralAnswer,
-							GeneralAnswer: model.ChatContentAssistantGeneralAnswer{
-								ID:      primitive.NewObjectID(),
-								Content: chatAIAnalyticalResult.Content,
-							},
-						}
-					}
-					chatAIAnalyticalResult.ProjectIDs = lo.Map(datas, func(item model.ChatContentAssistantProjectInfo, index int) primitive.ObjectID {
-						return item.ID
-					})
-					aiMsg.ContentAssistant.Fill = chatAIAnalyticalResult.Fill
-				}
-				return nil
 			}
-
-			if fcRet != nil {
-				if fcRet.FCType == model.FCSwap {
-					chatAIAnalyticalResult.Intention = model.ChatAIAnalyticalIntentionSwap
-					chatAIAnalyticalResult.IntentKeys = []string{fcRet.FCSwapResult.SwapInToken, fcRet.FCSwapResult.SwapOutToken}
-					jsonStr, _ := json.Marshal(fcRet.FCSwapResult)
-					chatAIAnalyticalResult.Content = string(jsonStr)
-					chatAIAnalyticalResult.View = string(fcRet.FCType)
-					chatAIAnalyticalResult.Fill = ""
-					chatAIAnalyticalResult.ProjectIDs = []primitive.ObjectID{}
-
-					aiMsg.ContentAssistant.Type = model.ChatAIAnalyticalIntentionSwap
-					aiMsg.ContentAssistant.Fill = ""
-					aiMsg.ContentAssistant.ProjectKeys = chatAIAnalyticalResult.IntentKeys
-					aiMsg.ContentAssistant.Tips = "Sure, you can swap here. If it doesn't meet your requirements, you can directly operate in the panel or modify your prompt."
-					swap := &model.ChatContentAssistantInfo{
-						ID:             primitive.NewObjectID(),
-						FuncCallingRet: *fcRet,
-					}
-					aiMsg.ContentAssistant.SwapInfo = &model.ChatContentAssistantSwapRes{
-						View: chatAIAnalyticalResult.View,
-						Swap: *swap,
-					}
-					aiMsg.ContentAssistant.Fill = chatAIAnalyticalResult.Fill
-					return nil
-				} else if fcRet.FCType == model.FCUniswap { // uniswap project
-					chatAIAnalyticalResult.Intention = model.ChatAIAnalyticalIntentionUniswap
-					chatAIAnalyticalResult.IntentKeys = []string{model.ChatAIAnalyticalIntentionUniswap}
-					jsonStr, _ := json.Marshal(fcRet.FCUniswapResult)
-					chatAIAnalyticalResult.Content = string(jsonStr)
-					chatAIAnalyticalResult.View = string(fcRet.FCType)
-					chatAIAnalyticalResult.Fill = ""
-					chatAIAnalyticalResult.ProjectIDs = []primitive.ObjectID{}
-
-					aiMsg.ContentAssistant.Type = model.ChatAIAnalyticalIntentionUniswap
-					aiMsg.ContentAssistant.Fill = ""
-					aiMsg.ContentAssistant.ProjectKeys = chatAIAnalyticalResult.IntentKeys
-					aiMsg.ContentAssistant.Tips = "Sure, you can swap here. If it doesn't meet your requirements, you can directly operate in the panel or modify your prompt."
-					uniswap := model.ChatContentAssistantInfo{
-						ID:             primitive.NewObjectID(),
-						FuncCallingRet: *fcRet,
-					}
-					aiMsg.ContentAssistant.Uniswap = &model.ChatContentAssistantUniswapRes{
-						View:    model.ChatContentAssistantSwapViewUniswap,
-						Uniswap: uniswap,
-					}
-					aiMsg.ContentAssistant.Fill = chatAIAnalyticalResult.Fill
-					return nil
-				} else if fcRet.FCType == model.FCDailyNewToken {
-					chatAIAnalyticalResult.Intention = model.ChatAIDailyNewToken
-					chatAIAnalyticalResult.IntentKeys = []string{model.ChatAIDailyNewToken}
-					// data result
-					jsonStr, _ := json.Marshal(fcRet.NewTokenResult)
-					chatAIAnalyticalResult.Content = string(jsonStr)
-					// 视图
-					chatAIAnalyticalResult.View = string(fcRet.FCType)
-					chatAIAnalyticalResult.Fill = ""
-					chatAIAnalyticalResult.ProjectIDs = []primitive.ObjectID{}
-					// build ai assistant message
-					aiMsg.ContentAssistant.Type = model.ChatAIDailyNewToken
-					aiMsg.ContentAssistant.Fill = ""
-					aiMsg.ContentAssistant.ProjectKeys = chatAIAnalyticalResult.IntentKeys
-					aiMsg.ContentAssistant.Tips = "here is daily new tokens"
-					newToken := model.ChatContentAssistantInfo{
-						ID:             primitive.NewObjectID(),
-						FuncCallingRet: *fcRet,
-					}
-					// pump.fun new token
-					aiMsg.ContentAssistant.DailyNewToken = &model.ChatContentAssistantNewTokenRes{
-						View:     model.ChatContentAssistantNewTokenView,
-						NewToken: newToken,
-					}
-					aiMsg.ContentAssistant.Fill = chatAIAnalyticalResult.Fill
-					return nil
-				} else if fcRet.FCType == model.FCTokenLaunchedTimeDistribution {
-					chatAIAnalyticalResult.Intention = model.ChatAITokenLaunchedTimeDistribution
-					chatAIAnalyticalResult.IntentKeys = []string{model.ChatAITokenLaunchedTimeDistribution}
-					// data
-					jsonStr, _ := json.Marshal(fcRet.TokenLTD)
-					chatAIAnalyticalResult.Content = string(jsonStr)
-					chatAIAnalyticalResult.View = string(fcRet.FCType)
-					chatAIAnalyticalResult.Fill = ""
-					chatAIAnalyticalResult.ProjectIDs = []primitive.ObjectID{}
-
-					aiMsg.ContentAssistant.Type = model.ChatAITokenLaunchedTimeDistribution
-					aiMsg.ContentAssistant.Fill = ""
-					aiMsg.ContentAssistant.ProjectKeys = chatAIAnalyticalResult.IntentKeys
-					aiMsg.ContentAssistant.Tips = "here is token launched time distribution "
-					tokenltd := model.ChatContentAssistantInfo{
-						ID:             primitive.NewObjectID(),
-						FuncCallingRet: *fcRet,
-					}
-					aiMsg.ContentAssistant.TokenLaunchedTime = &model.ChatContentAssistantTokenLtDistRes{
-						View:                     model.ChatContentAssistantTokenLTView,
-						LaunchedTimeDistribution: tokenltd,
-					}
-					aiMsg.ContentAssistant.Fill = chatAIAnalyticalResult.Fill
-					return nil
-				} else if fcRet.FCType == model.FCDailyTokenSwapCount {
-					chatAIAnalyticalResult.Intention = model.ChatAIDailyTokenSwapCount
-					chatAIAnalyticalResult.IntentKeys = []string{model.ChatAIDailyTokenSwapCount}
-					// data
-					jsonStr, _ := json.Marshal(fcRet.TokenSwapCount)
-					chatAIAnalyticalResult.Content = string(jsonStr)
-					chatAIAnalyticalResult.View = string(fcRet.FCType)
-					chatAIAnalyticalResult.Fill = ""
-					chatAIAnalyticalResult.ProjectIDs = []primitive.ObjectID{}
-
-					aiMsg.ContentAssistant.Type = model.ChatAIDailyTokenSwapCount
-					aiMsg.ContentAssistant.Fill = ""
-					aiMsg.ContentAssistant.ProjectKeys = chatAIAnalyticalResult.IntentKeys
-					aiMsg.ContentAssistant.Tips = "Here is daily token swap count"
-					swap := model.ChatContentAssistantInfo{
-						ID:             primitive.NewObjectID(),
-						FuncCallingRet: *fcRet,
-					}
-					aiMsg.ContentAssistant.TokenSwapCount = &model.ChatContentAssistantTokenSwapCountRes{
-						View:           model.ChatContentAssistantTokenSwapCountView,
-						TokenSwapCount: swap,
-					}
-					aiMsg.ContentAssistant.Fill = chatAIAnalyticalResult.Fill
-					return nil
-				} else if fcRet.FCType == model.FCTopTrader {
-					chatAIAnalyticalResult.Intention = model.ChatAITopTrader
-					chatAIAnalyticalResult.IntentKeys = []string{model.ChatAITopTrader}
-					// data
-					jsonStr, _ := json.Marshal(fcRet.TopTrader)
-					chatAIAnalyticalResult.Content = string(jsonStr)
-					chatAIAnalyticalResult.View = string(fcRet.FCType)
-					chatAIAnalyticalResult.Fill = ""
-					chatAIAnalyticalResult.ProjectIDs = []primitive.ObjectID{}
-
-					aiMsg.ContentAssistant.Type = model.ChatAITopTrader
-					aiMsg.ContentAssistant.Fill = ""
-					aiMsg.ContentAssistant.ProjectKeys = chatAIAnalyticalResult.IntentKeys
-					aiMsg.ContentAssistant.Tips = "Here is top trader"
-					tt := model.ChatContentAssistantInfo{
-						ID:             primitive.NewObjectID(),
-						FuncCallingRet: *fcRet,
-					}
-					aiMsg.ContentAssistant.TopTrader = &model.ChatContentAssistantTopTraderRes{
-						View:      model.ChatContentAssistantTopTrader,
-						TopTrader: tt,
-					}
-					aiMsg.ContentAssistant.Fill = chatAIAnalyticalResult.Fill
-					return nil
-				} else if fcRet.FCType == model.FCTraderOverview {
-					chatAIAnalyticalResult.Intention = model.ChatAITraderOverview
-					chatAIAnalyticalResult.IntentKeys = []string{model.ChatAITraderOverview}
-					// data
-					jsonStr, _ := json.Marshal(fcRet.TraderOverview)
-					chatAIAnalyticalResult.Content = string(jsonStr)
-					chatAIAnalyticalResult.View = string(fcRet.FCType)
-					chatAIAnalyticalResult.Fill = ""
-					chatAIAnalyticalResult.ProjectIDs = []primitive.ObjectID{}
-
-					aiMsg.ContentAssistant.Type = model.ChatAITraderOverview
-					aiMsg.ContentAssistant.Fill = ""
-					aiMsg.ContentAssistant.ProjectKeys = chatAIAnalyticalResult.IntentKeys
-					aiMsg.ContentAssistant.Tips = "Here is trader overview of the past 7 days"
-					to := model.ChatContentAssistantInfo{
-						ID:             primitive.NewObjectID(),
-						FuncCallingRet: *fcRet,
-					}
-					aiMsg.ContentAssistant.TraderOverview = &model.ChatContentAssistantTraderOverviewRes{
-						View:           model.ChatContentAssistantTraderOverview,
-						TraderOverview: to,
-					}
-					aiMsg.ContentAssistant.Fill = chatAIAnalyticalResult.Fill
-					return nil
-				}
+			if agent != nil {
+				questMsgs = withAgentPrompt(questMsgs, agent)
+				ctx.Ctx = extension.WithEnabledTools(ctx.Ctx, agent.EnabledTools)
 			}
-
+			questMsgs = s.projectIndex.Augment(ctx, questMsgs)
+			if err := s.answer(ctx, questMsgs, pjId, useStructuredOutput, emitter, s.fetchProjectDataByKey, aiMsg); err != nil {
+				return err
+			}
+			s.chartService.AttachChatChart(ctx, aiMsg.ContentAssistant)
 			return nil
 		}()
 		if err != nil {
@@ -733,6 +590,10 @@ func (s *ChatService) Completions(ctx *reqctx.ReqCtx, req *entity.ChatCompletion
 				ProjectKeys: relatedProjectInfo.Msg.ContentAssistant.ProjectKeys,
 			}
 
+			emit(entity.ChatStreamEventProjectFetch, &entity.ChatStreamProjectFetch{
+				ProjectKeys: relatedProjectInfo.Msg.ContentAssistant.ProjectKeys,
+				View:        req.Msg,
+			})
 			switch relatedProjectInfo.Msg.ContentAssistant.Type {
 			case model.ChatContentAssistantTypeFill:
 				return errors.New("internal error: failed to get last ai intention")
@@ -786,26 +647,54 @@ func (s *ChatService) Completions(ctx *reqctx.ReqCtx, req *entity.ChatCompletion
 		}
 	}
 
-	if err := s.chatDao.ChatHistoryAdd(ctx, &model.ChatHistory{
-		WindowID:  w.ID,
-		Index:     w.MsgNum,
-		Msg:       *userMsg,
-		ProjectId: pjId,
-	}); err != nil {
-		return nil, err
+	var promptName string
+	var promptVersion uint64
+	if promptTmpl != nil {
+		promptName, promptVersion = promptTmpl.Name, promptTmpl.Version
 	}
+	// regenerate answers the stored question again, otherwise the question is stored before the answer
+	parent := turn.parent
+	if turn.question != nil {
+		parent = int64(turn.question.Index)
+	} else {
+		if err := s.chatDao.ChatHistoryAdd(ctx, &model.ChatHistory{
+			WindowID:      w.ID,
+			Index:         w.MsgNum,
+			Msg:           *userMsg,
+			ParentIndex:   &parent,
+			ProjectId:     pjId,
+			AgentID:       agentID,
+			PromptName:    promptName,
+			PromptVersion: promptVersion,
+		}); err != nil {
+			return nil, err
+		}
+		parent = int64(w.MsgNum)
+		w.MsgNum++
+	}
+	aiIndex := w.MsgNum
 	if err := s.chatDao.ChatHistoryAdd(ctx, &model.ChatHistory{
-		WindowID:  w.ID,
-		Index:     w.MsgNum + 1,
-		Msg:       *aiMsg,
-		ProjectId: pjId,
+		WindowID:      w.ID,
+		Index:         aiIndex,
+		Msg:           *aiMsg,
+		ParentIndex:   &parent,
+		ProjectId:     pjId,
+		AgentID:       agentID,
+		PromptName:    promptName,
+		PromptVersion: promptVersion,
 	}); err != nil {
 		return nil, err
 	}
-	w.MsgNum += 2
+	w.MsgNum++
+	head := int64(aiIndex)
+	w.Head = &head
 	if err := s.chatDao.ChatWindowUpdate(ctx, w); err != nil {
 		return nil, err
 	}
+	if w.MsgNum == 2 {
+		s.asyncGenerateTitle(ctx, w, pjId, userMsg, aiMsg)
+	}
+	s.asyncSummarizeChatMemory(ctx, w, pjId, memoryOverflow)
 
 	var content any
 	if aiMsg.Role == model.ChatMsgRoleSystem {
@@ -815,10 +704,13 @@ func (s *ChatService) Completions(ctx *reqctx.ReqCtx, req *entity.ChatCompletion
 	}
 	return &entity.ChatCompletionsRes{
 		Msg: &entity.ChatMsg{
-			Timestamp: aiMsg.Timestamp,
-			Role:      aiMsg.Role,
-			Content:   content,
-			ProjectId: pjId,
+			Timestamp:   aiMsg.Timestamp,
+			Role:        aiMsg.Role,
+			Content:     content,
+			ProjectId:   pjId,
+			AgentID:     lo.Ternary(agentID.IsZero(), "", agentID.Hex()),
+			Index:       aiIndex,
+			ParentIndex: parent,
 		},
 	}, nil
 }
@@ -847,7 +739,7 @@ func (s *ChatService) Delete(ctx *reqctx.ReqCtx, req *entity.ChatDeleteReq) (*en
 	if w.Creator.Hex() != ctx.Caller {
 		return nil, errcode.ErrAccountPermission
 	}
-	if err := s.chatDao.ChatWindowDelete(ctx, req.ID); err != nil {
+	if err := s.deleteWindow(ctx, w.ID); err != nil {
 		return nil, err
 	}
 	return &entity.ChatDeleteRes{}, nil
@@ -868,7 +760,7 @@ func (s *ChatService) DeleteAll(ctx *reqctx.ReqCtx, req *entity.ChatDeleteAllReq
 	}
 
 	for _, w := range res {
-		if err := s.chatDao.ChatWindowDelete(ctx, w.ID.Hex()); err != nil {
+		if err := s.deleteWindow(ctx, w.ID); err != nil {
 			return nil, err
 		}
 	}
@@ -876,165 +768,12 @@ func (s *ChatService) DeleteAll(ctx *reqctx.ReqCtx, req *entity.ChatDeleteAllReq
 	return &entity.ChatDeleteAllRes{}, nil
 }
 
-func (s *ChatService) InsertAgents(ctx *reqctx.ReqCtx, userId string) error {
-	pin, err := s.userPluginDao.QueryByUserIdAndPj(ctx, userId, s.baseComponent.Config.AIBackend.UniProjectId)
-	if err != nil {
-		return err
-	}
-	if pin == nil {
-		uni := &model.UserPlugin{
-			UserId:      userId,
-			ProjectId:   s.baseComponent.Config.AIBackend.UniProjectId,
-			ProjectName: "Uniswap",
-			PinStatus:   0,
-		}
-		bmuni, err := model.NewBaseModel(userId)
-		if err != nil {
-			return err
-		}
-		uni.BaseModel = bmuni
-		err = s.userPluginDao.InsertUserPin(ctx, uni)
-		if err != nil {
-			return err
-		}
-	}
-
-	pinwyt, err := s.userPluginDao.QueryByUserIdAndPj(ctx, userId, s.baseComponent.Config.AIBackend.ProjectId)
-	if pinwyt == nil {
-		// wyt
-		wyt := &model.UserPlugin{
-			UserId:      userId,
-			ProjectId:   s.baseComponent.Config.AIBackend.ProjectId,
-			ProjectName: "WYT Agent",
-			PinStatus:   1,
-		}
-		wytbm, err := model.NewBaseModel(userId)
-		if err != nil {
-			return err
-		}
-		wyt.BaseModel = wytbm
-		err = s.userPluginDao.InsertUserPin(ctx, wyt)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func (s *ChatService) AgentList(ctx *reqctx.ReqCtx, req *entity.AgentListReq) (*entity.AgentListRes, error) {
-	objID, err := primitive.ObjectIDFromHex(ctx.Caller)
-	if err != nil {
-		return nil, err
-	}
-	rets := make([]*model.UserPluginDto, 0)
-	pinList, total, err := s.userPluginDao.UserPluginPinList(ctx, 0, 0, bson.M{
-		"is_deleted": false,
-		"user_id":    objID.Hex(),
-	}, nil)
-	if err != nil {
-		return nil, err
-	}
-	if len(pinList) == 0 || total == 0 {
-		err := s.InsertAgents(ctx, ctx.Caller)
-		if err != nil {
-			s.baseComponent.Logger.Info("insert user associated agent error", err)
-			return nil, err
-		}
-		rets = append(rets, &model.UserPluginDto{
-			UserId:      ctx.Caller,
-			ProjectId:   s.baseComponent.Config.AIBackend.ProjectId,
-			ProjectName: "WYT Agent",
-			PinStatus:   1,
-		}, &model.UserPluginDto{
-			UserId:      ctx.Caller,
-			ProjectId:   s.baseComponent.Config.AIBackend.UniProjectId,
-			ProjectName: "Uniswap",
-			PinStatus:   0,
-		})
-	} else {
-		for _, p := range pinList {
-			dto := &model.UserPluginDto{
-				UserId:      p.UserId,
-				ProjectId:   p.ProjectId,
-				ProjectName: p.ProjectName,
-				PinStatus:   p.PinStatus,
-				PinTime:     time.Time(p.UpdateTime).Unix(),
-			}
-			rets = append(rets, dto)
-		}
-	}
-	resp := &entity.AgentListRes{
-		Total: total,
-		List:  rets,
-	}
-	return resp, nil
-}
-
-func (s *ChatService) PinAgent(ctx *reqctx.ReqCtx, req *entity.AgentPinReq) error {
-	objID, err := primitive.ObjectIDFromHex(ctx.Caller)
-	if err != nil {
-		return err
-	}
-	up, err := s.userPluginDao.QueryByUserIdAndPj(ctx, objID.Hex(), req.ProjectId)
-	if err != nil {
-		if err == errcode.ErrUserPinNotExist {
-			up = nil
-		} else {
-			return err
-		}
-	}
-	if up == nil {
-		var pjName string
-		if req.ProjectId == s.baseComponent.Config.AIBackend.ProjectId {
-			pjName = "WYT Agent"
-		} else if req.ProjectId == s.baseComponent.Config.AIBackend.UniProjectId {
-			pjName = "Uniswap"
-		}
-		up = &model.UserPlugin{
-			UserId:      objID.Hex(),
-			ProjectId:   req.ProjectId,
-			ProjectName: pjName,
-			PinStatus:   1,
-		}
-		bm, err := model.NewBaseModel(ctx.Caller)
-		if err != nil {
-			return err
-		}
-		up.BaseModel = bm
-		err = s.userPluginDao.InsertUserPin(ctx, up)
-		if err != nil {
-			return err
-		}
-		return nil
-	} else {
-		up.PinStatus = 1
-	}
-
-	err = s.userPluginDao.UpdateUserPin(ctx, up)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (s *ChatService) UnpinAgent(ctx *reqctx.ReqCtx, req *entity.AgentUnPinReq) error {
-	objID, err := primitive.ObjectIDFromHex(ctx.Caller)
-	if err != nil {
+// deleteWindow deletes the window with its messages and revokes its shares
+func (s *ChatService) deleteWindow(ctx *reqctx.ReqCtx, id primitive.ObjectID) error {
+	if err := s.chatDao.ChatWindowDelete(ctx, id.Hex()); err != nil {
 		return err
 	}
-	up, err := s.userPluginDao.QueryByUserIdAndPj(ctx, objID.Hex(), req.ProjectId)
-	if err != nil {
-		return err
-	}
-	if up != nil {
-		up.PinStatus = 0
-	}
-	err = s.userPluginDao.UpdateUserPin(ctx, up)
-	if err != nil {
-		return err
-	}
-	return nil
+	return s.chatShareDao.RevokeByWindow(ctx, id)
 }
 
 // func patternMatchTransfer(msg string) *model.ChatAIAnalyticalResult {
@@ -1131,3 +870,200 @@ func (s *ChatService) UnpinAgent(ctx *reqctx.ReqCtx, req *entity.AgentUnPinReq)
 
 // 	return &res
 // }
+
+// askModel asks the model (or the wyt ai backend) the question, the answer is either the analytical result
+// or the result of a tool call
+func (s *ChatService) askModel(ctx *reqctx.ReqCtx, questMsgs []*extension.ChatgptMsg, pjId string, useStructuredOutput bool, emitter entity.ChatStreamEmitter) (*chatCacheEntry, error) {
+	emit := func(event string, data any) {
+		if emitter != nil {
+			emitter(event, data)
+		}
+	}
+	var retStr string
+	var fcRet *model.FuncCallingRet
+	var err error
+
+	llmCtx := extension.WithFuncCallListener(ctx.Ctx, func(name string, arguments string) {
+		emit(entity.ChatStreamEventFunctionCall, &entity.ChatStreamFunctionCall{
+			Name:      name,
+			Arguments: arguments,
+		})
+	})
+	var onDelta func(content string)
+	if emitter != nil {
+		onDelta = func(content string) {
+			emit(entity.ChatStreamEventToken, &entity.ChatStreamToken{Content: content})
+		}
+	}
+	if useStructuredOutput {
+		// always call this
+		retStr, fcRet, err = s.chatgptDriver.DocsFaqStream(llmCtx, questMsgs, pjId, onDelta)
+	} else {
+		retStr, fcRet, err = s.chatgptDriver.ChatWithStructuredOutputForProject(llmCtx, questMsgs, pjId, onDelta)
+	}
+	if err != nil {
+		ctx.AddCustomLogField("ai_err", err)
+		if strings.Contains(err.Error(), "not found") {
+			return nil, errors.New(traderNotFoundErrMsg)
+		}
+		return nil, errors.New(networkErrMsg)
+	}
+	res := &chatCacheEntry{FuncCallingRet: fcRet}
+	if retStr != "" {
+		if err := s.checkOutput(ctx, retStr); err != nil {
+			ctx.AddCustomLogField("ai_err", err)
+			return nil, errors.New(intentionErrMsg)
+		}
+		res.Result = &model.ChatAIAnalyticalResult{}
+		if err := json.Unmarshal([]byte(retStr), res.Result); err != nil {
+			ctx.AddCustomLogField("ai_err", err)
+			return nil, errors.New(intentionErrMsg)
+		}
+		ctx.AddCustomLogField("ai_analytical", retStr)
+	}
+	return res, nil
+}
+
+// withAgentPrompt puts the instructions of the agent first in msgs, the driver sends them after the system
+// prompt of the template (or the built-in one)
+func withAgentPrompt(msgs []*extension.ChatgptMsg, agent *model.Agent) []*extension.ChatgptMsg {
+	if agent.Prompt == "" {
+		return msgs
+	}
+	return append([]*extension.ChatgptMsg{{Role: "system", Content: agent.Prompt}}, msgs...)
+}
+
+// projectFetcher looks up the projects of the search and compare intentions
+type projectFetcher func(ctx *reqctx.ReqCtx, projectKeys []string, view string) ([]model.ChatContentAssistantProjectInfo, string, error)
+
+// answer asks the model (or the wyt ai backend) the question, which is the last of questMsgs, unless
+// the answer is cached, and fills the assistant message with the intention or the tool call result
+func (s *ChatService) answer(ctx *reqctx.ReqCtx, questMsgs []*extension.ChatgptMsg, pjId string, useStructuredOutput bool, emitter entity.ChatStreamEmitter, fetchProjects projectFetcher, aiMsg *model.ChatMsg) error {
+	emit := func(event string, data any) {
+		if emitter != nil {
+			emitter(event, data)
+		}
+	}
+	// 本次问答结果
+	var chatAIAnalyticalResult model.ChatAIAnalyticalResult
+
+	cacheKey := s.chatCacheKey(ctx, pjId, questMsgs)
+	ret, hit := s.cachedAnswer(ctx, cacheKey)
+	if hit {
+		if ret.Result != nil && ret.Result.Content != "" {
+			// like the wyt ai backend, the whole answer text is one token event
+			emit(entity.ChatStreamEventToken, &entity.ChatStreamToken{Content: ret.Result.Content})
+		}
+	} else {
+		var err error
+		ret, err = s.askModel(ctx, questMsgs, pjId, useStructuredOutput, emitter)
+		if err != nil {
+			return err
+		}
+		s.cacheAnswer(ctx, cacheKey, ret)
+	}
+	fcRet := ret.FuncCallingRet
+
+	if ret.Result != nil {
+		// a copy, the cached result is shared
+		chatAIAnalyticalResult = *ret.Result
+		emit(entity.ChatStreamEventIntent, &entity.ChatStreamIntent{
+			Intention:  chatAIAnalyticalResult.Intention,
+			View:       chatAIAnalyticalResult.View,
+			IntentKeys: chatAIAnalyticalResult.IntentKeys,
+		})
+		if (chatAIAnalyticalResult.Intention != model.ChatAIAnalyticalIntentionSearch) &&
+			(chatAIAnalyticalResult.Intention != model.ChatAIAnalyticalIntentionCompare) {
+			aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeGeneral
+			aiMsg.ContentAssistant.Fill = chatAIAnalyticalResult.Fill
+			aiMsg.ContentAssistant.Tips = "Here is the answer:"
+			aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeGeneral
+			aiMsg.ContentAssistant.GeneralAnswer = &model.ChatContentAssistantGeneralAnswerRes{
+				View: model.ChatContentAssistantProjectInfoViewGeneralAnswer,
+				GeneralAnswer: model.ChatContentAssistantGeneralAnswer{
+					ID:      primitive.NewObjectID(),
+					Content: chatAIAnalyticalResult.Content,
+				},
+			}
+		} else {
+			isGeneralFaq := false
+			emit(entity.ChatStreamEventProjectFetch, &entity.ChatStreamProjectFetch{
+				ProjectKeys: chatAIAnalyticalResult.IntentKeys,
+				View:        chatAIAnalyticalResult.View,
+			})
+			datas, view, err := fetchProjects(ctx, chatAIAnalyticalResult.IntentKeys, chatAIAnalyticalResult.View)
+			if err != nil {
+				ctx.AddCustomLogField("ai_err", err)
+				if chatAIAnalyticalResult.Content != "" {
+					isGeneralFaq = true
+				}
+			}
+			chatAIAnalyticalResult.View = view
+			aiMsg.ContentAssistant.ProjectKeys = chatAIAnalyticalResult.IntentKeys
+
+			switch chatAIAnalyticalResult.Intention {
+			case model.ChatAIAnalyticalIntentionSearch:
+				if len(chatAIAnalyticalResult.IntentKeys) == 0 {
+					return errors.New("the intention is not clear, and AI cannot analyze it. Please provide a more detailed description")
+				}
+				part := view
+				if view == "overview" {
+					part = "some info"
+				}
+				aiMsg.ContentAssistant.Tips = fmt.Sprintf("Here is %s about %s:", part, chatAIAnalyticalResult.IntentKeys[0])
+				if isGeneralFaq {
+					aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeGeneral
+					aiMsg.ContentAssistant.GeneralAnswer = &model.ChatContentAssistantGeneralAnswerRes{
+						View: model.ChatContentAssistantProjectInfoViewGeneralAnswer,
+						GeneralAnswer: model.ChatContentAssistantGeneralAnswer{
+							ID:      primitive.NewObjectID(),
+							Content: chatAIAnalyticalResult.Content,
+						},
+					}
+				} else {
+					aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeProjectInfo
+					aiMsg.ContentAssistant.ProjectInfo = &model.ChatContentAssistantProjectInfoRes{
+						View:    chatAIAnalyticalResult.View,
+						Project: datas[0],
+					}
+				}
+			case model.ChatAIAnalyticalIntentionCompare:
+				if len(chatAIAnalyticalResult.IntentKeys) < 2 {
+					return errors.New("Sorry, I didn't understand what you meant. Can you provide me with more information?")
+				}
+				if view == "overview" {
+					aiMsg.ContentAssistant.Tips = fmt.Sprintf("Here is a brief comparison about %s and %s:", chatAIAnalyticalResult.IntentKeys[0], chatAIAnalyticalResult.IntentKeys[1])
+				} else {
+					aiMsg.ContentAssistant.Tips = fmt.Sprintf("Here is %s about comparison of %s and %s:", view, chatAIAnalyticalResult.IntentKeys[0], chatAIAnalyticalResult.IntentKeys[1])
+				}
+				aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeProjectCompare
+				aiMsg.ContentAssistant.ProjectCompare = &model.ChatContentAssistantProjectCompareRes{
+					View:     chatAIAnalyticalResult.View,
+					Projects: datas,
+				}
+			default:
+				aiMsg.ContentAssistant.Type = model.ChatContentAssistantTypeGeneral
+				aiMsg.ContentAssistant.GeneralAnswer = &model.ChatContentAssistantGeneralAnswerRes{
+					View: model.ChatContentAssistantProjectInfoViewGeneralAnswer,
+					GeneralAnswer: model.ChatContentAssistantGeneralAnswer{
+						ID:      primitive.NewObjectID(),
+						Content: chatAIAnalyticalResult.Content,
+					},
+				}
+			}
+			chatAIAnalyticalResult.ProjectIDs = lo.Map(datas, func(item model.ChatContentAssistantProjectInfo, index int) primitive.ObjectID {
+				return item.ID
+			})
+			aiMsg.ContentAssistant.Fill = chatAIAnalyticalResult.Fill
+		}
+		return nil
+	}
+
+	if fcRet != nil {
+		if tool, ok := extension.LookupToolByType(fcRet.FCType); ok {
+			tool.RenderResult(fcRet, aiMsg.ContentAssistant, &chatAIAnalyticalResult)
+		}
+	}
+
+	return nil
+}
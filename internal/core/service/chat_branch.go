@@ -0,0 +1,201 @@
+package service
+
+import (
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+// chatTurnOpts answers a question on another branch than the active one, nil appends to the active branch
+type chatTurnOpts struct {
+	edit       *entity.ChatEditReq
+	regenerate *entity.ChatRegenerateReq
+}
+
+// chatTurn places the next exchange in the branch tree of the window
+type chatTurn struct {
+	// index of the message the question follows
+	parent int64
+	// question answered again by regenerate, nil when the question is added as a new message
+	question *model.ChatHistory
+}
+
+// chatTurn resolves where the exchange goes, the question of regenerate is copied to req.Msg
+func (s *ChatService) chatTurn(ctx *reqctx.ReqCtx, w *model.ChatWindow, req *entity.ChatCompletionsReq, opts *chatTurnOpts) (*chatTurn, error) {
+	switch {
+	case opts == nil:
+		return &chatTurn{parent: w.HeadIndex()}, nil
+	case opts.edit != nil:
+		if opts.edit.Msg == "" {
+			return nil, errcode.ErrRequestParameter.Wrap("msg cannot be empty")
+		}
+		h, err := s.chatDao.ChatHistoryQuery(ctx, w.ID, opts.edit.Index)
+		if err != nil {
+			return nil, err
+		}
+		if h.Msg.Role != model.ChatMsgRoleUser {
+			return nil, errcode.ErrRequestParameter.Wrap("only questions can be edited")
+		}
+		resetChatMemory(w, int64(h.Index))
+		return &chatTurn{parent: h.Parent()}, nil
+	case opts.regenerate != nil:
+		if int64(opts.regenerate.Index) != w.HeadIndex() {
+			return nil, errcode.ErrRequestParameter.Wrap("only the last answer can be regenerated")
+		}
+		h, err := s.chatDao.ChatHistoryQuery(ctx, w.ID, opts.regenerate.Index)
+		if err != nil {
+			return nil, err
+		}
+		if h.Msg.Role != model.ChatMsgRoleAssistant && h.Msg.Role != model.ChatMsgRoleSystem {
+			return nil, errcode.ErrRequestParameter.Wrap("only answers can be regenerated")
+		}
+		question, err := s.chatDao.ChatHistoryQuery(ctx, w.ID, uint64(h.Parent()))
+		if err != nil {
+			return nil, err
+		}
+		if question.Msg.Role != model.ChatMsgRoleUser || question.Msg.ContentUser == nil {
+			return nil, errcode.ErrRequestParameter.Wrap("only answers to questions can be regenerated")
+		}
+		resetChatMemory(w, int64(h.Index))
+		// another answer is wanted, not the cached one
+		ctx.PutValue(chatCacheBypassKey{}, true)
+		req.Msg = question.Msg.ContentUser.Content
+		return &chatTurn{parent: question.Parent(), question: question}, nil
+	}
+	return nil, errcode.ErrRequestParameter
+}
+
+// Regenerate answers the question of the last answer again, on a new branch next to it
+func (s *ChatService) Regenerate(ctx *reqctx.ReqCtx, req *entity.ChatRegenerateReq, useStructuredOutput bool) (*entity.ChatCompletionsRes, error) {
+	return s.RegenerateStream(ctx, req, useStructuredOutput, nil)
+}
+
+// RegenerateStream works like Regenerate and reports progress through emitter like CompletionsStream
+func (s *ChatService) RegenerateStream(ctx *reqctx.ReqCtx, req *entity.ChatRegenerateReq, useStructuredOutput bool, emitter entity.ChatStreamEmitter) (*entity.ChatCompletionsRes, error) {
+	return s.completions(ctx, &entity.ChatCompletionsReq{
+		ID:   req.ID,
+		Type: model.ChatMsgRoleUser,
+	}, useStructuredOutput, emitter, &chatTurnOpts{regenerate: req})
+}
+
+// Edit sends the edited question on a new branch next to the original question
+func (s *ChatService) Edit(ctx *reqctx.ReqCtx, req *entity.ChatEditReq, useStructuredOutput bool) (*entity.ChatCompletionsRes, error) {
+	return s.EditStream(ctx, req, useStructuredOutput, nil)
+}
+
+// EditStream works like Edit and reports progress through emitter like CompletionsStream
+func (s *ChatService) EditStream(ctx *reqctx.ReqCtx, req *entity.ChatEditReq, useStructuredOutput bool, emitter entity.ChatStreamEmitter) (*entity.ChatCompletionsRes, error) {
+	return s.completions(ctx, &entity.ChatCompletionsReq{
+		ID:   req.ID,
+		Type: model.ChatMsgRoleUser,
+		Msg:  req.Msg,
+	}, useStructuredOutput, emitter, &chatTurnOpts{edit: req})
+}
+
+// SwitchBranch makes the branch of the message the active one, the next question follows its last message
+func (s *ChatService) SwitchBranch(ctx *reqctx.ReqCtx, req *entity.ChatSwitchBranchReq) (*entity.ChatSwitchBranchRes, error) {
+	w, err := s.chatDao.ChatWindowQuery(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if w.Creator.Hex() != ctx.Caller {
+		return nil, errcode.ErrAccountPermission
+	}
+	histories, err := s.chatDao.ChatHistoryListSince(ctx, w.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !lo.ContainsBy(histories, func(item *model.ChatHistory) bool {
+		return item.Index == req.Index
+	}) {
+		return nil, errcode.ErrChatMsgNotExist
+	}
+
+	head := chatBranchLeaf(histories, int64(req.Index))
+	resetChatMemory(w, chatBranchFork(histories, w.HeadIndex(), head)+1)
+	w.Head = &head
+	if err := s.chatDao.ChatWindowUpdate(ctx, w); err != nil {
+		return nil, err
+	}
+	return &entity.ChatSwitchBranchRes{
+		Head: head,
+	}, nil
+}
+
+// chatBranchMsgs returns the messages of the branch of the message, head of the window when index is nil
+func chatBranchMsgs(ctx *reqctx.ReqCtx, chatDao chatStore, w *model.ChatWindow, index *int64) ([]*model.ChatHistory, map[int64][]uint64, error) {
+	histories, _, err := chatDao.ChatHistoryList(ctx, 0, 0, bson.M{
+		"is_deleted": false,
+		"window_id":  w.ID,
+	}, map[string]bool{"index": true})
+	if err != nil {
+		return nil, nil, err
+	}
+	head := w.HeadIndex()
+	if index != nil {
+		if !lo.ContainsBy(histories, func(item *model.ChatHistory) bool {
+			return int64(item.Index) == *index
+		}) {
+			return nil, nil, errcode.ErrChatMsgNotExist
+		}
+		head = *index
+	}
+	return chatBranchAncestors(histories, chatBranchLeaf(histories, head)), chatBranchChildren(histories), nil
+}
+
+// resetChatMemory drops the summary when it covers the message, which is no longer on the active branch
+func resetChatMemory(w *model.ChatWindow, index int64) {
+	if index < int64(w.SummaryIndex) {
+		w.Summary = ""
+		w.SummaryIndex = 0
+	}
+}
+
+// chatBranchChildren returns the indexes of the children of each message, -1 holds the first messages
+func chatBranchChildren(histories []*model.ChatHistory) map[int64][]uint64 {
+	children := map[int64][]uint64{}
+	for _, h := range histories {
+		children[h.Parent()] = append(children[h.Parent()], h.Index)
+	}
+	return children
+}
+
+// chatBranchLeaf follows the newest child of each message from the index down to the last message
+func chatBranchLeaf(histories []*model.ChatHistory, index int64) int64 {
+	children := chatBranchChildren(histories)
+	for len(children[index]) != 0 {
+		index = int64(lo.Max(children[index]))
+	}
+	return index
+}
+
+// chatBranchAncestors returns the messages from the first one to the index, following the parents.
+// It stops at the first parent missing from histories, e.g. when they are loaded from an index on.
+func chatBranchAncestors(histories []*model.ChatHistory, index int64) []*model.ChatHistory {
+	byIndex := lo.KeyBy(histories, func(item *model.ChatHistory) int64 {
+		return int64(item.Index)
+	})
+	var res []*model.ChatHistory
+	for h, ok := byIndex[index]; ok; h, ok = byIndex[h.Parent()] {
+		res = append(res, h)
+	}
+	return lo.Reverse(res)
+}
+
+// chatBranchFork returns the last message shared by the branches of a and b, -1 when they share none
+func chatBranchFork(histories []*model.ChatHistory, a int64, b int64) int64 {
+	onA := lo.SliceToMap(chatBranchAncestors(histories, a), func(item *model.ChatHistory) (uint64, bool) {
+		return item.Index, true
+	})
+	ancestors := chatBranchAncestors(histories, b)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if onA[ancestors[i].Index] {
+			return int64(ancestors[i].Index)
+		}
+	}
+	return -1
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+)
+
+// chatBranchTree is a window of two legacy messages (0, 1) and branches written since:
+// the answer 1 regenerated as 2, then the question 0 edited as 3 and answered with 4
+func chatBranchTree() []*model.ChatHistory {
+	parent := func(index int64) *int64 {
+		return &index
+	}
+	return []*model.ChatHistory{
+		{Index: 0},
+		{Index: 1},
+		{Index: 2, ParentIndex: parent(0)},
+		{Index: 3, ParentIndex: parent(-1)},
+		{Index: 4, ParentIndex: parent(3)},
+	}
+}
+
+func historyIndexes(histories []*model.ChatHistory) []uint64 {
+	var res []uint64
+	for _, h := range histories {
+		res = append(res, h.Index)
+	}
+	return res
+}
+
+func TestChatBranchChildren(t *testing.T) {
+	children := chatBranchChildren(chatBranchTree())
+	assert.Equal(t, []uint64{0, 3}, children[-1])
+	assert.Equal(t, []uint64{1, 2}, children[0])
+	assert.Equal(t, []uint64{4}, children[3])
+}
+
+func TestChatBranchLeaf(t *testing.T) {
+	histories := chatBranchTree()
+	assert.Equal(t, int64(2), chatBranchLeaf(histories, 0))
+	assert.Equal(t, int64(1), chatBranchLeaf(histories, 1))
+	assert.Equal(t, int64(4), chatBranchLeaf(histories, 3))
+	assert.Equal(t, int64(4), chatBranchLeaf(histories, -1))
+}
+
+func TestChatBranchAncestors(t *testing.T) {
+	histories := chatBranchTree()
+	assert.Equal(t, []uint64{0, 2}, historyIndexes(chatBranchAncestors(histories, 2)))
+	assert.Equal(t, []uint64{0, 1}, historyIndexes(chatBranchAncestors(histories, 1)))
+	assert.Equal(t, []uint64{3, 4}, historyIndexes(chatBranchAncestors(histories, 4)))
+	assert.Empty(t, chatBranchAncestors(histories, -1))
+	// loaded from index 2 on, like the chat memory after a summary
+	assert.Equal(t, []uint64{2}, historyIndexes(chatBranchAncestors(histories[2:], 2)))
+}
+
+func TestChatBranchFork(t *testing.T) {
+	histories := chatBranchTree()
+	assert.Equal(t, int64(0), chatBranchFork(histories, 1, 2))
+	assert.Equal(t, int64(-1), chatBranchFork(histories, 2, 4))
+	assert.Equal(t, int64(3), chatBranchFork(histories, 4, 3))
+}
+
+func TestResetChatMemory(t *testing.T) {
+	w := &model.ChatWindow{Summary: "summary", SummaryIndex: 4}
+	resetChatMemory(w, 4)
+	assert.Equal(t, "summary", w.Summary)
+	resetChatMemory(w, 3)
+	assert.Empty(t, w.Summary)
+	assert.Equal(t, uint64(0), w.SummaryIndex)
+}
+
+func TestChatWindowHeadIndex(t *testing.T) {
+	assert.Equal(t, int64(-1), (&model.ChatWindow{}).HeadIndex())
+	assert.Equal(t, int64(3), (&model.ChatWindow{MsgNum: 4}).HeadIndex())
+	head := int64(1)
+	assert.Equal(t, int64(1), (&model.ChatWindow{MsgNum: 4, Head: &head}).HeadIndex())
+}
+
+func TestPageSlice(t *testing.T) {
+	list := []int{1, 2, 3, 4, 5}
+	assert.Equal(t, list, pageSlice(list, 0, 2))
+	assert.Equal(t, []int{3, 4}, pageSlice(list, 2, 2))
+	assert.Equal(t, []int{5}, pageSlice(list, 3, 2))
+	assert.Nil(t, pageSlice(list, 4, 2))
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/pkg/cache"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const chatCacheNamespace = "chat_answer"
+
+// chatCacheBypassKey marks the requests that must not be answered from the cache (regenerate),
+// their answer still replaces the cached one
+type chatCacheBypassKey struct{}
+
+// defaultChatCacheTTLs keep the pump.fun data for a few minutes and the project info for an hour,
+// swap quotes are not cached
+var defaultChatCacheTTLs = map[string]time.Duration{
+	model.ChatAIAnalyticalIntentionSearch:  time.Hour,
+	model.ChatAIAnalyticalIntentionCompare: time.Hour,
+	model.ChatAIAnalyticalIntentionGeneral: time.Hour,
+	model.FCDailyNewToken:                  5 * time.Minute,
+	model.FCTokenLaunchedTimeDistribution:  5 * time.Minute,
+	model.FCDailyTokenSwapCount:            5 * time.Minute,
+	model.FCTopTrader:                      5 * time.Minute,
+	model.FCTraderOverview:                 5 * time.Minute,
+}
+
+// chatPromptTemplateKey holds the prompt template the question is answered with, the answers of
+// different templates are cached apart so they keep their own prompt stats
+type chatPromptTemplateKey struct{}
+
+// chatCacheEntry is the parsed answer of the model, the project data and the tool rendering are redone on a hit
+type chatCacheEntry struct {
+	Result         *model.ChatAIAnalyticalResult
+	FuncCallingRet *model.FuncCallingRet
+}
+
+// chatCacheKey identifies the question, empty when the cache is disabled. The agent, the language and the
+// prompt template are part of the key, and so is everything sent before the question (prompt, memory), which
+// makes the follow-up questions of a conversation only match the same conversation.
+func (s *ChatService) chatCacheKey(ctx *reqctx.ReqCtx, pjId string, msgs []*extension.ChatgptMsg) string {
+	if !s.baseComponent.Config.Extension.ChatCache.Enable || len(msgs) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	lang := "en"
+	if ctx.IsZHLang {
+		lang = "zh"
+	}
+	h.Write([]byte(lang))
+	if tmpl, ok := ctx.GetValue(chatPromptTemplateKey{}).(*model.PromptTemplate); ok {
+		schema, _ := json.Marshal(tmpl.Schema)
+		h.Write([]byte{0})
+		h.Write([]byte(tmpl.Name))
+		h.Write([]byte{0})
+		h.Write([]byte(strconv.FormatUint(tmpl.Version, 10)))
+		h.Write([]byte{0})
+		h.Write(schema)
+	}
+	for _, msg := range msgs[:len(msgs)-1] {
+		h.Write([]byte{0})
+		h.Write([]byte(msg.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(msg.Content))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(normalizeChatQuestion(msgs[len(msgs)-1].Content)))
+	// the agent prefix lets the admin purge one agent
+	return pjId + "_" + hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeChatQuestion ignores the case, the spacing and the trailing punctuation of the question
+func normalizeChatQuestion(q string) string {
+	q = strings.Join(strings.Fields(strings.ToLower(q)), " ")
+	return strings.TrimRightFunc(q, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+}
+
+func chatCacheTTL(cfg config.ChatCache, intention string) time.Duration {
+	if intention == "" {
+		return 0
+	}
+	if ttl, ok := cfg.TTLs[intention]; ok {
+		return ttl.ToDuration()
+	}
+	if ttl, ok := defaultChatCacheTTLs[intention]; ok {
+		return ttl
+	}
+	return cfg.DefaultTTL.ToDuration()
+}
+
+func (s *ChatService) cachedAnswer(ctx *reqctx.ReqCtx, key string) (*chatCacheEntry, bool) {
+	if key == "" || ctx.GetValue(chatCacheBypassKey{}) != nil {
+		return nil, false
+	}
+	entry, ok := cache.GetFromMemCache[*chatCacheEntry](s.baseComponent.MemCache, chatCacheNamespace, key)
+	if ok {
+		ctx.AddCustomLogField("chat_cache", "hit")
+	}
+	return entry, ok
+}
+
+func (s *ChatService) cacheAnswer(ctx *reqctx.ReqCtx, key string, entry *chatCacheEntry) {
+	if key == "" {
+		return
+	}
+	var intention string
+	switch {
+	case entry.FuncCallingRet != nil:
+		intention = entry.FuncCallingRet.FCType
+	case entry.Result != nil:
+		intention = entry.Result.Intention
+	}
+	ttl := chatCacheTTL(s.baseComponent.Config.Extension.ChatCache, intention)
+	if ttl <= 0 {
+		return
+	}
+	cache.PutToMemCacheWithTTL(s.baseComponent.MemCache, chatCacheNamespace, key, entry, ttl)
+}
+
+// AdminPurgeCache drops the cached answers of the agent, of all agents when no project id is given
+func (s *ChatService) AdminPurgeCache(ctx *reqctx.ReqCtx, req *entity.ChatCachePurgeReq) (*entity.ChatCachePurgeRes, error) {
+	prefix := ""
+	if req.ProjectId != "" {
+		prefix = req.ProjectId + "_"
+	}
+	purged := cache.DeleteFromMemCacheByPrefix(s.baseComponent.MemCache, chatCacheNamespace, prefix)
+	s.baseComponent.Logger.WithFields(logrus.Fields{
+		"project_id": req.ProjectId,
+		"purged":     purged,
+		"admin":      ctx.Caller,
+	}).Info("Chat cache purged")
+	return &entity.ChatCachePurgeRes{
+		Purged: purged,
+	}, nil
+}
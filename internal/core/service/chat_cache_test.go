@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/azure/azuretest"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func TestNormalizeChatQuestion(t *testing.T) {
+	assert.Equal(t, "what is eth tokenomics", normalizeChatQuestion("  What is  ETH\ttokenomics?? "))
+	assert.Equal(t, "以太坊的代币经济", normalizeChatQuestion("以太坊的代币经济？"))
+	assert.Equal(t, "top traders today", normalizeChatQuestion("Top traders today!"))
+}
+
+func TestChatCacheTTL(t *testing.T) {
+	cfg := config.ChatCache{
+		TTLs:       map[string]config.Duration{model.FCTopTrader: config.Duration(time.Minute)},
+		DefaultTTL: config.Duration(10 * time.Second),
+	}
+	assert.Equal(t, time.Minute, chatCacheTTL(cfg, model.FCTopTrader))
+	assert.Equal(t, 5*time.Minute, chatCacheTTL(cfg, model.FCTraderOverview))
+	assert.Equal(t, time.Hour, chatCacheTTL(cfg, model.ChatAIAnalyticalIntentionSearch))
+	assert.Equal(t, 10*time.Second, chatCacheTTL(cfg, model.FCSwap))
+	assert.Equal(t, time.Duration(0), chatCacheTTL(config.ChatCache{}, model.FCSwap))
+	assert.Equal(t, time.Duration(0), chatCacheTTL(cfg, ""))
+}
+
+func TestChatService_AnswerCache(t *testing.T) {
+	s, server := newMockChatService(t,
+		azuretest.Rule{Pattern: regexp.MustCompile("(?i)what is defi"), JSON: map[string]any{"intention": "general", "intent_keys": []string{}, "view": "", "content": "DeFi is decentralized finance"}},
+	)
+	s.baseComponent.Config.Extension.ChatCache.Enable = true
+	ask := func(ctx *reqctx.ReqCtx, question string) *model.ChatContentAssistant {
+		aiMsg := &model.ChatMsg{ContentAssistant: &model.ChatContentAssistant{}}
+		assert.Nil(t, s.answer(ctx, []*extension.ChatgptMsg{{Role: "user", Content: question}}, "pj1", false, nil, mockProjectFetcher, aiMsg))
+		return aiMsg.ContentAssistant
+	}
+	newCtx := func() *reqctx.ReqCtx {
+		return reqctx.NewReqCtx(context.Background(), logrus.New(), 1, "")
+	}
+
+	assert.Equal(t, "DeFi is decentralized finance", ask(newCtx(), "what is defi").GeneralAnswer.GeneralAnswer.Content)
+	// the same question asked differently is answered from the cache
+	assert.Equal(t, "DeFi is decentralized finance", ask(newCtx(), " What is DeFi? ").GeneralAnswer.GeneralAnswer.Content)
+	assert.Len(t, server.Requests(), 1)
+
+	// another language, another agent or regenerate ask the model
+	zhCtx := newCtx()
+	zhCtx.IsZHLang = true
+	ask(zhCtx, "what is defi")
+	aiMsg := &model.ChatMsg{ContentAssistant: &model.ChatContentAssistant{}}
+	assert.Nil(t, s.answer(newCtx(), []*extension.ChatgptMsg{{Role: "user", Content: "what is defi"}}, "pj2", false, nil, mockProjectFetcher, aiMsg))
+	regenerateCtx := newCtx()
+	regenerateCtx.PutValue(chatCacheBypassKey{}, true)
+	ask(regenerateCtx, "what is defi")
+	assert.Len(t, server.Requests(), 4)
+
+	res, err := s.AdminPurgeCache(newCtx(), &entity.ChatCachePurgeReq{ProjectId: "pj2"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, res.Purged)
+	res, err = s.AdminPurgeCache(newCtx(), &entity.ChatCachePurgeReq{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, res.Purged)
+	ask(newCtx(), "what is defi")
+	assert.Len(t, server.Requests(), 5)
+
+	// the answers of a prompt template are not served to another template or version
+	templateCtx := func(name string, version uint64) *reqctx.ReqCtx {
+		ctx := newCtx()
+		ctx.PutValue(chatPromptTemplateKey{}, &model.PromptTemplate{Name: name, Version: version})
+		return ctx
+	}
+	ask(templateCtx("a", 1), "what is defi")
+	ask(templateCtx("b", 1), "what is defi")
+	ask(templateCtx("a", 2), "what is defi")
+	assert.Len(t, server.Requests(), 8)
+	ask(templateCtx("a", 1), "what is defi")
+	assert.Len(t, server.Requests(), 8)
+}
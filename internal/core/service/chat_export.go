@@ -0,0 +1,108 @@
+package service
+
+import (
+	"bytes"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/chatexport"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+// Export renders the active branch of the window as a document
+func (s *ChatService) Export(ctx *reqctx.ReqCtx, req *entity.ChatExportReq) (*entity.Attachment, error) {
+	format, err := chatExportFormat(req.Format)
+	if err != nil {
+		return nil, err
+	}
+	w, err := s.chatDao.ChatWindowQuery(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if w.Creator.Hex() != ctx.Caller {
+		return nil, errcode.ErrAccountPermission
+	}
+	conv, err := s.exportConversation(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := chatexport.Write(&buf, format, conv); err != nil {
+		return nil, err
+	}
+	return &entity.Attachment{
+		Name:        chatexport.FileName(conv, format),
+		ContentType: chatexport.ContentType(format),
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// ExportAll renders every window of the caller into a zip, a file per window
+func (s *ChatService) ExportAll(ctx *reqctx.ReqCtx, req *entity.ChatExportAllReq) (*entity.Attachment, error) {
+	format, err := chatExportFormat(req.Format)
+	if err != nil {
+		return nil, err
+	}
+	objID, err := primitive.ObjectIDFromHex(ctx.Caller)
+	if err != nil {
+		return nil, err
+	}
+	windows, _, err := s.chatDao.ChatWindowList(ctx, 0, 0, bson.M{
+		"is_deleted": false,
+		"creator":    objID,
+	}, map[string]bool{"create_time": true})
+	if err != nil {
+		return nil, err
+	}
+
+	convs := make([]*chatexport.Conversation, 0, len(windows))
+	for _, w := range windows {
+		conv, err := s.exportConversation(ctx, w)
+		if err != nil {
+			return nil, err
+		}
+		convs = append(convs, conv)
+	}
+	var buf bytes.Buffer
+	if err := chatexport.WriteZip(&buf, format, convs); err != nil {
+		return nil, err
+	}
+	return &entity.Attachment{
+		Name:        "chats-" + time.Now().UTC().Format("2006-01-02") + ".zip",
+		ContentType: "application/zip",
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+func (s *ChatService) exportConversation(ctx *reqctx.ReqCtx, w *model.ChatWindow) (*chatexport.Conversation, error) {
+	branch, _, err := chatBranchMsgs(ctx, s.chatDao, w, nil)
+	if err != nil {
+		return nil, err
+	}
+	conv := &chatexport.Conversation{
+		ID:         w.ID.Hex(),
+		Title:      w.Title,
+		CreateTime: time.Time(w.CreateTime),
+		Msgs:       make([]*chatexport.Message, 0, len(branch)),
+	}
+	for _, h := range branch {
+		conv.Msgs = append(conv.Msgs, chatexport.NewMessage(h))
+	}
+	return conv, nil
+}
+
+func chatExportFormat(format string) (chatexport.Format, error) {
+	if format == "" {
+		return chatexport.FormatMarkdown, nil
+	}
+	if !chatexport.ValidFormat(format) {
+		return "", errcode.ErrRequestParameter.Wrap("format must be md, json or html")
+	}
+	return format, nil
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/chatexport"
+)
+
+func TestChatExportFormat(t *testing.T) {
+	format, err := chatExportFormat("")
+	assert.Nil(t, err)
+	assert.Equal(t, chatexport.FormatMarkdown, format)
+	format, err = chatExportFormat("html")
+	assert.Nil(t, err)
+	assert.Equal(t, chatexport.FormatHTML, format)
+	_, err = chatExportFormat("pdf")
+	assert.NotNil(t, err)
+}
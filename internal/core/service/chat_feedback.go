@@ -0,0 +1,237 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/dao"
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	chatFeedbackCommentMaxLen  = 1000
+	chatFeedbackExportLimit    = 20
+	chatFeedbackExportMaxLimit = 100
+)
+
+var chatFeedbackGroupFields = map[string][]string{
+	"intention": {"intention"},
+	"view":      {"view"},
+	"fc_type":   {"fc_type"},
+	"prompt":    {"prompt_name", "prompt_version"},
+}
+
+type ChatFeedbackService struct {
+	baseComponent   *base.Component
+	chatDao         chatStore
+	chatFeedbackDao *dao.ChatFeedbackDao
+}
+
+func NewChatFeedbackService(baseComponent *base.Component, chatDao *dao.ChatDao, chatFeedbackDao *dao.ChatFeedbackDao) *ChatFeedbackService {
+	return &ChatFeedbackService{
+		baseComponent:   baseComponent,
+		chatDao:         chatDao,
+		chatFeedbackDao: chatFeedbackDao,
+	}
+}
+
+// Rate saves the rating of an answer in a window of the caller
+func (s *ChatFeedbackService) Rate(ctx *reqctx.ReqCtx, req *entity.ChatFeedbackReq) (*entity.ChatFeedbackRes, error) {
+	if req.Rating != model.ChatFeedbackRatingUp && req.Rating != model.ChatFeedbackRatingDown {
+		return nil, errcode.ErrRequestParameter.Wrap("rating must be up or down")
+	}
+	if utf8.RuneCountInString(req.Comment) > chatFeedbackCommentMaxLen {
+		return nil, errcode.ErrRequestParameter.Wrap(fmt.Sprintf("comment is longer than %d characters", chatFeedbackCommentMaxLen))
+	}
+	w, err := s.chatDao.ChatWindowQuery(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if w.Creator.Hex() != ctx.Caller {
+		return nil, errcode.ErrAccountPermission
+	}
+	h, err := s.chatDao.ChatHistoryQuery(ctx, w.ID, req.Index)
+	if err != nil {
+		return nil, err
+	}
+	if h.Msg.Role != model.ChatMsgRoleAssistant && h.Msg.Role != model.ChatMsgRoleSystem {
+		return nil, errcode.ErrRequestParameter.Wrap("only answers can be rated")
+	}
+
+	intention, view, fcType := chatFeedbackFacets(h)
+	if err := s.chatFeedbackDao.Upsert(ctx, &model.ChatFeedback{
+		WindowID:      w.ID,
+		Index:         h.Index,
+		Rating:        req.Rating,
+		Comment:       req.Comment,
+		ProjectId:     h.ProjectId,
+		AgentID:       h.AgentID,
+		Intention:     intention,
+		View:          view,
+		FCType:        fcType,
+		PromptName:    h.PromptName,
+		PromptVersion: h.PromptVersion,
+	}); err != nil {
+		return nil, err
+	}
+	return &entity.ChatFeedbackRes{}, nil
+}
+
+func (s *ChatFeedbackService) AdminAnalytics(ctx *reqctx.ReqCtx, req *entity.ChatFeedbackAnalyticsReq) (*entity.ChatFeedbackAnalyticsRes, error) {
+	groupBy, err := chatFeedbackGroupBy(req.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := chatFeedbackFilter(req.ProjectId, req.AgentID, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.chatFeedbackDao.Stats(ctx, filter, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	res := &entity.ChatFeedbackAnalyticsRes{
+		List: list,
+	}
+	for _, stat := range list {
+		stat.UpRatio = chatFeedbackUpRatio(stat.Up, stat.Total)
+		res.Up += stat.Up
+		res.Down += stat.Down
+		res.Total += stat.Total
+	}
+	return res, nil
+}
+
+// AdminExport returns the conversations with the most thumbs-down, with the ratings next to the messages
+func (s *ChatFeedbackService) AdminExport(ctx *reqctx.ReqCtx, req *entity.ChatFeedbackExportReq) (*entity.ChatFeedbackExportRes, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = chatFeedbackExportLimit
+	}
+	if limit > chatFeedbackExportMaxLimit {
+		return nil, errcode.ErrRequestParameter.Wrap(fmt.Sprintf("limit cannot exceed %d", chatFeedbackExportMaxLimit))
+	}
+
+	filter, err := chatFeedbackFilter(req.ProjectId, req.AgentID, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	windows, err := s.chatFeedbackDao.WorstWindows(ctx, filter, limit)
+	if err != nil {
+		return nil, err
+	}
+	feedbacks, err := s.chatFeedbackDao.ListByWindows(ctx, lo.Map(windows, func(item *model.ChatFeedbackWindowStat, _ int) primitive.ObjectID {
+		return item.WindowID
+	}))
+	if err != nil {
+		return nil, err
+	}
+	feedbackMap := lo.KeyBy(feedbacks, func(item *model.ChatFeedback) string {
+		return fmt.Sprintf("%s/%d", item.WindowID.Hex(), item.Index)
+	})
+
+	res := &entity.ChatFeedbackExportRes{
+		List: []*entity.ChatFeedbackConversation{},
+	}
+	for _, stat := range windows {
+		w, err := s.chatDao.ChatWindowQuery(ctx, stat.WindowID.Hex())
+		if err != nil {
+			if err == errcode.ErrChatWindowNotExist {
+				continue
+			}
+			return nil, err
+		}
+		// the ratings of abandoned branches are only counted in the stats
+		histories, _, err := chatBranchMsgs(ctx, s.chatDao, w, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		conversation := &entity.ChatFeedbackConversation{
+			WindowID:  w.ID,
+			Title:     w.Title,
+			Creator:   w.Creator,
+			ProjectId: w.ProjectId,
+			Up:        stat.Up,
+			Down:      stat.Down,
+		}
+		for _, h := range histories {
+			msg := &entity.ChatFeedbackMsg{
+				ChatMsg: chatHistoryToMsg(h),
+			}
+			if f, ok := feedbackMap[fmt.Sprintf("%s/%d", w.ID.Hex(), h.Index)]; ok {
+				msg.Rating = f.Rating
+				msg.Comment = f.Comment
+			}
+			conversation.Msgs = append(conversation.Msgs, msg)
+		}
+		res.List = append(res.List, conversation)
+	}
+	return res, nil
+}
+
+// chatFeedbackFacets returns the intention, view and function calling type of the rated message
+func chatFeedbackFacets(h *model.ChatHistory) (intention string, view string, fcType string) {
+	if h.Msg.Role != model.ChatMsgRoleAssistant || h.Msg.ContentAssistant == nil {
+		return string(h.Msg.Role), "", ""
+	}
+	view, fcType = h.Msg.ContentAssistant.Facets()
+	return h.Msg.ContentAssistant.Type, view, fcType
+}
+
+func chatFeedbackGroupBy(groupBy string) ([]string, error) {
+	if groupBy == "" {
+		groupBy = "intention"
+	}
+	var fields []string
+	for _, name := range strings.Split(groupBy, ",") {
+		f, ok := chatFeedbackGroupFields[strings.TrimSpace(name)]
+		if !ok {
+			return nil, errcode.ErrRequestParameter.Wrap("group_by must be intention, view, fc_type or prompt")
+		}
+		fields = append(fields, f...)
+	}
+	return lo.Uniq(fields), nil
+}
+
+func chatFeedbackFilter(projectID string, agentID string, from int64, to int64) (bson.M, error) {
+	filter := bson.M{"is_deleted": false}
+	if projectID != "" {
+		filter["project_id"] = projectID
+	}
+	if agentID != "" {
+		objID, err := primitive.ObjectIDFromHex(agentID)
+		if err != nil {
+			return nil, errcode.ErrRequestParameter.Wrap("invalid agent_id")
+		}
+		filter["agent_id"] = objID
+	}
+	t := bson.M{}
+	if from > 0 {
+		t["$gte"] = time.Unix(from, 0)
+	}
+	if to > 0 {
+		t["$lte"] = time.Unix(to, 0)
+	}
+	if len(t) != 0 {
+		filter["update_time"] = t
+	}
+	return filter, nil
+}
+
+func chatFeedbackUpRatio(up int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(up) / float64(total)
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+)
+
+func TestChatFeedbackFacets(t *testing.T) {
+	intention, view, fcType := chatFeedbackFacets(&model.ChatHistory{Msg: model.ChatMsg{
+		Role: model.ChatMsgRoleAssistant,
+		ContentAssistant: &model.ChatContentAssistant{
+			Type: model.ChatContentAssistantTypeSwap,
+			SwapInfo: &model.ChatContentAssistantSwapRes{
+				View: model.ChatContentAssistantSwapViewNative,
+				Swap: model.ChatContentAssistantInfo{FuncCallingRet: model.FuncCallingRet{FCType: model.FCSwap}},
+			},
+		},
+	}})
+	assert.Equal(t, model.ChatContentAssistantTypeSwap, intention)
+	assert.Equal(t, model.ChatContentAssistantSwapViewNative, view)
+	assert.Equal(t, model.FCSwap, fcType)
+
+	intention, view, fcType = chatFeedbackFacets(&model.ChatHistory{Msg: model.ChatMsg{
+		Role: model.ChatMsgRoleAssistant,
+		ContentAssistant: &model.ChatContentAssistant{
+			Type: model.ChatContentAssistantTypeGeneral,
+			GeneralAnswer: &model.ChatContentAssistantGeneralAnswerRes{
+				View: model.ChatContentAssistantProjectInfoViewGeneralAnswer,
+			},
+		},
+	}})
+	assert.Equal(t, model.ChatContentAssistantTypeGeneral, intention)
+	assert.Equal(t, model.ChatContentAssistantProjectInfoViewGeneralAnswer, view)
+	assert.Empty(t, fcType)
+
+	intention, view, fcType = chatFeedbackFacets(&model.ChatHistory{Msg: model.ChatMsg{
+		Role:          model.ChatMsgRoleSystem,
+		ContentSystem: &model.ChatContentSystem{Content: "failed"},
+	}})
+	assert.Equal(t, model.ChatMsgRoleSystem, intention)
+	assert.Empty(t, view)
+	assert.Empty(t, fcType)
+}
+
+func TestChatFeedbackGroupBy(t *testing.T) {
+	fields, err := chatFeedbackGroupBy("")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"intention"}, fields)
+
+	fields, err = chatFeedbackGroupBy("intention, prompt,intention")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"intention", "prompt_name", "prompt_version"}, fields)
+
+	_, err = chatFeedbackGroupBy("user")
+	assert.NotNil(t, err)
+}
+
+func TestChatFeedbackFilter(t *testing.T) {
+	filter, err := chatFeedbackFilter("", "", 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, bson.M{"is_deleted": false}, filter)
+
+	agentID := primitive.NewObjectID()
+	filter, err = chatFeedbackFilter("uni", agentID.Hex(), 100, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "uni", filter["project_id"])
+	assert.Equal(t, agentID, filter["agent_id"])
+	assert.Equal(t, bson.M{"$gte": time.Unix(100, 0)}, filter["update_time"])
+
+	// agents are catalog ids, not project ids
+	_, err = chatFeedbackFilter("", "uni", 0, 0)
+	assert.NotNil(t, err)
+}
+
+func TestChatFeedbackUpRatio(t *testing.T) {
+	assert.Equal(t, 0.0, chatFeedbackUpRatio(0, 0))
+	assert.Equal(t, 0.75, chatFeedbackUpRatio(3, 4))
+}
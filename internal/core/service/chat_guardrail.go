@@ -0,0 +1,78 @@
+package service
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	guardrailRedact    = "redact"
+	guardrailInjection = "injection"
+	guardrailOutput    = "output"
+)
+
+// redactQuestion removes the secrets and personal data of the question. The redacted question is the one
+// stored, so the memory, the title, search and exports never see them either.
+func (s *ChatService) redactQuestion(ctx *reqctx.ReqCtx, question string) string {
+	question, rules := s.guard.Redact(question)
+	if len(rules) != 0 {
+		logGuardrail(ctx, guardrailRedact, rules, nil)
+	}
+	return question
+}
+
+// redactMemory redacts the previous questions sent along, they were stored unredacted before the guardrail was enabled
+func (s *ChatService) redactMemory(ctx *reqctx.ReqCtx, msgs []*extension.ChatgptMsg) {
+	var triggered []string
+	for _, msg := range msgs {
+		if msg.Role != model.ChatMsgRoleUser {
+			continue
+		}
+		var rules []string
+		msg.Content, rules = s.guard.Redact(msg.Content)
+		triggered = append(triggered, rules...)
+	}
+	if len(triggered) != 0 {
+		logGuardrail(ctx, guardrailRedact, triggered, logrus.Fields{"memory": true})
+	}
+}
+
+// checkInjection flags the question matching the prompt injection rules, it is refused when the config says so
+func (s *ChatService) checkInjection(ctx *reqctx.ReqCtx, question string) error {
+	rules := s.guard.DetectInjection(question)
+	if len(rules) == 0 {
+		return nil
+	}
+	logGuardrail(ctx, guardrailInjection, rules, logrus.Fields{"blocked": s.guard.BlockInjection()})
+	if s.guard.BlockInjection() {
+		return errors.New(injectionErrMsg)
+	}
+	return nil
+}
+
+// checkOutput validates the structured answer against the schema it was asked with before it is decoded
+func (s *ChatService) checkOutput(ctx *reqctx.ReqCtx, output string) error {
+	if err := s.guard.ValidateOutput(output, extension.ResponseSchema(ctx.Ctx)); err != nil {
+		logGuardrail(ctx, guardrailOutput, []string{"schema"}, logrus.Fields{"err": err.Error()})
+		return err
+	}
+	return nil
+}
+
+// logGuardrail logs every trigger, the logger of the request context carries the request id
+func logGuardrail(ctx *reqctx.ReqCtx, kind string, rules []string, fields logrus.Fields) {
+	ctx.AddCustomLogField("guardrail_"+kind, rules)
+	entry := ctx.Logger.WithFields(logrus.Fields{
+		"guardrail": kind,
+		"rules":     rules,
+		"caller":    ctx.Caller,
+	})
+	if fields != nil {
+		entry = entry.WithFields(fields)
+	}
+	entry.Warn("Guardrail triggered")
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const defaultChatMemoryTokenBudget = 2000
+
+type chatMemoryTurn struct {
+	index uint64
+	msg   *extension.ChatgptMsg
+}
+
+// chatMemory returns the previous turns of the branch ending at head sent along with the next question,
+// and the turns beyond the message limit or the model token budget which are not covered by w.Summary yet.
+// Those are left out of the question and folded into the summary in the background, see asyncSummarizeChatMemory.
+func (s *ChatService) chatMemory(ctx *reqctx.ReqCtx, w *model.ChatWindow, head int64) ([]*extension.ChatgptMsg, []chatMemoryTurn) {
+	cfg := s.baseComponent.Config.Extension.ChatMemory
+	if cfg.MaxHistoryMsgs <= 0 {
+		return nil, nil
+	}
+
+	histories, err := s.chatDao.ChatHistoryListSince(ctx, w.ID, w.SummaryIndex)
+	if err != nil {
+		ctx.AddCustomLogField("memory_err", err)
+		return chatSummaryMsgs(w.Summary), nil
+	}
+	var turns []chatMemoryTurn
+	for _, h := range chatBranchAncestors(histories, head) {
+		if msg := chatMsgToLLMMsg(h.Msg); msg != nil {
+			turns = append(turns, chatMemoryTurn{index: h.Index, msg: msg})
+		}
+	}
+
+	budget := chatMemoryTokenBudget(cfg, s.baseComponent.Config.Extension.Chatgpt.Model) - extension.EstimateTokens(w.Summary)
+	return foldChatMemory(w.Summary, turns, splitChatMemory(turns, cfg.MaxHistoryMsgs, budget))
+}
+
+// foldChatMemory sends the summary and the turns from keep on, the turns before keep are returned to be summarized
+func foldChatMemory(summary string, turns []chatMemoryTurn, keep int) ([]*extension.ChatgptMsg, []chatMemoryTurn) {
+	msgs := chatSummaryMsgs(summary)
+	for _, t := range turns[keep:] {
+		msgs = append(msgs, t.msg)
+	}
+	return msgs, turns[:keep]
+}
+
+// asyncSummarizeChatMemory folds the turns into the window summary in the background once the answer is stored,
+// so the summary never delays an answer. The summary is dropped when the window summary or branch changed
+// in the meantime, and computed again on a later question.
+func (s *ChatService) asyncSummarizeChatMemory(ctx *reqctx.ReqCtx, w *model.ChatWindow, pjId string, turns []chatMemoryTurn) {
+	if len(turns) == 0 {
+		return
+	}
+	// one summary per window at a time, the next question picks up whatever is left
+	if _, running := s.summarizing.LoadOrStore(w.ID, struct{}{}); running {
+		return
+	}
+	summary, summaryIndex := w.Summary, w.SummaryIndex
+	last := turns[len(turns)-1].index
+
+	bg := s.baseComponent.BackgroundContext()
+	bg.Caller = ctx.Caller
+	bg.CallerRole = ctx.CallerRole
+	bg.Ctx = extension.WithUsageListener(bg.Ctx, func(modelName string, usage extension.LLMUsage) {
+		s.llmUsageService.Record(bg, w.ID, pjId, modelName, usage)
+	})
+	s.baseComponent.SafeGo(func() {
+		defer s.summarizing.Delete(w.ID)
+		err := func() error {
+			newSummary, err := s.summarizeChatMemory(bg, summary, turns)
+			if err != nil {
+				return err
+			}
+			// the summarized turns must still be on the active branch
+			current, err := s.chatDao.ChatWindowQuery(bg, w.ID.Hex())
+			if err != nil {
+				return err
+			}
+			histories, err := s.chatDao.ChatHistoryListSince(bg, w.ID, summaryIndex)
+			if err != nil {
+				return err
+			}
+			onBranch := lo.ContainsBy(chatBranchAncestors(histories, chatBranchLeaf(histories, current.HeadIndex())), func(item *model.ChatHistory) bool {
+				return item.Index == last
+			})
+			if !onBranch {
+				return nil
+			}
+			_, err = s.chatDao.ChatWindowSetSummary(bg, w.ID, summaryIndex, newSummary, last+1)
+			return err
+		}()
+		if err != nil {
+			s.baseComponent.Logger.WithFields(logrus.Fields{
+				"err":       err,
+				"window_id": w.ID.Hex(),
+			}).Warn("Failed to summarize chat memory")
+		}
+	})
+}
+
+func (s *ChatService) summarizeChatMemory(ctx *reqctx.ReqCtx, summary string, turns []chatMemoryTurn) (string, error) {
+	var b strings.Builder
+	if summary != "" {
+		b.WriteString("Previous summary:\n")
+		b.WriteString(summary)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("New conversation turns:\n")
+	for _, t := range turns {
+		b.WriteString(fmt.Sprintf("%s: %s\n", t.msg.Role, t.msg.Content))
+	}
+	return s.chatgptDriver.ChatCompletionsWithContext(ctx.Ctx, []*extension.ChatgptMsg{
+		{Role: "system", Content: config.ChatSummaryPrompt},
+		{Role: "user", Content: b.String()},
+	})
+}
+
+func chatSummaryMsgs(summary string) []*extension.ChatgptMsg {
+	if summary == "" {
+		return nil
+	}
+	return []*extension.ChatgptMsg{{
+		Role:    "system",
+		Content: "Summary of the earlier conversation: " + summary,
+	}}
+}
+
+func chatMemoryTokenBudget(cfg config.ChatMemory, modelName string) int {
+	if budget, ok := cfg.TokenBudgets[modelName]; ok && budget > 0 {
+		return budget
+	}
+	if cfg.DefaultTokenBudget > 0 {
+		return cfg.DefaultTokenBudget
+	}
+	return defaultChatMemoryTokenBudget
+}
+
+// splitChatMemory returns the position of the first turn kept verbatim, newest turns are kept first
+// until maxMsgs or budget is reached
+func splitChatMemory(turns []chatMemoryTurn, maxMsgs int, budget int) int {
+	keep := len(turns)
+	used := 0
+	for i := len(turns) - 1; i >= 0; i-- {
+		if len(turns)-i > maxMsgs {
+			break
+		}
+		used += extension.EstimateTokens(turns[i].msg.Content)
+		if used > budget {
+			break
+		}
+		keep = i
+	}
+	return keep
+}
+
+// chatMsgToLLMMsg renders a stored message as plain text for the model, system (error) messages are skipped
+func chatMsgToLLMMsg(msg model.ChatMsg) *extension.ChatgptMsg {
+	switch msg.Role {
+	case model.ChatMsgRoleUser:
+		if msg.ContentUser == nil {
+			return nil
+		}
+		return &extension.ChatgptMsg{Role: "user", Content: msg.ContentUser.Content}
+	case model.ChatMsgRoleUserBuiltin:
+		if msg.ContentUserBuiltin == nil {
+			return nil
+		}
+		return &extension.ChatgptMsg{Role: "user", Content: "show " + msg.ContentUserBuiltin.Content}
+	case model.ChatMsgRoleAssistant:
+		if msg.ContentAssistant == nil {
+			return nil
+		}
+		c := msg.ContentAssistant
+		var parts []string
+		if c.Tips != "" {
+			parts = append(parts, c.Tips)
+		}
+		if c.GeneralAnswer != nil && c.GeneralAnswer.GeneralAnswer.Content != "" {
+			parts = append(parts, c.GeneralAnswer.GeneralAnswer.Content)
+		}
+		if len(c.ProjectKeys) > 0 {
+			parts = append(parts, fmt.Sprintf("(%s: %s)", c.Type, strings.Join(c.ProjectKeys, ", ")))
+		}
+		if len(parts) == 0 {
+			return nil
+		}
+		return &extension.ChatgptMsg{Role: "assistant", Content: strings.Join(parts, "\n")}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+)
+
+func TestSplitChatMemory(t *testing.T) {
+	turn := func(index uint64, words int) chatMemoryTurn {
+		return chatMemoryTurn{index: index, msg: &extension.ChatgptMsg{Role: "user", Content: strings.Repeat("abcd", words)}}
+	}
+	turns := []chatMemoryTurn{turn(0, 10), turn(1, 10), turn(2, 10), turn(3, 10)}
+
+	tests := []struct {
+		name    string
+		maxMsgs int
+		budget  int
+		want    int
+	}{
+		{name: "everything fits", maxMsgs: 10, budget: 100, want: 0},
+		{name: "message limit", maxMsgs: 2, budget: 100, want: 2},
+		{name: "token budget", maxMsgs: 10, budget: 25, want: 2},
+		{name: "nothing fits", maxMsgs: 10, budget: 5, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitChatMemory(turns, tt.maxMsgs, tt.budget))
+		})
+	}
+}
+
+func TestFoldChatMemory(t *testing.T) {
+	turn := func(index uint64, content string) chatMemoryTurn {
+		return chatMemoryTurn{index: index, msg: &extension.ChatgptMsg{Role: "user", Content: content}}
+	}
+	turns := []chatMemoryTurn{turn(4, "a"), turn(5, "b"), turn(6, "c")}
+
+	msgs, overflow := foldChatMemory("earlier", turns, 2)
+	assert.Equal(t, []*extension.ChatgptMsg{
+		{Role: "system", Content: "Summary of the earlier conversation: earlier"},
+		{Role: "user", Content: "c"},
+	}, msgs)
+	// summarized after the answer, the question is not held up by it
+	assert.Equal(t, turns[:2], overflow)
+
+	msgs, overflow = foldChatMemory("", turns, 0)
+	assert.Len(t, msgs, 3)
+	assert.Empty(t, overflow)
+}
+
+func TestChatMemoryTokenBudget(t *testing.T) {
+	cfg := config.ChatMemory{TokenBudgets: map[string]int{"gpt-4o": 8000}}
+	assert.Equal(t, 8000, chatMemoryTokenBudget(cfg, "gpt-4o"))
+	assert.Equal(t, defaultChatMemoryTokenBudget, chatMemoryTokenBudget(cfg, "llama3"))
+	cfg.DefaultTokenBudget = 1000
+	assert.Equal(t, 1000, chatMemoryTokenBudget(cfg, "llama3"))
+}
+
+func TestChatMsgToLLMMsg(t *testing.T) {
+	assert.Equal(t, &extension.ChatgptMsg{Role: "user", Content: "what is eth"}, chatMsgToLLMMsg(model.ChatMsg{
+		Role:        model.ChatMsgRoleUser,
+		ContentUser: &model.ChatContentUser{Content: "what is eth"},
+	}))
+	assert.Equal(t, &extension.ChatgptMsg{Role: "assistant", Content: "Here is the answer:\nETH is ..."}, chatMsgToLLMMsg(model.ChatMsg{
+		Role: model.ChatMsgRoleAssistant,
+		ContentAssistant: &model.ChatContentAssistant{
+			Tips: "Here is the answer:",
+			GeneralAnswer: &model.ChatContentAssistantGeneralAnswerRes{
+				GeneralAnswer: model.ChatContentAssistantGeneralAnswer{Content: "ETH is ..."},
+			},
+		},
+	}))
+	assert.Equal(t, &extension.ChatgptMsg{Role: "assistant", Content: "Here is some info about ETH:\n(project_info: ETH)"}, chatMsgToLLMMsg(model.ChatMsg{
+		Role: model.ChatMsgRoleAssistant,
+		ContentAssistant: &model.ChatContentAssistant{
+			Tips:        "Here is some info about ETH:",
+			Type:        model.ChatContentAssistantTypeProjectInfo,
+			ProjectKeys: []string{"ETH"},
+		},
+	}))
+	assert.Nil(t, chatMsgToLLMMsg(model.ChatMsg{
+		Role:          model.ChatMsgRoleSystem,
+		ContentSystem: &model.ChatContentSystem{Content: "network error"},
+	}))
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+// chatProjectId returns the project answering the message of the window and whether the agent router may replace it.
+// A window pinned to an agent only accepts its project, a project set on the message is an explicit choice of the user.
+func chatProjectId(w *model.ChatWindow, reqPjId string, defaultPjId string) (string, bool, error) {
+	if w.AgentPinned {
+		if reqPjId != "" && reqPjId != w.ProjectId {
+			return "", false, errcode.ErrPjWinIdErr
+		}
+		return w.ProjectId, false, nil
+	}
+	if reqPjId != "" {
+		return reqPjId, false, nil
+	}
+	if w.ProjectId != "" {
+		return w.ProjectId, true, nil
+	}
+	return defaultPjId, true, nil
+}
+
+// guardQuestion redacts the question and checks it for prompt injection before any model sees it,
+// the agent router then picks the project among the agents from the redacted question
+func (s *ChatService) guardQuestion(ctx *reqctx.ReqCtx, msg string, pjId string, routable bool) (string, string, error) {
+	question := s.redactQuestion(ctx, msg)
+	if err := s.checkInjection(ctx, question); err != nil {
+		return question, pjId, err
+	}
+	if routable && s.baseComponent.Config.Extension.AgentRouter.Enable {
+		pjId = s.routeAgent(ctx, pjId, question)
+	}
+	return question, pjId, nil
+}
+
+// routeAgent asks the model which of the agents visible to the caller fits the question best,
+// fallback is kept when there is nothing to choose from or the answer is not a known agent
+func (s *ChatService) routeAgent(ctx *reqctx.ReqCtx, fallback string, question string) string {
+	agents, err := s.agentService.visibleAgents(ctx)
+	if err != nil {
+		ctx.AddCustomLogField("router_err", err)
+		return fallback
+	}
+	if len(agents) < 2 {
+		return fallback
+	}
+	answer, err := s.chatgptDriver.ChatCompletionsWithContext(ctx.Ctx, agentRouterMsgs(agents, question))
+	if err != nil {
+		ctx.AddCustomLogField("router_err", err)
+		return fallback
+	}
+	agent := parseRoutedAgent(agents, answer)
+	if agent == nil {
+		ctx.AddCustomLogField("router_answer", answer)
+		return fallback
+	}
+	ctx.AddCustomLogField("routed_project_id", agent.ProjectId)
+	return agent.ProjectId
+}
+
+func agentRouterMsgs(agents []*model.Agent, question string) []*extension.ChatgptMsg {
+	var b strings.Builder
+	b.WriteString(config.ChatAgentRouterPrompt)
+	for _, agent := range agents {
+		b.WriteString(fmt.Sprintf("%s: %s - %s\n", agent.ProjectId, agent.Name, agent.Description))
+	}
+	return []*extension.ChatgptMsg{
+		{Role: "system", Content: b.String()},
+		{Role: "user", Content: question},
+	}
+}
+
+// parseRoutedAgent matches the router answer with the project id of an agent, or its name as models tend to
+// answer with it once in a while
+func parseRoutedAgent(agents []*model.Agent, answer string) *model.Agent {
+	answer = strings.Trim(strings.TrimSpace(answer), "\"'`.")
+	agent, ok := lo.Find(agents, func(item *model.Agent) bool {
+		return item.ProjectId == answer
+	})
+	if ok {
+		return agent
+	}
+	agent, ok = lo.Find(agents, func(item *model.Agent) bool {
+		return strings.EqualFold(item.Name, answer)
+	})
+	if ok {
+		return agent
+	}
+	return nil
+}
+
+// PinAgent makes the agent of the project answer all the following messages of the window
+func (s *ChatService) PinAgent(ctx *reqctx.ReqCtx, req *entity.ChatPinAgentReq) (*entity.ChatPinAgentRes, error) {
+	if req.ProjectId == "" {
+		return nil, errcode.ErrUserPinPjNotExist
+	}
+	w, err := s.chatDao.ChatWindowQuery(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if w.Creator.Hex() != ctx.Caller {
+		return nil, errcode.ErrAccountPermission
+	}
+	if _, err := s.agentService.visibleAgent(ctx, req.ProjectId); err != nil {
+		return nil, err
+	}
+	w.ProjectId = req.ProjectId
+	w.AgentPinned = true
+	if err := s.chatDao.ChatWindowUpdate(ctx, w); err != nil {
+		return nil, err
+	}
+	return &entity.ChatPinAgentRes{}, nil
+}
+
+// UnpinAgent routes the following messages of the window again, the pinned agent stays the fallback
+func (s *ChatService) UnpinAgent(ctx *reqctx.ReqCtx, req *entity.ChatUnpinAgentReq) (*entity.ChatUnpinAgentRes, error) {
+	w, err := s.chatDao.ChatWindowQuery(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if w.Creator.Hex() != ctx.Caller {
+		return nil, errcode.ErrAccountPermission
+	}
+	w.AgentPinned = false
+	if err := s.chatDao.ChatWindowUpdate(ctx, w); err != nil {
+		return nil, err
+	}
+	return &entity.ChatUnpinAgentRes{}, nil
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/dao"
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	chatSearchDefaultSize = 20
+	chatSearchMaxSize     = 100
+	// best matches checked against the active branches, the results are paged among them
+	chatSearchMaxHits = 1000
+	// runes of a snippet, and of them the runes kept before the first match
+	chatSearchSnippetRunes   = 160
+	chatSearchSnippetContext = 40
+)
+
+// Search finds the messages of the caller matching the query among the questions and the answers,
+// the messages of abandoned branches are skipped
+func (s *ChatService) Search(ctx *reqctx.ReqCtx, req *entity.ChatSearchReq) (*entity.ChatSearchRes, error) {
+	q := strings.TrimSpace(req.Q)
+	if q == "" {
+		return nil, errcode.ErrRequestParameter.Wrap("q cannot be empty")
+	}
+	creator, err := primitive.ObjectIDFromHex(ctx.Caller)
+	if err != nil {
+		return nil, err
+	}
+	size := req.Size
+	if size == 0 {
+		size = chatSearchDefaultSize
+	}
+	size = min(size, chatSearchMaxSize)
+	page := max(req.Page, 1)
+
+	filter, err := chatSearchFilter(creator, q, req)
+	if err != nil {
+		return nil, err
+	}
+	hits, _, err := s.chatDao.ChatHistorySearch(ctx, 1, chatSearchMaxHits, filter)
+	if err != nil {
+		return nil, err
+	}
+	windowIDs := lo.Uniq(lo.Map(hits, func(item *model.ChatHistorySearchHit, _ int) primitive.ObjectID {
+		return item.WindowID
+	}))
+	titles := map[primitive.ObjectID]string{}
+	if len(windowIDs) != 0 {
+		windows, _, err := s.chatDao.ChatWindowList(ctx, 0, 0, bson.M{"_id": bson.M{"$in": windowIDs}}, nil)
+		if err != nil {
+			return nil, err
+		}
+		histories, err := s.chatDao.ChatHistoryBranches(ctx, windowIDs)
+		if err != nil {
+			return nil, err
+		}
+		hits = chatSearchActiveHits(hits, windows, histories)
+		for _, w := range windows {
+			titles[w.ID] = w.Title
+		}
+	}
+	total := int64(len(hits))
+	hits = lo.Slice(hits, int((page-1)*size), int(page*size))
+
+	terms := chatSearchTerms(q)
+	list := make([]*entity.ChatSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		res := &entity.ChatSearchResult{
+			WindowID:    hit.WindowID,
+			WindowTitle: titles[hit.WindowID],
+			Index:       hit.Index,
+			Role:        hit.Msg.Role,
+			Timestamp:   hit.CreateTime,
+			ProjectId:   hit.ProjectId,
+			Snippet:     chatSearchSnippet(chatSearchTexts(hit.Msg), terms),
+			Score:       hit.Score,
+		}
+		if hit.Msg.ContentAssistant != nil {
+			res.Intention = hit.Msg.ContentAssistant.Type
+		}
+		list = append(list, res)
+	}
+	return &entity.ChatSearchRes{
+		List:  list,
+		Total: total,
+	}, nil
+}
+
+// chatSearchActiveHits keeps the hits on the active branch of their window, in order
+func chatSearchActiveHits(hits []*model.ChatHistorySearchHit, windows []*model.ChatWindow, histories []*model.ChatHistory) []*model.ChatHistorySearchHit {
+	byWindow := lo.GroupBy(histories, func(item *model.ChatHistory) primitive.ObjectID {
+		return item.WindowID
+	})
+	active := map[primitive.ObjectID]map[uint64]bool{}
+	for _, w := range windows {
+		branch := chatBranchAncestors(byWindow[w.ID], chatBranchLeaf(byWindow[w.ID], w.HeadIndex()))
+		active[w.ID] = lo.SliceToMap(branch, func(item *model.ChatHistory) (uint64, bool) {
+			return item.Index, true
+		})
+	}
+	return lo.Filter(hits, func(item *model.ChatHistorySearchHit, _ int) bool {
+		return active[item.WindowID][item.Index]
+	})
+}
+
+func chatSearchFilter(creator primitive.ObjectID, q string, req *entity.ChatSearchReq) (bson.M, error) {
+	filter := bson.M{
+		"creator":    creator,
+		"is_deleted": false,
+	}
+	if chatSearchUnsegmented(q) {
+		terms := chatSearchTerms(q)
+		if len(terms) == 0 {
+			return nil, errcode.ErrRequestParameter.Wrap("q has no word to search")
+		}
+		filter["$and"] = chatSearchRegexTerms(terms)
+	} else {
+		filter["$text"] = bson.M{"$search": q}
+	}
+	if req.AgentID != "" {
+		agentID, err := primitive.ObjectIDFromHex(req.AgentID)
+		if err != nil {
+			return nil, errcode.ErrRequestParameter.Wrap("invalid agent_id")
+		}
+		filter["agent_id"] = agentID
+	}
+	if req.Intention != "" {
+		filter["msg.content_assistant.type"] = req.Intention
+	}
+	t := bson.M{}
+	if req.From > 0 {
+		t["$gte"] = time.Unix(req.From, 0)
+	}
+	if req.To > 0 {
+		t["$lte"] = time.Unix(req.To, 0)
+	}
+	if len(t) != 0 {
+		filter["create_time"] = t
+	}
+	return filter, nil
+}
+
+// chatSearchUnsegmented reports whether q has chinese or japanese text, the text index splits words on spaces
+// and punctuation so it only finds such text as whole sentences
+func chatSearchUnsegmented(q string) bool {
+	return strings.IndexFunc(q, func(r rune) bool {
+		return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+	}) >= 0
+}
+
+// chatSearchRegexTerms matches the messages containing every term in one of the searched texts, the terms
+// are escaped so they match literally. The scan is bounded by the creator index.
+func chatSearchRegexTerms(terms []string) []bson.M {
+	return lo.Map(terms, func(term string, _ int) bson.M {
+		return bson.M{"$or": lo.Map(dao.ChatHistorySearchFields, func(field string, _ int) bson.M {
+			return bson.M{field: bson.M{"$regex": regexp.QuoteMeta(term), "$options": "i"}}
+		})}
+	})
+}
+
+// chatSearchTerms are the lower case words of the query to highlight, negated words are skipped
+func chatSearchTerms(q string) []string {
+	var terms []string
+	for _, field := range strings.Fields(q) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		term := strings.ToLower(strings.Trim(field, "\"'"))
+		if term != "" {
+			terms = append(terms, term)
+		}
+	}
+	terms = lo.Uniq(terms)
+	// the longer term wins when terms overlap
+	sort.SliceStable(terms, func(i, j int) bool {
+		return len([]rune(terms[i])) > len([]rune(terms[j]))
+	})
+	return terms
+}
+
+// chatSearchTexts are the indexed texts of the message
+func chatSearchTexts(msg model.ChatMsg) []string {
+	var texts []string
+	if msg.ContentUser != nil {
+		texts = append(texts, msg.ContentUser.Content)
+	}
+	if c := msg.ContentAssistant; c != nil {
+		texts = append(texts, c.Tips)
+		if c.GeneralAnswer != nil {
+			texts = append(texts, c.GeneralAnswer.GeneralAnswer.Content)
+		}
+	}
+	return lo.Compact(texts)
+}
+
+// chatSearchSnippet cuts the first text with a match around its first match and highlights the terms
+func chatSearchSnippet(texts []string, terms []string) string {
+	if len(texts) == 0 {
+		return ""
+	}
+	text, pos := []rune(texts[0]), -1
+	for _, t := range texts {
+		runes := []rune(t)
+		if p, _ := chatSearchMatch(runes, terms, 0); p >= 0 {
+			text, pos = runes, p
+			break
+		}
+	}
+
+	start := 0
+	if pos > chatSearchSnippetContext {
+		start = pos - chatSearchSnippetContext
+	}
+	end := min(len(text), start+chatSearchSnippetRunes)
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	for i := start; i < end; {
+		p, n := chatSearchMatch(text[:end], terms, i)
+		if p < 0 {
+			b.WriteString(html.EscapeString(string(text[i:end])))
+			break
+		}
+		b.WriteString(html.EscapeString(string(text[i:p])))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(string(text[p : p+n])))
+		b.WriteString("</em>")
+		i = p + n
+	}
+	if end < len(text) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// chatSearchMatch returns the position and length (in runes) of the first term found from the position on,
+// -1 when there is none
+func chatSearchMatch(text []rune, terms []string, from int) (int, int) {
+	for i := from; i < len(text); i++ {
+		for _, term := range terms {
+			if n := len([]rune(term)); i+n <= len(text) && runesEqualFold(text[i:i+n], term) {
+				return i, n
+			}
+		}
+	}
+	return -1, 0
+}
+
+func runesEqualFold(runes []rune, lower string) bool {
+	i := 0
+	for _, r := range lower {
+		if unicode.ToLower(runes[i]) != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+)
+
+func TestChatSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"uniswap", "eth"}, chatSearchTerms(`ETH "Uniswap" -btc eth`))
+	assert.Empty(t, chatSearchTerms("  -btc "))
+}
+
+func TestChatSearchSnippet(t *testing.T) {
+	terms := chatSearchTerms("eth swap")
+	assert.Equal(t, "How to <em>swap</em> <em>ETH</em> to &lt;USDC&gt;?", chatSearchSnippet([]string{"How to swap ETH to <USDC>?"}, terms))
+	// the first text with a match is used
+	assert.Equal(t, "<em>Swap</em> route found", chatSearchSnippet([]string{"Here you go", "Swap route found"}, terms))
+	assert.Equal(t, "no match", chatSearchSnippet([]string{"no match"}, terms))
+	assert.Empty(t, chatSearchSnippet(nil, terms))
+
+	long := strings.Repeat("a", 100) + " ETH " + strings.Repeat("b", 300)
+	snippet := chatSearchSnippet([]string{long}, terms)
+	assert.True(t, strings.HasPrefix(snippet, "..."+strings.Repeat("a", chatSearchSnippetContext-1)+" <em>ETH</em>"))
+	assert.True(t, strings.HasSuffix(snippet, "b..."))
+}
+
+func TestChatSearchTexts(t *testing.T) {
+	assert.Equal(t, []string{"what is ETH"}, chatSearchTexts(model.ChatMsg{
+		ContentUser: &model.ChatContentUser{Content: "what is ETH"},
+	}))
+	assert.Equal(t, []string{"ETH is a chain"}, chatSearchTexts(model.ChatMsg{
+		ContentAssistant: &model.ChatContentAssistant{GeneralAnswer: &model.ChatContentAssistantGeneralAnswerRes{
+			GeneralAnswer: model.ChatContentAssistantGeneralAnswer{Content: "ETH is a chain"},
+		}},
+	}))
+}
+
+func TestChatSearchFilter(t *testing.T) {
+	creator := primitive.NewObjectID()
+	agentID := primitive.NewObjectID()
+	filter, err := chatSearchFilter(creator, "eth", &entity.ChatSearchReq{AgentID: agentID.Hex(), Intention: "swap", From: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$search": "eth"}, filter["$text"])
+	assert.Equal(t, creator, filter["creator"])
+	assert.Equal(t, false, filter["is_deleted"])
+	assert.Equal(t, agentID, filter["agent_id"])
+	assert.Equal(t, "swap", filter["msg.content_assistant.type"])
+	assert.Contains(t, filter["create_time"], "$gte")
+	assert.NotContains(t, filter["create_time"], "$lte")
+
+	filter, err = chatSearchFilter(creator, "eth", &entity.ChatSearchReq{})
+	assert.NoError(t, err)
+	assert.NotContains(t, filter, "create_time")
+	assert.NotContains(t, filter, "agent_id")
+
+	_, err = chatSearchFilter(creator, "eth", &entity.ChatSearchReq{AgentID: "uni"})
+	assert.Error(t, err)
+
+	// chinese is not split into words by the text index, it is matched literally in the same fields
+	filter, err = chatSearchFilter(creator, "以太坊 gas(费)", &entity.ChatSearchReq{})
+	assert.NoError(t, err)
+	assert.NotContains(t, filter, "$text")
+	assert.Equal(t, []bson.M{
+		{"$or": []bson.M{
+			{"msg.content_user.content": bson.M{"$regex": `gas\(费\)`, "$options": "i"}},
+			{"msg.content_assistant.tips": bson.M{"$regex": `gas\(费\)`, "$options": "i"}},
+			{"msg.content_assistant.general_answer.general_answer.content": bson.M{"$regex": `gas\(费\)`, "$options": "i"}},
+		}},
+		{"$or": []bson.M{
+			{"msg.content_user.content": bson.M{"$regex": "以太坊", "$options": "i"}},
+			{"msg.content_assistant.tips": bson.M{"$regex": "以太坊", "$options": "i"}},
+			{"msg.content_assistant.general_answer.general_answer.content": bson.M{"$regex": "以太坊", "$options": "i"}},
+		}},
+	}, filter["$and"])
+	assert.Equal(t, creator, filter["creator"])
+	assert.True(t, chatSearchUnsegmented("イーサリアム"))
+	assert.False(t, chatSearchUnsegmented("ethereum 이더리움"))
+
+	_, err = chatSearchFilter(creator, "-以太坊", &entity.ChatSearchReq{})
+	assert.Error(t, err)
+}
+
+func TestChatSearchActiveHits(t *testing.T) {
+	parent := func(i int64) *int64 { return &i }
+	head := int64(3)
+	w1 := &model.ChatWindow{BaseModel: model.BaseModel{ID: primitive.NewObjectID()}, MsgNum: 4, Head: &head}
+	w2 := &model.ChatWindow{BaseModel: model.BaseModel{ID: primitive.NewObjectID()}, MsgNum: 2}
+	// w1: 0 <- 1 was regenerated as 0 <- 2 <- 3, so 1 is abandoned
+	histories := []*model.ChatHistory{
+		{WindowID: w1.ID, Index: 0},
+		{WindowID: w1.ID, Index: 1},
+		{WindowID: w1.ID, Index: 2, ParentIndex: parent(0)},
+		{WindowID: w1.ID, Index: 3},
+		{WindowID: w2.ID, Index: 0},
+		{WindowID: w2.ID, Index: 1},
+	}
+	hit := func(w *model.ChatWindow, index uint64) *model.ChatHistorySearchHit {
+		return &model.ChatHistorySearchHit{ChatHistory: model.ChatHistory{WindowID: w.ID, Index: index}}
+	}
+	hits := []*model.ChatHistorySearchHit{hit(w1, 1), hit(w2, 1), hit(w1, 3), hit(w1, 0)}
+	res := chatSearchActiveHits(hits, []*model.ChatWindow{w1, w2}, histories)
+	assert.Equal(t, []*model.ChatHistorySearchHit{hits[1], hits[2], hits[3]}, res)
+}
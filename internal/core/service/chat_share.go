@@ -0,0 +1,161 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/dao"
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	chatShareIDBytes = 16
+	// keeps a snapshot far below the mongodb document size limit
+	chatShareMaxMsgs = 500
+)
+
+// chatShareStore keeps the shared snapshots, implemented by dao.ChatShareDao
+type chatShareStore interface {
+	Add(ctx *reqctx.ReqCtx, e *model.ChatShare) error
+	QueryByShareID(ctx *reqctx.ReqCtx, shareID string) (*model.ChatShare, error)
+	ListByWindow(ctx *reqctx.ReqCtx, windowID primitive.ObjectID) ([]*model.ChatShare, error)
+	Revoke(ctx *reqctx.ReqCtx, id string) error
+	RevokeByWindow(ctx *reqctx.ReqCtx, windowID primitive.ObjectID) error
+}
+
+type ChatShareService struct {
+	baseComponent *base.Component
+	chatDao       chatStore
+	chatShareDao  chatShareStore
+}
+
+func NewChatShareService(baseComponent *base.Component, chatDao *dao.ChatDao, chatShareDao *dao.ChatShareDao) *ChatShareService {
+	return &ChatShareService{
+		baseComponent: baseComponent,
+		chatDao:       chatDao,
+		chatShareDao:  chatShareDao,
+	}
+}
+
+// Share freezes the current messages of the active branch of the window, the messages sent afterwards are not shared
+func (s *ChatShareService) Share(ctx *reqctx.ReqCtx, req *entity.ChatShareReq) (*entity.ChatShareRes, error) {
+	w, err := s.chatDao.ChatWindowQuery(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if w.Creator.Hex() != ctx.Caller {
+		return nil, errcode.ErrAccountPermission
+	}
+	histories, err := s.chatDao.ChatHistoryListSince(ctx, w.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	// only the active branch is shared
+	msgs := chatBranchAncestors(histories, w.HeadIndex())
+	if len(msgs) == 0 {
+		return nil, errcode.ErrRequestParameter.Wrap("chat window has no messages to share")
+	}
+	if len(msgs) > chatShareMaxMsgs {
+		return nil, errcode.ErrRequestParameter.Wrap(fmt.Sprintf("chat window has more than %d messages to share", chatShareMaxMsgs))
+	}
+
+	shareID, err := newChatShareID()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.chatShareDao.Add(ctx, &model.ChatShare{
+		ShareID:   shareID,
+		WindowID:  w.ID,
+		Title:     w.Title,
+		ProjectId: w.ProjectId,
+		Msgs:      msgs,
+	}); err != nil {
+		return nil, err
+	}
+	return &entity.ChatShareRes{
+		ShareID: shareID,
+	}, nil
+}
+
+func (s *ChatShareService) Revoke(ctx *reqctx.ReqCtx, req *entity.ChatShareRevokeReq) (*entity.ChatShareRevokeRes, error) {
+	share, err := s.chatShareDao.QueryByShareID(ctx, req.ShareID)
+	if err != nil {
+		return nil, err
+	}
+	if share.Creator.Hex() != ctx.Caller {
+		return nil, errcode.ErrAccountPermission
+	}
+	if err := s.chatShareDao.Revoke(ctx, share.ID.Hex()); err != nil {
+		return nil, err
+	}
+	return &entity.ChatShareRevokeRes{}, nil
+}
+
+// List returns the shares of the window that have not been revoked
+func (s *ChatShareService) List(ctx *reqctx.ReqCtx, req *entity.ChatShareListReq) (*entity.ChatShareListRes, error) {
+	w, err := s.chatDao.ChatWindowQuery(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if w.Creator.Hex() != ctx.Caller {
+		return nil, errcode.ErrAccountPermission
+	}
+	shares, err := s.chatShareDao.ListByWindow(ctx, w.ID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*entity.ChatShareInfo, 0, len(shares))
+	for _, share := range shares {
+		list = append(list, &entity.ChatShareInfo{
+			ShareID:    share.ShareID,
+			Title:      share.Title,
+			CreateTime: share.CreateTime,
+			MsgNum:     uint64(len(share.Msgs)),
+		})
+	}
+	return &entity.ChatShareListRes{
+		List: list,
+	}, nil
+}
+
+// View returns the shared messages to anyone knowing the share id, newest first like History
+func (s *ChatShareService) View(ctx *reqctx.ReqCtx, req *entity.ChatShareViewReq) (*entity.ChatShareViewRes, error) {
+	share, err := s.chatShareDao.QueryByShareID(ctx, req.ShareID)
+	if err != nil {
+		return nil, err
+	}
+	head := int64(-1)
+	if len(share.Msgs) != 0 {
+		head = int64(share.Msgs[len(share.Msgs)-1].Index)
+	}
+	msgs := make([]*entity.ChatMsg, 0, len(share.Msgs))
+	for i := len(share.Msgs) - 1; i >= 0; i-- {
+		msgs = append(msgs, chatHistoryToMsg(share.Msgs[i]))
+	}
+	return &entity.ChatShareViewRes{
+		ChatHistoryRes: entity.ChatHistoryRes{
+			Title:      share.Title,
+			CreateTime: share.CreateTime,
+			ProjectId:  share.ProjectId,
+			MsgNum:     uint64(len(msgs)),
+			Msgs:       msgs,
+			Head:       head,
+		},
+	}, nil
+}
+
+// newChatShareID returns 128 random bits, url safe
+func newChatShareID() (string, error) {
+	b := make([]byte, chatShareIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func TestNewChatShareID(t *testing.T) {
+	id, err := newChatShareID()
+	assert.Nil(t, err)
+	b, err := base64.RawURLEncoding.DecodeString(id)
+	assert.Nil(t, err)
+	assert.Len(t, b, chatShareIDBytes)
+
+	other, err := newChatShareID()
+	assert.Nil(t, err)
+	assert.NotEqual(t, id, other)
+}
+
+// memChatStore keeps the windows and messages of the share tests, only the calls used there are implemented
+type memChatStore struct {
+	chatStore
+	windows   []*model.ChatWindow
+	histories []*model.ChatHistory
+}
+
+func (m *memChatStore) ChatWindowQuery(ctx *reqctx.ReqCtx, id string) (*model.ChatWindow, error) {
+	w, ok := lo.Find(m.windows, func(item *model.ChatWindow) bool {
+		return item.ID.Hex() == id && !item.IsDeleted
+	})
+	if !ok {
+		return nil, errcode.ErrChatWindowNotExist
+	}
+	return w, nil
+}
+
+func (m *memChatStore) ChatWindowDelete(ctx *reqctx.ReqCtx, id string) error {
+	for _, w := range m.windows {
+		if w.ID.Hex() == id {
+			w.IsDeleted = true
+		}
+	}
+	for _, h := range m.histories {
+		if h.WindowID.Hex() == id {
+			h.IsDeleted = true
+		}
+	}
+	return nil
+}
+
+func (m *memChatStore) ChatHistoryListSince(ctx *reqctx.ReqCtx, windowID primitive.ObjectID, fromIndex uint64) ([]*model.ChatHistory, error) {
+	return lo.Filter(m.histories, func(item *model.ChatHistory, _ int) bool {
+		return item.WindowID == windowID && item.Index >= fromIndex && !item.IsDeleted
+	}), nil
+}
+
+// memChatShareStore keeps the shares of the share tests
+type memChatShareStore struct {
+	shares []*model.ChatShare
+}
+
+func (m *memChatShareStore) Add(ctx *reqctx.ReqCtx, e *model.ChatShare) error {
+	var err error
+	e.BaseModel, err = model.NewBaseModel(ctx.Caller)
+	if err != nil {
+		return err
+	}
+	e.ID = primitive.NewObjectID()
+	m.shares = append(m.shares, e)
+	return nil
+}
+
+func (m *memChatShareStore) QueryByShareID(ctx *reqctx.ReqCtx, shareID string) (*model.ChatShare, error) {
+	share, ok := lo.Find(m.shares, func(item *model.ChatShare) bool {
+		return item.ShareID == shareID && !item.IsDeleted
+	})
+	if !ok {
+		return nil, errcode.ErrChatShareNotExist
+	}
+	return share, nil
+}
+
+func (m *memChatShareStore) ListByWindow(ctx *reqctx.ReqCtx, windowID primitive.ObjectID) ([]*model.ChatShare, error) {
+	return lo.Filter(m.shares, func(item *model.ChatShare, _ int) bool {
+		return item.WindowID == windowID && !item.IsDeleted
+	}), nil
+}
+
+func (m *memChatShareStore) Revoke(ctx *reqctx.ReqCtx, id string) error {
+	for _, share := range m.shares {
+		if share.ID.Hex() == id {
+			share.IsDeleted = true
+		}
+	}
+	return nil
+}
+
+func (m *memChatShareStore) RevokeByWindow(ctx *reqctx.ReqCtx, windowID primitive.ObjectID) error {
+	for _, share := range m.shares {
+		if share.WindowID == windowID {
+			share.IsDeleted = true
+		}
+	}
+	return nil
+}
+
+// newMockChatShareServices returns the share and chat services sharing the stores, the window of owner
+// holds chatBranchTree with the edited question 3 and its answer 4 as the active branch
+func newMockChatShareServices(t *testing.T, owner primitive.ObjectID) (*ChatShareService, *ChatService, *memChatShareStore, *model.ChatWindow) {
+	w := &model.ChatWindow{Title: "defi", MsgNum: 5}
+	w.ID = primitive.NewObjectID()
+	w.Creator = owner
+	histories := chatBranchTree()
+	for _, h := range histories {
+		h.WindowID = w.ID
+		h.Msg = model.ChatMsg{Role: model.ChatMsgRoleUser, ContentUser: &model.ChatContentUser{Content: "question"}}
+	}
+	chats := &memChatStore{windows: []*model.ChatWindow{w}, histories: histories}
+	shares := &memChatShareStore{}
+	baseComponent := base.NewMockBaseComponent(t)
+	return &ChatShareService{baseComponent: baseComponent, chatDao: chats, chatShareDao: shares},
+		&ChatService{baseComponent: baseComponent, chatDao: chats, chatShareDao: shares},
+		shares, w
+}
+
+func TestChatShareService_Share(t *testing.T) {
+	owner := primitive.NewObjectID()
+	s, _, _, w := newMockChatShareServices(t, owner)
+	ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, owner.Hex())
+
+	// only the owner shares the window
+	other := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, primitive.NewObjectID().Hex())
+	_, err := s.Share(other, &entity.ChatShareReq{ID: w.ID.Hex()})
+	assert.Equal(t, errcode.ErrAccountPermission, err)
+
+	res, err := s.Share(ctx, &entity.ChatShareReq{ID: w.ID.Hex()})
+	assert.Nil(t, err)
+	// anyone knowing the share id sees the active branch, newest first
+	view, err := s.View(reqctx.NewReqCtx(context.Background(), logrus.New(), 1, ""), &entity.ChatShareViewReq{ShareID: res.ShareID})
+	assert.Nil(t, err)
+	assert.Equal(t, "defi", view.Title)
+	assert.Equal(t, int64(4), view.Head)
+	assert.Equal(t, []uint64{4, 3}, lo.Map(view.Msgs, func(item *entity.ChatMsg, _ int) uint64 { return item.Index }))
+
+	// the messages sent afterwards are not shared
+	chats := s.chatDao.(*memChatStore)
+	chats.histories = append(chats.histories, &model.ChatHistory{WindowID: w.ID, Index: 5, ParentIndex: lo.ToPtr(int64(4))})
+	w.MsgNum = 6
+	view, err = s.View(ctx, &entity.ChatShareViewReq{ShareID: res.ShareID})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), view.MsgNum)
+
+	list, err := s.List(ctx, &entity.ChatShareListReq{ID: w.ID.Hex()})
+	assert.Nil(t, err)
+	assert.Equal(t, []*entity.ChatShareInfo{{ShareID: res.ShareID, Title: "defi", CreateTime: list.List[0].CreateTime, MsgNum: 2}}, list.List)
+	_, err = s.List(other, &entity.ChatShareListReq{ID: w.ID.Hex()})
+	assert.Equal(t, errcode.ErrAccountPermission, err)
+}
+
+func TestChatShareService_Revoke(t *testing.T) {
+	owner := primitive.NewObjectID()
+	s, _, _, w := newMockChatShareServices(t, owner)
+	ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, owner.Hex())
+	res, err := s.Share(ctx, &entity.ChatShareReq{ID: w.ID.Hex()})
+	assert.Nil(t, err)
+
+	other := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, primitive.NewObjectID().Hex())
+	_, err = s.Revoke(other, &entity.ChatShareRevokeReq{ShareID: res.ShareID})
+	assert.Equal(t, errcode.ErrAccountPermission, err)
+	_, err = s.View(ctx, &entity.ChatShareViewReq{ShareID: res.ShareID})
+	assert.Nil(t, err)
+
+	_, err = s.Revoke(ctx, &entity.ChatShareRevokeReq{ShareID: res.ShareID})
+	assert.Nil(t, err)
+	_, err = s.View(ctx, &entity.ChatShareViewReq{ShareID: res.ShareID})
+	assert.Equal(t, errcode.ErrChatShareNotExist, err)
+	_, err = s.Revoke(ctx, &entity.ChatShareRevokeReq{ShareID: res.ShareID})
+	assert.Equal(t, errcode.ErrChatShareNotExist, err)
+}
+
+func TestChatService_DeleteRevokesShares(t *testing.T) {
+	owner := primitive.NewObjectID()
+	s, chatService, shares, w := newMockChatShareServices(t, owner)
+	ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, owner.Hex())
+	res, err := s.Share(ctx, &entity.ChatShareReq{ID: w.ID.Hex()})
+	assert.Nil(t, err)
+	// the shares of other windows are kept
+	kept := &model.ChatShare{ShareID: "kept", WindowID: primitive.NewObjectID()}
+	assert.Nil(t, shares.Add(ctx, kept))
+
+	_, err = chatService.Delete(ctx, &entity.ChatDeleteReq{ID: w.ID.Hex()})
+	assert.Nil(t, err)
+	_, err = s.View(ctx, &entity.ChatShareViewReq{ShareID: res.ShareID})
+	assert.Equal(t, errcode.ErrChatShareNotExist, err)
+	_, err = s.View(ctx, &entity.ChatShareViewReq{ShareID: "kept"})
+	assert.Nil(t, err)
+}
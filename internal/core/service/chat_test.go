@@ -0,0 +1,475 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/component/datapuller"
+	dpmodel "github.com/wyt-labs/wyt-core/internal/core/component/datapuller/model"
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/azure/azuretest"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func newMockChatService(t *testing.T, rules ...azuretest.Rule) (*ChatService, *azuretest.Server) {
+	return newMockChatServiceWithConfig(t, nil, rules...)
+}
+
+// newMockChatServiceWithConfig lets setup change the config before the driver is created, the pump data
+// tools are served by mockPumpSource
+func newMockChatServiceWithConfig(t *testing.T, setup func(cfg *config.Config), rules ...azuretest.Rule) (*ChatService, *azuretest.Server) {
+	server := azuretest.NewServer(rules...)
+	t.Cleanup(server.Close)
+	baseComponent := base.NewMockBaseComponent(t)
+	if setup != nil {
+		setup(baseComponent.Config)
+	}
+	driver, err := extension.NewChatgptDriver(&extension.ChatgptConfig{
+		Endpoint:       server.Endpoint(),
+		EndpointFull:   server.EndpointFull("gpt-4o"),
+		APIKey:         azuretest.APIKey,
+		Model:          "gpt-4o",
+		EmbeddingModel: "text-embedding-3-small",
+	}, baseComponent, datapuller.NewPumpDataService(baseComponent, mockPumpSource{}))
+	assert.Nil(t, err)
+	return &ChatService{
+		baseComponent: baseComponent,
+		chatgptDriver: driver,
+	}, server
+}
+
+// mockPumpSource answers the pump data queries with one or two rows laid out as the sql queries,
+// trader "unknown" has no trades
+type mockPumpSource struct{}
+
+func mockPumpResults(cols []string, rows ...[]any) *dpmodel.DatasetQueryResults {
+	ret := &dpmodel.DatasetQueryResults{}
+	for _, col := range cols {
+		ret.Data.Cols = append(ret.Data.Cols, dpmodel.DatasetQueryResultsCol{Name: col})
+	}
+	ret.Data.Rows = rows
+	return ret
+}
+
+func (mockPumpSource) DailyLaunchedTokenInfo(duration int, timezone string) (*dpmodel.DatasetQueryResults, error) {
+	return mockPumpResults([]string{"day", "total_count", "p2r_count", "p2r_ratio"},
+		[]any{"2024-09-19T00:00:00Z", 10.0, 1.0, 0.1},
+		[]any{"2024-09-20T00:00:00Z", 20.0, 5.0, 0.25}), nil
+}
+
+func (mockPumpSource) LaunchedTokenTimeDistribution(duration int, timezone string) (*dpmodel.DatasetQueryResults, error) {
+	return mockPumpResults([]string{"time_range", "launched_count"}, []any{"[00:00~00:30)", 7.0}), nil
+}
+
+func (mockPumpSource) DailyTradeCounts(duration int, timezone string) (*dpmodel.DatasetQueryResults, error) {
+	return mockPumpResults([]string{"day", "trade_count"}, []any{"2024-09-20T00:00:00Z", 1234.0}), nil
+}
+
+func (mockPumpSource) TraderOverview(trader string) (*dpmodel.DatasetQueryResults, error) {
+	return mockPumpSource{}.TraderOverviewV2(trader, "", 0)
+}
+
+func (mockPumpSource) TraderOverviewV2(trader string, tz string, days int) (*dpmodel.DatasetQueryResults, error) {
+	cols := []string{"total_net_profit", "net_profit_win_ratio", "traded_token_count", "avg_sol_cost_per_token",
+		"avg_fee_per_token", "avg_tip_per_token", "created_token_count"}
+	if trader == "unknown" {
+		return mockPumpResults(cols), nil
+	}
+	return mockPumpResults(cols, []any{2.0, 0.5, 4.0, 0.5, 0.01, 0.01, 1.0}), nil
+}
+
+func (mockPumpSource) TraderTxTimeDistribution(trader string, duration int, timezone string) (*dpmodel.DatasetQueryResults, error) {
+	return mockPumpResults([]string{"time_range", "tx_count"}, []any{"[00:00~00:30)", 3.0}), nil
+}
+
+func (mockPumpSource) TraderProfitTokenDistribution(trader string, duration int, timezone string) (*dpmodel.DatasetQueryResults, error) {
+	if trader == "unknown" {
+		return mockPumpResults([]string{"profit_margin_bucket", "token_count"}), nil
+	}
+	return mockPumpResults([]string{"profit_margin_bucket", "token_count"}, []any{"0% ~ 50%", 4.0}), nil
+}
+
+func (mockPumpSource) TraderProfitDistribution(trader string, duration int, timezone string) (*dpmodel.DatasetQueryResults, error) {
+	return mockPumpResults([]string{"day", "net_profit", "gross_profit"}, []any{"2024-09-20T00:00:00Z", 2.0, 2.1}), nil
+}
+
+func (mockPumpSource) TopTrader(duration int, winRatio float32) (*dpmodel.DatasetQueryResults, error) {
+	return mockPumpResults([]string{"trader", "total_net_profit", "net_profit_win_ratio", "gross_profit_win_ratio", "total_tx_count"},
+		[]any{"T1", 12.5, 0.75, 0.8, 40.0}), nil
+}
+
+// mockProjectFetcher knows BTC and ETH
+func mockProjectFetcher(ctx *reqctx.ReqCtx, projectKeys []string, view string) ([]model.ChatContentAssistantProjectInfo, string, error) {
+	var res []model.ChatContentAssistantProjectInfo
+	for _, key := range projectKeys {
+		if key != "BTC" && key != "ETH" {
+			return nil, "", errors.Errorf("project %s not found", key)
+		}
+		res = append(res, model.ChatContentAssistantProjectInfo{
+			ID:       primitive.NewObjectID(),
+			Overview: &model.ChatContentAssistantProjectOverview{Name: key},
+		})
+	}
+	if view == "" {
+		view = "overview"
+	}
+	return res, view, nil
+}
+
+func TestChatService_Answer(t *testing.T) {
+	analytical := func(intention string, keys []string, view string, content string) map[string]any {
+		return map[string]any{"intention": intention, "intent_keys": keys, "view": view, "content": content}
+	}
+	s, _ := newMockChatService(t,
+		azuretest.Rule{Pattern: regexp.MustCompile("^what is defi$"), JSON: analytical("general", []string{}, "", "DeFi is decentralized finance")},
+		azuretest.Rule{Pattern: regexp.MustCompile("^tokenomics of btc$"), JSON: analytical("search", []string{"BTC"}, "tokenomics", "")},
+		azuretest.Rule{Pattern: regexp.MustCompile("^about btc$"), JSON: analytical("search", []string{"BTC"}, "overview", "")},
+		azuretest.Rule{Pattern: regexp.MustCompile("^about unknown$"), JSON: analytical("search", []string{"UNKNOWN"}, "overview", "no data about it")},
+		azuretest.Rule{Pattern: regexp.MustCompile("^about it$"), JSON: analytical("search", []string{}, "overview", "")},
+		azuretest.Rule{Pattern: regexp.MustCompile("^btc vs eth$"), JSON: analytical("compare", []string{"BTC", "ETH"}, "overview", "")},
+		azuretest.Rule{Pattern: regexp.MustCompile("^compare btc$"), JSON: analytical("compare", []string{"BTC"}, "overview", "")},
+		azuretest.Rule{Pattern: regexp.MustCompile("^swap"), ToolCall: &azuretest.ToolCall{Name: "swap", Arguments: `{"swap_in_token":"ETH","swap_out_token":"USDT"}`}},
+		azuretest.Rule{Pattern: regexp.MustCompile("^new tokens$"), ToolCall: &azuretest.ToolCall{Name: "daily_new_tokens", Arguments: `{"duration":2,"timezone":"UTC"}`}},
+		azuretest.Rule{Pattern: regexp.MustCompile("^launch times$"), ToolCall: &azuretest.ToolCall{Name: "token_launched_time_distribution", Arguments: `{"duration":1}`}},
+		azuretest.Rule{Pattern: regexp.MustCompile("^daily swap counts$"), ToolCall: &azuretest.ToolCall{Name: "daily_token_swap_counts", Arguments: `{"duration":1}`}},
+		azuretest.Rule{Pattern: regexp.MustCompile("^top traders$"), ToolCall: &azuretest.ToolCall{Name: "top_traders", Arguments: `{"duration":7}`}},
+		azuretest.Rule{Pattern: regexp.MustCompile("^trader T1$"), ToolCall: &azuretest.ToolCall{Name: "trader_overview", Arguments: `{"address":"T1"}`}},
+		azuretest.Rule{Pattern: regexp.MustCompile("^trader unknown$"), ToolCall: &azuretest.ToolCall{Name: "trader_overview", Arguments: `{"address":"unknown"}`}},
+		azuretest.Rule{Pattern: regexp.MustCompile("^garbage$"), Content: "not json"},
+		azuretest.Rule{Pattern: regexp.MustCompile("^broken$"), Status: 400},
+	)
+
+	tests := []struct {
+		name     string
+		question string
+		check    func(t *testing.T, content *model.ChatContentAssistant)
+		err      string
+	}{
+		{
+			name:     "general answer",
+			question: "what is defi",
+			check: func(t *testing.T, content *model.ChatContentAssistant) {
+				assert.Equal(t, model.ChatContentAssistantTypeGeneral, content.Type)
+				assert.Equal(t, "Here is the answer:", content.Tips)
+				assert.Equal(t, "DeFi is decentralized finance", content.GeneralAnswer.GeneralAnswer.Content)
+			},
+		},
+		{
+			name:     "search with view",
+			question: "tokenomics of btc",
+			check: func(t *testing.T, content *model.ChatContentAssistant) {
+				assert.Equal(t, model.ChatContentAssistantTypeProjectInfo, content.Type)
+				assert.Equal(t, "Here is tokenomics about BTC:", content.Tips)
+				assert.Equal(t, "tokenomics", content.ProjectInfo.View)
+				assert.Equal(t, "BTC", content.ProjectInfo.Project.Overview.Name)
+			},
+		},
+		{
+			name:     "search overview",
+			question: "about btc",
+			check: func(t *testing.T, content *model.ChatContentAssistant) {
+				assert.Equal(t, "Here is some info about BTC:", content.Tips)
+				assert.Equal(t, []string{"BTC"}, content.ProjectKeys)
+			},
+		},
+		{
+			name:     "search unknown project falls back to the general answer",
+			question: "about unknown",
+			check: func(t *testing.T, content *model.ChatContentAssistant) {
+				assert.Equal(t, model.ChatContentAssistantTypeGeneral, content.Type)
+				assert.Equal(t, "no data about it", content.GeneralAnswer.GeneralAnswer.Content)
+			},
+		},
+		{
+			name:     "search without project",
+			question: "about it",
+			err:      "the intention is not clear",
+		},
+		{
+			name:     "compare",
+			question: "btc vs eth",
+			check: func(t *testing.T, content *model.ChatContentAssistant) {
+				assert.Equal(t, model.ChatContentAssistantTypeProjectCompare, content.Type)
+				assert.Equal(t, "Here is a brief comparison about BTC and ETH:", content.Tips)
+				assert.Equal(t, 2, len(content.ProjectCompare.Projects))
+			},
+		},
+		{
+			name:     "compare a single project",
+			question: "compare btc",
+			err:      "Sorry, I didn't understand what you meant",
+		},
+		{
+			name:     "swap tool call",
+			question: "swap 1 ETH to USDT",
+			check: func(t *testing.T, content *model.ChatContentAssistant) {
+				assert.Equal(t, model.ChatAIAnalyticalIntentionSwap, content.Type)
+				assert.Equal(t, []string{"ETH", "USDT"}, content.ProjectKeys)
+				assert.Equal(t, "USDT", content.SwapInfo.Swap.FuncCallingRet.FCSwapResult.SwapOutToken)
+			},
+		},
+		{
+			name:     "daily new tokens tool call",
+			question: "new tokens",
+			check: func(t *testing.T, content *model.ChatContentAssistant) {
+				assert.Equal(t, model.ChatAIDailyNewToken, content.Type)
+				assert.Equal(t, []string{model.ChatAIDailyNewToken}, content.ProjectKeys)
+				rows := content.DailyNewToken.NewToken.FuncCallingRet.NewTokenResult.DailyNewToken.Rows
+				assert.Equal(t, 2, len(rows))
+				assert.Equal(t, int64(5), rows[1].P2RCount)
+			},
+		},
+		{
+			name:     "token launched time distribution tool call",
+			question: "launch times",
+			check: func(t *testing.T, content *model.ChatContentAssistant) {
+				assert.Equal(t, model.ChatAITokenLaunchedTimeDistribution, content.Type)
+				rows := content.TokenLaunchedTime.LaunchedTimeDistribution.FuncCallingRet.TokenLTD.LaunchTimeDt.Rows
+				assert.Equal(t, int64(7), rows[0].LaunchedCount)
+			},
+		},
+		{
+			name:     "daily token swap counts tool call",
+			question: "daily swap counts",
+			check: func(t *testing.T, content *model.ChatContentAssistant) {
+				assert.Equal(t, model.ChatAIDailyTokenSwapCount, content.Type)
+				rows := content.TokenSwapCount.TokenSwapCount.FuncCallingRet.TokenSwapCount.TxCounts.Rows
+				assert.Equal(t, int64(1234), rows[0].TradeCount)
+			},
+		},
+		{
+			name:     "top traders tool call",
+			question: "top traders",
+			check: func(t *testing.T, content *model.ChatContentAssistant) {
+				assert.Equal(t, model.ChatAITopTrader, content.Type)
+				rows := content.TopTrader.TopTrader.FuncCallingRet.TopTrader.TopTraders.Rows
+				assert.Equal(t, "T1", rows[0].Trader)
+				assert.Equal(t, int64(40), rows[0].TotalTxCount)
+			},
+		},
+		{
+			name:     "trader overview tool call",
+			question: "trader T1",
+			check: func(t *testing.T, content *model.ChatContentAssistant) {
+				assert.Equal(t, model.ChatAITraderOverview, content.Type)
+				details := content.TraderOverview.TraderOverview.FuncCallingRet.TraderOverview.TraderDetails
+				assert.Equal(t, "T1", details.Trader.Address)
+				assert.Equal(t, int64(4), details.Info.TradedTokenCount)
+				assert.Equal(t, 1, len(details.ProfitDistribution))
+			},
+		},
+		{
+			name:     "trader overview of an unknown trader",
+			question: "trader unknown",
+			err:      traderNotFoundErrMsg,
+		},
+		{
+			name:     "invalid analytical result",
+			question: "garbage",
+			err:      intentionErrMsg,
+		},
+		{
+			name:     "model error",
+			question: "broken",
+			err:      networkErrMsg,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, "")
+			aiMsg := &model.ChatMsg{ContentAssistant: &model.ChatContentAssistant{}}
+			err := s.answer(ctx, []*extension.ChatgptMsg{{Role: "user", Content: tt.question}}, "", false, nil, mockProjectFetcher, aiMsg)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.Nil(t, err)
+			tt.check(t, aiMsg.ContentAssistant)
+		})
+	}
+}
+
+func TestChatService_AnswerWytAI(t *testing.T) {
+	// the uniswap tool is executed by the wyt ai backend, which returns the resolved result
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/doc-search/search/uni", r.URL.Path)
+		assert.Equal(t, "key", r.Header.Get("apikey"))
+		_ = json.NewEncoder(w).Encode(entity.Response{
+			ToolResults: []entity.ToolResult{{
+				ToolName: "uniswap",
+				Result: entity.ToolResultData{
+					Resolved: true,
+					Result:   map[string]any{"url": "https://app.uniswap.org/swap?inputCurrency=ETH"},
+				},
+			}},
+		})
+	}))
+	t.Cleanup(backend.Close)
+	s, server := newMockChatServiceWithConfig(t, func(cfg *config.Config) {
+		cfg.AIBackend.AIEnv = "prod"
+		cfg.AIBackend.Endpoint = backend.URL
+		cfg.AIBackend.APIKey = "key"
+	})
+
+	ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, "")
+	aiMsg := &model.ChatMsg{ContentAssistant: &model.ChatContentAssistant{}}
+	err := s.answer(ctx, []*extension.ChatgptMsg{{Role: "user", Content: "swap ETH on uniswap"}}, "uni", true, nil, mockProjectFetcher, aiMsg)
+	assert.Nil(t, err)
+	content := aiMsg.ContentAssistant
+	assert.Equal(t, model.ChatAIAnalyticalIntentionUniswap, content.Type)
+	assert.Equal(t, model.ChatContentAssistantSwapViewUniswap, content.Uniswap.View)
+	assert.Equal(t, "https://app.uniswap.org/swap?inputCurrency=ETH", content.Uniswap.Uniswap.FuncCallingRet.FCUniswapResult.Url)
+	// the model is not asked
+	assert.Equal(t, 0, len(server.Requests()))
+}
+
+func TestChatService_AnswerWytAIUsage(t *testing.T) {
+	var reported entity.Usage
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(entity.Response{
+			Object: entity.Object{Intention: "general", Content: "DeFi is decentralized finance"},
+			Usage:  reported,
+		})
+	}))
+	t.Cleanup(backend.Close)
+	s, _ := newMockChatServiceWithConfig(t, func(cfg *config.Config) {
+		cfg.AIBackend.AIEnv = "prod"
+		cfg.AIBackend.Endpoint = backend.URL
+		cfg.Extension.LLMQuotas = []config.LLMQuota{{Role: "member", DailyTokens: 100}}
+	})
+	store := newMemLLMUsageStore()
+	s.llmUsageService = &LLMUsageService{baseComponent: s.baseComponent, llmUsageDao: store}
+
+	user := primitive.NewObjectID().Hex()
+	ask := func(question string) {
+		ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, "")
+		ctx.Caller = user
+		ctx.CallerRole = model.UserRoleMember
+		assert.Nil(t, s.llmUsageService.CheckQuota(ctx))
+		// as in completions
+		ctx.Ctx = extension.WithUsageListener(ctx.Ctx, func(modelName string, usage extension.LLMUsage) {
+			assert.Equal(t, extension.WytAIUsageModel, modelName)
+			s.llmUsageService.Record(ctx, primitive.NewObjectID(), "", modelName, usage)
+		})
+		aiMsg := &model.ChatMsg{ContentAssistant: &model.ChatContentAssistant{}}
+		assert.Nil(t, s.answer(ctx, []*extension.ChatgptMsg{{Role: "user", Content: question}}, "", true, nil, mockProjectFetcher, aiMsg))
+	}
+
+	// the usage reported by the backend is counted
+	reported = entity.Usage{PromptTokens: 30, CompletionTokens: 10, TotalTokens: 40}
+	ask("what is defi")
+	assert.Equal(t, int64(40), store.tokens[user])
+
+	// and estimated when it reports none
+	reported = entity.Usage{}
+	ask("what is defi, again")
+	assert.Equal(t, 2, store.calls[user])
+	assert.True(t, store.tokens[user] > 40)
+
+	// the counters feed the quota
+	store.tokens[user] = 100
+	ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, "")
+	ctx.Caller = user
+	ctx.CallerRole = model.UserRoleMember
+	assert.ErrorContains(t, s.llmUsageService.CheckQuota(ctx), "daily limit")
+}
+
+func TestChatService_AnswerAgentPrompt(t *testing.T) {
+	s, server := newMockChatService(t)
+	agent := &model.Agent{Prompt: "Only talk about Uniswap."}
+	question := []*extension.ChatgptMsg{{Role: "user", Content: "what is defi"}}
+	ask := func(ctx *reqctx.ReqCtx) {
+		aiMsg := &model.ChatMsg{ContentAssistant: &model.ChatContentAssistant{}}
+		assert.Nil(t, s.answer(ctx, withAgentPrompt(question, agent), "", false, nil, mockProjectFetcher, aiMsg))
+	}
+
+	// the agent instructions follow the built-in prompt
+	ask(reqctx.NewReqCtx(context.Background(), logrus.New(), 1, ""))
+	// or the template assigned to the window, they are not dropped
+	ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, "")
+	ctx.Ctx = extension.WithSystemPrompt(ctx.Ctx, "Answer tersely.")
+	question[0].Content = "what is defi, tersely"
+	ask(ctx)
+
+	requests := server.Requests()
+	assert.Equal(t, 2, len(requests))
+	messages := func(req azuretest.Request) []string {
+		return lo.Map(req.Messages, func(item azuretest.Message, _ int) string { return item.Role + ": " + item.Content })
+	}
+	assert.Equal(t, []string{
+		"system: " + config.ChatPromptWithStructureOutputModeEnabled,
+		"system: Only talk about Uniswap.",
+		"user: what is defi",
+	}, messages(requests[0]))
+	assert.Equal(t, []string{
+		"system: Answer tersely.",
+		"system: Only talk about Uniswap.",
+		"user: what is defi, tersely",
+	}, messages(requests[1]))
+
+	// agents without instructions add nothing
+	assert.Equal(t, question, withAgentPrompt(question, &model.Agent{}))
+}
+
+func TestChatService_AnswerStream(t *testing.T) {
+	s, server := newMockChatService(t,
+		azuretest.Rule{
+			Pattern: regexp.MustCompile("btc"),
+			JSON:    map[string]any{"intention": "search", "intent_keys": []string{"BTC"}, "view": "overview"},
+		},
+		azuretest.Rule{
+			Pattern: regexp.MustCompile("defi"),
+			JSON:    map[string]any{"intention": "general", "intent_keys": []string{}, "view": "", "content": "DeFi is \"decentralized\" finance"},
+		},
+	)
+
+	t.Run("project search", func(t *testing.T) {
+		var events []string
+		ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, "")
+		aiMsg := &model.ChatMsg{ContentAssistant: &model.ChatContentAssistant{}}
+		err := s.answer(ctx, []*extension.ChatgptMsg{{Role: "user", Content: "about btc"}}, "", true, func(event string, data any) {
+			events = append(events, event)
+		}, mockProjectFetcher, aiMsg)
+		assert.Nil(t, err)
+		// no answer text, no token events
+		assert.Equal(t, []string{entity.ChatStreamEventIntent, entity.ChatStreamEventProjectFetch}, events)
+		assert.Equal(t, model.ChatContentAssistantTypeProjectInfo, aiMsg.ContentAssistant.Type)
+		// no wyt ai backend configured, DocsFaq is answered by the model
+		assert.Equal(t, "json_schema", server.Requests()[0].ResponseFormat)
+		assert.True(t, server.Requests()[0].Stream)
+	})
+
+	t.Run("answer text is streamed", func(t *testing.T) {
+		var events []string
+		var deltas []string
+		ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, "")
+		aiMsg := &model.ChatMsg{ContentAssistant: &model.ChatContentAssistant{}}
+		err := s.answer(ctx, []*extension.ChatgptMsg{{Role: "user", Content: "what is defi"}}, "", true, func(event string, data any) {
+			events = append(events, event)
+			if token, ok := data.(*entity.ChatStreamToken); ok {
+				deltas = append(deltas, token.Content)
+			}
+		}, mockProjectFetcher, aiMsg)
+		assert.Nil(t, err)
+		// the answer text arrives in several deltas, before the intent and the final result
+		assert.Greater(t, len(deltas), 1)
+		assert.Equal(t, "DeFi is \"decentralized\" finance", strings.Join(deltas, ""))
+		assert.Equal(t, entity.ChatStreamEventIntent, events[len(events)-1])
+		assert.Equal(t, "DeFi is \"decentralized\" finance", aiMsg.ContentAssistant.GeneralAnswer.GeneralAnswer.Content)
+	})
+}
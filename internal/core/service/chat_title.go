@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const chatTitleMaxRunes = 50
+
+// asyncGenerateTitle names the window after its first exchange in the background,
+// the title is accounted to the caller like the answer
+func (s *ChatService) asyncGenerateTitle(ctx *reqctx.ReqCtx, w *model.ChatWindow, pjId string, userMsg *model.ChatMsg, aiMsg *model.ChatMsg) {
+	if !s.baseComponent.Config.Extension.ChatTitle.Enable || w.TitleIsSet {
+		return
+	}
+	msgs := chatTitleMsgs(ctx.IsZHLang, userMsg, aiMsg)
+	if msgs == nil {
+		return
+	}
+
+	bg := s.baseComponent.BackgroundContext()
+	bg.Caller = ctx.Caller
+	bg.CallerRole = ctx.CallerRole
+	bg.IsZHLang = ctx.IsZHLang
+	bg.Ctx = extension.WithUsageListener(bg.Ctx, func(modelName string, usage extension.LLMUsage) {
+		s.llmUsageService.Record(bg, w.ID, pjId, modelName, usage)
+	})
+	s.baseComponent.SafeGo(func() {
+		answer, err := s.chatgptDriver.ChatCompletionsWithContext(bg.Ctx, msgs)
+		if err == nil {
+			title := cleanChatTitle(answer)
+			if title == "" {
+				return
+			}
+			_, err = s.chatDao.ChatWindowSetGeneratedTitle(bg, w.ID, title)
+		}
+		if err != nil {
+			s.baseComponent.Logger.WithFields(logrus.Fields{
+				"err":       err,
+				"window_id": w.ID.Hex(),
+			}).Warn("Failed to generate chat title")
+		}
+	})
+}
+
+// chatTitleMsgs builds the title request from the first exchange, nil when the question cannot be rendered
+func chatTitleMsgs(isZHLang bool, userMsg *model.ChatMsg, aiMsg *model.ChatMsg) []*extension.ChatgptMsg {
+	question := chatMsgToLLMMsg(*userMsg)
+	if question == nil {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("user: %s\n", question.Content))
+	if answer := chatMsgToLLMMsg(*aiMsg); answer != nil {
+		b.WriteString(fmt.Sprintf("assistant: %s\n", answer.Content))
+	}
+	return []*extension.ChatgptMsg{
+		{Role: "system", Content: fmt.Sprintf(config.ChatTitlePrompt, lo.Ternary(isZHLang, "Chinese", "English"))},
+		{Role: "user", Content: b.String()},
+	}
+}
+
+// cleanChatTitle keeps the first line of the model answer without quotes, cut to chatTitleMaxRunes
+func cleanChatTitle(answer string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(answer), "\n")
+	title = strings.TrimPrefix(strings.TrimSpace(title), "Title:")
+	title = strings.Trim(strings.TrimSpace(title), "\"'`“”《》.。")
+	if runes := []rune(title); len(runes) > chatTitleMaxRunes {
+		title = strings.TrimSpace(string(runes[:chatTitleMaxRunes]))
+	}
+	return title
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+)
+
+func TestCleanChatTitle(t *testing.T) {
+	assert.Equal(t, "Bitcoin Price Overview", cleanChatTitle("\"Bitcoin Price Overview\"\n"))
+	assert.Equal(t, "ETH vs SOL", cleanChatTitle("Title: ETH vs SOL.\nThe user compares two chains"))
+	assert.Equal(t, "比特币行情", cleanChatTitle("《比特币行情》。"))
+	assert.Empty(t, cleanChatTitle("  \n"))
+	assert.Len(t, []rune(cleanChatTitle(strings.Repeat("a", 80))), chatTitleMaxRunes)
+}
+
+func TestChatTitleMsgs(t *testing.T) {
+	userMsg := &model.ChatMsg{Role: model.ChatMsgRoleUser, ContentUser: &model.ChatContentUser{Content: "What is BTC?"}}
+	aiMsg := &model.ChatMsg{Role: model.ChatMsgRoleAssistant, ContentAssistant: &model.ChatContentAssistant{Tips: "Here is some info about BTC:"}}
+
+	msgs := chatTitleMsgs(true, userMsg, aiMsg)
+	assert.Len(t, msgs, 2)
+	assert.Contains(t, msgs[0].Content, "in Chinese")
+	assert.Equal(t, "user: What is BTC?\nassistant: Here is some info about BTC:\n", msgs[1].Content)
+
+	// a failed answer is a system message, the title only uses the question
+	msgs = chatTitleMsgs(false, userMsg, &model.ChatMsg{Role: model.ChatMsgRoleSystem, ContentSystem: &model.ChatContentSystem{Content: "failed"}})
+	assert.Contains(t, msgs[0].Content, "in English")
+	assert.Equal(t, "user: What is BTC?\n", msgs[1].Content)
+
+	assert.Nil(t, chatTitleMsgs(false, &model.ChatMsg{Role: model.ChatMsgRoleUser}, aiMsg))
+}
@@ -12,5 +12,12 @@ func init() {
 		NewFileSystemService,
 		NewChatService,
 		NewWebsiteService,
+		NewProjectIndexService,
+		NewLLMUsageService,
+		NewPromptService,
+		NewChatFeedbackService,
+		NewAgentService,
+		NewChatShareService,
+		NewChartService,
 	)
 }
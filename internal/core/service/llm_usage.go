@@ -0,0 +1,153 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/dao"
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+var userRoleNames = map[model.UserRole]string{
+	model.UserRoleMember:  "member",
+	model.UserRoleManager: "manager",
+	model.UserRoleAdmin:   "admin",
+}
+
+var llmUsageReportGroupFields = map[string]string{
+	"":        "",
+	"user":    "user_id",
+	"project": "project_id",
+}
+
+// llmUsageStore keeps the usage records and the per user stats, implemented by dao.LLMUsageDao
+type llmUsageStore interface {
+	Add(ctx *reqctx.ReqCtx, e *model.LLMUsage) error
+	UserTotalTokens(ctx *reqctx.ReqCtx, userID primitive.ObjectID, period model.LLMUsagePeriod, date string) (int64, error)
+	Stats(ctx *reqctx.ReqCtx, filter bson.M, groupBy string) ([]*model.LLMUsageStat, error)
+}
+
+type LLMUsageService struct {
+	baseComponent *base.Component
+	llmUsageDao   llmUsageStore
+}
+
+func NewLLMUsageService(baseComponent *base.Component, llmUsageDao *dao.LLMUsageDao) *LLMUsageService {
+	return &LLMUsageService{
+		baseComponent: baseComponent,
+		llmUsageDao:   llmUsageDao,
+	}
+}
+
+// Record saves the usage of a model call made for the caller, failures are only logged
+// since the answer has already been paid for
+func (s *LLMUsageService) Record(ctx *reqctx.ReqCtx, windowID primitive.ObjectID, projectID string, modelName string, usage extension.LLMUsage) {
+	if err := s.llmUsageDao.Add(ctx, &model.LLMUsage{
+		WindowID:         windowID,
+		ProjectId:        projectID,
+		Model:            modelName,
+		PromptTokens:     int64(usage.PromptTokens),
+		CompletionTokens: int64(usage.CompletionTokens),
+		TotalTokens:      int64(usage.TotalTokens),
+	}); err != nil {
+		ctx.AddCustomLogField("usage_err", err)
+	}
+}
+
+// CheckQuota returns errcode.ErrChatLLMQuotaExceeded when the caller has used up the daily or monthly tokens of the role
+func (s *LLMUsageService) CheckQuota(ctx *reqctx.ReqCtx) error {
+	quota, ok := llmQuotaForRole(s.baseComponent.Config.Extension.LLMQuotas, ctx.CallerRole)
+	if !ok {
+		return nil
+	}
+	userID, err := primitive.ObjectIDFromHex(ctx.Caller)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, limit := range []struct {
+		period model.LLMUsagePeriod
+		name   string
+		tokens int64
+	}{
+		{period: model.LLMUsagePeriodDay, name: "daily", tokens: quota.DailyTokens},
+		{period: model.LLMUsagePeriodMonth, name: "monthly", tokens: quota.MonthlyTokens},
+	} {
+		if limit.tokens <= 0 {
+			continue
+		}
+		used, err := s.llmUsageDao.UserTotalTokens(ctx, userID, limit.period, model.LLMUsagePeriodDate(limit.period, now))
+		if err != nil {
+			return err
+		}
+		if used >= limit.tokens {
+			ctx.AddCustomLogField("used_tokens", used)
+			return errcode.ErrChatLLMQuotaExceeded.Wrap(fmt.Sprintf("%s limit of %d tokens reached", limit.name, limit.tokens))
+		}
+	}
+	return nil
+}
+
+func (s *LLMUsageService) AdminReport(ctx *reqctx.ReqCtx, req *entity.ChatUsageReportReq) (*entity.ChatUsageReportRes, error) {
+	if req.Period == "" {
+		req.Period = model.LLMUsagePeriodDay
+	}
+	if req.Period != model.LLMUsagePeriodDay && req.Period != model.LLMUsagePeriodMonth {
+		return nil, errcode.ErrRequestParameter.Wrap("period must be day or month")
+	}
+	groupField, ok := llmUsageReportGroupFields[req.GroupBy]
+	if !ok {
+		return nil, errcode.ErrRequestParameter.Wrap("group_by must be user or project")
+	}
+
+	filter := bson.M{"period": req.Period}
+	date := bson.M{}
+	if req.From != "" {
+		date["$gte"] = req.From
+	}
+	if req.To != "" {
+		date["$lte"] = req.To
+	}
+	if len(date) != 0 {
+		filter["date"] = date
+	}
+	if req.UserID != "" {
+		userID, err := primitive.ObjectIDFromHex(req.UserID)
+		if err != nil {
+			return nil, errcode.ErrRequestParameter.Wrap("invalid user_id")
+		}
+		filter["user_id"] = userID
+	}
+	if req.ProjectId != "" {
+		filter["project_id"] = req.ProjectId
+	}
+
+	list, err := s.llmUsageDao.Stats(ctx, filter, groupField)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.ChatUsageReportRes{
+		List: list,
+		TotalTokens: lo.SumBy(list, func(item *model.LLMUsageStat) int64 {
+			return item.TotalTokens
+		}),
+	}, nil
+}
+
+// llmQuotaForRole returns the quota of the role, roles without quota are unlimited
+func llmQuotaForRole(quotas []config.LLMQuota, role model.UserRole) (config.LLMQuota, bool) {
+	return lo.Find(quotas, func(item config.LLMQuota) bool {
+		return item.Role == userRoleNames[role]
+	})
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+// memLLMUsageStore counts the tokens of each user like the stats of dao.LLMUsageDao, the period is ignored
+type memLLMUsageStore struct {
+	lock   sync.Mutex
+	tokens map[string]int64
+	calls  map[string]int
+}
+
+func newMemLLMUsageStore() *memLLMUsageStore {
+	return &memLLMUsageStore{tokens: map[string]int64{}, calls: map[string]int{}}
+}
+
+func (m *memLLMUsageStore) Add(ctx *reqctx.ReqCtx, e *model.LLMUsage) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.tokens[ctx.Caller] += e.TotalTokens
+	m.calls[ctx.Caller]++
+	return nil
+}
+
+func (m *memLLMUsageStore) UserTotalTokens(ctx *reqctx.ReqCtx, userID primitive.ObjectID, period model.LLMUsagePeriod, date string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.tokens[userID.Hex()], nil
+}
+
+func (m *memLLMUsageStore) Stats(ctx *reqctx.ReqCtx, filter bson.M, groupBy string) ([]*model.LLMUsageStat, error) {
+	return nil, nil
+}
+
+func TestLLMQuotaForRole(t *testing.T) {
+	quotas := []config.LLMQuota{
+		{Role: "member", DailyTokens: 1000, MonthlyTokens: 20000},
+		{Role: "manager", MonthlyTokens: 100000},
+	}
+
+	quota, ok := llmQuotaForRole(quotas, model.UserRoleMember)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1000), quota.DailyTokens)
+	quota, ok = llmQuotaForRole(quotas, model.UserRoleManager)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), quota.DailyTokens)
+	_, ok = llmQuotaForRole(quotas, model.UserRoleAdmin)
+	assert.False(t, ok)
+	_, ok = llmQuotaForRole(nil, model.UserRoleMember)
+	assert.False(t, ok)
+}
+
+func TestLLMUsagePeriodDate(t *testing.T) {
+	// periods are in UTC
+	now := time.Date(2024, 4, 1, 1, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	assert.Equal(t, "2024-03-31", model.LLMUsagePeriodDate(model.LLMUsagePeriodDay, now))
+	assert.Equal(t, "2024-03", model.LLMUsagePeriodDate(model.LLMUsagePeriodMonth, now))
+}
@@ -30,9 +30,11 @@ type ProjectService struct {
 	marketDatasource  *datasource.Market
 	metricsDatasource *datasource.Metrics
 	socialDatasource  *datasource.Social
+	projectIndex      *ProjectIndexService
+	chartService      *ChartService
 }
 
-func NewProjectService(baseComponent *base.Component, projectDao *dao.ProjectDao, miscDao *dao.MiscDao, marketDatasource *datasource.Market, metricsDatasource *datasource.Metrics, socialDatasource *datasource.Social) *ProjectService {
+func NewProjectService(baseComponent *base.Component, projectDao *dao.ProjectDao, miscDao *dao.MiscDao, marketDatasource *datasource.Market, metricsDatasource *datasource.Metrics, socialDatasource *datasource.Social, projectIndex *ProjectIndexService, chartService *ChartService) *ProjectService {
 	return &ProjectService{
 		baseComponent:     baseComponent,
 		projectDao:        projectDao,
@@ -40,6 +42,8 @@ func NewProjectService(baseComponent *base.Component, projectDao *dao.ProjectDao
 		marketDatasource:  marketDatasource,
 		metricsDatasource: metricsDatasource,
 		socialDatasource:  socialDatasource,
+		projectIndex:      projectIndex,
+		chartService:      chartService,
 	}
 }
 
@@ -634,9 +638,15 @@ func (s *ProjectService) MetricsCompare(ctx *reqctx.ReqCtx, req *entity.ProjectM
 		}
 	}
 
-	return &entity.ProjectMetricsCompareRes{
+	names := make(map[string]string, len(infos))
+	for _, info := range infos {
+		names[info.ID.Hex()] = info.Basic.Name
+	}
+	compareRes := &entity.ProjectMetricsCompareRes{
 		Metrics: res,
-	}, nil
+	}
+	compareRes.ChartURL = s.chartService.MetricsCompareChartURL(ctx, req.Type, compareRes, names)
+	return compareRes, nil
 }
 
 // nolint
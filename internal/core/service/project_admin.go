@@ -176,6 +176,7 @@ func (s *ProjectService) AdminPublish(ctx *reqctx.ReqCtx, req *entity.ProjectPub
 			}).Error("Failed to update subscription for new project")
 		}
 	})
+	s.projectIndex.AsyncRefresh(info)
 	return &entity.ProjectPublishRes{}, nil
 }
 
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/dao"
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+const (
+	defaultRAGTopK      = 3
+	defaultRAGChunkSize = 1000
+)
+
+type projectChunkHit struct {
+	chunk *model.ProjectChunk
+	score float64
+}
+
+// ProjectIndexService keeps the embeddings of the published projects in memory (persisted in the project_chunk
+// collection) and retrieves the chunks relevant to a question
+type ProjectIndexService struct {
+	baseComponent   *base.Component
+	projectDao      *dao.ProjectDao
+	projectChunkDao *dao.ProjectChunkDao
+	chatgptDriver   *extension.ChatgptDriver
+
+	lock   sync.RWMutex
+	chunks []*model.ProjectChunk
+}
+
+func NewProjectIndexService(baseComponent *base.Component, projectDao *dao.ProjectDao, projectChunkDao *dao.ProjectChunkDao, chatgptDriver *extension.ChatgptDriver) *ProjectIndexService {
+	s := &ProjectIndexService{
+		baseComponent:   baseComponent,
+		projectDao:      projectDao,
+		projectChunkDao: projectChunkDao,
+		chatgptDriver:   chatgptDriver,
+	}
+	baseComponent.RegisterLifecycleHook(s)
+	return s
+}
+
+func (s *ProjectIndexService) Start() error {
+	if !s.baseComponent.Config.Extension.RAG.Enable {
+		return nil
+	}
+	s.baseComponent.SafeGo(func() {
+		ctx := s.baseComponent.BackgroundContext()
+		if err := s.load(ctx); err != nil {
+			s.baseComponent.Logger.WithField("err", err).Error("Failed to load project index")
+			return
+		}
+		s.backfill(ctx)
+	})
+	return nil
+}
+
+func (s *ProjectIndexService) Stop() error {
+	return nil
+}
+
+func (s *ProjectIndexService) load(ctx *reqctx.ReqCtx) error {
+	chunks, err := s.projectChunkDao.ListAll(ctx, s.baseComponent.Config.Extension.Chatgpt.EmbeddingModel)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	s.chunks = chunks
+	s.lock.Unlock()
+	return nil
+}
+
+// backfill indexes the published projects without chunks, e.g. published before the index existed
+// or embedded with another model
+func (s *ProjectIndexService) backfill(ctx *reqctx.ReqCtx) {
+	projects, _, err := s.projectDao.List(ctx, true, 0, 0, bson.M{"is_deleted": false}, nil)
+	if err != nil {
+		s.baseComponent.Logger.WithField("err", err).Error("Failed to list projects to index")
+		return
+	}
+	s.lock.RLock()
+	indexed := lo.SliceToMap(s.chunks, func(item *model.ProjectChunk) (primitive.ObjectID, struct{}) {
+		return item.ProjectID, struct{}{}
+	})
+	s.lock.RUnlock()
+	for _, p := range projects {
+		if _, ok := indexed[p.ID]; ok {
+			continue
+		}
+		if err := s.Refresh(ctx, p); err != nil {
+			s.baseComponent.Logger.WithFields(logrus.Fields{
+				"err":     err,
+				"project": p.Basic.Name,
+			}).Error("Failed to index project")
+		}
+	}
+}
+
+// Refresh rebuilds the chunks of a published project
+func (s *ProjectIndexService) Refresh(ctx *reqctx.ReqCtx, p *model.Project) error {
+	cfg := s.baseComponent.Config.Extension
+	chunks := buildProjectChunks(p, lo.Ternary(cfg.RAG.ChunkSize > 0, cfg.RAG.ChunkSize, defaultRAGChunkSize))
+	vectors, err := s.chatgptDriver.Embed(ctx.Ctx, lo.Map(chunks, func(item *model.ProjectChunk, _ int) string {
+		return item.Content
+	}))
+	if err != nil {
+		return err
+	}
+	for i, chunk := range chunks {
+		chunk.Embedding = vectors[i]
+		chunk.EmbeddingModel = cfg.Chatgpt.EmbeddingModel
+	}
+	if err := s.projectChunkDao.Replace(ctx, p.ID, chunks); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.chunks = append(lo.Filter(s.chunks, func(item *model.ProjectChunk, _ int) bool {
+		return item.ProjectID != p.ID
+	}), chunks...)
+	return nil
+}
+
+// AsyncRefresh refreshes the project chunks in the background, used on publish
+func (s *ProjectIndexService) AsyncRefresh(p *model.Project) {
+	if !s.baseComponent.Config.Extension.RAG.Enable {
+		return
+	}
+	s.baseComponent.SafeGo(func() {
+		if err := s.Refresh(s.baseComponent.BackgroundContext(), p); err != nil {
+			s.baseComponent.Logger.WithFields(logrus.Fields{
+				"err":     err,
+				"project": p.Basic.Name,
+			}).Error("Failed to index project")
+		}
+	})
+}
+
+// Augment adds the chunks relevant to the question (the last message) to msgs as a system message placed before it,
+// msgs are returned unchanged when retrieval is disabled or nothing relevant is found
+func (s *ProjectIndexService) Augment(ctx *reqctx.ReqCtx, msgs []*extension.ChatgptMsg) []*extension.ChatgptMsg {
+	cfg := s.baseComponent.Config.Extension.RAG
+	if !cfg.Enable || len(msgs) == 0 {
+		return msgs
+	}
+	s.lock.RLock()
+	chunks := s.chunks
+	s.lock.RUnlock()
+	if len(chunks) == 0 {
+		return msgs
+	}
+
+	question := msgs[len(msgs)-1]
+	vectors, err := s.chatgptDriver.Embed(ctx.Ctx, []string{question.Content})
+	if err != nil {
+		ctx.AddCustomLogField("rag_err", err)
+		return msgs
+	}
+	hits := rankProjectChunks(chunks, vectors[0], lo.Ternary(cfg.TopK > 0, cfg.TopK, defaultRAGTopK), cfg.MinScore)
+	if len(hits) == 0 {
+		return msgs
+	}
+	ctx.AddCustomLogField("rag_chunks", lo.Map(hits, func(item projectChunkHit, _ int) string {
+		return item.chunk.ID.Hex()
+	}))
+
+	res := append([]*extension.ChatgptMsg{}, msgs[:len(msgs)-1]...)
+	res = append(res, ragContextMsg(hits), question)
+	return res
+}
+
+func ragContextMsg(hits []projectChunkHit) *extension.ChatgptMsg {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(config.ChatRAGPrompt))
+	for i, hit := range hits {
+		b.WriteString(fmt.Sprintf("\n\n[%d] %s (%s):\n%s", i+1, hit.chunk.ProjectName, hit.chunk.Section, hit.chunk.Content))
+	}
+	return &extension.ChatgptMsg{Role: "system", Content: b.String()}
+}
+
+// rankProjectChunks returns the topK chunks most similar to the query with a score of at least minScore
+func rankProjectChunks(chunks []*model.ProjectChunk, query []float32, topK int, minScore float64) []projectChunkHit {
+	var hits []projectChunkHit
+	for _, chunk := range chunks {
+		score := cosineSimilarity(chunk.Embedding, query)
+		if score >= minScore && score > 0 {
+			hits = append(hits, projectChunkHit{chunk: chunk, score: score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].score > hits[j].score
+	})
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+	return hits
+}
+
+func cosineSimilarity(a []float32, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// buildProjectChunks renders the description, tokenomics, team and funding of the project as text chunks
+func buildProjectChunks(p *model.Project, chunkSize int) []*model.ProjectChunk {
+	name := p.Basic.Name
+	if p.Tokenomics.TokenSymbol != "" {
+		name = fmt.Sprintf("%s (%s)", p.Basic.Name, p.Tokenomics.TokenSymbol)
+	}
+
+	sections := map[model.ProjectChunkSection][]string{}
+	add := func(section model.ProjectChunkSection, format string, args ...any) {
+		text := strings.TrimSpace(fmt.Sprintf(format, args...))
+		if text != "" {
+			sections[section] = append(sections[section], text)
+		}
+	}
+
+	add(model.ProjectChunkSectionDescription, "%s", p.Basic.Description)
+	if p.Basic.FoundedDate != "" {
+		add(model.ProjectChunkSectionDescription, "Founded: %s.", p.Basic.FoundedDate)
+	}
+	if p.Basic.LaunchDate != "" {
+		add(model.ProjectChunkSectionDescription, "Launched: %s.", p.Basic.LaunchDate)
+	}
+
+	t := p.Tokenomics
+	if t.TokenIssuance {
+		add(model.ProjectChunkSectionTokenomics, "Token %s (%s), total supply %g, circulating supply %g.", t.TokenName, t.TokenSymbol, t.TotalSupply, t.CirculatingSupply)
+		if t.TokenIssuanceDate != "" {
+			add(model.ProjectChunkSectionTokenomics, "Issued on %s.", t.TokenIssuanceDate)
+		}
+		if len(t.InitialDistribution) > 0 {
+			add(model.ProjectChunkSectionTokenomics, "Initial distribution: %s.", strings.Join(lo.Map(t.InitialDistribution, func(item model.DistributionInfo, _ int) string {
+				return fmt.Sprintf("%s %d%%", item.Slice, item.Percentage)
+			}), ", "))
+		}
+	}
+	add(model.ProjectChunkSectionTokenomics, "%s", t.Description)
+
+	for _, m := range p.Team.Members {
+		status := ""
+		if m.IsDeparted {
+			status = ", departed"
+		}
+		add(model.ProjectChunkSectionTeam, "%s (%s%s): %s", m.Name, m.Title, status, m.Description)
+	}
+
+	f := p.Funding
+	if len(f.FundingDetails) > 0 {
+		add(model.ProjectChunkSectionFunding, "%d funding rounds, %d USD raised in total.", f.Highlights.FundingRounds, f.Highlights.TotalFundingAmount)
+	}
+	for _, d := range f.FundingDetails {
+		text := fmt.Sprintf("%s round on %s raised %d USD", d.Round, d.Date, d.Amount)
+		if d.Valuation > 0 {
+			text += fmt.Sprintf(" at a %d USD valuation", d.Valuation)
+		}
+		if d.LeadInvestors != "" {
+			text += ", led by " + d.LeadInvestors
+		}
+		if d.Investors != "" {
+			text += ", investors: " + d.Investors
+		}
+		add(model.ProjectChunkSectionFunding, "%s.", text)
+	}
+
+	var chunks []*model.ProjectChunk
+	for _, section := range []model.ProjectChunkSection{
+		model.ProjectChunkSectionDescription,
+		model.ProjectChunkSectionTokenomics,
+		model.ProjectChunkSectionTeam,
+		model.ProjectChunkSectionFunding,
+	} {
+		if len(sections[section]) == 0 {
+			continue
+		}
+		prefix := fmt.Sprintf("%s %s: ", name, section)
+		for _, text := range splitChunkText(strings.Join(sections[section], "\n"), chunkSize-len(prefix)) {
+			chunks = append(chunks, &model.ProjectChunk{
+				ProjectID:   p.ID,
+				ProjectName: p.Basic.Name,
+				Section:     section,
+				Content:     prefix + text,
+			})
+		}
+	}
+	return chunks
+}
+
+// splitChunkText splits text into pieces of at most size bytes, cutting at line or word boundaries when possible
+func splitChunkText(text string, size int) []string {
+	if size <= 0 {
+		size = defaultRAGChunkSize
+	}
+	var res []string
+	for len(text) > size {
+		cut := strings.LastIndex(text[:size], "\n")
+		if cut <= 0 {
+			cut = strings.LastIndex(text[:size], " ")
+		}
+		if cut <= 0 {
+			cut = size
+			// do not split a multi-byte character
+			for cut > 0 && text[cut]&0xC0 == 0x80 {
+				cut--
+			}
+		}
+		res = append(res, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		res = append(res, text)
+	}
+	return res
+}
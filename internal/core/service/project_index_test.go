@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/azure/azuretest"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func TestBuildProjectChunks(t *testing.T) {
+	p := &model.Project{
+		BaseModel: model.BaseModel{ID: primitive.NewObjectID()},
+		Basic: model.ProjectBasic{
+			Name:        "Bitcoin",
+			Description: "A peer-to-peer electronic cash system.",
+			FoundedDate: "2008-10-31",
+		},
+		Tokenomics: model.ProjectTokenomics{
+			TokenIssuance: true,
+			TokenName:     "Bitcoin",
+			TokenSymbol:   "BTC",
+			TotalSupply:   21000000,
+			InitialDistribution: []model.DistributionInfo{
+				{Slice: "Mining", Percentage: 100},
+			},
+		},
+		Team: model.ProjectTeam{
+			Members: []model.ProjectTeamMember{
+				{Name: "Satoshi Nakamoto", Title: "Founder", Description: "Pseudonymous creator", IsDeparted: true},
+			},
+		},
+	}
+
+	chunks := buildProjectChunks(p, 1000)
+	assert.Equal(t, 3, len(chunks))
+	assert.Equal(t, model.ProjectChunkSectionDescription, chunks[0].Section)
+	assert.Equal(t, "Bitcoin (BTC) description: A peer-to-peer electronic cash system.\nFounded: 2008-10-31.", chunks[0].Content)
+	assert.Equal(t, model.ProjectChunkSectionTokenomics, chunks[1].Section)
+	assert.Contains(t, chunks[1].Content, "Initial distribution: Mining 100%.")
+	assert.Equal(t, "Bitcoin (BTC) team: Satoshi Nakamoto (Founder, departed): Pseudonymous creator", chunks[2].Content)
+	for _, chunk := range chunks {
+		assert.Equal(t, p.ID, chunk.ProjectID)
+		assert.Equal(t, "Bitcoin", chunk.ProjectName)
+	}
+
+	// long sections are split, every piece keeps the prefix
+	p.Basic.Description = strings.Repeat("word ", 100)
+	chunks = buildProjectChunks(p, 100)
+	description := 0
+	for _, chunk := range chunks {
+		assert.True(t, strings.HasPrefix(chunk.Content, "Bitcoin (BTC) "+string(chunk.Section)+": "))
+		assert.True(t, len(chunk.Content) <= 100)
+		if chunk.Section == model.ProjectChunkSectionDescription {
+			description++
+		}
+	}
+	assert.True(t, description > 1)
+}
+
+func TestSplitChunkText(t *testing.T) {
+	assert.Equal(t, []string{"ab cd", "ef"}, splitChunkText("ab cd ef", 6))
+	assert.Equal(t, []string{"line one", "line two"}, splitChunkText("line one\nline two", 12))
+	assert.Equal(t, []string{"abcd", "efgh"}, splitChunkText("abcdefgh", 4))
+	// multi-byte characters are not cut
+	assert.Equal(t, []string{"中", "文"}, splitChunkText("中文", 4))
+	assert.Nil(t, splitChunkText("", 4))
+}
+
+func TestRankProjectChunks(t *testing.T) {
+	chunks := []*model.ProjectChunk{
+		{Content: "a", Embedding: []float32{1, 0}},
+		{Content: "b", Embedding: []float32{0.8, 0.6}},
+		{Content: "c", Embedding: []float32{0, 1}},
+		{Content: "d", Embedding: []float32{1}},
+	}
+	hits := rankProjectChunks(chunks, []float32{1, 0}, 3, 0)
+	assert.Equal(t, 2, len(hits))
+	assert.Equal(t, "a", hits[0].chunk.Content)
+	assert.InDelta(t, 1, hits[0].score, 1e-6)
+	assert.Equal(t, "b", hits[1].chunk.Content)
+
+	assert.Equal(t, 1, len(rankProjectChunks(chunks, []float32{1, 0}, 1, 0)))
+	assert.Equal(t, 1, len(rankProjectChunks(chunks, []float32{1, 0}, 3, 0.9)))
+	assert.Equal(t, 0.0, cosineSimilarity([]float32{0, 0}, []float32{1, 0}))
+}
+
+func TestProjectIndexService_Augment(t *testing.T) {
+	chatService, server := newMockChatService(t)
+	baseComponent := chatService.baseComponent
+	s := &ProjectIndexService{
+		baseComponent: baseComponent,
+		chatgptDriver: chatService.chatgptDriver,
+	}
+	for _, chunk := range []*model.ProjectChunk{
+		{ProjectName: "Bitcoin", Section: model.ProjectChunkSectionFunding, Content: "Bitcoin (BTC) funding: no venture funding, bitcoin was mined from genesis."},
+		{ProjectName: "Uniswap", Section: model.ProjectChunkSectionTeam, Content: "Uniswap (UNI) team: Hayden Adams founded the uniswap protocol."},
+	} {
+		chunk.ID = primitive.NewObjectID()
+		chunk.Embedding = azuretest.Embedding(chunk.Content)
+		s.chunks = append(s.chunks, chunk)
+	}
+	ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, "")
+	msgs := []*extension.ChatgptMsg{{Role: "assistant", Content: "hi"}, {Role: "user", Content: "who founded uniswap"}}
+
+	// disabled
+	assert.Equal(t, msgs, s.Augment(ctx, msgs))
+
+	baseComponent.Config.Extension.RAG.Enable = true
+	baseComponent.Config.Extension.RAG.TopK = 1
+	res := s.Augment(ctx, msgs)
+	if !assert.Equal(t, 3, len(res)) {
+		return
+	}
+	assert.Equal(t, msgs[0], res[0])
+	assert.Equal(t, "system", res[1].Role)
+	assert.Contains(t, res[1].Content, "[1] Uniswap (team):\nUniswap (UNI) team: Hayden Adams")
+	assert.NotContains(t, res[1].Content, "Bitcoin")
+	assert.Equal(t, msgs[1], res[2])
+	// the caller's messages are left untouched
+	assert.Equal(t, 2, len(msgs))
+
+	// nothing relevant
+	baseComponent.Config.Extension.RAG.MinScore = 0.99
+	assert.Equal(t, msgs, s.Augment(ctx, msgs))
+	assert.Equal(t, 0, len(server.Requests()))
+}
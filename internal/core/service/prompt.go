@@ -0,0 +1,152 @@
+package service
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"github.com/wyt-labs/wyt-core/internal/core/dao"
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+// promptCacheTTL bounds how long the active templates cached by an instance miss the admin writes
+// made on other instances
+const promptCacheTTL = time.Minute
+
+// promptTemplateStore keeps the template versions, implemented by dao.PromptDao
+type promptTemplateStore interface {
+	Add(ctx *reqctx.ReqCtx, e *model.PromptTemplate) error
+	ExistsByName(ctx *reqctx.ReqCtx, name string) (bool, error)
+	Latest(ctx *reqctx.ReqCtx, name string) (*model.PromptTemplate, error)
+	QueryVersion(ctx *reqctx.ReqCtx, name string, version uint64) (*model.PromptTemplate, error)
+	SetActive(ctx *reqctx.ReqCtx, name string, version uint64, isActive bool) error
+	List(ctx *reqctx.ReqCtx, page uint64, size uint64, filter any, sort map[string]bool) ([]*model.PromptTemplate, int64, error)
+	ListActive(ctx *reqctx.ReqCtx) ([]*model.PromptTemplate, error)
+}
+
+type PromptService struct {
+	baseComponent *base.Component
+	promptDao     promptTemplateStore
+
+	// active templates, reloaded after promptCacheTTL or an admin write
+	lock         sync.Mutex
+	active       []*model.PromptTemplate
+	activeLoaded time.Time
+}
+
+func NewPromptService(baseComponent *base.Component, promptDao *dao.PromptDao) *PromptService {
+	return &PromptService{
+		baseComponent: baseComponent,
+		promptDao:     promptDao,
+	}
+}
+
+// Apply returns the rendered system prompt of the template assigned to the window, it replaces the built-in
+// prompt (see extension.WithSystemPrompt). The template is nil when no template is offered to the agent.
+// When backend is set the wyt ai backend answers the question, only the templates bypassing it are offered.
+func (s *PromptService) Apply(ctx *reqctx.ReqCtx, agentID string, windowID string, backend bool) (string, *model.PromptTemplate) {
+	templates, err := s.activeTemplates(ctx)
+	if err != nil {
+		ctx.AddCustomLogField("prompt_err", err)
+		return "", nil
+	}
+	if backend {
+		templates = lo.Filter(templates, func(item *model.PromptTemplate, _ int) bool {
+			return item.BypassBackend
+		})
+	}
+	tmpl := selectPromptTemplate(templates, agentID, windowID)
+	if tmpl == nil {
+		return "", nil
+	}
+	prompt, err := renderPromptTemplate(tmpl.Name, tmpl.Content, model.PromptVars{
+		Date:     time.Now().UTC().Format("2006-01-02"),
+		AgentID:  agentID,
+		Language: lo.Ternary(ctx.IsZHLang, "zh", "en"),
+	})
+	if err != nil {
+		// templates are checked when saved, this only happens with edits made in the database
+		ctx.AddCustomLogField("prompt_err", err)
+		return "", nil
+	}
+	ctx.AddCustomLogField("prompt", tmpl.Name)
+	ctx.AddCustomLogField("prompt_version", tmpl.Version)
+	return prompt, tmpl
+}
+
+func (s *PromptService) activeTemplates(ctx *reqctx.ReqCtx) ([]*model.PromptTemplate, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.activeLoaded.IsZero() && time.Since(s.activeLoaded) < promptCacheTTL {
+		return s.active, nil
+	}
+	templates, err := s.promptDao.ListActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.active = templates
+	s.activeLoaded = time.Now()
+	return templates, nil
+}
+
+// invalidate drops the cached active templates after an admin write
+func (s *PromptService) invalidate() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.active = nil
+	s.activeLoaded = time.Time{}
+}
+
+// selectPromptTemplate picks one of the templates offered to the agent by weight, the templates targeting
+// the agent take precedence over the generic ones. The same key (chat window) always gets the same template
+// while the templates do not change.
+func selectPromptTemplate(templates []*model.PromptTemplate, agentID string, key string) *model.PromptTemplate {
+	candidates := lo.Filter(templates, func(item *model.PromptTemplate, _ int) bool {
+		return item.Weight > 0 && lo.Contains(item.AgentIDs, agentID)
+	})
+	if len(candidates) == 0 {
+		candidates = lo.Filter(templates, func(item *model.PromptTemplate, _ int) bool {
+			return item.Weight > 0 && len(item.AgentIDs) == 0
+		})
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Name < candidates[j].Name
+	})
+
+	total := lo.SumBy(candidates, func(item *model.PromptTemplate) uint64 {
+		return item.Weight
+	})
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	point := h.Sum64() % total
+	for _, c := range candidates {
+		if point < c.Weight {
+			return c
+		}
+		point -= c.Weight
+	}
+	return candidates[len(candidates)-1]
+}
+
+func renderPromptTemplate(name string, content string, vars model.PromptVars) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse prompt template")
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, vars); err != nil {
+		return "", errors.Wrap(err, "failed to render prompt template")
+	}
+	return b.String(), nil
+}
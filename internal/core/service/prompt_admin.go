@@ -0,0 +1,129 @@
+package service
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func (s *PromptService) AdminAdd(ctx *reqctx.ReqCtx, req *entity.PromptTemplateAddReq) (*entity.PromptTemplateAddRes, error) {
+	if err := checkPromptTemplate(req); err != nil {
+		return nil, err
+	}
+	exist, err := s.promptDao.ExistsByName(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if exist {
+		return nil, errcode.ErrPromptTemplateAlreadyExists
+	}
+
+	tmpl := newPromptTemplate(req, 1)
+	if err := s.promptDao.Add(ctx, tmpl); err != nil {
+		if err == errcode.ErrPromptTemplateVersionExists {
+			return nil, errcode.ErrPromptTemplateAlreadyExists
+		}
+		return nil, err
+	}
+	s.invalidate()
+	return &entity.PromptTemplateAddRes{
+		Name:    tmpl.Name,
+		Version: tmpl.Version,
+	}, nil
+}
+
+func (s *PromptService) AdminUpdate(ctx *reqctx.ReqCtx, req *entity.PromptTemplateUpdateReq) (*entity.PromptTemplateUpdateRes, error) {
+	addReq := (*entity.PromptTemplateAddReq)(req)
+	if err := checkPromptTemplate(addReq); err != nil {
+		return nil, err
+	}
+	latest, err := s.promptDao.Latest(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := newPromptTemplate(addReq, latest.Version+1)
+	tmpl.IsActive = false
+	if err := s.promptDao.Add(ctx, tmpl); err != nil {
+		return nil, err
+	}
+	if err := s.promptDao.SetActive(ctx, tmpl.Name, tmpl.Version, true); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return &entity.PromptTemplateUpdateRes{
+		Name:    tmpl.Name,
+		Version: tmpl.Version,
+	}, nil
+}
+
+func (s *PromptService) AdminSetActive(ctx *reqctx.ReqCtx, req *entity.PromptTemplateSetActiveReq) (*entity.PromptTemplateSetActiveRes, error) {
+	if req.IsActive {
+		if _, err := s.promptDao.QueryVersion(ctx, req.Name, req.Version); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.promptDao.SetActive(ctx, req.Name, req.Version, req.IsActive); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return &entity.PromptTemplateSetActiveRes{}, nil
+}
+
+func (s *PromptService) AdminList(ctx *reqctx.ReqCtx, req *entity.PromptTemplateListReq) (*entity.PromptTemplateListRes, error) {
+	filter := bson.M{"is_deleted": false, "is_active": true}
+	sort := map[string]bool{"name": true}
+	if req.Name != "" {
+		filter = bson.M{"is_deleted": false, "name": req.Name}
+		sort = map[string]bool{"version": false}
+	}
+	list, total, err := s.promptDao.List(ctx, req.Page, req.Size, filter, sort)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.PromptTemplateListRes{
+		List:  list,
+		Total: total,
+	}, nil
+}
+
+func newPromptTemplate(req *entity.PromptTemplateAddReq, version uint64) *model.PromptTemplate {
+	weight := req.Weight
+	if weight == 0 {
+		weight = 1
+	}
+	return &model.PromptTemplate{
+		Name:     req.Name,
+		Version:  version,
+		Content:  req.Content,
+		Schema:   req.Schema,
+		AgentIDs: req.AgentIDs,
+		Weight:   weight,
+		IsActive: true,
+		Remark:   req.Remark,
+
+		BypassBackend: req.BypassBackend,
+	}
+}
+
+// checkPromptTemplate renders the template with sample variables so mistakes are reported when saving
+func checkPromptTemplate(req *entity.PromptTemplateAddReq) error {
+	if req.Name == "" {
+		return errcode.ErrRequestParameter.Wrap("name cannot be empty")
+	}
+	if req.Content == "" {
+		return errcode.ErrRequestParameter.Wrap("content cannot be empty")
+	}
+	if _, err := renderPromptTemplate(req.Name, req.Content, model.PromptVars{}); err != nil {
+		return errcode.ErrPromptTemplateInvalid.Wrap(err.Error())
+	}
+	if len(req.Schema) != 0 {
+		if _, ok := req.Schema["schema"].(map[string]any); !ok {
+			return errcode.ErrPromptTemplateInvalid.Wrap("schema must be a json_schema object with name, schema and strict")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+	"github.com/wyt-labs/wyt-core/internal/pkg/errcode"
+	"github.com/wyt-labs/wyt-core/pkg/reqctx"
+)
+
+func TestSelectPromptTemplate(t *testing.T) {
+	generic := &model.PromptTemplate{Name: "generic", Weight: 1}
+	control := &model.PromptTemplate{Name: "a-control", AgentIDs: []string{"agent"}, Weight: 3}
+	variant := &model.PromptTemplate{Name: "b-variant", AgentIDs: []string{"agent"}, Weight: 1}
+	disabled := &model.PromptTemplate{Name: "disabled", AgentIDs: []string{"other"}, Weight: 0}
+	templates := []*model.PromptTemplate{variant, generic, control, disabled}
+
+	assert.Nil(t, selectPromptTemplate(nil, "agent", "w"))
+	// agents without their own templates get the generic ones
+	assert.Equal(t, generic, selectPromptTemplate(templates, "", "w"))
+	assert.Equal(t, generic, selectPromptTemplate(templates, "other", "w"))
+	assert.Nil(t, selectPromptTemplate([]*model.PromptTemplate{control, disabled}, "other", "w"))
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		key := fmt.Sprintf("window-%d", i)
+		tmpl := selectPromptTemplate(templates, "agent", key)
+		counts[tmpl.Name]++
+		// the assignment is sticky
+		assert.Equal(t, tmpl, selectPromptTemplate([]*model.PromptTemplate{control, generic, variant}, "agent", key))
+	}
+	assert.Equal(t, 0, counts["generic"])
+	assert.InDelta(t, 3000, counts["a-control"], 200)
+	assert.InDelta(t, 1000, counts["b-variant"], 200)
+}
+
+// memPromptStore keeps the templates of TestPromptService_Apply, only the calls used there are implemented
+type memPromptStore struct {
+	templates   []*model.PromptTemplate
+	listActives int
+	// Add fails like the unique (name, version) index
+	duplicate bool
+}
+
+func (m *memPromptStore) Add(ctx *reqctx.ReqCtx, e *model.PromptTemplate) error {
+	if m.duplicate {
+		return errcode.ErrPromptTemplateVersionExists
+	}
+	m.templates = append(m.templates, e)
+	return nil
+}
+
+func (m *memPromptStore) ExistsByName(ctx *reqctx.ReqCtx, name string) (bool, error) {
+	return lo.ContainsBy(m.templates, func(item *model.PromptTemplate) bool {
+		return item.Name == name
+	}), nil
+}
+
+func (m *memPromptStore) Latest(ctx *reqctx.ReqCtx, name string) (*model.PromptTemplate, error) {
+	return nil, errcode.ErrPromptTemplateNotExist
+}
+
+func (m *memPromptStore) QueryVersion(ctx *reqctx.ReqCtx, name string, version uint64) (*model.PromptTemplate, error) {
+	tmpl, ok := lo.Find(m.templates, func(item *model.PromptTemplate) bool {
+		return item.Name == name && item.Version == version
+	})
+	if !ok {
+		return nil, errcode.ErrPromptTemplateNotExist
+	}
+	return tmpl, nil
+}
+
+func (m *memPromptStore) SetActive(ctx *reqctx.ReqCtx, name string, version uint64, isActive bool) error {
+	for _, item := range m.templates {
+		if item.Name == name {
+			item.IsActive = isActive && item.Version == version
+		}
+	}
+	return nil
+}
+
+func (m *memPromptStore) List(ctx *reqctx.ReqCtx, page uint64, size uint64, filter any, sort map[string]bool) ([]*model.PromptTemplate, int64, error) {
+	return nil, 0, nil
+}
+
+func (m *memPromptStore) ListActive(ctx *reqctx.ReqCtx) ([]*model.PromptTemplate, error) {
+	m.listActives++
+	return lo.Filter(m.templates, func(item *model.PromptTemplate, _ int) bool {
+		return item.IsActive
+	}), nil
+}
+
+func TestPromptService_Apply(t *testing.T) {
+	store := &memPromptStore{}
+	s := &PromptService{baseComponent: base.NewMockBaseComponent(t), promptDao: store}
+	ctx := reqctx.NewReqCtx(context.Background(), logrus.New(), 1, "")
+
+	prompt, tmpl := s.Apply(ctx, "agent", "w", false)
+	assert.Equal(t, "", prompt)
+	assert.Nil(t, tmpl)
+
+	// admin writes are visible to the next question
+	_, err := s.AdminAdd(ctx, &entity.PromptTemplateAddReq{Name: "terse", Content: "Answer {{.AgentID}} questions tersely."})
+	assert.Nil(t, err)
+	prompt, tmpl = s.Apply(ctx, "agent", "w", false)
+	assert.Equal(t, "Answer agent questions tersely.", prompt)
+	assert.Equal(t, "terse", tmpl.Name)
+
+	// the active templates are loaded once
+	for i := 0; i < 5; i++ {
+		s.Apply(ctx, "agent", "w", false)
+	}
+	assert.Equal(t, 2, store.listActives)
+
+	_, err = s.AdminSetActive(ctx, &entity.PromptTemplateSetActiveReq{Name: "terse", Version: 1, IsActive: false})
+	assert.Nil(t, err)
+	_, tmpl = s.Apply(ctx, "agent", "w", false)
+	assert.Nil(t, tmpl)
+	assert.Equal(t, 3, store.listActives)
+
+	// questions answered by the wyt ai backend only get the templates bypassing it
+	_, err = s.AdminSetActive(ctx, &entity.PromptTemplateSetActiveReq{Name: "terse", Version: 1, IsActive: true})
+	assert.Nil(t, err)
+	_, tmpl = s.Apply(ctx, "agent", "w", true)
+	assert.Nil(t, tmpl)
+	_, err = s.AdminAdd(ctx, &entity.PromptTemplateAddReq{Name: "bypass", Content: "Answer briefly.", AgentIDs: []string{"agent"}, BypassBackend: true})
+	assert.Nil(t, err)
+	_, tmpl = s.Apply(ctx, "agent", "w", true)
+	assert.Equal(t, "bypass", tmpl.Name)
+
+	// the versions added meanwhile by another instance are reported
+	store.duplicate = true
+	_, err = s.AdminAdd(ctx, &entity.PromptTemplateAddReq{Name: "racing", Content: "hi"})
+	assert.Equal(t, errcode.ErrPromptTemplateAlreadyExists, err)
+}
+
+func TestRenderPromptTemplate(t *testing.T) {
+	vars := model.PromptVars{Date: "2024-05-01", AgentID: "agent", Language: "zh"}
+	res, err := renderPromptTemplate("t", "Today is {{.Date}}.{{if eq .Language \"zh\"}} Answer in Chinese.{{end}}", vars)
+	assert.Nil(t, err)
+	assert.Equal(t, "Today is 2024-05-01. Answer in Chinese.", res)
+
+	_, err = renderPromptTemplate("t", "{{.Unknown}}", vars)
+	assert.ErrorContains(t, err, "failed to render prompt template")
+	_, err = renderPromptTemplate("t", "{{.Date", vars)
+	assert.ErrorContains(t, err, "failed to parse prompt template")
+}
+
+func TestCheckPromptTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		req  entity.PromptTemplateAddReq
+		code uint32
+	}{
+		{name: "valid", req: entity.PromptTemplateAddReq{Name: "t", Content: "{{.AgentID}}"}},
+		{name: "valid schema", req: entity.PromptTemplateAddReq{Name: "t", Content: "hi", Schema: map[string]any{"name": "s", "schema": map[string]any{}}}},
+		{name: "no name", req: entity.PromptTemplateAddReq{Content: "hi"}, code: errcode.DecodeError(errcode.ErrRequestParameter)},
+		{name: "unknown variable", req: entity.PromptTemplateAddReq{Name: "t", Content: "{{.Agent}}"}, code: errcode.DecodeError(errcode.ErrPromptTemplateInvalid)},
+		{name: "schema without schema", req: entity.PromptTemplateAddReq{Name: "t", Content: "hi", Schema: map[string]any{"type": "object"}}, code: errcode.DecodeError(errcode.ErrPromptTemplateInvalid)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPromptTemplate(&tt.req)
+			if tt.code == 0 {
+				assert.Nil(t, err)
+				return
+			}
+			assert.Equal(t, tt.code, errcode.DecodeError(err))
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/wyt-labs/wyt-core/internal/core/component/okxswap"
 	"github.com/wyt-labs/wyt-core/internal/core/service"
 	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
 	"github.com/wyt-labs/wyt-core/pkg/basic"
 	"github.com/wyt-labs/wyt-core/pkg/extension"
 	"github.com/wyt-labs/wyt-core/pkg/mutex"
@@ -21,6 +22,12 @@ type CoreAPI struct {
 	FileSystemService *service.FileSystemService
 	ChatService       *service.ChatService
 	WebsiteService    *service.WebsiteService
+	LLMUsageService   *service.LLMUsageService
+	PromptService     *service.PromptService
+	FeedbackService   *service.ChatFeedbackService
+	AgentService      *service.AgentService
+	ShareService      *service.ChatShareService
+	ChartService      *service.ChartService
 	PumpDataService   *datapuller.PumpDataService
 	OkxDexServiceApi  *okxswap.OkxSwapApi
 }
@@ -33,6 +40,12 @@ func NewCoreAPI(
 	fileSystemService *service.FileSystemService,
 	chatService *service.ChatService,
 	websiteService *service.WebsiteService,
+	llmUsageService *service.LLMUsageService,
+	promptService *service.PromptService,
+	feedbackService *service.ChatFeedbackService,
+	agentService *service.AgentService,
+	shareService *service.ChatShareService,
+	chartService *service.ChartService,
 	pumpDataService *datapuller.PumpDataService,
 	okxDexServiceApi *okxswap.OkxSwapApi,
 ) (*CoreAPI, error) {
@@ -44,6 +57,12 @@ func NewCoreAPI(
 		FileSystemService: fileSystemService,
 		ChatService:       chatService,
 		WebsiteService:    websiteService,
+		LLMUsageService:   llmUsageService,
+		PromptService:     promptService,
+		FeedbackService:   feedbackService,
+		AgentService:      agentService,
+		ShareService:      shareService,
+		ChartService:      chartService,
 		PumpDataService:   pumpDataService,
 		OkxDexServiceApi:  okxDexServiceApi,
 	}, nil
@@ -54,13 +73,21 @@ func NewChatgptDriver(
 	pumpDataService *datapuller.PumpDataService,
 ) (*extension.ChatgptDriver, error) {
 	baseComponent.Logger.Info("chatgpt driver init")
-	cfg := baseComponent.Config.Extension.Chatgpt
-	return extension.NewChatgptDriver(&extension.ChatgptConfig{
-		Endpoint:        cfg.Endpoint,
-		EndpointFull:    cfg.EndpointFull,
-		APIKey:          cfg.APIKey,
-		Model:           cfg.Model,
-		Temperature:     cfg.Temperature,
-		PresencePenalty: cfg.PresencePenalty,
-	}, baseComponent, pumpDataService)
+	return extension.NewChatgptDriver(ChatgptConfig(baseComponent.Config), baseComponent, pumpDataService)
+}
+
+// ChatgptConfig maps the llm settings of the app config to the driver config
+func ChatgptConfig(cfg *config.Config) *extension.ChatgptConfig {
+	return &extension.ChatgptConfig{
+		Provider:        cfg.Extension.Chatgpt.Provider,
+		Endpoint:        cfg.Extension.Chatgpt.Endpoint,
+		EndpointFull:    cfg.Extension.Chatgpt.EndpointFull,
+		APIKey:          cfg.Extension.Chatgpt.APIKey,
+		Model:           cfg.Extension.Chatgpt.Model,
+		EmbeddingModel:  cfg.Extension.Chatgpt.EmbeddingModel,
+		Temperature:     cfg.Extension.Chatgpt.Temperature,
+		PresencePenalty: cfg.Extension.Chatgpt.PresencePenalty,
+		FakeRules:       cfg.Extension.Chatgpt.FakeRules,
+		ToolPolicies:    cfg.Extension.ToolPolicies,
+	}
 }
@@ -1,11 +1,14 @@
 package azure
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
 type Message struct {
@@ -20,13 +23,22 @@ type RequestTool struct {
 
 type RequestResponseFormat struct {
 	Type       string `json:"type"`
-	JsonSchema any    `json:"json_schema"`
+	JsonSchema any    `json:"json_schema,omitempty"`
+}
+
+type RequestStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type RequestBody struct {
-	Messages       []Message             `json:"messages"`
-	Tools          []RequestTool         `json:"tools"`
-	ResponseFormat RequestResponseFormat `json:"response_format"`
+	Model           string                 `json:"model,omitempty"`
+	Messages        []Message              `json:"messages"`
+	Tools           []RequestTool          `json:"tools,omitempty"`
+	ResponseFormat  *RequestResponseFormat `json:"response_format,omitempty"`
+	Temperature     *float32               `json:"temperature,omitempty"`
+	PresencePenalty *float32               `json:"presence_penalty,omitempty"`
+	Stream          bool                   `json:"stream,omitempty"`
+	StreamOptions   *RequestStreamOptions  `json:"stream_options,omitempty"`
 }
 
 type ResponseTool struct {
@@ -46,8 +58,34 @@ type ResponseChoice struct {
 	} `json:"message"`
 }
 
+type ResponseUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
 type APIResponse struct {
 	Choices []ResponseChoice `json:"choices"`
+	Usage   *ResponseUsage   `json:"usage,omitempty"`
+}
+
+type StreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			Role      string `json:"role"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				Id       string `json:"id"`
+				Type     string `json:"type"`
+				Function struct {
+					Arguments string `json:"arguments"`
+					Name      string `json:"name"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *ResponseUsage `json:"usage,omitempty"`
 }
 
 type ChatAPI struct {
@@ -55,6 +93,9 @@ type ChatAPI struct {
 	token          string
 	tools          []RequestTool
 	responseFormat RequestResponseFormat
+	// model is sent in the body, azure deployments bake it into the url instead
+	model      string
+	bearerAuth bool
 }
 
 // NewAzureChatAPI creates a chat client that utilizes Azure OpenAPI service
@@ -67,46 +108,164 @@ func NewAzureChatAPI(apiUrl string, token string, responseFormat RequestResponse
 	}
 }
 
+// NewOpenAIChatAPI creates a chat client for OpenAI compatible servers (vLLM, llama.cpp, Ollama...),
+// baseUrl is the api root such as http://localhost:11434/v1
+func NewOpenAIChatAPI(baseUrl string, token string, model string) *ChatAPI {
+	return &ChatAPI{
+		apiUrl:     strings.TrimSuffix(baseUrl, "/") + "/chat/completions",
+		token:      token,
+		model:      model,
+		bearerAuth: true,
+	}
+}
+
 // Chat with Azure Chat API
 func (a *ChatAPI) Chat(messages []Message) (*APIResponse, error) {
-	requestBody := RequestBody{Messages: messages,
-		Tools:          a.tools,
-		ResponseFormat: a.responseFormat,
+	return a.Complete(context.Background(), a.defaultRequest(messages))
+}
+
+// ChatStream requests a streamed completion, onDelta receives every content fragment as it arrives,
+// the returned response is assembled from all chunks and has the same shape as Chat's
+func (a *ChatAPI) ChatStream(messages []Message, onDelta func(content string)) (*APIResponse, error) {
+	return a.CompleteStream(context.Background(), a.defaultRequest(messages), onDelta)
+}
+
+func (a *ChatAPI) defaultRequest(messages []Message) RequestBody {
+	requestBody := RequestBody{
+		Messages: messages,
+		Tools:    a.tools,
+	}
+	if a.responseFormat.Type != "" {
+		requestBody.ResponseFormat = &a.responseFormat
+	}
+	return requestBody
+}
+
+// Complete sends a fully specified request, tools and response format configured on the client are not applied
+func (a *ChatAPI) Complete(ctx context.Context, requestBody RequestBody) (*APIResponse, error) {
+	requestBody.Stream = false
+	requestBody.StreamOptions = nil
+	resp, err := a.do(ctx, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	var apiResponse APIResponse
+	err = json.Unmarshal(body, &apiResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %v", err)
+	}
+
+	return &apiResponse, nil
+}
+
+// CompleteStream is the streamed variant of Complete
+func (a *ChatAPI) CompleteStream(ctx context.Context, requestBody RequestBody, onDelta func(content string)) (*APIResponse, error) {
+	requestBody.Stream = true
+	requestBody.StreamOptions = &RequestStreamOptions{IncludeUsage: true}
+	resp, err := a.do(ctx, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var role string
+	var toolCalls []ResponseTool
+	var usage *ResponseUsage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+		var chunk StreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("error unmarshaling stream chunk: %v", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Role != "" {
+				role = choice.Delta.Role
+			}
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				if onDelta != nil {
+					onDelta(choice.Delta.Content)
+				}
+			}
+			for _, tc := range choice.Delta.ToolCalls {
+				for len(toolCalls) <= tc.Index {
+					toolCalls = append(toolCalls, ResponseTool{})
+				}
+				if tc.Id != "" {
+					toolCalls[tc.Index].Id = tc.Id
+				}
+				if tc.Type != "" {
+					toolCalls[tc.Index].Type = tc.Type
+				}
+				toolCalls[tc.Index].Function.Name += tc.Function.Name
+				toolCalls[tc.Index].Function.Arguments += tc.Function.Arguments
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading stream: %v", err)
+	}
+
+	choice := ResponseChoice{}
+	choice.Message.Content = content.String()
+	choice.Message.Role = role
+	choice.Message.ToolCalls = toolCalls
+	return &APIResponse{Choices: []ResponseChoice{choice}, Usage: usage}, nil
+}
+
+func (a *ChatAPI) do(ctx context.Context, requestBody RequestBody) (*http.Response, error) {
+	if requestBody.Model == "" {
+		requestBody.Model = a.model
 	}
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling request body: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", a.apiUrl, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", a.apiUrl, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("api-key", a.token)
+	if a.bearerAuth {
+		if a.token != "" {
+			req.Header.Set("Authorization", "Bearer "+a.token)
+		}
+	} else {
+		req.Header.Set("api-key", a.token)
+	}
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error sending request: %v", err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
-	}
 
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("API request failed with status code %d: %s", resp.StatusCode, string(body))
 	}
-
-	var apiResponse APIResponse
-	err = json.Unmarshal(body, &apiResponse)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling response: %v", err)
-	}
-
-	return &apiResponse, nil
+	return resp, nil
 }
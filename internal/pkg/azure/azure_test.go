@@ -0,0 +1,57 @@
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChatAPI_ChatStream(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"delta":{"role":"assistant"}}]}`,
+		`{"choices":[{"delta":{"content":"{\"intention\":"}}]}`,
+		`{"choices":[{"delta":{"content":"\"search\"}"}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"top_traders","arguments":"{\"dura"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"tion\":7}"}}]}}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key", r.Header.Get("api-key"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	api := NewAzureChatAPI(server.URL, "key", RequestResponseFormat{Type: "json_object"}, nil)
+	var deltas []string
+	res, err := api.ChatStream([]Message{{Role: "user", Content: "hi"}}, func(content string) {
+		deltas = append(deltas, content)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`{"intention":`, `"search"}`}, deltas)
+	assert.Equal(t, 1, len(res.Choices))
+	assert.Equal(t, "assistant", res.Choices[0].Message.Role)
+	assert.Equal(t, strings.Join(deltas, ""), res.Choices[0].Message.Content)
+	assert.Equal(t, 1, len(res.Choices[0].Message.ToolCalls))
+	assert.Equal(t, "call_1", res.Choices[0].Message.ToolCalls[0].Id)
+	assert.Equal(t, "top_traders", res.Choices[0].Message.ToolCalls[0].Function.Name)
+	assert.Equal(t, `{"duration":7}`, res.Choices[0].Message.ToolCalls[0].Function.Arguments)
+}
+
+func TestChatAPI_ChatStreamStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte("rate limited"))
+	}))
+	defer server.Close()
+
+	api := NewAzureChatAPI(server.URL, "key", RequestResponseFormat{}, nil)
+	_, err := api.ChatStream([]Message{{Role: "user", Content: "hi"}}, nil)
+	assert.ErrorContains(t, err, "429")
+}
@@ -0,0 +1,372 @@
+// Package azuretest provides an in-process Azure OpenAI chat completions and embeddings server for offline tests,
+// it speaks the wire format used by azure.ChatAPI and azopenai.Client
+package azuretest
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	APIKey     = "azuretest-key"
+	APIVersion = "2024-08-01-preview"
+
+	EmbeddingDimensions = 64
+)
+
+type ToolCall struct {
+	Name      string
+	Arguments string
+}
+
+// Rule scripts the answer to the prompts whose last user message matches Pattern,
+// rules are tried in order and the first match wins
+type Rule struct {
+	Pattern *regexp.Regexp
+	Content string
+	// JSON is marshaled as the content, used for response_format json_schema answers
+	JSON any
+	// ToolCall is returned as tool_calls, or function_call for requests using the legacy functions
+	ToolCall *ToolCall
+	// Status replies with an error when it is not 0 or 200
+	Status int
+}
+
+// Request is a chat completions request received by the server
+type Request struct {
+	Deployment string
+	Messages   []Message
+	// Tools lists the offered tool (or legacy function) names
+	Tools          []string
+	ResponseFormat string
+	Stream         bool
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type Server struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	rules    []Rule
+	requests []Request
+}
+
+type chatRequest struct {
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+	Functions []struct {
+		Name string `json:"name"`
+	} `json:"functions"`
+	Tools []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+	ResponseFormat *struct {
+		Type string `json:"type"`
+	} `json:"response_format"`
+	Stream        bool `json:"stream"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+var deploymentPath = regexp.MustCompile(`^/openai/deployments/([^/]+)/(chat/completions|embeddings)$`)
+
+// NewServer starts a server answering with rules, unmatched prompts are echoed back
+// (as a general answer for json_schema requests)
+func NewServer(rules ...Rule) *Server {
+	s := &Server{rules: rules}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Endpoint is the resource endpoint passed to azopenai.NewClientWithKeyCredential
+func (s *Server) Endpoint() string {
+	return s.URL
+}
+
+// EndpointFull is the chat completions url of the deployment used by azure.NewAzureChatAPI
+func (s *Server) EndpointFull(deployment string) string {
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s", s.URL, deployment, APIVersion)
+}
+
+// AddRules appends rules, matched after the existing ones
+func (s *Server) AddRules(rules ...Rule) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.rules = append(s.rules, rules...)
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	matches := deploymentPath.FindStringSubmatch(r.URL.Path)
+	if r.Method != http.MethodPost || matches == nil {
+		writeError(w, http.StatusNotFound, "404", "Resource not found")
+		return
+	}
+	if r.Header.Get("api-key") != APIKey {
+		writeError(w, http.StatusUnauthorized, "401", "Access denied due to invalid subscription key")
+		return
+	}
+	if matches[2] == "embeddings" {
+		s.handleEmbeddings(w, r)
+		return
+	}
+	var body chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	req := Request{
+		Deployment: matches[1],
+		Stream:     body.Stream,
+	}
+	for _, msg := range body.Messages {
+		req.Messages = append(req.Messages, Message{Role: msg.Role, Content: messageText(msg.Content)})
+	}
+	for _, f := range body.Functions {
+		req.Tools = append(req.Tools, f.Name)
+	}
+	for _, tool := range body.Tools {
+		req.Tools = append(req.Tools, tool.Function.Name)
+	}
+	if body.ResponseFormat != nil {
+		req.ResponseFormat = body.ResponseFormat.Type
+	}
+
+	s.lock.Lock()
+	s.requests = append(s.requests, req)
+	rule := s.match(lastUserContent(req.Messages))
+	s.lock.Unlock()
+
+	if rule.Status != 0 && rule.Status != http.StatusOK {
+		writeError(w, rule.Status, fmt.Sprint(rule.Status), "scripted error")
+		return
+	}
+	content := rule.Content
+	if rule.JSON != nil {
+		raw, _ := json.Marshal(rule.JSON)
+		content = string(raw)
+	}
+	if content == "" && rule.ToolCall == nil {
+		content = lastUserContent(req.Messages)
+		if req.ResponseFormat == "json_schema" {
+			raw, _ := json.Marshal(map[string]any{
+				"content":     content,
+				"intent_keys": []string{},
+				"intention":   "",
+				"view":        "overview",
+			})
+			content = string(raw)
+		}
+	}
+	u := usage{
+		PromptTokens:     countWords(req.Messages),
+		CompletionTokens: len(strings.Fields(content)),
+	}
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+
+	if body.Stream {
+		writeStream(w, content, rule.ToolCall, body.StreamOptions != nil && body.StreamOptions.IncludeUsage, u)
+		return
+	}
+
+	msg := map[string]any{
+		"role":    "assistant",
+		"content": content,
+	}
+	finishReason := "stop"
+	if rule.ToolCall != nil {
+		if len(body.Functions) > 0 {
+			msg["function_call"] = map[string]any{"name": rule.ToolCall.Name, "arguments": rule.ToolCall.Arguments}
+			finishReason = "function_call"
+		} else {
+			msg["tool_calls"] = []any{toolCallJSON(rule.ToolCall)}
+			finishReason = "tool_calls"
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":      "chatcmpl-azuretest",
+		"object":  "chat.completion",
+		"created": 0,
+		"model":   req.Deployment,
+		"choices": []any{map[string]any{
+			"index":         0,
+			"finish_reason": finishReason,
+			"message":       msg,
+		}},
+		"usage": u,
+	})
+}
+
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Input []string `json:"input"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	var data []map[string]any
+	tokens := 0
+	for i, input := range body.Input {
+		data = append(data, map[string]any{"object": "embedding", "index": i, "embedding": Embedding(input)})
+		tokens += len(strings.Fields(input))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"data":   data,
+		"usage":  map[string]any{"prompt_tokens": tokens, "total_tokens": tokens},
+	})
+}
+
+// Embedding is the vector returned for text by the embeddings endpoint: a normalized bag of lowercase words
+// hashed into EmbeddingDimensions buckets, so texts sharing words are similar
+func Embedding(text string) []float32 {
+	vector := make([]float32, EmbeddingDimensions)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(word))
+		vector[h.Sum32()%EmbeddingDimensions]++
+	}
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		for i := range vector {
+			vector[i] = float32(float64(vector[i]) / math.Sqrt(norm))
+		}
+	}
+	return vector
+}
+
+func (s *Server) match(question string) Rule {
+	for _, rule := range s.rules {
+		if rule.Pattern == nil || rule.Pattern.MatchString(question) {
+			return rule
+		}
+	}
+	return Rule{}
+}
+
+func writeStream(w http.ResponseWriter, content string, toolCall *ToolCall, includeUsage bool, u usage) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	chunk := func(delta map[string]any) {
+		raw, _ := json.Marshal(map[string]any{
+			"id":      "chatcmpl-azuretest",
+			"object":  "chat.completion.chunk",
+			"choices": []any{map[string]any{"index": 0, "delta": delta}},
+		})
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", raw)
+	}
+	chunk(map[string]any{"role": "assistant"})
+	words := strings.SplitAfter(content, " ")
+	for _, word := range words {
+		if word != "" {
+			chunk(map[string]any{"content": word})
+		}
+	}
+	if toolCall != nil {
+		call := toolCallJSON(toolCall)
+		call["index"] = 0
+		chunk(map[string]any{"tool_calls": []any{call}})
+	}
+	if includeUsage {
+		raw, _ := json.Marshal(map[string]any{"choices": []any{}, "usage": u})
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", raw)
+	}
+	_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func toolCallJSON(toolCall *ToolCall) map[string]any {
+	return map[string]any{
+		"id":   "call_" + toolCall.Name,
+		"type": "function",
+		"function": map[string]any{
+			"name":      toolCall.Name,
+			"arguments": toolCall.Arguments,
+		},
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// messageText accepts both the plain string content and the content parts array
+func messageText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	_ = json.Unmarshal(raw, &parts)
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteString(part.Text)
+	}
+	return b.String()
+}
+
+func lastUserContent(msgs []Message) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == "user" {
+			return msgs[i].Content
+		}
+	}
+	return ""
+}
+
+func countWords(msgs []Message) int {
+	n := 0
+	for _, msg := range msgs {
+		n += len(strings.Fields(msg.Content))
+	}
+	return n
+}
@@ -0,0 +1,77 @@
+package azuretest
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/azure"
+)
+
+func TestServer_ChatAPI(t *testing.T) {
+	server := NewServer(
+		Rule{Pattern: regexp.MustCompile("(?i)bitcoin"), JSON: map[string]any{"intention": "search", "intent_keys": []string{"BTC"}}},
+		Rule{Pattern: regexp.MustCompile("(?i)traders"), ToolCall: &ToolCall{Name: "top_traders", Arguments: `{"duration":7}`}},
+		Rule{Pattern: regexp.MustCompile("(?i)overloaded"), Status: 429},
+	)
+	defer server.Close()
+	api := azure.NewAzureChatAPI(server.EndpointFull("gpt-4o"), APIKey, azure.RequestResponseFormat{}, nil)
+
+	tests := []struct {
+		name     string
+		question string
+		content  string
+		toolName string
+		err      string
+	}{
+		{name: "json rule", question: "tell me about Bitcoin", content: `{"intent_keys":["BTC"],"intention":"search"}`},
+		{name: "tool call rule", question: "top traders", toolName: "top_traders"},
+		{name: "unmatched echo", question: "hello there", content: "hello there"},
+		{name: "scripted error", question: "overloaded", err: "429"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := api.Complete(context.Background(), azure.RequestBody{
+				Messages: []azure.Message{{Role: "system", Content: "be brief"}, {Role: "user", Content: tt.question}},
+			})
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.content, res.Choices[0].Message.Content)
+			if tt.toolName != "" {
+				assert.Equal(t, tt.toolName, res.Choices[0].Message.ToolCalls[0].Function.Name)
+			}
+			assert.True(t, res.Usage.TotalTokens > 0)
+		})
+	}
+
+	last := server.Requests()[len(server.Requests())-1]
+	assert.Equal(t, "gpt-4o", last.Deployment)
+	assert.Equal(t, []Message{{Role: "system", Content: "be brief"}, {Role: "user", Content: "overloaded"}}, last.Messages)
+
+	_, err := azure.NewAzureChatAPI(server.EndpointFull("gpt-4o"), "wrong", azure.RequestResponseFormat{}, nil).Chat([]azure.Message{{Role: "user", Content: "hi"}})
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestServer_ChatAPIStream(t *testing.T) {
+	server := NewServer(Rule{Pattern: regexp.MustCompile("swap"), Content: "sure, swapping now", ToolCall: &ToolCall{Name: "swap", Arguments: `{"swap_in_token":"ETH"}`}})
+	defer server.Close()
+	api := azure.NewAzureChatAPI(server.EndpointFull("gpt-4o"), APIKey, azure.RequestResponseFormat{}, nil)
+
+	var deltas []string
+	res, err := api.ChatStream([]azure.Message{{Role: "user", Content: "swap ETH"}}, func(content string) {
+		deltas = append(deltas, content)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sure, ", "swapping ", "now"}, deltas)
+	assert.Equal(t, strings.Join(deltas, ""), res.Choices[0].Message.Content)
+	assert.Equal(t, "swap", res.Choices[0].Message.ToolCalls[0].Function.Name)
+	assert.Equal(t, `{"swap_in_token":"ETH"}`, res.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, 2+3, res.Usage.TotalTokens)
+	assert.True(t, server.Requests()[0].Stream)
+}
@@ -0,0 +1,85 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type EmbeddingRequestBody struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type EmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage *ResponseUsage `json:"usage,omitempty"`
+}
+
+type EmbeddingAPI struct {
+	apiUrl string
+	token  string
+	model  string
+}
+
+// NewOpenAIEmbeddingAPI creates an embeddings client for OpenAI compatible servers, baseUrl is the api root
+func NewOpenAIEmbeddingAPI(baseUrl string, token string, model string) *EmbeddingAPI {
+	return &EmbeddingAPI{
+		apiUrl: strings.TrimSuffix(baseUrl, "/") + "/embeddings",
+		token:  token,
+		model:  model,
+	}
+}
+
+// Embed returns one vector per input, in the input order, and the usage reported by the server (nil when missing)
+func (a *EmbeddingAPI) Embed(ctx context.Context, input []string) ([][]float32, *ResponseUsage, error) {
+	jsonBody, err := json.Marshal(EmbeddingRequestBody{Model: a.model, Input: input})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error marshaling request body: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", a.apiUrl, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("API request failed with status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	var res EmbeddingResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, nil, fmt.Errorf("error unmarshaling response: %v", err)
+	}
+	if len(res.Data) != len(input) {
+		return nil, nil, fmt.Errorf("expected %d embeddings, got %d", len(input), len(res.Data))
+	}
+	sort.Slice(res.Data, func(i, j int) bool {
+		return res.Data[i].Index < res.Data[j].Index
+	})
+	vectors := make([][]float32, len(res.Data))
+	for i, item := range res.Data {
+		vectors[i] = item.Embedding
+	}
+	return vectors, res.Usage, nil
+}
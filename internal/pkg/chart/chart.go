@@ -0,0 +1,333 @@
+// Package chart renders the line, bar, histogram and pie charts of quantitative answers as png or svg
+package chart
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	gochart "github.com/wcharczuk/go-chart/v2"
+)
+
+type Kind = string
+
+const (
+	KindLine      Kind = "line"
+	KindBar       Kind = "bar"
+	KindHistogram Kind = "histogram"
+	KindPie       Kind = "pie"
+)
+
+type Format = string
+
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+)
+
+const (
+	defaultWidth  = 800
+	defaultHeight = 400
+	defaultBins   = 10
+	// x axis labels shown at most, the others are skipped
+	maxXTicks = 8
+)
+
+var ErrNoData = errors.New("chart has no data")
+
+// Series is one set of values, Values[i] belongs to Labels[i] (or Times[i]) of the spec
+type Series struct {
+	Name   string    `json:"name"`
+	Values []float64 `json:"values"`
+}
+
+// Spec describes a chart independently of the output format
+type Spec struct {
+	Kind   Kind   `json:"kind"`
+	Title  string `json:"title"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+
+	// category of each value: the x axis of line, bar and histogram charts, the slices of pie charts
+	Labels []string `json:"labels,omitempty"`
+	// x axis of line charts over time, used instead of Labels
+	Times  []time.Time `json:"times,omitempty"`
+	Series []Series    `json:"series"`
+
+	// histogram only: without Labels the values of the first series are raw samples, put into Bins buckets
+	Bins int `json:"bins,omitempty"`
+}
+
+func ValidFormat(format Format) bool {
+	return format == FormatPNG || format == FormatSVG
+}
+
+func ContentType(format Format) string {
+	if format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// ID identifies the rendered chart by its content, the same data gives the same id
+func ID(spec *Spec, format Format) string {
+	raw, _ := json.Marshal(spec)
+	sum := sha256.Sum256(append(raw, format...))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Render writes the chart in the format (png by default)
+func Render(w io.Writer, spec *Spec, format Format) error {
+	if len(spec.Series) == 0 || len(spec.Series[0].Values) == 0 {
+		return ErrNoData
+	}
+	provider := gochart.PNG
+	if format == FormatSVG {
+		provider = gochart.SVG
+	}
+	switch spec.Kind {
+	case KindLine:
+		return renderLine(w, spec, provider)
+	case KindBar:
+		return renderBar(w, spec, spec.Labels, spec.Series[0].Values, 0, provider)
+	case KindHistogram:
+		labels, values := spec.Labels, spec.Series[0].Values
+		if len(labels) == 0 {
+			labels, values = Bucket(values, spec.Bins)
+		}
+		// adjacent bars
+		return renderBar(w, spec, labels, values, 1, provider)
+	case KindPie:
+		return renderPie(w, spec, provider)
+	}
+	return errors.Errorf("unsupported chart kind: %s", spec.Kind)
+}
+
+func size(spec *Spec) (int, int) {
+	width, height := spec.Width, spec.Height
+	if width <= 0 {
+		width = defaultWidth
+	}
+	if height <= 0 {
+		height = defaultHeight
+	}
+	return width, height
+}
+
+func renderLine(w io.Writer, spec *Spec, provider gochart.RendererProvider) error {
+	width, height := size(spec)
+	graph := gochart.Chart{
+		Title:  spec.Title,
+		Width:  width,
+		Height: height,
+		Background: gochart.Style{
+			Padding: gochart.Box{Top: 50, Left: 20, Right: 20, Bottom: 20},
+		},
+		YAxis: gochart.YAxis{
+			ValueFormatter: formatValue,
+			Range:          valueRange(allValues(spec.Series)),
+		},
+	}
+	for i, series := range spec.Series {
+		style := gochart.Style{
+			StrokeColor: gochart.GetDefaultColor(i),
+			StrokeWidth: 2,
+		}
+		if len(spec.Times) != 0 {
+			n := min(len(spec.Times), len(series.Values))
+			if n < 2 {
+				return errors.Wrap(ErrNoData, "a line needs two points at least")
+			}
+			graph.Series = append(graph.Series, gochart.TimeSeries{
+				Name:    series.Name,
+				Style:   style,
+				XValues: spec.Times[:n],
+				YValues: series.Values[:n],
+			})
+			continue
+		}
+		if len(series.Values) < 2 {
+			return errors.Wrap(ErrNoData, "a line needs two points at least")
+		}
+		graph.Series = append(graph.Series, gochart.ContinuousSeries{
+			Name:    series.Name,
+			Style:   style,
+			XValues: indexes(len(series.Values)),
+			YValues: series.Values,
+		})
+	}
+	if len(spec.Times) != 0 {
+		graph.XAxis.ValueFormatter = gochart.TimeDateValueFormatter
+	} else {
+		graph.XAxis.Ticks = xTicks(spec.Labels, len(spec.Series[0].Values))
+	}
+	if len(spec.Series) > 1 {
+		graph.Elements = []gochart.Renderable{gochart.Legend(&graph)}
+	}
+	return graph.Render(provider, w)
+}
+
+func renderBar(w io.Writer, spec *Spec, labels []string, values []float64, spacing int, provider gochart.RendererProvider) error {
+	width, height := size(spec)
+	graph := gochart.BarChart{
+		Title:  spec.Title,
+		Width:  width,
+		Height: height,
+		Background: gochart.Style{
+			Padding: gochart.Box{Top: 50, Left: 20, Right: 20, Bottom: 20},
+		},
+		BarSpacing: spacing,
+		YAxis: gochart.YAxis{
+			ValueFormatter: formatValue,
+			Range:          valueRange(values),
+		},
+	}
+	step := tickStep(len(values))
+	for i, value := range values {
+		label := ""
+		if i < len(labels) && i%step == 0 {
+			label = labels[i]
+		}
+		graph.Bars = append(graph.Bars, gochart.Value{
+			Label: label,
+			Value: value,
+			Style: gochart.Style{
+				FillColor:   gochart.GetDefaultColor(0),
+				StrokeColor: gochart.GetDefaultColor(0),
+			},
+		})
+	}
+	if spacing != 0 {
+		// the bars fill the width
+		graph.BarWidth = max(1, (width-100)/len(values)-spacing)
+	}
+	return graph.Render(provider, w)
+}
+
+func renderPie(w io.Writer, spec *Spec, provider gochart.RendererProvider) error {
+	width, height := size(spec)
+	graph := gochart.PieChart{
+		Title:  spec.Title,
+		Width:  width,
+		Height: height,
+	}
+	var total float64
+	for i, value := range spec.Series[0].Values {
+		if value <= 0 {
+			continue
+		}
+		label := ""
+		if i < len(spec.Labels) {
+			label = spec.Labels[i]
+		}
+		total += value
+		graph.Values = append(graph.Values, gochart.Value{Label: label, Value: value})
+	}
+	if total == 0 {
+		return errors.Wrap(ErrNoData, "a pie needs positive values")
+	}
+	return graph.Render(provider, w)
+}
+
+// Bucket counts the samples in bins buckets of the same width, labeled by their lower bound
+func Bucket(samples []float64, bins int) ([]string, []float64) {
+	if len(samples) == 0 {
+		return nil, nil
+	}
+	if bins <= 0 {
+		bins = defaultBins
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range samples {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	if hi == lo {
+		return []string{formatValue(lo)}, []float64{float64(len(samples))}
+	}
+	width := (hi - lo) / float64(bins)
+	labels := make([]string, bins)
+	counts := make([]float64, bins)
+	for i := range labels {
+		labels[i] = formatValue(lo + float64(i)*width)
+	}
+	for _, v := range samples {
+		// the maximum goes to the last bucket
+		i := min(int((v-lo)/width), bins-1)
+		counts[i]++
+	}
+	return labels, counts
+}
+
+// valueRange keeps zero in sight and avoids the empty range go-chart refuses to draw
+func valueRange(values []float64) *gochart.ContinuousRange {
+	lo, hi := 0.0, 0.0
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+	return &gochart.ContinuousRange{Min: lo, Max: hi}
+}
+
+func allValues(series []Series) []float64 {
+	var res []float64
+	for _, s := range series {
+		res = append(res, s.Values...)
+	}
+	return res
+}
+
+func indexes(n int) []float64 {
+	res := make([]float64, n)
+	for i := range res {
+		res[i] = float64(i)
+	}
+	return res
+}
+
+func tickStep(n int) int {
+	return max(1, int(math.Ceil(float64(n)/maxXTicks)))
+}
+
+func xTicks(labels []string, n int) []gochart.Tick {
+	if len(labels) == 0 {
+		return nil
+	}
+	var ticks []gochart.Tick
+	step := tickStep(n)
+	for i := 0; i < n && i < len(labels); i += step {
+		ticks = append(ticks, gochart.Tick{Value: float64(i), Label: labels[i]})
+	}
+	return ticks
+}
+
+// formatValue prints large values with a unit: 1.5K, 2.3M, 4B
+func formatValue(v any) string {
+	f, ok := v.(float64)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	abs := math.Abs(f)
+	switch {
+	case abs >= 1e9:
+		return trimFloat(f/1e9) + "B"
+	case abs >= 1e6:
+		return trimFloat(f/1e6) + "M"
+	case abs >= 1e3:
+		return trimFloat(f/1e3) + "K"
+	}
+	return trimFloat(f)
+}
+
+func trimFloat(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,68 @@
+package chart
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	day := time.Date(2024, 9, 21, 0, 0, 0, 0, time.UTC)
+	specs := []*Spec{
+		{Kind: KindLine, Title: "New tokens", Labels: []string{"09-21", "09-22", "09-23"}, Series: []Series{
+			{Name: "total", Values: []float64{100, 200, 150}},
+			{Name: "p2r", Values: []float64{50, 100, 80}},
+		}},
+		{Kind: KindLine, Times: []time.Time{day, day.AddDate(0, 0, 1)}, Series: []Series{{Values: []float64{1e9, 1.2e9}}}},
+		{Kind: KindBar, Labels: []string{"BTC", "ETH"}, Series: []Series{{Values: []float64{1.2e12, 4e11}}}},
+		// a flat bar chart is drawn too
+		{Kind: KindBar, Labels: []string{"a"}, Series: []Series{{Values: []float64{0}}}},
+		{Kind: KindHistogram, Labels: []string{"0-1h", "1-2h", "2-3h"}, Series: []Series{{Values: []float64{3, 5, 2}}}},
+		{Kind: KindHistogram, Bins: 4, Series: []Series{{Values: []float64{1, 2, 2, 3, 8, 9}}}},
+		{Kind: KindPie, Labels: []string{"win", "loss", "none"}, Series: []Series{{Values: []float64{7, 3, 0}}}},
+	}
+	for _, spec := range specs {
+		var buf bytes.Buffer
+		assert.Nil(t, Render(&buf, spec, FormatPNG), spec.Kind)
+		img, err := png.Decode(&buf)
+		assert.Nil(t, err)
+		assert.Equal(t, defaultWidth, img.Bounds().Dx())
+
+		buf.Reset()
+		assert.Nil(t, Render(&buf, spec, FormatSVG), spec.Kind)
+		assert.Contains(t, buf.String(), "<svg")
+	}
+
+	var buf bytes.Buffer
+	assert.ErrorIs(t, Render(&buf, &Spec{Kind: KindBar}, FormatPNG), ErrNoData)
+	assert.ErrorIs(t, Render(&buf, &Spec{Kind: KindLine, Series: []Series{{Values: []float64{1}}}}, FormatPNG), ErrNoData)
+	assert.ErrorIs(t, Render(&buf, &Spec{Kind: KindPie, Series: []Series{{Values: []float64{0, 0}}}}, FormatPNG), ErrNoData)
+	assert.EqualError(t, Render(&buf, &Spec{Kind: "radar", Series: []Series{{Values: []float64{1}}}}, FormatPNG), "unsupported chart kind: radar")
+}
+
+func TestBucket(t *testing.T) {
+	labels, counts := Bucket([]float64{1, 2, 2, 3, 8, 9}, 4)
+	assert.Equal(t, []string{"1", "3", "5", "7"}, labels)
+	assert.Equal(t, []float64{3, 1, 0, 2}, counts)
+
+	labels, counts = Bucket([]float64{5, 5}, 4)
+	assert.Equal(t, []string{"5"}, labels)
+	assert.Equal(t, []float64{2}, counts)
+}
+
+func TestID(t *testing.T) {
+	spec := &Spec{Kind: KindBar, Labels: []string{"a"}, Series: []Series{{Values: []float64{1}}}}
+	assert.Equal(t, ID(spec, FormatPNG), ID(&Spec{Kind: KindBar, Labels: []string{"a"}, Series: []Series{{Values: []float64{1}}}}, FormatPNG))
+	assert.NotEqual(t, ID(spec, FormatPNG), ID(spec, FormatSVG))
+	assert.Len(t, ID(spec, FormatPNG), 32)
+}
+
+func TestFormatValue(t *testing.T) {
+	assert.Equal(t, "1.5K", formatValue(1500.0))
+	assert.Equal(t, "2.35M", formatValue(2345678.0))
+	assert.Equal(t, "-4B", formatValue(-4e9))
+	assert.Equal(t, "0.12", formatValue(0.1234))
+}
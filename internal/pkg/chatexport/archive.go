@@ -0,0 +1,51 @@
+package chatexport
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+const fileTitleMaxLen = 40
+
+// FileName names the export of the conversation after its date, title and id, e.g. 2024-05-01-eth-tokenomics-6632....md
+func FileName(c *Conversation, format Format) string {
+	name := c.CreateTime.UTC().Format("2006-01-02")
+	if title := fileTitle(c.Title); title != "" {
+		name += "-" + title
+	}
+	return fmt.Sprintf("%s-%s.%s", name, c.ID, format)
+}
+
+// fileTitle keeps the letters and digits of the title, the other runes separate words
+func fileTitle(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	res := []rune(strings.Join(words, "-"))
+	if len(res) > fileTitleMaxLen {
+		res = []rune(strings.TrimRight(string(res[:fileTitleMaxLen]), "-"))
+	}
+	return string(res)
+}
+
+// WriteZip writes every conversation as a file of the zip
+func WriteZip(w io.Writer, format Format, convs []*Conversation) error {
+	zw := zip.NewWriter(w)
+	for _, c := range convs {
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     FileName(c, format),
+			Method:   zip.Deflate,
+			Modified: c.CreateTime,
+		})
+		if err != nil {
+			return err
+		}
+		if err := Write(f, format, c); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,268 @@
+// Package chatexport renders chat histories as documents that can be pasted into notes.
+// The structured assistant answers are flattened into tables.
+package chatexport
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+)
+
+type Format = string
+
+const (
+	FormatMarkdown Format = "md"
+	FormatJSON     Format = "json"
+	FormatHTML     Format = "html"
+)
+
+func ValidFormat(format Format) bool {
+	switch format {
+	case FormatMarkdown, FormatJSON, FormatHTML:
+		return true
+	}
+	return false
+}
+
+func ContentType(format Format) string {
+	switch format {
+	case FormatJSON:
+		return "application/json; charset=utf-8"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	}
+	return "text/markdown; charset=utf-8"
+}
+
+type Conversation struct {
+	ID         string     `json:"id"`
+	Title      string     `json:"title"`
+	CreateTime time.Time  `json:"create_time"`
+	Msgs       []*Message `json:"msgs"`
+}
+
+type Message struct {
+	Index int64     `json:"index"`
+	Role  string    `json:"role"`
+	Time  time.Time `json:"time"`
+	// Content is the stored message, kept as is in the json export
+	Content *model.ChatMsg `json:"content"`
+	Blocks  []*Block       `json:"-"`
+}
+
+// Block is a section of a rendered message, one of Text, Table or Link is set
+type Block struct {
+	Heading string `json:"heading,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Table   *Table `json:"table,omitempty"`
+	Link    string `json:"link,omitempty"`
+}
+
+func NewMessage(h *model.ChatHistory) *Message {
+	return &Message{
+		Index:   int64(h.Index),
+		Role:    h.Msg.Role,
+		Time:    time.Time(h.CreateTime),
+		Content: &h.Msg,
+		Blocks:  MsgBlocks(&h.Msg),
+	}
+}
+
+// Write renders the conversation in the format
+func Write(w io.Writer, format Format, c *Conversation) error {
+	switch format {
+	case FormatJSON:
+		return WriteJSON(w, c)
+	case FormatHTML:
+		return WriteHTML(w, c)
+	}
+	return WriteMarkdown(w, c)
+}
+
+// MsgBlocks renders the content of the message according to its role and, for the assistant, its type
+func MsgBlocks(msg *model.ChatMsg) []*Block {
+	switch msg.Role {
+	case model.ChatMsgRoleUser:
+		if msg.ContentUser != nil {
+			return textBlocks(msg.ContentUser.Content)
+		}
+	case model.ChatMsgRoleUserBuiltin:
+		if msg.ContentUserBuiltin != nil {
+			return textBlocks(msg.ContentUserBuiltin.Content)
+		}
+	case model.ChatMsgRoleSystem:
+		if msg.ContentSystem != nil {
+			return textBlocks(msg.ContentSystem.Content)
+		}
+	case model.ChatMsgRoleAssistant:
+		if msg.ContentAssistant != nil {
+			return assistantBlocks(msg.ContentAssistant)
+		}
+	}
+	return nil
+}
+
+func assistantBlocks(c *model.ChatContentAssistant) []*Block {
+	blocks := textBlocks(c.Tips)
+	switch {
+	case c.GeneralAnswer != nil:
+		blocks = append(blocks, textBlocks(c.GeneralAnswer.GeneralAnswer.Content)...)
+	case c.ProjectInfo != nil:
+		blocks = append(blocks, projectBlocks(&c.ProjectInfo.Project, c.ProjectInfo.View)...)
+	case c.ProjectCompare != nil:
+		blocks = append(blocks, compareBlocks(c.ProjectCompare.Projects, c.ProjectCompare.View)...)
+	case c.SwapInfo != nil:
+		blocks = append(blocks, valueBlocks("Swap", c.SwapInfo.Swap.FuncCallingRet.FCSwapResult)...)
+	case c.DailyNewToken != nil:
+		if vo := c.DailyNewToken.NewToken.FuncCallingRet.NewTokenResult.DailyNewToken; vo != nil {
+			blocks = append(blocks, valueBlocks("Daily New Tokens", vo.Rows)...)
+		}
+	case c.TokenLaunchedTime != nil:
+		if vo := c.TokenLaunchedTime.LaunchedTimeDistribution.FuncCallingRet.TokenLTD.LaunchTimeDt; vo != nil {
+			blocks = append(blocks, valueBlocks("Token Launched Time Distribution", vo.Rows)...)
+		}
+	case c.TokenSwapCount != nil:
+		if vo := c.TokenSwapCount.TokenSwapCount.FuncCallingRet.TokenSwapCount.TxCounts; vo != nil {
+			blocks = append(blocks, valueBlocks("Daily Token Swap Count", vo.Rows)...)
+		}
+	case c.TopTrader != nil:
+		if vo := c.TopTrader.TopTrader.FuncCallingRet.TopTrader.TopTraders; vo != nil {
+			blocks = append(blocks, valueBlocks("Top Traders", vo.Rows)...)
+		}
+	case c.TraderOverview != nil:
+		blocks = append(blocks, valueBlocks("Trader Overview", c.TraderOverview.TraderOverview.FuncCallingRet.TraderOverview.TraderDetails)...)
+	case c.Uniswap != nil:
+		if url := c.Uniswap.Uniswap.FuncCallingRet.FCUniswapResult.Url; url != "" {
+			blocks = append(blocks, &Block{Heading: "Uniswap", Link: url})
+		}
+	case c.Tool != nil:
+		ret := c.Tool.Tool.FuncCallingRet
+		heading := humanize(c.Tool.View)
+		if ret.ToolResult != nil {
+			blocks = append(blocks, valueBlocks(heading, ret.ToolResult)...)
+		} else {
+			blocks = append(blocks, valueBlocks(heading, ret.RemoteFunctionResult)...)
+		}
+	default:
+		blocks = append(blocks, textBlocks(c.Fill)...)
+	}
+	if c.ChartURL != "" {
+		blocks = append(blocks, &Block{Heading: "Chart", Link: c.ChartURL})
+	}
+	return blocks
+}
+
+// projectViews are the sections of a project in display order
+var projectViews = []model.ChatContentAssistantProjectInfoView{
+	model.ChatContentAssistantProjectInfoViewOverview,
+	model.ChatContentAssistantProjectInfoViewTokenomics,
+	model.ChatContentAssistantProjectInfoViewProfitability,
+	model.ChatContentAssistantProjectInfoViewTeam,
+	model.ChatContentAssistantProjectInfoViewFunding,
+	model.ChatContentAssistantProjectInfoViewExchanges,
+}
+
+func projectSection(p *model.ChatContentAssistantProjectInfo, view model.ChatContentAssistantProjectInfoView) any {
+	switch view {
+	case model.ChatContentAssistantProjectInfoViewOverview:
+		return p.Overview
+	case model.ChatContentAssistantProjectInfoViewTokenomics:
+		return p.Tokenomics
+	case model.ChatContentAssistantProjectInfoViewProfitability:
+		return p.Profitability
+	case model.ChatContentAssistantProjectInfoViewTeam:
+		return p.Team
+	case model.ChatContentAssistantProjectInfoViewFunding:
+		return p.Funding
+	case model.ChatContentAssistantProjectInfoViewExchanges:
+		return p.Exchanges
+	}
+	return nil
+}
+
+// sectionViews are the sections shown for the view, every section that has data for the view all
+func sectionViews(view model.ChatContentAssistantProjectInfoView) []model.ChatContentAssistantProjectInfoView {
+	for _, v := range projectViews {
+		if v == view {
+			return []model.ChatContentAssistantProjectInfoView{v}
+		}
+	}
+	return projectViews
+}
+
+func projectBlocks(p *model.ChatContentAssistantProjectInfo, view model.ChatContentAssistantProjectInfoView) []*Block {
+	var blocks []*Block
+	for _, v := range sectionViews(view) {
+		blocks = append(blocks, valueBlocks(humanize(v), projectSection(p, v))...)
+	}
+	return blocks
+}
+
+// compareBlocks puts the projects side by side, a column per project and a row per scalar field of the section.
+// The nested fields (team members, funding rounds...) do not line up and are listed per project.
+func compareBlocks(projects []model.ChatContentAssistantProjectInfo, view model.ChatContentAssistantProjectInfoView) []*Block {
+	var blocks []*Block
+	for _, v := range sectionViews(view) {
+		t := &Table{Header: []string{"Field"}}
+		var rowNames []string
+		rows := map[string][]string{}
+		var nested []*Block
+		for i := range projects {
+			name := projectName(&projects[i], i)
+			t.Header = append(t.Header, name)
+			for _, b := range valueBlocks(name, projectSection(&projects[i], v)) {
+				if b.Heading != name {
+					b.Heading = name + " " + b.Heading
+					nested = append(nested, b)
+					continue
+				}
+				if b.Table == nil {
+					continue
+				}
+				for _, row := range b.Table.Rows {
+					if _, ok := rows[row[0]]; !ok {
+						rowNames = append(rowNames, row[0])
+						rows[row[0]] = make([]string, len(projects))
+					}
+					rows[row[0]][i] = row[1]
+				}
+			}
+		}
+		if len(rowNames) == 0 && len(nested) == 0 {
+			continue
+		}
+		for _, name := range rowNames {
+			t.Rows = append(t.Rows, append([]string{name}, rows[name]...))
+		}
+		blocks = append(blocks, &Block{Heading: humanize(v), Table: t})
+		blocks = append(blocks, nested...)
+	}
+	return blocks
+}
+
+func projectName(p *model.ChatContentAssistantProjectInfo, i int) string {
+	if p.Overview != nil && p.Overview.Name != "" {
+		return p.Overview.Name
+	}
+	if p.Tokenomics != nil && p.Tokenomics.TokenName != "" {
+		return p.Tokenomics.TokenName
+	}
+	return fmt.Sprintf("Project %d", i+1)
+}
+
+func textBlocks(text string) []*Block {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	return []*Block{{Text: text}}
+}
+
+func sortRows(rows [][]string) {
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0] < rows[j][0]
+	})
+}
@@ -0,0 +1,171 @@
+package chatexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	pumpmodel "github.com/wyt-labs/wyt-core/internal/core/component/datapuller/model"
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+)
+
+func assistantMsg(c *model.ChatContentAssistant) *model.ChatMsg {
+	return &model.ChatMsg{Role: model.ChatMsgRoleAssistant, ContentAssistant: c}
+}
+
+func TestMsgBlocks_ProjectCompare(t *testing.T) {
+	blocks := MsgBlocks(assistantMsg(&model.ChatContentAssistant{
+		Type: model.ChatContentAssistantTypeProjectCompare,
+		Tips: "ETH vs SOL",
+		ProjectCompare: &model.ChatContentAssistantProjectCompareRes{
+			View: model.ChatContentAssistantProjectInfoViewOverview,
+			Projects: []model.ChatContentAssistantProjectInfo{
+				{ID: primitive.NewObjectID(), Overview: &model.ChatContentAssistantProjectOverview{Name: "Ethereum", LogoURL: "https://logo", TokenSymbol: "ETH", TokenPrice: 3000.5}},
+				{Overview: &model.ChatContentAssistantProjectOverview{Name: "Solana", TokenSymbol: "SOL", TokenMarketCap: 100,
+					Tags: []model.ChatContentAssistantProjectInfoTag{{ID: primitive.NewObjectID(), Name: "L1"}}}},
+			},
+		},
+	}))
+	assert.Len(t, blocks, 3)
+	assert.Equal(t, "ETH vs SOL", blocks[0].Text)
+	assert.Equal(t, "Overview", blocks[1].Heading)
+	assert.Equal(t, &Table{
+		Header: []string{"Field", "Ethereum", "Solana"},
+		Rows: [][]string{
+			{"Name", "Ethereum", "Solana"},
+			{"Token Symbol", "ETH", "SOL"},
+			{"Token Price", "3000.5", ""},
+			{"Token Market Cap", "", "100"},
+		},
+	}, blocks[1].Table)
+	// ids and logos are left out, nested lists are kept per project
+	assert.Equal(t, "Solana Tags", blocks[2].Heading)
+	assert.Equal(t, &Table{Header: []string{"Name", "Description"}, Rows: [][]string{{"L1", ""}}}, blocks[2].Table)
+}
+
+func TestMsgBlocks_TraderOverview(t *testing.T) {
+	blocks := MsgBlocks(assistantMsg(&model.ChatContentAssistant{
+		TraderOverview: &model.ChatContentAssistantTraderOverviewRes{
+			TraderOverview: model.ChatContentAssistantInfo{FuncCallingRet: model.FuncCallingRet{
+				TraderOverview: model.TraderOverviewFuncCallingResult{TraderDetails: &pumpmodel.TraderDetailVO{
+					Trader: &pumpmodel.Trader{Address: "abc"},
+					Profit: []*pumpmodel.TraderProfitData{{NetProfit: 1.25, GrossProfit: 2, Date: "2024-05-01"}},
+				}},
+			}},
+		},
+	}))
+	assert.Len(t, blocks, 3)
+	assert.Equal(t, "Trader Overview", blocks[0].Heading)
+	assert.Nil(t, blocks[0].Table)
+	assert.Equal(t, &Table{Header: []string{"Field", "Value"}, Rows: [][]string{{"Address", "abc"}}}, blocks[1].Table)
+	assert.Equal(t, "Profit", blocks[2].Heading)
+	assert.Equal(t, &Table{
+		Header: []string{"Net Profit", "Gross Profit", "Date"},
+		Rows:   [][]string{{"1.25", "2", "2024-05-01"}},
+	}, blocks[2].Table)
+}
+
+func TestMsgBlocks_Chart(t *testing.T) {
+	blocks := MsgBlocks(assistantMsg(&model.ChatContentAssistant{
+		Tips:     "Here is the answer:",
+		ChartURL: "http://wyt.test/api/v1/fs/files/chart/abc",
+	}))
+	assert.Len(t, blocks, 2)
+	assert.Equal(t, &Block{Heading: "Chart", Link: "http://wyt.test/api/v1/fs/files/chart/abc"}, blocks[1])
+}
+
+func TestMsgBlocks_Tool(t *testing.T) {
+	blocks := MsgBlocks(assistantMsg(&model.ChatContentAssistant{
+		Tool: &model.ChatContentAssistantToolRes{
+			View: "gas_price",
+			Tool: model.ChatContentAssistantInfo{FuncCallingRet: model.FuncCallingRet{
+				ToolResult: map[string]any{"slow": 1.5, "fast": 3, "nested": map[string]any{}},
+			}},
+		},
+	}))
+	assert.Len(t, blocks, 1)
+	assert.Equal(t, "Gas Price", blocks[0].Heading)
+	assert.Equal(t, [][]string{{"fast", "3"}, {"slow", "1.5"}}, blocks[0].Table.Rows)
+}
+
+func testConversation() *Conversation {
+	created := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	user := &model.ChatHistory{Index: 0, Msg: model.ChatMsg{Role: model.ChatMsgRoleUser, ContentUser: &model.ChatContentUser{Content: "swap <b>1 ETH</b>"}}}
+	user.CreateTime = model.JSONTime(created)
+	ai := &model.ChatHistory{Index: 1, Msg: *assistantMsg(&model.ChatContentAssistant{
+		Type: model.ChatContentAssistantTypeSwap,
+		SwapInfo: &model.ChatContentAssistantSwapRes{
+			Swap: model.ChatContentAssistantInfo{FuncCallingRet: model.FuncCallingRet{
+				FCSwapResult: model.SwapFuncCallingResult{SwapInToken: "ETH", AmountIn: 1, SwapOutToken: "USDC|x"},
+			}},
+		},
+	})}
+	return &Conversation{
+		ID:         "6632",
+		Title:      "Swap ETH / USDC?",
+		CreateTime: created,
+		Msgs:       []*Message{NewMessage(user), NewMessage(ai)},
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteMarkdown(&buf, testConversation()))
+	out := buf.String()
+	assert.Contains(t, out, "# Swap ETH / USDC?\n")
+	assert.Contains(t, out, "## User · 2024-05-01 08:00:00 UTC\n\nswap <b>1 ETH</b>\n")
+	assert.Contains(t, out, "### Swap\n\n| Field | Value |\n| --- | --- |\n| Swap In Token | ETH |\n| Amount In | 1 |\n| Swap Out Token | USDC\\|x |\n")
+}
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteHTML(&buf, testConversation()))
+	out := buf.String()
+	assert.Contains(t, out, "swap &lt;b&gt;1 ETH&lt;/b&gt;")
+	assert.Contains(t, out, "<td>Swap In Token</td><td>ETH</td>")
+	assert.NotContains(t, out, "<b>")
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteJSON(&buf, testConversation()))
+	var res map[string]any
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &res))
+	msgs := res["msgs"].([]any)
+	assert.Len(t, msgs, 2)
+	content := msgs[1].(map[string]any)["content"].(map[string]any)
+	assert.Equal(t, "swap", content["content_assistant"].(map[string]any)["type"])
+}
+
+func TestFileName(t *testing.T) {
+	c := testConversation()
+	assert.Equal(t, "2024-05-01-swap-eth-usdc-6632.md", FileName(c, FormatMarkdown))
+	c.Title = "以太坊 代币经济"
+	assert.Equal(t, "2024-05-01-以太坊-代币经济-6632.html", FileName(c, FormatHTML))
+	c.Title = "??"
+	assert.Equal(t, "2024-05-01-6632.json", FileName(c, FormatJSON))
+	assert.Equal(t, "this-is-a-very-long-title-that-goes-on-a", fileTitle("This is a very long title that goes on and on"))
+}
+
+func TestWriteZip(t *testing.T) {
+	second := testConversation()
+	second.ID = "6633"
+	var buf bytes.Buffer
+	assert.Nil(t, WriteZip(&buf, FormatMarkdown, []*Conversation{testConversation(), second}))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+	assert.Len(t, zr.File, 2)
+	assert.Equal(t, "2024-05-01-swap-eth-usdc-6633.md", zr.File[1].Name)
+	f, err := zr.File[0].Open()
+	assert.Nil(t, err)
+	data, err := io.ReadAll(f)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "# Swap ETH / USDC?")
+}
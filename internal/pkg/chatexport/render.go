@@ -0,0 +1,151 @@
+package chatexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+const timeLayout = "2006-01-02 15:04:05 UTC"
+
+var roleNames = map[string]string{
+	"user":         "User",
+	"user_builtin": "User",
+	"system":       "System",
+	"assistant":    "Assistant",
+}
+
+func roleName(role string) string {
+	if name, ok := roleNames[role]; ok {
+		return name
+	}
+	return role
+}
+
+func WriteJSON(w io.Writer, c *Conversation) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+func WriteMarkdown(w io.Writer, c *Conversation) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", mdInline(c.Title))
+	if !c.CreateTime.IsZero() {
+		fmt.Fprintf(&b, "_Created %s_\n\n", c.CreateTime.UTC().Format(timeLayout))
+	}
+	for _, msg := range c.Msgs {
+		fmt.Fprintf(&b, "## %s", roleName(msg.Role))
+		if !msg.Time.IsZero() {
+			fmt.Fprintf(&b, " · %s", msg.Time.UTC().Format(timeLayout))
+		}
+		b.WriteString("\n\n")
+		for _, block := range msg.Blocks {
+			if block.Heading != "" {
+				fmt.Fprintf(&b, "### %s\n\n", mdInline(block.Heading))
+			}
+			switch {
+			case block.Table != nil:
+				writeMarkdownTable(&b, block.Table)
+			case block.Link != "":
+				fmt.Fprintf(&b, "<%s>\n\n", block.Link)
+			case block.Text != "":
+				b.WriteString(strings.TrimSpace(block.Text))
+				b.WriteString("\n\n")
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownTable(b *strings.Builder, t *Table) {
+	cells := func(row []string) {
+		b.WriteString("|")
+		for _, cell := range row {
+			b.WriteString(" ")
+			b.WriteString(mdCell(cell))
+			b.WriteString(" |")
+		}
+		b.WriteString("\n")
+	}
+	cells(t.Header)
+	b.WriteString("|")
+	b.WriteString(strings.Repeat(" --- |", len(t.Header)))
+	b.WriteString("\n")
+	for _, row := range t.Rows {
+		cells(row)
+	}
+	b.WriteString("\n")
+}
+
+// mdCell keeps a value on one line of its table cell
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+func mdInline(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var htmlTemplate = template.Must(template.New("chat").Funcs(template.FuncMap{
+	"role": roleName,
+	"time": func(c *Conversation) string {
+		if c.CreateTime.IsZero() {
+			return ""
+		}
+		return c.CreateTime.UTC().Format(timeLayout)
+	},
+	"msgTime": func(m *Message) string {
+		if m.Time.IsZero() {
+			return ""
+		}
+		return m.Time.UTC().Format(timeLayout)
+	},
+	"safeURL": func(s string) template.URL {
+		// the links come from tool results, anything but http(s) is dropped
+		if strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://") {
+			return template.URL(s)
+		}
+		return ""
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #222; }
+.msg { border-top: 1px solid #ddd; padding: .5em 0 1em; }
+.role { font-weight: 600; }
+.time { color: #888; font-size: .85em; margin-left: .5em; }
+.text { white-space: pre-wrap; }
+table { border-collapse: collapse; margin: .5em 0; }
+th, td { border: 1px solid #ccc; padding: .25em .6em; text-align: left; vertical-align: top; }
+th { background: #f5f5f5; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with time .}}<p class="time">Created {{.}}</p>{{end}}
+{{range .Msgs}}<div class="msg">
+<div><span class="role">{{role .Role}}</span>{{with msgTime .}}<span class="time">{{.}}</span>{{end}}</div>
+{{range .Blocks}}{{with .Heading}}<h3>{{.}}</h3>
+{{end}}{{if .Table}}<table>
+<tr>{{range .Table.Header}}<th>{{.}}</th>{{end}}</tr>
+{{range .Table.Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{else if .Link}}<p><a href="{{safeURL .Link}}">{{.Link}}</a></p>
+{{else if .Text}}<div class="text">{{.Text}}</div>
+{{end}}{{end}}</div>
+{{end}}</body>
+</html>
+`))
+
+func WriteHTML(w io.Writer, c *Conversation) error {
+	return htmlTemplate.Execute(w, c)
+}
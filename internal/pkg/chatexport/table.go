@@ -0,0 +1,242 @@
+package chatexport
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+)
+
+var (
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	jsonTimeType = reflect.TypeOf(model.JSONTime{})
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// Table is a rendered table, a struct renders as a Field/Value table and a slice of structs with a column per field
+type Table struct {
+	Header []string   `json:"header"`
+	Rows   [][]string `json:"rows"`
+}
+
+// valueBlocks renders v as tables: the scalar fields of a struct in one table, each nested struct or
+// slice of structs in its own block. Empty values and ids are left out.
+func valueBlocks(heading string, v any) []*Block {
+	rv := indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		return structBlocks(heading, rv)
+	case reflect.Slice, reflect.Array:
+		if t := sliceTable(rv); t != nil {
+			return []*Block{{Heading: heading, Table: t}}
+		}
+	case reflect.Map:
+		if t := mapTable(rv); t != nil {
+			return []*Block{{Heading: heading, Table: t}}
+		}
+	}
+	if s := scalar(rv); s != "" {
+		return []*Block{{Heading: heading, Text: s}}
+	}
+	return nil
+}
+
+func structBlocks(heading string, rv reflect.Value) []*Block {
+	kv := &Table{Header: []string{"Field", "Value"}}
+	var nested []*Block
+	for i := 0; i < rv.NumField(); i++ {
+		name, ok := fieldName(rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		f := indirect(rv.Field(i))
+		if !f.IsValid() || f.IsZero() {
+			continue
+		}
+		if isScalar(f) {
+			if s := scalar(f); s != "" {
+				kv.Rows = append(kv.Rows, []string{name, s})
+			}
+			continue
+		}
+		nested = append(nested, valueBlocks(name, f.Interface())...)
+	}
+	var res []*Block
+	if len(kv.Rows) != 0 {
+		res = append(res, &Block{Heading: heading, Table: kv})
+	} else if heading != "" && len(nested) != 0 {
+		res = append(res, &Block{Heading: heading})
+	}
+	return append(res, nested...)
+}
+
+func sliceTable(rv reflect.Value) *Table {
+	if rv.Len() == 0 {
+		return nil
+	}
+	elem := rv.Type().Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct || elem == jsonTimeType || elem == timeType {
+		if s := scalar(rv); s != "" {
+			return &Table{Header: []string{"Value"}, Rows: [][]string{{s}}}
+		}
+		return nil
+	}
+
+	var fields []int
+	t := &Table{}
+	for i := 0; i < elem.NumField(); i++ {
+		name, ok := fieldName(elem.Field(i))
+		if !ok || !isScalarType(elem.Field(i).Type) {
+			continue
+		}
+		fields = append(fields, i)
+		t.Header = append(t.Header, name)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	for i := 0; i < rv.Len(); i++ {
+		item := indirect(rv.Index(i))
+		if !item.IsValid() {
+			continue
+		}
+		row := make([]string, 0, len(fields))
+		for _, f := range fields {
+			row = append(row, scalar(indirect(item.Field(f))))
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+func mapTable(rv reflect.Value) *Table {
+	t := &Table{Header: []string{"Field", "Value"}}
+	keys := rv.MapKeys()
+	for _, k := range keys {
+		if s := scalar(indirect(rv.MapIndex(k))); s != "" {
+			t.Rows = append(t.Rows, []string{fmt.Sprint(k.Interface()), s})
+		}
+	}
+	if len(t.Rows) == 0 {
+		return nil
+	}
+	// map order is random
+	sortRows(t.Rows)
+	return t
+}
+
+// fieldName is the readable name of an exported field from its json name, false for the fields left out
+func fieldName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() || f.Type == objectIDType {
+		return "", false
+	}
+	if f.Type.Kind() == reflect.Slice && f.Type.Elem() == objectIDType {
+		return "", false
+	}
+	name := f.Name
+	if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag != "" {
+		if tag == "-" {
+			return "", false
+		}
+		name = tag
+	}
+	// pictures do not fit in a note
+	if strings.HasSuffix(name, "logo_url") || strings.HasSuffix(name, "picture_url") || strings.HasSuffix(name, "avatar_url") {
+		return "", false
+	}
+	return humanize(name), true
+}
+
+func humanize(name string) string {
+	words := strings.Split(name, "_")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isScalar(v reflect.Value) bool {
+	return isScalarType(v.Type())
+}
+
+func isScalarType(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == jsonTimeType || t == timeType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		return false
+	case reflect.Slice, reflect.Array:
+		return isScalarType(t.Elem())
+	}
+	return true
+}
+
+func scalar(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Type() {
+	case jsonTimeType:
+		return formatTime(time.Time(v.Interface().(model.JSONTime)))
+	case timeType:
+		return formatTime(v.Interface().(time.Time))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Slice, reflect.Array:
+		if !isScalarType(v.Type().Elem()) {
+			return ""
+		}
+		var items []string
+		for i := 0; i < v.Len(); i++ {
+			if s := scalar(indirect(v.Index(i))); s != "" {
+				items = append(items, s)
+			}
+		}
+		return strings.Join(items, ", ")
+	}
+	return ""
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timeLayout)
+}
@@ -16,6 +16,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/wyt-labs/wyt-core/pkg/log"
 	"github.com/wyt-labs/wyt-core/pkg/util"
@@ -37,10 +38,23 @@ const (
 	MarketDriverTypeOkx     = "okx"
 )
 
+const (
+	PumpDataSourceMetabase = "metabase"
+	PumpDataSourceSQL      = "sql"
+)
+
 func DefaultConfig(rootPath string) *Config {
 	return &Config{
 		RootPath: rootPath,
 		App:      App{},
+		Backends: Backends{
+			MetabaseCards:  DefaultMetabaseCards(),
+			PumpDataSource: PumpDataSourceMetabase,
+			PumpDataSQL: PumpDataSQL{
+				Driver:       "postgres",
+				QueryTimeout: Duration(30 * time.Second),
+			},
+		},
 	}
 }
 
@@ -0,0 +1,104 @@
+package config
+
+const (
+	MetabaseParamTypeNumber   = "number/="
+	MetabaseParamTypeCategory = "category"
+)
+
+// DefaultMetabaseCards are the cards of the pump data queries, the variant names are lowercase because
+// the config keys are not case sensitive. The columns are the ones the rows are decoded from.
+func DefaultMetabaseCards() map[string]MetabaseCard {
+	days := MetabaseCardParam{Name: "days", Type: MetabaseParamTypeNumber}
+	trader := MetabaseCardParam{Name: "trader", Type: MetabaseParamTypeCategory}
+	// the v1 card has more columns, they are not used
+	traderOverview := []string{"total_net_profit", "net_profit_win_ratio", "traded_token_count", "avg_sol_cost_per_token",
+		"avg_fee_per_token", "avg_tip_per_token", "created_token_count"}
+	return map[string]MetabaseCard{
+		"daily_launched_tokens": {
+			DefaultVariant: "utc",
+			Variants: map[string]MetabaseCardVariant{
+				"utc": {CardID: 106, ParamIDs: map[string]string{"days": "bb79b15e-1245-4166-96f0-c6baaa71567c"}},
+				"cst": {CardID: 115, ParamIDs: map[string]string{"days": "a3066d17-b2fc-4d12-bf4a-92f81ab71d66"}},
+			},
+			Params:  []MetabaseCardParam{days},
+			Columns: []string{"day", "total_count", "p2r_count", "p2r_ratio"},
+		},
+		"launched_token_time_distribution": {
+			DefaultVariant: "utc",
+			Variants: map[string]MetabaseCardVariant{
+				"utc": {CardID: 107, ParamIDs: map[string]string{"days": "4afba805-4047-477a-b1aa-399e06f5f5e4"}},
+				"cst": {CardID: 114, ParamIDs: map[string]string{"days": "a9330250-fca8-46b2-88ed-0fef40ef981e"}},
+			},
+			Params:  []MetabaseCardParam{days},
+			Columns: []string{"time_range", "launched_count"},
+		},
+		"daily_trade_counts": {
+			DefaultVariant: "utc",
+			Variants: map[string]MetabaseCardVariant{
+				"utc": {CardID: 108, ParamIDs: map[string]string{"days": "59c5518a-8697-4d92-8531-4ba301e6ab85"}},
+				"cst": {CardID: 116, ParamIDs: map[string]string{"days": "59c5518a-8697-4d92-8531-4ba301e6ab85"}},
+			},
+			Params:  []MetabaseCardParam{days},
+			Columns: []string{"day", "trade_count"},
+		},
+		"trader_overview": {
+			DefaultVariant: "utc",
+			Variants: map[string]MetabaseCardVariant{
+				"utc": {CardID: 110, ParamIDs: map[string]string{"trader": "cd81bc1d-4a11-4375-84ea-e018c5d9ddef"}},
+			},
+			Params:  []MetabaseCardParam{trader},
+			Columns: traderOverview,
+		},
+		"trader_overview_v2": {
+			// the timezone is a parameter of the card
+			DefaultVariant: "all",
+			Variants: map[string]MetabaseCardVariant{
+				"all": {CardID: 140, ParamIDs: map[string]string{
+					"trader": "3332d084-956d-489e-87f2-e634c4d0e42d",
+					"tz":     "d988ac7f-ecb0-40d7-8123-5410e5598ebc",
+					"days":   "a9218770-8bb2-474d-ab33-11ffdc7e2052",
+				}},
+			},
+			Params:  []MetabaseCardParam{trader, {Name: "tz", Type: MetabaseParamTypeCategory}, days},
+			Columns: traderOverview,
+		},
+		"trader_tx_time_distribution": {
+			DefaultVariant: "utc",
+			Variants: map[string]MetabaseCardVariant{
+				"utc": {CardID: 112, ParamIDs: map[string]string{"trader": "b8f94534-7734-4e40-b8e4-212e0d876cda", "days": "e164924c-5361-411d-82de-34de55cd3e67"}},
+				"cst": {CardID: 120, ParamIDs: map[string]string{"trader": "b8f94534-7734-4e40-b8e4-212e0d876cda", "days": "e164924c-5361-411d-82de-34de55cd3e67"}},
+			},
+			Params:  []MetabaseCardParam{trader, days},
+			Columns: []string{"time_range", "tx_count"},
+		},
+		"trader_profit_token_distribution": {
+			DefaultVariant: "utc",
+			Variants: map[string]MetabaseCardVariant{
+				"utc": {CardID: 113, ParamIDs: map[string]string{"trader": "586e6393-9ff0-4eac-9918-d10984d441c7", "days": "43a557d5-fbc8-4cd7-b8e4-101564b47695"}},
+				"cst": {CardID: 119, ParamIDs: map[string]string{"trader": "596faff0-6ef4-42cf-be21-b854f2db58af", "days": "208313ef-a20b-427c-a4ea-9d154e053aed"}},
+			},
+			Params:  []MetabaseCardParam{trader, days},
+			Columns: []string{"profit_margin_bucket", "token_count"},
+		},
+		"trader_profit_distribution": {
+			DefaultVariant: "cst",
+			Variants: map[string]MetabaseCardVariant{
+				"utc": {CardID: 111, ParamIDs: map[string]string{"trader": "90848092-e63d-4b0a-8e07-265fd413e2f2", "days": "a8098d1f-6d88-4e64-8b3f-4ee5a0e92994"}},
+				"cst": {CardID: 118, ParamIDs: map[string]string{"trader": "f4bba41b-2de9-4fa1-9651-96d696aa221e", "days": "158050e1-75eb-4729-8567-1214ba6ff071"}},
+			},
+			Params:  []MetabaseCardParam{trader, days},
+			Columns: []string{"day", "net_profit", "gross_profit"},
+		},
+		"top_traders": {
+			// one card per period
+			DefaultVariant: "1d",
+			Variants: map[string]MetabaseCardVariant{
+				"1d":  {CardID: 131, ParamIDs: map[string]string{"win_ratio": "ec83280c-ba44-4968-9899-4a37d4f8a318"}},
+				"7d":  {CardID: 124, ParamIDs: map[string]string{"win_ratio": "6f67544d-f340-4dd4-91ce-56a225d95a25"}},
+				"30d": {CardID: 125, ParamIDs: map[string]string{"win_ratio": "04f74b13-e4df-4836-b94b-72c1170dffcd"}},
+			},
+			Params:  []MetabaseCardParam{{Name: "win_ratio", Type: MetabaseParamTypeNumber}},
+			Columns: []string{"trader", "total_net_profit", "net_profit_win_ratio", "gross_profit_win_ratio", "total_tx_count"},
+		},
+	}
+}
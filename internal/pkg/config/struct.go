@@ -113,6 +113,55 @@ type Backends struct {
 	MetabaseURL      string `mapstructure:"metabase_url" toml:"metabase_url"`
 	MetabaseUserName string `mapstructure:"metabase_username" toml:"metabase_username"`
 	MetabasePassword string `mapstructure:"metabase_password" toml:"metabase_password"`
+	// saved questions of metabase by logical query name
+	MetabaseCards map[string]MetabaseCard `mapstructure:"metabase_cards" toml:"metabase_cards"`
+	// backend of the pump data queries: metabase or sql
+	PumpDataSource string      `mapstructure:"pump_data_source" toml:"pump_data_source"`
+	PumpDataSQL    PumpDataSQL `mapstructure:"pump_data_sql" toml:"pump_data_sql"`
+}
+
+// PumpDataSQL reads the pump data from the analytics database, the queries are the sql files of
+// internal/core/component/datapuller/sql/<dialect>
+type PumpDataSQL struct {
+	// database/sql driver, only postgres is linked in
+	Driver string `mapstructure:"driver" toml:"driver"`
+	DSN    string `mapstructure:"dsn" toml:"dsn"`
+	// dialect of the sql files (postgres, sqlite), guessed from the driver when empty. The sqlite queries run
+	// in the unit tests, the postgres ones with make pump-data-sql-test. ClickHouse is not supported: no driver
+	// is linked and its queries could not be tested, it needs a dialect and sql files of its own.
+	Dialect string `mapstructure:"dialect" toml:"dialect"`
+	// query name -> version of its sql file, the latest version is used when missing
+	Versions     map[string]int `mapstructure:"versions" toml:"versions"`
+	QueryTimeout Duration       `mapstructure:"query_timeout" toml:"query_timeout"`
+	MaxOpenConns int            `mapstructure:"max_open_conns" toml:"max_open_conns"`
+}
+
+// MetabaseCard is a saved question of metabase, it can have one card per variant (the timezone of the
+// daily queries, the period of the top traders)
+type MetabaseCard struct {
+	// variant used when the query gives none
+	DefaultVariant string                         `mapstructure:"default_variant" toml:"default_variant"`
+	Variants       map[string]MetabaseCardVariant `mapstructure:"variants" toml:"variants"`
+	Params         []MetabaseCardParam            `mapstructure:"params" toml:"params"`
+	// columns the results must have (after ColumnNames), the results missing one are refused, the other
+	// columns are ignored
+	Columns []string `mapstructure:"columns" toml:"columns"`
+	// column of the card -> column the rows are decoded from (the one of the sql query), for the cards
+	// naming their columns otherwise. The names are compared without case like the config keys.
+	ColumnNames map[string]string `mapstructure:"column_names" toml:"column_names"`
+}
+
+type MetabaseCardVariant struct {
+	CardID int `mapstructure:"card_id" toml:"card_id"`
+	// template tag -> parameter id, the ids change when the card is rebuilt
+	ParamIDs map[string]string `mapstructure:"param_ids" toml:"param_ids"`
+}
+
+type MetabaseCardParam struct {
+	// template tag of the card
+	Name string `mapstructure:"name" toml:"name"`
+	// metabase parameter type: category, number/=
+	Type string `mapstructure:"type" toml:"type"`
 }
 
 type DatasourceCoincap struct {
@@ -190,16 +239,139 @@ type Twitter struct {
 }
 
 type Chatgpt struct {
+	// azure(default), openai (any openai compatible server: vLLM, llama.cpp, Ollama...) or fake
+	Provider string `mapstructure:"provider" toml:"provider"`
+	// azure: resource endpoint, openai: api root such as http://localhost:11434/v1
 	Endpoint        string  `mapstructure:"endpoint" toml:"endpoint"`
 	EndpointFull    string  `mapstructure:"endpoint_full" toml:"endpoint_full"`
 	APIKey          string  `mapstructure:"api_key" toml:"api_key"`
 	Model           string  `mapstructure:"model" toml:"model"`
+	EmbeddingModel  string  `mapstructure:"embedding_model" toml:"embedding_model"`
 	Temperature     float32 `mapstructure:"temperature" toml:"temperature"`
 	PresencePenalty float32 `mapstructure:"presence_penalty" toml:"presence_penalty"`
+	// scripted answers of the fake provider
+	FakeRules []FakeLLMRule `mapstructure:"fake_rules" toml:"fake_rules"`
+}
+
+type FakeLLMRule struct {
+	// regexp matched against the last user message
+	Pattern       string `mapstructure:"pattern" toml:"pattern"`
+	Content       string `mapstructure:"content" toml:"content"`
+	ToolName      string `mapstructure:"tool_name" toml:"tool_name"`
+	ToolArguments string `mapstructure:"tool_arguments" toml:"tool_arguments"`
+}
+
+type ChatMemory struct {
+	// number of previous messages sent along with a question, 0 only sends the question
+	MaxHistoryMsgs int `mapstructure:"max_history_msgs" toml:"max_history_msgs"`
+	// history token budget keyed by model name, turns beyond the budget are summarized in the background
+	TokenBudgets       map[string]int `mapstructure:"token_budgets" toml:"token_budgets"`
+	DefaultTokenBudget int            `mapstructure:"default_token_budget" toml:"default_token_budget"`
+}
+
+// RAG retrieves chunks of the published projects relevant to the question and adds them to the prompt
+type RAG struct {
+	Enable bool `mapstructure:"enable" toml:"enable"`
+	// number of chunks added to the prompt
+	TopK int `mapstructure:"top_k" toml:"top_k"`
+	// chunks less similar to the question (cosine) are ignored
+	MinScore float64 `mapstructure:"min_score" toml:"min_score"`
+	// max characters of one chunk
+	ChunkSize int `mapstructure:"chunk_size" toml:"chunk_size"`
+}
+
+// ToolPolicy restricts the tools offered to the model for an agent (project id), "*" applies to all agents
+// without their own policy
+type ToolPolicy struct {
+	ProjectId string `mapstructure:"project_id" toml:"project_id"`
+	// when set, only these tools are offered
+	Enabled  []string `mapstructure:"enabled" toml:"enabled"`
+	Disabled []string `mapstructure:"disabled" toml:"disabled"`
+}
+
+// LLMQuota limits the model tokens a user of the role may spend in chat, 0 is unlimited
+type LLMQuota struct {
+	// member, manager or admin
+	Role          string `mapstructure:"role" toml:"role"`
+	DailyTokens   int64  `mapstructure:"daily_tokens" toml:"daily_tokens"`
+	MonthlyTokens int64  `mapstructure:"monthly_tokens" toml:"monthly_tokens"`
+}
+
+// AgentRouter picks the agent answering each message of a chat window that is not pinned to one agent
+type AgentRouter struct {
+	Enable bool `mapstructure:"enable" toml:"enable"`
+}
+
+// ChatTitle names a chat window with the llm after its first exchange, unless the user renamed it
+type ChatTitle struct {
+	Enable bool `mapstructure:"enable" toml:"enable"`
+}
+
+// Guardrail screens the questions before they are sent to the llm (azure or the wyt ai backend)
+// and the structured answers before they are decoded
+type Guardrail struct {
+	Enable bool `mapstructure:"enable" toml:"enable"`
+	// only the rules below apply, without the built-in ones (wallet secrets, emails, phone numbers, injections)
+	DisableBuiltinRules bool            `mapstructure:"disable_builtin_rules" toml:"disable_builtin_rules"`
+	RedactRules         []GuardrailRule `mapstructure:"redact_rules" toml:"redact_rules"`
+	InjectionRules      []GuardrailRule `mapstructure:"injection_rules" toml:"injection_rules"`
+	// refuses the questions flagged as prompt injection, they are only logged otherwise
+	BlockInjection bool `mapstructure:"block_injection" toml:"block_injection"`
+	// checks the structured answers against the response schema
+	ValidateOutput bool `mapstructure:"validate_output" toml:"validate_output"`
+}
+
+type GuardrailRule struct {
+	Name string `mapstructure:"name" toml:"name"`
+	// go regexp
+	Pattern string `mapstructure:"pattern" toml:"pattern"`
+	// replaces the matches of a redaction rule, may refer to the groups of the pattern (${1}),
+	// [REDACTED:<name>] by default
+	Replacement string `mapstructure:"replacement" toml:"replacement"`
+}
+
+// ChatCache reuses the answers to the same question asked to the same agent in the same language
+type ChatCache struct {
+	Enable bool `mapstructure:"enable" toml:"enable"`
+	// ttl keyed by intention (search, compare, general) or function call type (top_trader, trader_overview...),
+	// overrides the built-in ttls
+	TTLs map[string]Duration `mapstructure:"ttls" toml:"ttls"`
+	// ttl of the intentions without their own, 0 does not cache them
+	DefaultTTL Duration `mapstructure:"default_ttl" toml:"default_ttl"`
+}
+
+// PumpDataCache keeps the results of the pump data queries, the expired ones are still served for StaleTTL
+// while they are refreshed in the background
+type PumpDataCache struct {
+	Enable bool `mapstructure:"enable" toml:"enable"`
+	// ttl keyed by query name (daily_launched_tokens, top_traders, trader_overview_v2...), overrides the built-in ttls
+	TTLs map[string]Duration `mapstructure:"ttls" toml:"ttls"`
+	// ttl of the queries without their own in TTLs, replaces the built-in ttls when set
+	DefaultTTL Duration `mapstructure:"default_ttl" toml:"default_ttl"`
+	// 0 waits for the fresh results once expired
+	StaleTTL Duration `mapstructure:"stale_ttl" toml:"stale_ttl"`
+}
+
+// Chart renders the quantitative answers (pump.fun data, metrics comparisons) as images hosted in the file system,
+// their url is sent next to the data
+type Chart struct {
+	Enable bool `mapstructure:"enable" toml:"enable"`
+	// png (default) or svg
+	Format string `mapstructure:"format" toml:"format"`
 }
 
 type Extension struct {
-	Chatgpt Chatgpt `mapstructure:"chatgpt" toml:"chatgpt"`
+	Chatgpt       Chatgpt       `mapstructure:"chatgpt" toml:"chatgpt"`
+	ChatMemory    ChatMemory    `mapstructure:"chat_memory" toml:"chat_memory"`
+	ToolPolicies  []ToolPolicy  `mapstructure:"tool_policies" toml:"tool_policies"`
+	RAG           RAG           `mapstructure:"rag" toml:"rag"`
+	LLMQuotas     []LLMQuota    `mapstructure:"llm_quotas" toml:"llm_quotas"`
+	AgentRouter   AgentRouter   `mapstructure:"agent_router" toml:"agent_router"`
+	ChatTitle     ChatTitle     `mapstructure:"chat_title" toml:"chat_title"`
+	Guardrail     Guardrail     `mapstructure:"guardrail" toml:"guardrail"`
+	ChatCache     ChatCache     `mapstructure:"chat_cache" toml:"chat_cache"`
+	Chart         Chart         `mapstructure:"chart" toml:"chart"`
+	PumpDataCache PumpDataCache `mapstructure:"pump_data_cache" toml:"pump_data_cache"`
 }
 
 type Cache struct {
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+)
+
+type AgentAddReq struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	ProjectId   string `json:"project_id"`
+	Prompt      string `json:"prompt"`
+	// registered tool names, all tools when empty
+	EnabledTools []string `json:"enabled_tools"`
+	// public (default), internal or hidden
+	Visibility    model.AgentVisibility `json:"visibility"`
+	DefaultPinned bool                  `json:"default_pinned"`
+	Order         int                   `json:"order"`
+}
+
+type AgentAddRes struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+// AgentUpdateReq replaces the fields of the agent
+type AgentUpdateReq struct {
+	ID string `json:"id"`
+	AgentAddReq
+}
+
+type AgentUpdateRes struct {
+}
+
+type AgentDeleteReq struct {
+	ID string `json:"id"`
+}
+
+type AgentDeleteRes struct {
+}
+
+type AgentAdminListReq struct {
+	Page uint64 `json:"page" form:"page"`
+	Size uint64 `json:"size" form:"size"`
+}
+
+type AgentAdminListRes struct {
+	List  []*model.Agent `json:"list"`
+	Total int64          `json:"total"`
+}
+
+// AgentPinOrderReq pins the agents in the given order, the other pinned agents follow them
+type AgentPinOrderReq struct {
+	ProjectIds []string `json:"project_ids"`
+}
@@ -34,6 +34,23 @@ type ChatUpdateReq struct {
 type ChatUpdateRes struct {
 }
 
+// ChatPinAgentReq makes the agent of the project answer all messages of the window
+type ChatPinAgentReq struct {
+	ID        string `json:"id"`
+	ProjectId string `json:"project_id"`
+}
+
+type ChatPinAgentRes struct {
+}
+
+// ChatUnpinAgentReq lets the agent router pick the agent of each message of the window again
+type ChatUnpinAgentReq struct {
+	ID string `json:"id"`
+}
+
+type ChatUnpinAgentRes struct {
+}
+
 type ChatDeleteReq struct {
 	ID string `json:"id"`
 }
@@ -68,12 +85,20 @@ type ChatMsg struct {
 	Role      model.ChatMsgRole `json:"role"`
 	Content   any               `json:"content"`
 	ProjectId string            `json:"project_id"`
+	AgentID   string            `json:"agent_id,omitempty"`
+	// index of the previous message of the branch, -1 for the first message
+	ParentIndex int64 `json:"parent_index"`
+	// indexes of the versions of the message (itself included) when it has been edited or regenerated
+	Siblings []uint64 `json:"siblings,omitempty"`
 }
 
 type ChatHistoryReq struct {
 	ID   string `json:"id" form:"id"`
 	Page uint64 `json:"page" form:"page"`
 	Size uint64 `json:"size" form:"size"`
+	// index of any message of the branch to list, the active branch by default.
+	// The branch continues with the newest version below the message.
+	Branch *int64 `json:"branch" form:"branch"`
 }
 
 type ChatHistoryRes struct {
@@ -82,6 +107,31 @@ type ChatHistoryRes struct {
 	ProjectId  string         `json:"project_id"`
 	MsgNum     uint64         `json:"msg_num"`
 	Msgs       []*ChatMsg     `json:"msgs"`
+	// index of the last message of the listed branch
+	Head int64 `json:"head"`
+}
+
+type ChatRegenerateReq struct {
+	ID string `json:"id"`
+	// index of the last answer
+	Index uint64 `json:"index"`
+}
+
+type ChatEditReq struct {
+	ID string `json:"id"`
+	// index of the edited question
+	Index uint64 `json:"index"`
+	Msg   string `json:"msg"`
+}
+
+type ChatSwitchBranchReq struct {
+	ID string `json:"id"`
+	// index of any message of the branch
+	Index uint64 `json:"index"`
+}
+
+type ChatSwitchBranchRes struct {
+	Head int64 `json:"head"`
 }
 
 type ChatCompletionsReq struct {
@@ -96,6 +146,46 @@ type ChatCompletionsRes struct {
 	Msg *ChatMsg `json:"msg"`
 }
 
+// server-sent event names of /chat/completions/stream, the final result is sent as "done" by the api layer
+const (
+	ChatStreamEventAgent        = "agent"
+	ChatStreamEventIntent       = "intent"
+	ChatStreamEventProjectFetch = "project_fetch"
+	ChatStreamEventFunctionCall = "function_call"
+	ChatStreamEventToken        = "token"
+)
+
+// ChatStreamEmitter sends one intermediate event to the client
+type ChatStreamEmitter func(event string, data any)
+
+// ChatStreamAgent is the agent the router picked to answer the question
+type ChatStreamAgent struct {
+	AgentID   string `json:"agent_id"`
+	ProjectId string `json:"project_id"`
+	Name      string `json:"name"`
+}
+
+type ChatStreamIntent struct {
+	Intention  string   `json:"intention"`
+	View       string   `json:"view"`
+	IntentKeys []string `json:"intent_keys"`
+}
+
+type ChatStreamProjectFetch struct {
+	ProjectKeys []string `json:"project_keys"`
+	View        string   `json:"view"`
+}
+
+type ChatStreamFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// ChatStreamToken is the next fragment of the answer text, json output is reduced to its content field
+type ChatStreamToken struct {
+	Content string `json:"content"`
+}
+
 // generate by https://api.aidocs.chat/docs#tag/doc-search/operation/searchDocuments
 type Message struct {
 	Type string `json:"type"`
@@ -180,3 +270,170 @@ type Response struct {
 	ToolResults   []ToolResult  `json:"toolResults"`
 	Usage         Usage         `json:"usage"`
 }
+
+type ChatUsageReportReq struct {
+	// day (default) or month
+	Period string `json:"period" form:"period"`
+	// inclusive dates of the period, 2006-01-02 for days and 2006-01 for months
+	From string `json:"from" form:"from"`
+	To   string `json:"to" form:"to"`
+	// sums all users and projects by default, user or project breaks the sums down
+	GroupBy   string `json:"group_by" form:"group_by"`
+	UserID    string `json:"user_id" form:"user_id"`
+	ProjectId string `json:"project_id" form:"project_id"`
+}
+
+type ChatUsageReportRes struct {
+	List        []*model.LLMUsageStat `json:"list"`
+	TotalTokens int64                 `json:"total_tokens"`
+}
+
+type ChatFeedbackReq struct {
+	// chat window id
+	ID    string `json:"id"`
+	Index uint64 `json:"index"`
+	// up or down
+	Rating  string `json:"rating"`
+	Comment string `json:"comment"`
+}
+
+type ChatFeedbackRes struct {
+}
+
+type ChatFeedbackAnalyticsReq struct {
+	// comma separated fields among intention (default), view, fc_type and prompt
+	GroupBy   string `json:"group_by" form:"group_by"`
+	ProjectId string `json:"project_id" form:"project_id"`
+	// id of the agent (in the catalog) that answered the rated message
+	AgentID string `json:"agent_id" form:"agent_id"`
+	// unix seconds of the rating time, 0 leaves the range open
+	From int64 `json:"from" form:"from"`
+	To   int64 `json:"to" form:"to"`
+}
+
+type ChatFeedbackAnalyticsRes struct {
+	List  []*model.ChatFeedbackStat `json:"list"`
+	Up    int64                     `json:"up"`
+	Down  int64                     `json:"down"`
+	Total int64                     `json:"total"`
+}
+
+type ChatFeedbackExportReq struct {
+	// number of conversations, 20 by default
+	Limit     int64  `json:"limit" form:"limit"`
+	ProjectId string `json:"project_id" form:"project_id"`
+	AgentID   string `json:"agent_id" form:"agent_id"`
+	From      int64  `json:"from" form:"from"`
+	To        int64  `json:"to" form:"to"`
+}
+
+type ChatFeedbackExportRes struct {
+	List []*ChatFeedbackConversation `json:"list"`
+}
+
+// ChatFeedbackConversation is a rated conversation with the ratings next to the messages
+type ChatFeedbackConversation struct {
+	WindowID  primitive.ObjectID `json:"window_id"`
+	Title     string             `json:"title"`
+	Creator   primitive.ObjectID `json:"creator"`
+	ProjectId string             `json:"project_id"`
+	Up        int64              `json:"up"`
+	Down      int64              `json:"down"`
+	Msgs      []*ChatFeedbackMsg `json:"msgs"`
+}
+
+type ChatFeedbackMsg struct {
+	*ChatMsg
+	Rating  string `json:"rating,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type ChatSearchReq struct {
+	Q string `json:"q" form:"q"`
+	// id of the agent (in the catalog) that answered the turn
+	AgentID string `json:"agent_id" form:"agent_id"`
+	// assistant content type, e.g. project_info or general_answer
+	Intention string `json:"intention" form:"intention"`
+	// unix seconds of the message time, 0 leaves the range open
+	From int64  `json:"from" form:"from"`
+	To   int64  `json:"to" form:"to"`
+	Page uint64 `json:"page" form:"page"`
+	Size uint64 `json:"size" form:"size"`
+}
+
+type ChatSearchRes struct {
+	List  []*ChatSearchResult `json:"list"`
+	Total int64               `json:"total"`
+}
+
+type ChatSearchResult struct {
+	WindowID    primitive.ObjectID `json:"window_id"`
+	WindowTitle string             `json:"window_title"`
+	Index       uint64             `json:"index"`
+	Role        model.ChatMsgRole  `json:"role"`
+	Timestamp   model.JSONTime     `json:"timestamp"`
+	ProjectId   string             `json:"project_id"`
+	Intention   string             `json:"intention,omitempty"`
+	// html escaped excerpt of the message, the matched terms are wrapped in <em></em>
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+type ChatShareReq struct {
+	ID string `json:"id"`
+}
+
+type ChatShareRes struct {
+	ShareID string `json:"share_id"`
+}
+
+type ChatShareRevokeReq struct {
+	ShareID string `json:"share_id"`
+}
+
+type ChatShareRevokeRes struct {
+}
+
+type ChatShareListReq struct {
+	ID string `json:"id" form:"id"`
+}
+
+type ChatShareInfo struct {
+	ShareID    string         `json:"share_id"`
+	Title      string         `json:"title"`
+	CreateTime model.JSONTime `json:"create_time"`
+	MsgNum     uint64         `json:"msg_num"`
+}
+
+// ChatShareListRes is the shares of the window that have not been revoked, newest first
+type ChatShareListRes struct {
+	List []*ChatShareInfo `json:"list"`
+}
+
+type ChatShareViewReq struct {
+	ShareID string `json:"share_id" form:"share_id"`
+}
+
+// ChatShareViewRes is the shared window as /chat/history returns it, CreateTime is the time of the share
+type ChatShareViewRes struct {
+	ChatHistoryRes
+}
+
+type ChatExportReq struct {
+	ID string `json:"id" form:"id"`
+	// md, json or html, md by default
+	Format string `json:"format" form:"format"`
+}
+
+type ChatExportAllReq struct {
+	Format string `json:"format" form:"format"`
+}
+
+type ChatCachePurgeReq struct {
+	// agent whose answers are purged, empty purges all
+	ProjectId string `json:"project_id"`
+}
+
+type ChatCachePurgeRes struct {
+	Purged int `json:"purged"`
+}
@@ -20,6 +20,7 @@ const (
 	BucketTypeMisc BucketType = iota
 	BucketTypeAvatar
 	BucketTypeProject
+	BucketTypeChart
 )
 
 func (t BucketType) String() string {
@@ -30,6 +31,8 @@ func (t BucketType) String() string {
 		return "avatar"
 	case BucketTypeProject:
 		return "project"
+	case BucketTypeChart:
+		return "chart"
 	default:
 		return "misc"
 	}
@@ -42,3 +45,10 @@ type FileUploadReq struct {
 type FileUploadRes struct {
 	URL string `json:"url"`
 }
+
+// Attachment is a generated file sent as a download instead of the json response
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
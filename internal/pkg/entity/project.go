@@ -774,4 +774,6 @@ type ProjectMetrics struct {
 
 type ProjectMetricsCompareRes struct {
 	Metrics map[string][]ProjectMetrics `json:"metrics"`
+	// line chart of the metrics, empty when charts are disabled
+	ChartURL string `json:"chart_url,omitempty"`
 }
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+)
+
+type PromptTemplateAddReq struct {
+	Name string `json:"name"`
+	// text/template with the fields of model.PromptVars, e.g. {{.Date}}
+	Content string `json:"content"`
+	// json_schema object (name, schema, strict) of the answer, empty keeps the default schema
+	Schema   map[string]any `json:"schema"`
+	AgentIDs []string       `json:"agent_ids"`
+	// weight in the A/B assignment among the templates of an agent, defaults to 1
+	Weight uint64 `json:"weight"`
+	Remark string `json:"remark"`
+	// see model.PromptTemplate.BypassBackend
+	BypassBackend bool `json:"bypass_backend"`
+}
+
+type PromptTemplateAddRes struct {
+	Name    string `json:"name"`
+	Version uint64 `json:"version"`
+}
+
+// PromptTemplateUpdateReq adds a version of an existing template, the new version becomes the active one
+type PromptTemplateUpdateReq PromptTemplateAddReq
+
+type PromptTemplateUpdateRes PromptTemplateAddRes
+
+// PromptTemplateSetActiveReq activates a version (e.g. to roll back) or removes the template from the assignment
+type PromptTemplateSetActiveReq struct {
+	Name     string `json:"name"`
+	Version  uint64 `json:"version"`
+	IsActive bool   `json:"is_active"`
+}
+
+type PromptTemplateSetActiveRes struct {
+}
+
+type PromptTemplateListReq struct {
+	// all versions of the template, or the active templates when empty
+	Name string `json:"name" form:"name"`
+	Page uint64 `json:"page" form:"page"`
+	Size uint64 `json:"size" form:"size"`
+}
+
+type PromptTemplateListRes struct {
+	List  []*model.PromptTemplate `json:"list"`
+	Total int64                   `json:"total"`
+}
@@ -0,0 +1,6 @@
+package errcode
+
+var (
+	ErrAgentNotExist      = NewCustomError(10601, "agent not exist")
+	ErrAgentAlreadyExists = NewCustomError(10602, "agent of the project already exists")
+)
@@ -1,5 +1,8 @@
 package errcode
 
 var (
-	ErrChatWindowNotExist = NewCustomError(10401, "chat window not exist")
+	ErrChatWindowNotExist   = NewCustomError(10401, "chat window not exist")
+	ErrChatLLMQuotaExceeded = NewCustomError(10402, "chat token quota exceeded")
+	ErrChatMsgNotExist      = NewCustomError(10403, "chat message not exist")
+	ErrChatShareNotExist    = NewCustomError(10404, "chat share not exist")
 )
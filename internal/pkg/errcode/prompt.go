@@ -0,0 +1,8 @@
+package errcode
+
+var (
+	ErrPromptTemplateNotExist      = NewCustomError(10501, "prompt template not exist")
+	ErrPromptTemplateAlreadyExists = NewCustomError(10502, "prompt template already exists")
+	ErrPromptTemplateInvalid       = NewCustomError(10503, "invalid prompt template")
+	ErrPromptTemplateVersionExists = NewCustomError(10504, "prompt template version already exists, reload and retry")
+)
@@ -0,0 +1,265 @@
+// Package guardrail screens the text exchanged with the llm: it redacts secrets and personal data from the
+// questions, flags prompt injection attempts and checks the structured answers against their schema.
+package guardrail
+
+import (
+	_ "embed"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+)
+
+// Rule is a named pattern, Replacement (which may refer to the groups of Pattern, e.g. ${1}) replaces
+// the matches of the redaction rules
+type Rule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+	// Accept, when set, keeps only the matches whose secret group (the whole match without one) it accepts
+	Accept func(secret string) bool
+}
+
+// bip39English is the english wordlist of bip39, https://github.com/bitcoin/bips/blob/master/bip-0039/english.txt
+//
+//go:embed bip39_english.txt
+var bip39English string
+
+var bip39Words = func() map[string]bool {
+	words := strings.Fields(bip39English)
+	res := make(map[string]bool, len(words))
+	for _, word := range words {
+		res[word] = true
+	}
+	return res
+}()
+
+// isSeedPhrase tells whether the text is a bip39 phrase: 12 to 24 words, a multiple of 3, all of the wordlist
+func isSeedPhrase(text string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == ','
+	})
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return false
+	}
+	for _, word := range words {
+		if !bip39Words[word] {
+			return false
+		}
+	}
+	return true
+}
+
+func redactRule(name string, pattern string) *Rule {
+	return &Rule{
+		Name:        name,
+		Pattern:     regexp.MustCompile(pattern),
+		Replacement: "[REDACTED:" + name + "]",
+	}
+}
+
+// keyCue is the text announcing a private key, up to the key on the same line
+const keyCue = `(?i)(\b(?:(?:private|secret|priv|signing)[ _-]?keys?|secret)\b[^\n]{0,24}?)\b`
+
+// BuiltinRedactRules run in order, the wallet secrets go first since their digits would match the phone numbers.
+// The hex and base58 keys have the shape of the evm transaction hashes and the solana signatures, they are
+// only redacted after a cue such as "private key".
+var BuiltinRedactRules = []*Rule{
+	// solana keypair file, 64 bytes
+	redactRule("private_key", `\[\s*(?:\d{1,3}\s*,\s*){63}\d{1,3}\s*\]`),
+	// evm private key
+	{
+		Name:        "private_key",
+		Pattern:     regexp.MustCompile(keyCue + `(?:0x)?[0-9a-fA-F]{64}\b`),
+		Replacement: "${1}[REDACTED:private_key]",
+	},
+	// solana secret key in base58
+	{
+		Name:        "private_key",
+		Pattern:     regexp.MustCompile(keyCue + `[1-9A-HJ-NP-Za-km-z]{86,88}\b`),
+		Replacement: "${1}[REDACTED:private_key]",
+	},
+	// bitcoin wif
+	redactRule("private_key", `\b[5KL][1-9A-HJ-NP-Za-km-z]{50,51}\b`),
+	{
+		Name:        "seed_phrase",
+		Pattern:     regexp.MustCompile(`(?i)(\b(?:seed|mnemonic|recovery|secret)\s*(?:phrase|words?)?\s*(?:is|are|:|=)?\s*)(?P<secret>(?:[a-z]{3,8}[\s,]+){11,23}[a-z]{3,8})\b`),
+		Replacement: "${1}[REDACTED:seed_phrase]",
+		Accept:      isSeedPhrase,
+	},
+	// a line of 12 or 24 lowercase words, the way wallets show the phrase
+	{
+		Name:        "seed_phrase",
+		Pattern:     regexp.MustCompile(`(?m)^[ \t]*(?:[a-z]{3,8}[ \t]+){11}(?:(?:[a-z]{3,8}[ \t]+){12})?[a-z]{3,8}[ \t]*$`),
+		Replacement: "[REDACTED:seed_phrase]",
+		Accept:      isSeedPhrase,
+	},
+	redactRule("email", `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+	redactRule("phone", `\+\d{1,3}[\s.-]?\(?\d{1,4}\)?(?:[\s.-]?\d{2,4}){2,4}\b`),
+	redactRule("phone", `(?:\(\d{3}\)\s?|\b\d{3}[\s.-])\d{3}[\s.-]\d{4}\b`),
+	// mainland china mobile
+	redactRule("phone", `\b1[3-9]\d{9}\b`),
+}
+
+func injectionRule(name string, pattern string) *Rule {
+	return &Rule{
+		Name:    name,
+		Pattern: regexp.MustCompile(pattern),
+	}
+}
+
+var BuiltinInjectionRules = []*Rule{
+	injectionRule("ignore_instructions", `(?i)\b(?:ignore|disregard|forget|override)\b.{0,40}\b(?:previous|prior|above|earlier|all|system|your)\b.{0,40}\b(?:instructions?|prompts?|rules|directions|guidelines)\b`),
+	injectionRule("ignore_instructions", `(?:忽略|无视|忘记|忘掉).{0,20}(?:之前|以上|上面|所有|系统).{0,20}(?:指令|提示|规则|设定)`),
+	injectionRule("reveal_prompt", `(?i)\b(?:reveal|show|print|repeat|output|leak|tell me)\b.{0,40}\b(?:system|hidden|initial|original)\s+(?:prompt|instructions?|message)`),
+	injectionRule("role_override", `(?i)\b(?:you are now|from now on,? you are|act as|pretend to be)\b.{0,40}\b(?:DAN|jailbroken|unrestricted|unfiltered|developer mode|without (?:any )?restrictions)`),
+	injectionRule("jailbreak", `(?i)\b(?:jailbreak|developer mode|DAN mode)\b`),
+	injectionRule("fake_role_tag", `(?im)<\|?\s*/?\s*(?:system|im_start|im_end)\s*\|?>|^\s*(?:system|assistant)\s*:`),
+}
+
+// Guard applies the rules of the config, a nil Guard (guardrail disabled) changes nothing
+type Guard struct {
+	redactRules    []*Rule
+	injectionRules []*Rule
+	blockInjection bool
+	validateOutput bool
+}
+
+func New(cfg config.Guardrail) (*Guard, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+	g := &Guard{
+		blockInjection: cfg.BlockInjection,
+		validateOutput: cfg.ValidateOutput,
+	}
+	if !cfg.DisableBuiltinRules {
+		g.redactRules = append(g.redactRules, BuiltinRedactRules...)
+		g.injectionRules = append(g.injectionRules, BuiltinInjectionRules...)
+	}
+	for _, r := range cfg.RedactRules {
+		rule, err := configRule(r)
+		if err != nil {
+			return nil, err
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = "[REDACTED:" + rule.Name + "]"
+		}
+		g.redactRules = append(g.redactRules, rule)
+	}
+	for _, r := range cfg.InjectionRules {
+		rule, err := configRule(r)
+		if err != nil {
+			return nil, err
+		}
+		g.injectionRules = append(g.injectionRules, rule)
+	}
+	return g, nil
+}
+
+func configRule(r config.GuardrailRule) (*Rule, error) {
+	if r.Name == "" {
+		return nil, errors.Errorf("guardrail rule %q has no name", r.Pattern)
+	}
+	pattern, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid pattern of guardrail rule %s", r.Name)
+	}
+	return &Rule{
+		Name:        r.Name,
+		Pattern:     pattern,
+		Replacement: r.Replacement,
+	}, nil
+}
+
+// Redact replaces the matches of the redaction rules, it returns the names of the rules that matched
+func (g *Guard) Redact(text string) (string, []string) {
+	if g == nil {
+		return text, nil
+	}
+	var triggered []string
+	for _, rule := range g.redactRules {
+		var matched bool
+		text, matched = rule.redact(text)
+		if matched {
+			triggered = appendUnique(triggered, rule.Name)
+		}
+	}
+	return text, triggered
+}
+
+// redact replaces the accepted matches of the rule
+func (r *Rule) redact(text string) (string, bool) {
+	secret := r.Pattern.SubexpIndex("secret")
+	var b []byte
+	last := 0
+	matched := false
+	for _, m := range r.Pattern.FindAllStringSubmatchIndex(text, -1) {
+		if r.Accept != nil {
+			start, end := m[0], m[1]
+			if secret > 0 {
+				start, end = m[2*secret], m[2*secret+1]
+			}
+			if !r.Accept(text[start:end]) {
+				continue
+			}
+		}
+		matched = true
+		b = append(b, text[last:m[0]]...)
+		b = r.Pattern.ExpandString(b, r.Replacement, text, m)
+		last = m[1]
+	}
+	if !matched {
+		return text, false
+	}
+	return string(append(b, text[last:]...)), true
+}
+
+// DetectInjection returns the names of the injection rules matching the text
+func (g *Guard) DetectInjection(text string) []string {
+	if g == nil {
+		return nil
+	}
+	var triggered []string
+	for _, rule := range g.injectionRules {
+		if rule.Pattern.MatchString(text) {
+			triggered = appendUnique(triggered, rule.Name)
+		}
+	}
+	return triggered
+}
+
+// BlockInjection tells whether the flagged questions are refused, otherwise they are only logged
+func (g *Guard) BlockInjection() bool {
+	return g != nil && g.blockInjection
+}
+
+// ValidateOutput checks the structured answer against the json_schema object (name, schema, strict) it was
+// asked with, nothing is checked unless the config enables it. The enums of a schema that is not strict
+// only guide the model (the default schema has no "general" intention), they are not enforced.
+func (g *Guard) ValidateOutput(output string, responseSchema any) error {
+	if g == nil || !g.validateOutput {
+		return nil
+	}
+	jsonSchema, ok := asMap(responseSchema)
+	if !ok {
+		return nil
+	}
+	schema, ok := asMap(jsonSchema["schema"])
+	if !ok {
+		return nil
+	}
+	strict, _ := jsonSchema["strict"].(bool)
+	return validator{checkEnum: strict}.validateJSON([]byte(output), schema)
+}
+
+func appendUnique(list []string, name string) []string {
+	for _, item := range list {
+		if item == name {
+			return list
+		}
+	}
+	return append(list, name)
+}
@@ -0,0 +1,211 @@
+package guardrail
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ValidateJSON checks data against the json schema. It covers the keywords used by the response schemas:
+// type, enum, properties, required, additionalProperties, items and anyOf.
+func ValidateJSON(data []byte, schema map[string]any) error {
+	return validator{checkEnum: true}.validateJSON(data, schema)
+}
+
+type validator struct {
+	checkEnum bool
+}
+
+func (vd validator) validateJSON(data []byte, schema map[string]any) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return errors.Wrap(err, "output is not json")
+	}
+	return vd.validate("$", v, schema)
+}
+
+func (vd validator) validate(path string, v any, schema map[string]any) error {
+	if t, ok := schema["type"]; ok {
+		types, ok := asSlice(t)
+		if !ok {
+			types = []any{t}
+		}
+		matched := false
+		for _, t := range types {
+			if name, _ := t.(string); isType(v, name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return errors.Errorf("%s: want type %v, got %s", path, t, jsonType(v))
+		}
+	}
+	if enum, ok := asSlice(schema["enum"]); ok && vd.checkEnum {
+		if !containsValue(enum, v) {
+			return errors.Errorf("%s: %v is not one of %v", path, v, enum)
+		}
+	}
+	if anyOf, ok := asSlice(schema["anyOf"]); ok {
+		var firstErr error
+		for _, item := range anyOf {
+			sub, ok := asMap(item)
+			if !ok {
+				continue
+			}
+			err := vd.validate(path, v, sub)
+			if err == nil {
+				firstErr = nil
+				break
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if firstErr != nil {
+			return firstErr
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		return vd.validateObject(path, v, schema)
+	case []any:
+		items, ok := asMap(schema["items"])
+		if !ok {
+			return nil
+		}
+		for i, item := range v {
+			if err := vd.validate(fmt.Sprintf("%s[%d]", path, i), item, items); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (vd validator) validateObject(path string, v map[string]any, schema map[string]any) error {
+	required, _ := asSlice(schema["required"])
+	for _, name := range required {
+		key, _ := name.(string)
+		if _, ok := v[key]; !ok {
+			return errors.Errorf("%s: missing property %s", path, key)
+		}
+	}
+	properties, _ := asMap(schema["properties"])
+	additional := schema["additionalProperties"]
+	// sorted, the first error does not depend on the map order
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := v[key]
+		prop, ok := asMap(properties[key])
+		if !ok {
+			if allowed, isBool := additional.(bool); isBool && !allowed {
+				return errors.Errorf("%s: unexpected property %s", path, key)
+			}
+			prop, ok = asMap(additional)
+			if !ok {
+				continue
+			}
+		}
+		if err := vd.validate(path+"."+key, value, prop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isType(v any, name string) bool {
+	switch name {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	}
+	return jsonType(v) == name
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func containsValue(enum []any, v any) bool {
+	for _, item := range enum {
+		if reflect.DeepEqual(normalizeNumber(item), v) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeNumber turns the numbers of a schema written in go (or read from mongodb) into float64 like the decoded json
+func normalizeNumber(v any) any {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	}
+	return v
+}
+
+// asMap accepts the schemas written in go as well as the ones decoded from mongodb (prompt templates)
+func asMap(v any) (map[string]any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		return v, true
+	case primitive.M:
+		return v, true
+	case primitive.D:
+		m := make(map[string]any, len(v))
+		for _, e := range v {
+			m[e.Key] = e.Value
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+func asSlice(v any) ([]any, bool) {
+	switch v := v.(type) {
+	case []any:
+		return v, true
+	case primitive.A:
+		return v, true
+	case []string:
+		res := make([]any, 0, len(v))
+		for _, item := range v {
+			res = append(res, item)
+		}
+		return res, true
+	}
+	return nil, false
+}
@@ -0,0 +1,138 @@
+package intenteval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// CategoryDelta is the accuracy change of a category, a missing side is nil
+type CategoryDelta struct {
+	Category string `json:"category"`
+	Baseline *Score `json:"baseline"`
+	Current  *Score `json:"current"`
+}
+
+// Diff compares a run with the stored baseline report
+type Diff struct {
+	// Regressions passed in the baseline and fail now
+	Regressions []*CaseResult `json:"regressions"`
+	// Fixes failed in the baseline and pass now
+	Fixes []*CaseResult `json:"fixes"`
+	// Added cases are not in the baseline
+	Added      []*CaseResult    `json:"added"`
+	Removed    []string         `json:"removed"`
+	Categories []*CategoryDelta `json:"categories"`
+}
+
+// LoadBaseline reads a report saved by SaveBaseline
+func LoadBaseline(path string) (*Report, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read baseline")
+	}
+	var report Report
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return nil, errors.Wrap(err, "failed to parse baseline")
+	}
+	return &report, nil
+}
+
+func SaveBaseline(path string, report *Report) error {
+	raw, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(raw, '\n'), 0o644); err != nil {
+		return errors.Wrap(err, "failed to write baseline")
+	}
+	return nil
+}
+
+func NewDiff(baseline *Report, current *Report) *Diff {
+	diff := &Diff{}
+	for _, res := range current.Results {
+		before := baseline.Result(res.ID)
+		switch {
+		case before == nil:
+			diff.Added = append(diff.Added, res)
+		case before.Passed && !res.Passed:
+			diff.Regressions = append(diff.Regressions, res)
+		case !before.Passed && res.Passed:
+			diff.Fixes = append(diff.Fixes, res)
+		}
+	}
+	for _, res := range baseline.Results {
+		if current.Result(res.ID) == nil {
+			diff.Removed = append(diff.Removed, res.ID)
+		}
+	}
+
+	categories := map[string]*CategoryDelta{}
+	var names []string
+	for _, name := range append(baseline.SortedCategories(), current.SortedCategories()...) {
+		if _, ok := categories[name]; ok {
+			continue
+		}
+		categories[name] = &CategoryDelta{
+			Category: name,
+			Baseline: baseline.Categories[name],
+			Current:  current.Categories[name],
+		}
+		names = append(names, name)
+	}
+	for _, name := range names {
+		diff.Categories = append(diff.Categories, categories[name])
+	}
+	return diff
+}
+
+// WriteReport prints the scores and the failed cases
+func WriteReport(w io.Writer, report *Report) {
+	_, _ = fmt.Fprintf(w, "accuracy: %s\n", formatScore(&report.Score))
+	for _, name := range report.SortedCategories() {
+		_, _ = fmt.Fprintf(w, "  %-32s %s\n", name, formatScore(report.Categories[name]))
+	}
+	for _, res := range report.Results {
+		if res.Passed {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "FAIL %s: %s\n", res.ID, res.Question)
+		for _, m := range res.Mismatches {
+			_, _ = fmt.Fprintf(w, "    %s\n", m)
+		}
+		if res.Err != "" {
+			_, _ = fmt.Fprintf(w, "    err: %s\n", res.Err)
+		}
+	}
+}
+
+// WriteDiff prints the accuracy changes and the cases that changed outcome
+func WriteDiff(w io.Writer, diff *Diff) {
+	_, _ = fmt.Fprintln(w, "compared with baseline:")
+	for _, c := range diff.Categories {
+		_, _ = fmt.Fprintf(w, "  %-32s %s -> %s\n", c.Category, formatScore(c.Baseline), formatScore(c.Current))
+	}
+	for _, res := range diff.Regressions {
+		_, _ = fmt.Fprintf(w, "REGRESSION %s: %s\n", res.ID, res.Question)
+	}
+	for _, res := range diff.Fixes {
+		_, _ = fmt.Fprintf(w, "FIXED %s: %s\n", res.ID, res.Question)
+	}
+	for _, res := range diff.Added {
+		_, _ = fmt.Fprintf(w, "NEW %s (passed: %t)\n", res.ID, res.Passed)
+	}
+	for _, id := range diff.Removed {
+		_, _ = fmt.Fprintf(w, "REMOVED %s\n", id)
+	}
+}
+
+func formatScore(s *Score) string {
+	if s == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d (%.1f%%)", s.Passed, s.Total, s.Accuracy*100)
+}
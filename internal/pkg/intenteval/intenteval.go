@@ -0,0 +1,240 @@
+// Package intenteval scores how the configured model classifies the intention of golden questions,
+// it catches prompt or model changes breaking the parsing into model.ChatAIAnalyticalResult.
+// Only the llm provider is evaluated: the wyt ai backend answering the DocsFaq questions in production
+// runs the tools itself and does not report the classification.
+package intenteval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+)
+
+// Case is a golden question, the empty expectations are not checked
+type Case struct {
+	ID string `json:"id"`
+	// Category groups the scores, defaults to the expected intention
+	Category  string   `json:"category,omitempty"`
+	Question  string   `json:"question"`
+	Intention string   `json:"intention"`
+	View      string   `json:"view,omitempty"`
+	Keys      []string `json:"keys,omitempty"`
+	// Arguments of the expected tool call, only the listed ones are checked
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+type Classifier interface {
+	ClassifyIntent(ctx context.Context, msgs []*extension.ChatgptMsg, projectId string) (*extension.IntentClassification, error)
+}
+
+type Runner struct {
+	Classifier Classifier
+	// SystemPrompt replaces the built-in prompt of the driver when not empty, see extension.WithSystemPrompt
+	SystemPrompt string
+	// AgentID selects the tools offered to the model
+	AgentID string
+}
+
+type CaseResult struct {
+	ID        string   `json:"id"`
+	Category  string   `json:"category"`
+	Question  string   `json:"question"`
+	Intention string   `json:"intention"`
+	View      string   `json:"view,omitempty"`
+	Keys      []string `json:"keys,omitempty"`
+	// Arguments of the tool call, nil when the model did not call a tool
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Passed    bool           `json:"passed"`
+	// Mismatches explains why the case failed
+	Mismatches []string `json:"mismatches,omitempty"`
+	Err        string   `json:"err,omitempty"`
+}
+
+type Score struct {
+	Total    int     `json:"total"`
+	Passed   int     `json:"passed"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+type Report struct {
+	Score
+	Categories map[string]*Score `json:"categories"`
+	Results    []*CaseResult     `json:"results"`
+}
+
+// LoadDataset reads the json array of cases at path
+func LoadDataset(path string) ([]*Case, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read dataset")
+	}
+	var cases []*Case
+	if err := json.Unmarshal(raw, &cases); err != nil {
+		return nil, errors.Wrap(err, "failed to parse dataset")
+	}
+	ids := map[string]bool{}
+	for i, c := range cases {
+		if c.ID == "" || c.Question == "" {
+			return nil, errors.Errorf("case %d: id and question are required", i)
+		}
+		if ids[c.ID] {
+			return nil, errors.Errorf("case %s is duplicated", c.ID)
+		}
+		ids[c.ID] = true
+	}
+	return cases, nil
+}
+
+// Run classifies every case in order, a failed call only fails its case
+func (r *Runner) Run(ctx context.Context, cases []*Case) *Report {
+	var results []*CaseResult
+	for _, c := range cases {
+		results = append(results, r.runCase(ctx, c))
+	}
+	return NewReport(results)
+}
+
+func (r *Runner) runCase(ctx context.Context, c *Case) *CaseResult {
+	res := &CaseResult{
+		ID:       c.ID,
+		Category: lo.Ternary(c.Category != "", c.Category, c.Intention),
+		Question: c.Question,
+	}
+	if r.SystemPrompt != "" {
+		ctx = extension.WithSystemPrompt(ctx, r.SystemPrompt)
+	}
+	msgs := []*extension.ChatgptMsg{{Role: "user", Content: c.Question}}
+
+	classification, err := r.Classifier.ClassifyIntent(ctx, msgs, r.AgentID)
+	if err != nil {
+		res.Err = err.Error()
+		res.Mismatches = []string{"classification failed"}
+		return res
+	}
+	res.Intention = classification.Result.Intention
+	res.View = classification.Result.View
+	res.Keys = classification.Result.IntentKeys
+	if classification.ToolCall != nil {
+		res.Arguments = map[string]any{}
+		if err := json.Unmarshal([]byte(classification.ToolCall.Arguments), &res.Arguments); err != nil {
+			res.Err = errors.Wrap(err, "invalid tool call arguments").Error()
+		}
+	}
+	res.Mismatches = mismatches(c, res)
+	res.Passed = len(res.Mismatches) == 0
+	return res
+}
+
+// mismatches compares case-insensitively, the keys in any order
+func mismatches(c *Case, res *CaseResult) []string {
+	var m []string
+	if !strings.EqualFold(c.Intention, res.Intention) {
+		m = append(m, fmt.Sprintf("intention: want %q, got %q", c.Intention, res.Intention))
+	}
+	if c.View != "" && !strings.EqualFold(c.View, res.View) {
+		m = append(m, fmt.Sprintf("view: want %q, got %q", c.View, res.View))
+	}
+	if len(c.Keys) != 0 && !sameKeys(c.Keys, res.Keys) {
+		m = append(m, fmt.Sprintf("keys: want %v, got %v", c.Keys, res.Keys))
+	}
+	if len(c.Arguments) != 0 && res.Arguments == nil {
+		m = append(m, "arguments: want a tool call, got none")
+		return m
+	}
+	names := lo.Keys(c.Arguments)
+	sort.Strings(names)
+	for _, name := range names {
+		got, ok := res.Arguments[name]
+		if !ok {
+			m = append(m, fmt.Sprintf("argument %s: want %v, got none", name, c.Arguments[name]))
+			continue
+		}
+		if !sameArgument(c.Arguments[name], got) {
+			m = append(m, fmt.Sprintf("argument %s: want %v, got %v", name, c.Arguments[name], got))
+		}
+	}
+	return m
+}
+
+// sameArgument compares the json values, strings case-insensitively
+func sameArgument(want any, got any) bool {
+	normalize := func(value any) any {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return value
+		}
+		var res any
+		if err := json.Unmarshal(raw, &res); err != nil {
+			return value
+		}
+		return res
+	}
+	want, got = normalize(want), normalize(got)
+	if w, ok := want.(string); ok {
+		g, ok := got.(string)
+		return ok && strings.EqualFold(strings.TrimSpace(w), strings.TrimSpace(g))
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+func sameKeys(want []string, got []string) bool {
+	normalize := func(keys []string) []string {
+		res := lo.Uniq(lo.Map(keys, func(item string, _ int) string {
+			return strings.ToLower(strings.TrimSpace(item))
+		}))
+		sort.Strings(res)
+		return res
+	}
+	return slices.Equal(normalize(want), normalize(got))
+}
+
+// NewReport scores the results overall and per category
+func NewReport(results []*CaseResult) *Report {
+	report := &Report{
+		Categories: map[string]*Score{},
+		Results:    results,
+	}
+	for _, res := range results {
+		score, ok := report.Categories[res.Category]
+		if !ok {
+			score = &Score{}
+			report.Categories[res.Category] = score
+		}
+		score.add(res.Passed)
+		report.Score.add(res.Passed)
+	}
+	return report
+}
+
+func (s *Score) add(passed bool) {
+	s.Total++
+	if passed {
+		s.Passed++
+	}
+	s.Accuracy = float64(s.Passed) / float64(s.Total)
+}
+
+// Result returns the result of the case, nil when the report does not have it
+func (r *Report) Result(id string) *CaseResult {
+	res, _ := lo.Find(r.Results, func(item *CaseResult) bool {
+		return item.ID == id
+	})
+	return res
+}
+
+// SortedCategories returns the category names in alphabetical order
+func (r *Report) SortedCategories() []string {
+	names := lo.Keys(r.Categories)
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,159 @@
+package intenteval
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/azure/azuretest"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/pkg/extension"
+)
+
+func goldenRules() []azuretest.Rule {
+	return []azuretest.Rule{
+		{Pattern: regexp.MustCompile("BTC"), JSON: map[string]any{"intention": "search", "intent_keys": []string{"btc"}, "view": "overview"}},
+		{Pattern: regexp.MustCompile("tokenomics"), JSON: map[string]any{"intention": "search", "intent_keys": []string{"Ethereum"}, "view": "tokenomics"}},
+		{Pattern: regexp.MustCompile("Compare"), JSON: map[string]any{"intention": "compare", "intent_keys": []string{"SOL", "ETH"}, "view": "overview"}},
+		{Pattern: regexp.MustCompile("swap"), ToolCall: &azuretest.ToolCall{Name: "swap", Arguments: `{"swap_in_token":"ETH","source_chain":"Ethereum"}`}},
+		{Pattern: regexp.MustCompile("top traders"), ToolCall: &azuretest.ToolCall{Name: "top_traders", Arguments: `{"duration":7}`}},
+		// wrong on purpose: the question asks for the daily new tokens
+		{Pattern: regexp.MustCompile("new tokens"), ToolCall: &azuretest.ToolCall{Name: "token_launched_time_distribution", Arguments: `{}`}},
+		{Pattern: regexp.MustCompile("DeFi"), JSON: map[string]any{"intention": "", "intent_keys": []string{}, "content": "DeFi is decentralized finance", "view": "overview"}},
+	}
+}
+
+func newMockRunner(t *testing.T, server *azuretest.Server) *Runner {
+	d, err := extension.NewChatgptDriver(&extension.ChatgptConfig{
+		Endpoint:     server.Endpoint(),
+		EndpointFull: server.EndpointFull("gpt-4o"),
+		APIKey:       azuretest.APIKey,
+		Model:        "gpt-4o",
+	}, base.NewMockBaseComponent(t), nil)
+	assert.Nil(t, err)
+	return &Runner{
+		Classifier: d,
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	server := azuretest.NewServer(goldenRules()...)
+	defer server.Close()
+
+	cases, err := LoadDataset("testdata/golden.json")
+	assert.Nil(t, err)
+	report := newMockRunner(t, server).Run(context.Background(), cases)
+
+	assert.Equal(t, 7, report.Total)
+	assert.Equal(t, 6, report.Passed)
+	assert.Equal(t, Score{Total: 2, Passed: 2, Accuracy: 1}, *report.Categories["search"])
+	assert.Equal(t, Score{Total: 2, Passed: 1, Accuracy: 0.5}, *report.Categories["pump"])
+	failed := report.Result("daily-new-tokens")
+	assert.False(t, failed.Passed)
+	assert.Equal(t, []string{
+		`intention: want "daily_new_token", got "token_launched_time_distribution"`,
+		`view: want "daily_new_token", got "token_launched_time_distribution"`,
+	}, failed.Mismatches)
+
+	assert.Equal(t, "Ethereum", report.Result("swap-eth-bsc").Arguments["source_chain"])
+	assert.Nil(t, report.Result("search-btc").Arguments)
+
+	// the built-in system prompt comes first, once, and the tools are offered
+	requests := server.Requests()
+	assert.Len(t, requests, 7)
+	assert.Equal(t, "system", requests[0].Messages[0].Role)
+	assert.Equal(t, config.ChatPromptWithStructureOutputModeEnabled, requests[0].Messages[0].Content)
+	assert.Len(t, requests[0].Messages, 2)
+	assert.Contains(t, requests[0].Tools, "top_traders")
+
+	var out bytes.Buffer
+	WriteReport(&out, report)
+	assert.Contains(t, out.String(), "accuracy: 6/7 (85.7%)")
+	assert.Contains(t, out.String(), "FAIL daily-new-tokens")
+}
+
+func TestMismatches(t *testing.T) {
+	c := &Case{Intention: "swap", View: "swap", Arguments: map[string]any{"swap_in_token": "ETH", "amount_in": 1}}
+	tests := []struct {
+		name string
+		res  *CaseResult
+		want []string
+	}{
+		{
+			name: "same arguments",
+			res:  &CaseResult{Intention: "swap", View: "swap", Arguments: map[string]any{"swap_in_token": "eth", "amount_in": 1.0, "dest_chain": "BSC"}},
+		},
+		{
+			name: "wrong argument",
+			res:  &CaseResult{Intention: "swap", View: "swap", Arguments: map[string]any{"swap_in_token": "USDT", "amount_in": 1.0}},
+			want: []string{"argument swap_in_token: want ETH, got USDT"},
+		},
+		{
+			name: "missing argument",
+			res:  &CaseResult{Intention: "swap", View: "swap", Arguments: map[string]any{"swap_in_token": "ETH"}},
+			want: []string{"argument amount_in: want 1, got none"},
+		},
+		{
+			name: "no tool call",
+			res:  &CaseResult{Intention: "swap", View: "swap"},
+			want: []string{"arguments: want a tool call, got none"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mismatches(c, tt.res))
+		})
+	}
+}
+
+func TestNewDiff(t *testing.T) {
+	server := azuretest.NewServer(goldenRules()...)
+	defer server.Close()
+	cases, err := LoadDataset("testdata/golden.json")
+	assert.Nil(t, err)
+	baselinePath := filepath.Join(t.TempDir(), "baseline.json")
+	assert.Nil(t, SaveBaseline(baselinePath, newMockRunner(t, server).Run(context.Background(), cases)))
+	baseline, err := LoadBaseline(baselinePath)
+	assert.Nil(t, err)
+
+	// a prompt change breaks the compare case and fixes the daily new tokens case
+	changed := azuretest.NewServer(append([]azuretest.Rule{
+		{Pattern: regexp.MustCompile("Compare"), JSON: map[string]any{"intention": "search", "intent_keys": []string{"ETH"}, "view": "overview"}},
+		{Pattern: regexp.MustCompile("new tokens"), ToolCall: &azuretest.ToolCall{Name: "daily_new_tokens", Arguments: `{}`}},
+	}, goldenRules()...)...)
+	defer changed.Close()
+	current := newMockRunner(t, changed).Run(context.Background(), append(cases, &Case{ID: "extra", Category: "general", Question: "What is DeFi?"}))
+
+	diff := NewDiff(baseline, current)
+	assert.Equal(t, []string{"compare-eth-sol"}, resultIDs(diff.Regressions))
+	assert.Equal(t, []string{"daily-new-tokens"}, resultIDs(diff.Fixes))
+	assert.Equal(t, []string{"extra"}, resultIDs(diff.Added))
+	assert.Empty(t, diff.Removed)
+	compare := diff.Categories[0]
+	assert.Equal(t, "compare", compare.Category)
+	assert.Equal(t, 1, compare.Baseline.Passed)
+	assert.Equal(t, 0, compare.Current.Passed)
+
+	var out bytes.Buffer
+	WriteDiff(&out, diff)
+	assert.Contains(t, out.String(), "REGRESSION compare-eth-sol")
+}
+
+func TestSameKeys(t *testing.T) {
+	assert.True(t, sameKeys([]string{"ETH", "SOL"}, []string{"sol", " eth"}))
+	assert.False(t, sameKeys([]string{"ETH", "SOL"}, []string{"ETH"}))
+	assert.False(t, sameKeys([]string{"ETH"}, nil))
+}
+
+func resultIDs(results []*CaseResult) []string {
+	var ids []string
+	for _, res := range results {
+		ids = append(ids, res.ID)
+	}
+	return ids
+}
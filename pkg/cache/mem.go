@@ -2,6 +2,7 @@ package cache
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -34,3 +35,22 @@ func PutToMemCache[T any](c *MemCache, ns string, k string, v T) {
 func genKey(ns string, k string) string {
 	return fmt.Sprintf("%s_%s", ns, k)
 }
+
+// PutToMemCacheWithTTL keeps the value for ttl instead of the default expiration
+func PutToMemCacheWithTTL[T any](c *MemCache, ns string, k string, v T, ttl time.Duration) {
+	c.c.Set(genKey(ns, k), v, ttl)
+}
+
+// DeleteFromMemCacheByPrefix deletes the keys of the namespace starting with prefix, an empty prefix
+// deletes the whole namespace. It returns the number of deleted keys.
+func DeleteFromMemCacheByPrefix(c *MemCache, ns string, prefix string) int {
+	p := genKey(ns, prefix)
+	n := 0
+	for k := range c.c.Items() {
+		if strings.HasPrefix(k, p) {
+			c.c.Delete(k)
+			n++
+		}
+	}
+	return n
+}
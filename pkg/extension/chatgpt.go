@@ -5,12 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/wyt-labs/wyt-core/internal/pkg/azure"
 	"github.com/wyt-labs/wyt-core/internal/pkg/base"
 	"github.com/wyt-labs/wyt-core/internal/pkg/config"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
@@ -22,13 +19,19 @@ import (
 )
 
 type ChatgptConfig struct {
+	// Provider selects the LLMProvider implementation, see NewLLMProvider
+	Provider string
 	Endpoint string
 	// EndpointFull with api version and model name baked in
-	EndpointFull    string
-	APIKey          string
-	Model           string
+	EndpointFull string
+	APIKey       string
+	Model        string
+	// EmbeddingModel is the embeddings deployment (azure) or model name
+	EmbeddingModel  string
 	Temperature     float32
 	PresencePenalty float32
+	FakeRules       []config.FakeLLMRule
+	ToolPolicies    []config.ToolPolicy
 }
 
 type WytAIClient struct {
@@ -56,34 +59,56 @@ func NewWYTAIClient(baseComponent *base.Component, envUrl string, projectId, uni
 	}, nil
 }
 
+// WytAIUsageModel is the model name the usage of the wyt ai backend is reported under
+const WytAIUsageModel = "wyt-ai"
+
+// wytAIUsage converts the usage reported by the wyt ai backend, it is estimated from the request and
+// response bodies when the backend reports none
+func wytAIUsage(req entity.Request, resp []byte, usage entity.Usage) LLMUsage {
+	if usage.TotalTokens > 0 || usage.PromptTokens > 0 || usage.CompletionTokens > 0 {
+		ret := LLMUsage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			TotalTokens:      usage.TotalTokens,
+		}
+		if ret.TotalTokens == 0 {
+			ret.TotalTokens = ret.PromptTokens + ret.CompletionTokens
+		}
+		return ret
+	}
+	var ret LLMUsage
+	for _, msg := range req.Messages {
+		ret.PromptTokens += EstimateTokens(msg.Text)
+	}
+	for _, history := range req.History {
+		for _, msg := range history.Messages {
+			for _, content := range msg.Content {
+				ret.PromptTokens += EstimateTokens(content.Text)
+			}
+		}
+	}
+	ret.CompletionTokens = EstimateTokens(string(resp))
+	ret.TotalTokens = ret.PromptTokens + ret.CompletionTokens
+	return ret
+}
+
 type ChatgptDriver struct {
-	cfg    *ChatgptConfig
-	client *azopenai.Client
-	//azureRestClient supports json structured payload
-	azureRestClient *azure.ChatAPI
+	cfg             *ChatgptConfig
+	provider        LLMProvider
 	pumpDataService *datapuller.PumpDataService
-	wytAIClient     *WytAIClient
+	// nil when no wyt ai backend is configured, DocsFaq then falls back to the provider
+	wytAIClient *WytAIClient
 }
 
 func NewChatgptDriver(cfg *ChatgptConfig, baseComponent *base.Component, pumpDataService *datapuller.PumpDataService) (*ChatgptDriver, error) {
-	keyCredential := azcore.NewKeyCredential(cfg.APIKey)
-	client, err := azopenai.NewClientWithKeyCredential(cfg.Endpoint, keyCredential, nil)
-	azureRestClient := azure.NewAzureChatAPI(
-		cfg.EndpointFull,
-		cfg.APIKey,
-		azure.RequestResponseFormat{
-			Type:       "json_schema",
-			JsonSchema: config.ChatSchemaWithStructureOutputModeEnabled,
-		},
-		lo.Map(SupportedFunctions(), func(item azopenai.FunctionDefinition, _ int) azure.RequestTool {
-			return azure.RequestTool{
-				Type:     "function",
-				Function: item,
-			}
-		}))
+	provider, err := NewLLMProvider(cfg)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create chatgpt client")
+		return nil, err
 	}
+	return NewChatgptDriverWithProvider(cfg, provider, baseComponent, pumpDataService)
+}
+
+func NewChatgptDriverWithProvider(cfg *ChatgptConfig, provider LLMProvider, baseComponent *base.Component, pumpDataService *datapuller.PumpDataService) (*ChatgptDriver, error) {
 	var envUrl, pjId, uniPjId, apiKey string
 	if baseComponent.Config.AIBackend.AIEnv == "prod" {
 		envUrl = baseComponent.Config.AIBackend.Endpoint
@@ -96,34 +121,170 @@ func NewChatgptDriver(cfg *ChatgptConfig, baseComponent *base.Component, pumpDat
 		uniPjId = baseComponent.Config.AIBackend.DevUniProjectId
 		apiKey = baseComponent.Config.AIBackend.DevAPIKey
 	}
-	wytAIClient, err := NewWYTAIClient(
-		baseComponent,
-		envUrl,
-		pjId,
-		uniPjId,
-		apiKey,
-	)
-
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create wyt ai client")
+	var wytAIClient *WytAIClient
+	if envUrl != "" {
+		var err error
+		wytAIClient, err = NewWYTAIClient(
+			baseComponent,
+			envUrl,
+			pjId,
+			uniPjId,
+			apiKey,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create wyt ai client")
+		}
 	}
 
 	return &ChatgptDriver{
 		cfg:             cfg,
-		client:          client,
-		azureRestClient: azureRestClient,
+		provider:        provider,
 		pumpDataService: pumpDataService,
 		wytAIClient:     wytAIClient,
 	}, nil
 }
 
+// Provider returns the llm backend in use
+func (d *ChatgptDriver) Provider() LLMProvider {
+	return d.provider
+}
+
+// HasBackend reports whether DocsFaq questions are answered by the wyt ai backend, see WithBypassBackend
+func (d *ChatgptDriver) HasBackend() bool {
+	return d.wytAIClient != nil
+}
+
+// Embed returns the embedding of each text, see LLMProvider.Embed, and reports the usage
+// under the embedding model
+func (d *ChatgptDriver) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	vectors, usage, err := d.provider.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if usage.TotalTokens == 0 {
+		for _, text := range texts {
+			usage.PromptTokens += EstimateTokens(text)
+		}
+		usage.TotalTokens = usage.PromptTokens
+	}
+	notifyUsage(ctx, d.cfg.EmbeddingModel, usage)
+	return vectors, nil
+}
+
 type ChatgptMsg struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
 }
 
+type funcCallListenerKey struct{}
+
+// WithFuncCallListener returns a context that reports every function call (name and raw arguments) made
+// by the driver while serving a request, so callers can surface progress before the call finishes
+func WithFuncCallListener(ctx context.Context, listener func(name string, arguments string)) context.Context {
+	return context.WithValue(ctx, funcCallListenerKey{}, listener)
+}
+
+func notifyFuncCall(ctx context.Context, name string, arguments string) {
+	if listener, ok := ctx.Value(funcCallListenerKey{}).(func(string, string)); ok && listener != nil {
+		listener(name, arguments)
+	}
+}
+
+type usageListenerKey struct{}
+
+// WithUsageListener returns a context that reports the token usage of every model call made by the driver
+// while serving a request, model is the configured chat or embedding model, or WytAIUsageModel
+func WithUsageListener(ctx context.Context, listener func(model string, usage LLMUsage)) context.Context {
+	return context.WithValue(ctx, usageListenerKey{}, listener)
+}
+
+func notifyUsage(ctx context.Context, model string, usage LLMUsage) {
+	if listener, ok := ctx.Value(usageListenerKey{}).(func(string, LLMUsage)); ok && listener != nil {
+		listener(model, usage)
+	}
+}
+
+type responseSchemaKey struct{}
+
+// WithResponseSchema returns a context whose structured output calls follow schema (a json_schema object with
+// name, schema and strict) instead of config.ChatSchemaWithStructureOutputModeEnabled
+func WithResponseSchema(ctx context.Context, schema any) context.Context {
+	return context.WithValue(ctx, responseSchemaKey{}, schema)
+}
+
+// ResponseSchema returns the json_schema object the structured output calls of ctx follow
+func ResponseSchema(ctx context.Context) any {
+	if schema := ctx.Value(responseSchemaKey{}); schema != nil {
+		return schema
+	}
+	return config.ChatSchemaWithStructureOutputModeEnabled
+}
+
+type systemPromptKey struct{}
+
+// WithSystemPrompt returns a context whose structured output calls are sent with prompt as the system prompt
+// instead of config.ChatPromptWithStructureOutputModeEnabled
+func WithSystemPrompt(ctx context.Context, prompt string) context.Context {
+	return context.WithValue(ctx, systemPromptKey{}, prompt)
+}
+
+// SystemPrompt returns the system prompt the structured output calls of ctx are sent with
+func SystemPrompt(ctx context.Context) string {
+	if prompt, ok := ctx.Value(systemPromptKey{}).(string); ok && prompt != "" {
+		return prompt
+	}
+	return config.ChatPromptWithStructureOutputModeEnabled
+}
+
+type bypassBackendKey struct{}
+
+// WithBypassBackend has the DocsFaq calls of ctx answered by the model even when the wyt ai backend is
+// configured, the backend cannot take the system prompt or schema of ctx
+func WithBypassBackend(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassBackendKey{}, true)
+}
+
+func bypassBackend(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassBackendKey{}).(bool)
+	return bypass
+}
+
+// withSystemPrompt puts the system prompt of ctx first in msgs
+func withSystemPrompt(ctx context.Context, msgs []*ChatgptMsg) []*ChatgptMsg {
+	return append([]*ChatgptMsg{{Role: "system", Content: SystemPrompt(ctx)}}, msgs...)
+}
+
+// complete sends req to the provider, streaming to onDelta when it is not nil, and reports the usage
+func (d *ChatgptDriver) complete(ctx context.Context, req *LLMRequest, onDelta func(content string)) (*LLMResponse, error) {
+	var res *LLMResponse
+	var err error
+	if onDelta != nil {
+		res, err = d.provider.ChatStream(ctx, req, onDelta)
+	} else {
+		res, err = d.provider.Chat(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	notifyUsage(ctx, d.cfg.Model, res.Usage)
+	return res, nil
+}
+
 // DocsFaq connect wyt ai backend
 func (d *ChatgptDriver) DocsFaq(ctx context.Context, msgs []*ChatgptMsg, projectId string) (string, *model.FuncCallingRet, error) {
+	return d.DocsFaqStream(ctx, msgs, projectId, nil)
+}
+
+// DocsFaqStream works like DocsFaq, the answer text (the content field of the output) is sent to onDelta
+// when it is not nil. The wyt ai backend does not stream, its answer text is sent as a single delta.
+// Questions are answered by the model when no backend is configured or ctx bypasses it (see WithBypassBackend).
+func (d *ChatgptDriver) DocsFaqStream(ctx context.Context, msgs []*ChatgptMsg, projectId string, onDelta func(content string)) (string, *model.FuncCallingRet, error) {
+	if d.wytAIClient == nil || bypassBackend(ctx) {
+		return d.ChatWithStructuredOutputForProject(ctx, msgs, projectId, onDelta)
+	}
 	var pjId string
 	if projectId == "" {
 		pjId = d.wytAIClient.ProjectId
@@ -134,7 +295,11 @@ func (d *ChatgptDriver) DocsFaq(ctx context.Context, msgs []*ChatgptMsg, project
 		"Content-Type": "application/json",
 		"apikey":       d.wytAIClient.APIKey,
 	}
-	msgs2post := lo.Map(msgs, func(item *ChatgptMsg, index int) entity.Message {
+	if len(msgs) == 0 {
+		return "", nil, errors.New("no message to send")
+	}
+	// the last message is the question, the previous ones are the conversation memory
+	msgs2post := lo.Map(msgs[len(msgs)-1:], func(item *ChatgptMsg, index int) entity.Message {
 		return entity.Message{
 			Type: "text",
 			Text: item.Content,
@@ -142,7 +307,12 @@ func (d *ChatgptDriver) DocsFaq(ctx context.Context, msgs []*ChatgptMsg, project
 	})
 	pjRelated := []string{}
 	// history messages
-	hisMsgs := []entity.HistoryMessage{}
+	hisMsgs := lo.Map(msgs[:len(msgs)-1], func(item *ChatgptMsg, index int) entity.HistoryMessage {
+		return entity.HistoryMessage{
+			Role:    item.Role,
+			Content: []entity.Message{{Type: "text", Text: item.Content}},
+		}
+	})
 	history := entity.History{Messages: hisMsgs}
 	historys := []entity.History{history}
 
@@ -165,6 +335,7 @@ func (d *ChatgptDriver) DocsFaq(ctx context.Context, msgs []*ChatgptMsg, project
 		d.wytAIClient.BaseComponent.Logger.Error("failed to unmarshal wyt ai response ", err)
 		return "", nil, err
 	}
+	notifyUsage(ctx, WytAIUsageModel, wytAIUsage(body, resp, res.Usage))
 	// check any unresolved tool results
 	for _, rest := range res.ToolResults {
 		if !rest.Result.Resolved {
@@ -175,28 +346,20 @@ func (d *ChatgptDriver) DocsFaq(ctx context.Context, msgs []*ChatgptMsg, project
 				return "", nil, err
 			}
 			d.wytAIClient.BaseComponent.Logger.Info("wyt ai response(function call):", string(bytesResult))
-			callRet, err := d.handleFunctionCall(ctx, &azopenai.FunctionCall{Name: &rest.ToolName, Arguments: to.Ptr(string(bytesResult))})
+			callRet, err := d.handleFunctionCall(ctx, pjId, rest.ToolName, string(bytesResult))
 			if err != nil {
 				return "", nil, err
 			}
 			return "", callRet, nil
 		} else {
-			ret, err := mapRemoteFunctionToFuncCallingRet(
-				rest.ToolName,
-				rest.Result.Result,
-			)
-			if err != nil {
-				d.wytAIClient.BaseComponent.Logger.Error("failed to map remote function to func calling ret", err)
-				return "", nil, err
+			// the backend runs its tools regardless of the agent settings, the disabled results are dropped
+			if !d.toolAllowed(ctx, pjId, rest.ToolName) {
+				return "", nil, toolDisabledError(rest.ToolName, pjId)
 			}
+			notifyFuncCall(ctx, rest.ToolName, "")
+			ret := decodeRemoteToolResult(rest.ToolName, rest.Result.Result)
 			jsonResult, _ := json.Marshal(ret)
 			d.wytAIClient.BaseComponent.Logger.Info("wyt ai response(function call):", string(jsonResult))
-			if ret.FCType == model.FCUniswap {
-				return "", &model.FuncCallingRet{
-					FCType:          model.FCUniswap,
-					FCUniswapResult: ret.FCUniswapResult,
-				}, nil
-			}
 			return "", ret, nil
 		}
 	}
@@ -206,113 +369,73 @@ func (d *ChatgptDriver) DocsFaq(ctx context.Context, msgs []*ChatgptMsg, project
 		return "", nil, err
 	}
 	d.wytAIClient.BaseComponent.Logger.Info("WYT AI response:", string(bytes))
+	if onDelta != nil {
+		contentDeltas(onDelta)(string(bytes))
+	}
 	return string(bytes), nil, nil
 }
 
 func (d *ChatgptDriver) ChatCompletions(msgs []*ChatgptMsg) (string, error) {
-	res, err := d.client.GetChatCompletions(
-		context.Background(),
-		azopenai.ChatCompletionsOptions{
-			Messages: lo.Map(msgs, func(item *ChatgptMsg, index int) azopenai.ChatRequestMessageClassification {
-				if item.Role == "user" {
-					return &azopenai.ChatRequestUserMessage{
-						Content: azopenai.NewChatRequestUserMessageContent(item.Content),
-					}
-				} else {
-					return &azopenai.ChatRequestSystemMessage{
-						Content: to.Ptr(item.Content),
-					}
-				}
-			}),
-
-			DeploymentName:  &d.cfg.Model,
-			Temperature:     &d.cfg.Temperature,
-			PresencePenalty: &d.cfg.PresencePenalty,
-		},
-		&azopenai.GetChatCompletionsOptions{},
-	)
+	return d.ChatCompletionsWithContext(context.Background(), msgs)
+}
+
+// ChatCompletionsWithContext is ChatCompletions bound to the request context, e.g. to report the usage
+func (d *ChatgptDriver) ChatCompletionsWithContext(ctx context.Context, msgs []*ChatgptMsg) (string, error) {
+	res, err := d.complete(ctx, &LLMRequest{
+		Messages: msgs,
+	}, nil)
 	if err != nil {
 		return "", err
 	}
-
-	if len(res.Choices) == 0 {
-		return "", errors.New("not found choices")
-	}
-
-	return *res.Choices[0].Message.Content, nil
+	return res.Content, nil
 }
 
 func (d *ChatgptDriver) ChatCompletionsWithFuncCall(msgs []*ChatgptMsg) (string, *model.FuncCallingRet, error) {
 	ctx := context.Background()
-	res, err := d.client.GetChatCompletions(
-		ctx,
-		azopenai.ChatCompletionsOptions{
-			Messages: lo.Map(msgs, func(item *ChatgptMsg, index int) azopenai.ChatRequestMessageClassification {
-				if item.Role == "user" {
-					return &azopenai.ChatRequestUserMessage{
-						Content: azopenai.NewChatRequestUserMessageContent(item.Content),
-					}
-				} else {
-					return &azopenai.ChatRequestSystemMessage{
-						Content: to.Ptr(item.Content),
-					}
-				}
-			}),
-			DeploymentName:  &d.cfg.Model,
-			Temperature:     &d.cfg.Temperature,
-			PresencePenalty: &d.cfg.PresencePenalty,
-			Functions:       SupportedFunctions(),
-			FunctionCall:    &azopenai.ChatCompletionsOptionsFunctionCall{Value: to.Ptr("auto")},
-		},
-		&azopenai.GetChatCompletionsOptions{},
-	)
+	res, err := d.complete(ctx, &LLMRequest{
+		Messages: msgs,
+		Tools:    d.llmTools(ctx, ""),
+	}, nil)
 	if err != nil {
 		return "", nil, err
 	}
 
-	if len(res.Choices) == 0 {
-		return "", nil, errors.New("not found choices")
-	}
-
-	fmt.Printf("res: %+v\n", res.Choices[0].Message.Content)
-
-	if res.Choices[0].Message.FunctionCall != nil {
-		fmt.Printf("function call, Name %+v, Arguments %+v\n", *res.Choices[0].Message.FunctionCall.Name, *res.Choices[0].Message.FunctionCall.Arguments)
-		fcret, err := d.handleFunctionCall(ctx, res.Choices[0].Message.FunctionCall)
+	if len(res.ToolCalls) > 0 {
+		fcret, err := d.handleFunctionCall(ctx, "", res.ToolCalls[0].Name, res.ToolCalls[0].Arguments)
 		if err != nil {
 			return "", nil, err
 		}
 		return "", fcret, nil
 	}
 
-	return *res.Choices[0].Message.Content, nil, nil
+	return res.Content, nil, nil
 }
 
-// ChatWithStructuredOutput uses custom azure restful api making a direct API call to the azure api endpoint.
-// This is useful since official Azure SDK doesn't support 2024-08-01 version yet.
+// ChatWithStructuredOutput asks the model for an answer following config.ChatSchemaWithStructureOutputModeEnabled
+// (see WithResponseSchema), or a call of one of the registered tools
 func (d *ChatgptDriver) ChatWithStructuredOutput(msgs []*ChatgptMsg) (string, *model.FuncCallingRet, error) {
-	ctx := context.Background()
-	res, err := d.azureRestClient.Chat(lo.Map(msgs, func(item *ChatgptMsg, _ int) azure.Message {
-		return azure.Message{
-			Role:    item.Role,
-			Content: item.Content,
-		}
-	}))
+	return d.ChatWithStructuredOutputForProject(context.Background(), msgs, "", nil)
+}
 
+// ChatWithStructuredOutputForProject only offers the tools enabled for the agent (project id),
+// the answer text (the content field of the output) is streamed to onDelta when it is not nil
+func (d *ChatgptDriver) ChatWithStructuredOutputForProject(ctx context.Context, msgs []*ChatgptMsg, projectId string, onDelta func(content string)) (string, *model.FuncCallingRet, error) {
+	req := &LLMRequest{
+		Messages:       withSystemPrompt(ctx, msgs),
+		Tools:          d.llmTools(ctx, projectId),
+		ResponseSchema: ResponseSchema(ctx),
+	}
+	if onDelta != nil {
+		onDelta = contentDeltas(onDelta)
+	}
+	res, err := d.complete(ctx, req, onDelta)
 	if err != nil {
 		return "", nil, err
 	}
 
-	if len(res.Choices) == 0 {
-		return "", nil, errors.New("not found choices")
-	}
-
-	if len(res.Choices[0].Message.ToolCalls) > 0 {
-		toolCall := res.Choices[0].Message.ToolCalls[0]
-		fcret, err := d.handleFunctionCall(ctx, &azopenai.FunctionCall{
-			Name:      &toolCall.Function.Name,
-			Arguments: &toolCall.Function.Arguments,
-		})
+	if len(res.ToolCalls) > 0 {
+		toolCall := res.ToolCalls[0]
+		fcret, err := d.handleFunctionCall(ctx, projectId, toolCall.Name, toolCall.Arguments)
 		if err != nil {
 			return "", nil, err
 		}
@@ -322,73 +445,56 @@ func (d *ChatgptDriver) ChatWithStructuredOutput(msgs []*ChatgptMsg) (string, *m
 		return "", fcret, nil
 	}
 
-	return res.Choices[0].Message.Content, nil, nil
+	return res.Content, nil, nil
+}
+
+// IntentClassification is how the model understood a question, ToolCall is set when it chose a tool
+type IntentClassification struct {
+	Result   model.ChatAIAnalyticalResult
+	ToolCall *LLMToolCall
 }
 
-func (d *ChatgptDriver) handleFunctionCall(ctx context.Context, functionCall *azopenai.FunctionCall) (*model.FuncCallingRet, error) {
-	if functionCall.Name == nil || functionCall.Arguments == nil {
-		return nil, fmt.Errorf("invalid function call response")
+// ClassifyIntent sends the same request as ChatWithStructuredOutputForProject but does not run the chosen tool,
+// a tool call is reported with the intention and view it would be rendered with
+func (d *ChatgptDriver) ClassifyIntent(ctx context.Context, msgs []*ChatgptMsg, projectId string) (*IntentClassification, error) {
+	res, err := d.complete(ctx, &LLMRequest{
+		Messages:       withSystemPrompt(ctx, msgs),
+		Tools:          d.llmTools(ctx, projectId),
+		ResponseSchema: ResponseSchema(ctx),
+	}, nil)
+	if err != nil {
+		return nil, err
 	}
-	switch *functionCall.Name {
-	case "swap":
-		ret, err := d.Swap(ctx, *functionCall.Arguments)
-		if err != nil {
-			return nil, err
-		}
-		return &model.FuncCallingRet{
-			FCType:       model.FCSwap,
-			FCSwapResult: *ret,
-		}, nil
-	case "daily_new_tokens":
-		ret, err := d.DailyNewTokens(ctx, *functionCall.Arguments)
-		if err != nil {
-			return nil, err
-		}
-		return &model.FuncCallingRet{
-			FCType:         model.FCDailyNewToken,
-			NewTokenResult: *ret,
-		}, nil
-	case "token_launched_time_distribution":
-		ret, err := d.TokenLaunchedTimeDistribution(ctx, *functionCall.Arguments)
-		if err != nil {
-			return nil, err
-		}
-		return &model.FuncCallingRet{
-			FCType:   model.FCTokenLaunchedTimeDistribution,
-			TokenLTD: *ret,
-		}, nil
-	case "daily_token_swap_counts":
-		ret, err := d.DailyTokenSwapCounts(ctx, *functionCall.Arguments)
-		if err != nil {
-			return nil, err
+
+	if len(res.ToolCalls) > 0 {
+		toolCall := res.ToolCalls[0]
+		tool, ok := LookupTool(toolCall.Name)
+		if !ok {
+			return nil, fmt.Errorf("unknown function: %s", toolCall.Name)
 		}
-		return &model.FuncCallingRet{
-			FCType:         model.FCDailyTokenSwapCount,
-			TokenSwapCount: *ret,
-		}, nil
-	case "top_traders":
-		ret, err := d.TopTraders(ctx, *functionCall.Arguments)
-		if err != nil {
-			return nil, err
+		intention := tool.Render.Intention
+		if intention == "" {
+			intention = tool.FCType
 		}
-		return &model.FuncCallingRet{
-			FCType:    model.FCTopTrader,
-			TopTrader: *ret,
-		}, nil
-	case "trader_overview":
-		ret, err := d.TraderOverview(ctx, *functionCall.Arguments)
-		if err != nil {
-			return nil, err
-		}
-		return &model.FuncCallingRet{
-			FCType:         model.FCTraderOverview,
-			TraderOverview: *ret,
+		return &IntentClassification{
+			Result: model.ChatAIAnalyticalResult{
+				Intention: intention,
+				View:      tool.FCType,
+			},
+			ToolCall: &toolCall,
 		}, nil
-	default:
-		return nil, fmt.Errorf("unknown function: %s", *functionCall.Name)
 	}
+
+	var classification IntentClassification
+	if err := json.Unmarshal([]byte(res.Content), &classification.Result); err != nil {
+		return nil, errors.Wrap(err, "failed to parse intent")
+	}
+	return &classification, nil
 }
 
+// defaultSwapDEX is the dex of a swap that does not name one
+const defaultSwapDEX = "WYT Swap"
+
 // swap
 func (d *ChatgptDriver) Swap(ctx context.Context, params string) (*model.SwapFuncCallingResult, error) {
 	swapParams := &entity.SwapParams{}
@@ -398,16 +504,21 @@ func (d *ChatgptDriver) Swap(ctx context.Context, params string) (*model.SwapFun
 	}
 	var ret model.SwapFuncCallingResult
 	ret.DEX = swapParams.DEX
+	if ret.DEX == "" {
+		ret.DEX = defaultSwapDEX
+	}
 	ret.AmountIn = swapParams.AmountIn
 	ret.SwapInToken = swapParams.SwapInToken
 	ret.SwapOutToken = swapParams.SwapOutToken
 	ret.SwapOut = swapParams.SwapOut
 	ret.SourceChain = swapParams.SourceChain
-	// dest is not specified, use source chain as dest chain
-	if len(swapParams.DestChain) == 0 {
-		ret.DestChain = swapParams.SourceChain
-	} else {
-		ret.DestChain = swapParams.DestChain
+	ret.DestChain = swapParams.DestChain
+	// only one chain is specified, swap on that chain
+	if len(ret.DestChain) == 0 {
+		ret.DestChain = ret.SourceChain
+	}
+	if len(ret.SourceChain) == 0 {
+		ret.SourceChain = ret.DestChain
 	}
 	return &ret, nil
 }
@@ -543,20 +654,12 @@ type Function struct {
 }
 
 func (d *ChatgptDriver) ChatCompletionsFunctionCall(ctx context.Context, userMsg string, functions []Function) (string, error) {
-	resp, err := d.client.GetChatCompletions(ctx, azopenai.ChatCompletionsOptions{
-		DeploymentName: &d.cfg.Model,
-		Messages: []azopenai.ChatRequestMessageClassification{
-			&azopenai.ChatRequestUserMessage{
-				Content: azopenai.NewChatRequestUserMessageContent(userMsg),
-			},
-		},
-		FunctionCall: &azopenai.ChatCompletionsOptionsFunctionCall{
-			Value: to.Ptr("auto"),
-		},
-		Functions: lo.Map(functions, func(item Function, index int) azopenai.FunctionDefinition {
-			return azopenai.FunctionDefinition{
-				Name:        &item.Name,
-				Description: &item.Description,
+	resp, err := d.complete(ctx, &LLMRequest{
+		Messages: []*ChatgptMsg{{Role: "user", Content: userMsg}},
+		Tools: lo.Map(functions, func(item Function, index int) LLMTool {
+			return LLMTool{
+				Name:        item.Name,
+				Description: item.Description,
 				Parameters:  item.Parameters,
 			}
 		}),
@@ -566,56 +669,22 @@ func (d *ChatgptDriver) ChatCompletionsFunctionCall(ctx context.Context, userMsg
 		return "", err
 	}
 
-	replyContent := ""
-	if resp.Choices[0].Message.Content != nil {
-		replyContent = *resp.Choices[0].Message.Content
-	}
-	funcCall := resp.Choices[0].Message.FunctionCall
-	if funcCall == nil {
+	replyContent := resp.Content
+	if len(resp.ToolCalls) == 0 {
 		if replyContent != "" {
 			return replyContent, nil
 		}
 		return "", errors.New("not a function call response")
 	}
-	if funcCall.Name == nil {
+	funcCall := resp.ToolCalls[0]
+	if funcCall.Name == "" {
 		return "", fmt.Errorf("parse function name is missing: %v", replyContent)
 	}
-	if funcCall.Arguments == nil {
-		return fmt.Sprintf("your intent is parsed, call-function: %s, no arguments", *funcCall.Name), nil
-	}
-
-	return fmt.Sprintf("your intent is parsed, call-function: %s, arguments: %s", *funcCall.Name, *funcCall.Arguments), nil
-}
-
-// mapRemoteFunctionToFuncCallingRet maps the remote function call result to FuncCallingRet
-func mapRemoteFunctionToFuncCallingRet(name string, result map[string]any) (*model.FuncCallingRet, error) {
-	ret := &model.FuncCallingRet{}
-	switch name {
-	case "uniswap":
-		ret.FCUniswapResult = mapToStruct[model.UniswapFuncCallingResult](result)
-		ret.FCType = model.FCUniswap
-	case "swap":
-		ret.FCSwapResult = mapToStruct[model.SwapFuncCallingResult](result)
-		ret.FCType = model.FCSwap
-	case "daily_new_tokens":
-		ret.NewTokenResult = mapToStruct[model.DailyNewTokensFuncCallingResult](result)
-		ret.FCType = model.FCDailyNewToken
-	case "token_launched_time_distribution":
-		ret.TokenLTD = mapToStruct[model.TokenLaunchedTimeDtFuncCallingResult](result)
-		ret.FCType = model.FCTokenLaunchedTimeDistribution
-	case "daily_token_swap_counts":
-		ret.TokenSwapCount = mapToStruct[model.DailyTokenSwapCountsFuncCallingResult](result)
-		ret.FCType = model.FCDailyTokenSwapCount
-	case "top_traders":
-		ret.TopTrader = mapToStruct[model.TopTradersFuncCallingResult](result)
-		ret.FCType = model.FCTopTrader
-	case "trader_overview":
-		ret.TraderOverview = mapToStruct[model.TraderOverviewFuncCallingResult](result)
-		ret.FCType = model.FCTraderOverview
-	default:
-		ret.RemoteFunctionResult = result
+	if funcCall.Arguments == "" {
+		return fmt.Sprintf("your intent is parsed, call-function: %s, no arguments", funcCall.Name), nil
 	}
-	return ret, nil
+
+	return fmt.Sprintf("your intent is parsed, call-function: %s, arguments: %s", funcCall.Name, funcCall.Arguments), nil
 }
 
 // / mapToStruct converts a map[string]any to a struct
@@ -625,137 +694,3 @@ func mapToStruct[T any](data map[string]any) T {
 	_ = json.Unmarshal(bytes, &ret)
 	return ret
 }
-
-func SupportedFunctions() []azopenai.FunctionDefinition {
-	return []azopenai.FunctionDefinition{
-		{
-			Name:        to.Ptr("swap"),
-			Description: to.Ptr("Use one crypto token swap for another token"),
-			Parameters: map[string]any{
-				"required": []string{},
-				"type":     "object",
-				"properties": map[string]any{
-					"swap_in_token": map[string]any{
-						"type":        "string",
-						"description": "Swap input token symbol.",
-					},
-					"source_chain": map[string]any{
-						"type":        "string",
-						"description": "The chain where the input token is located. Same as the destination chain if no chain specified for source_chain (should not be null in this case!). If user specified source_chain, use it instead!",
-					},
-					"amount_in": map[string]any{
-						"type":        "number",
-						"description": "Swap input token amount.",
-					},
-					"dest_chain": map[string]any{
-						"type":        "string",
-						"description": "The chain where the output token is located. Same as the source chain if no chain specified for dest_chain (should not be null in this case!). If user specified dest_chain, use it instead!",
-					},
-					"swap_out_token": map[string]any{
-						"type":        "string",
-						"description": "Swap output token symbol.",
-					},
-					"dex": map[string]any{
-						"type":        "string",
-						"description": "Swap on which DEX.",
-					},
-				},
-			},
-		},
-		// pump.fun 每日新创建代币数量以及上raydium的数量
-		{
-			Name:        to.Ptr("daily_new_tokens"),
-			Description: to.Ptr("For several consecutive days, the number of new tokens created daily by pump.fun and the number of tokens listed on Reydium."),
-			Parameters: map[string]any{
-				"required": []string{},
-				"type":     "object",
-				"properties": map[string]any{
-					"duration": map[string]any{
-						"type":        "number",
-						"description": "The number of consecutive days for which you want to view data.",
-					},
-					"timezone": map[string]any{
-						"type":        "string",
-						"description": "time zone.",
-					},
-				},
-			},
-		},
-		// pump.fun 每日新创建代币的时间分布，按半小时划分
-		{
-			Name:        to.Ptr("token_launched_time_distribution"),
-			Description: to.Ptr("Time distribution of dump.fun new Token creation (by half hour)."),
-			Parameters: map[string]any{
-				"required": []string{},
-				"type":     "object",
-				"properties": map[string]any{
-					"duration": map[string]any{
-						"type":        "number",
-						"description": "The number of consecutive days for which you want to view data.",
-					},
-					"timezone": map[string]any{
-						"type":        "string",
-						"description": "time zone.",
-					},
-				},
-			},
-		},
-		// pump.fun 每日token交换（swap）交易统计
-		{
-			Name:        to.Ptr("daily_token_swap_counts"),
-			Description: to.Ptr("Daily statistics of pump.fun's token exchange (swap) transactions."),
-			Parameters: map[string]any{
-				"required": []string{},
-				"type":     "object",
-				"properties": map[string]any{
-					"duration": map[string]any{
-						"type":        "number",
-						"description": "The number of consecutive days for which you want to view data.",
-					},
-					"timezone": map[string]any{
-						"type":        "string",
-						"description": "time zone.",
-					},
-				},
-			},
-		},
-		// pump.fun top trader
-		{
-			Name:        to.Ptr("top_traders"),
-			Description: to.Ptr("List of top (high net profit) traders on pump.fun."),
-			Parameters: map[string]any{
-				"required": []string{},
-				"type":     "object",
-				"properties": map[string]any{
-					"duration": map[string]any{
-						"type":        "number",
-						"description": "The number of consecutive days for which you want to view data.",
-					},
-					"winRatio": map[string]any{
-						"type":        "number",
-						"description": "Trader's win rate.",
-					},
-					"timezone": map[string]any{
-						"type":        "string",
-						"description": "time zone.",
-					},
-				},
-			},
-		},
-		// pump.fun trader 概览信息
-		{
-			Name:        to.Ptr("trader_overview"),
-			Description: to.Ptr("Overview information for pump.fun traders."),
-			Parameters: map[string]any{
-				"required": []string{},
-				"type":     "object",
-				"properties": map[string]any{
-					"address": map[string]any{
-						"type":        "string",
-						"description": "pump.fun trader’s address.",
-					},
-				},
-			},
-		},
-	}
-}
@@ -4,15 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
 	"github.com/wyt-labs/wyt-core/internal/core/component/datapuller"
 	"github.com/wyt-labs/wyt-core/internal/core/model"
-	"github.com/wyt-labs/wyt-core/internal/pkg/azure"
 	"github.com/wyt-labs/wyt-core/internal/pkg/base"
 )
 
@@ -20,7 +19,15 @@ func anyToString(value any) string {
 	return fmt.Sprintf("%v", value)
 }
 
+// liveLLMEnv opts in to the tests against the configured llm, wyt ai backend and metabase,
+// e.g. WYT_TEST_LIVE_LLM=1 with the endpoints and keys of a dev config
+const liveLLMEnv = "WYT_TEST_LIVE_LLM"
+
+// BuildChatGpt returns a driver for the live services, the test is skipped unless liveLLMEnv is set
 func BuildChatGpt(t *testing.T) *ChatgptDriver {
+	if os.Getenv(liveLLMEnv) == "" {
+		t.Skipf("%s is not set", liveLLMEnv)
+	}
 	baseComponent := base.NewMockBaseComponent(t)
 	// metabase
 	metabaseDataSource, err := datapuller.NewMetabaseDataSource(baseComponent)
@@ -298,9 +305,8 @@ func TestChatgptDriver_RemoteFunction(t *testing.T) {
 
 func TestChatgptDriver_Swap(t *testing.T) {
 	type fields struct {
-		cfg             *ChatgptConfig
-		client          *azopenai.Client
-		azureRestClient *azure.ChatAPI
+		cfg      *ChatgptConfig
+		provider LLMProvider
 	}
 	type args struct {
 		ctx    context.Context
@@ -353,9 +359,8 @@ func TestChatgptDriver_Swap(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := &ChatgptDriver{
-				cfg:             tt.fields.cfg,
-				client:          tt.fields.client,
-				azureRestClient: tt.fields.azureRestClient,
+				cfg:      tt.fields.cfg,
+				provider: tt.fields.provider,
 			}
 			jsonParams, _ := json.Marshal(tt.args.params)
 			got, err := d.Swap(tt.args.ctx, string(jsonParams))
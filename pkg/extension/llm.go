@@ -0,0 +1,150 @@
+package extension
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/azure"
+)
+
+const (
+	LLMProviderAzure  = "azure"
+	LLMProviderOpenAI = "openai"
+	LLMProviderFake   = "fake"
+)
+
+type LLMTool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+type LLMToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+type LLMUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+type LLMRequest struct {
+	Messages []*ChatgptMsg
+	// Tools the model may call, empty disables function calling
+	Tools []LLMTool
+	// ResponseSchema is the json_schema object (name, schema, strict) the answer must follow, nil for free text
+	ResponseSchema  any
+	Temperature     *float32
+	PresencePenalty *float32
+}
+
+type LLMResponse struct {
+	Content   string
+	ToolCalls []LLMToolCall
+	Usage     LLMUsage
+}
+
+// LLMProvider is a chat model backend, one request covers plain chat, function calling and structured output
+type LLMProvider interface {
+	Name() string
+	Chat(ctx context.Context, req *LLMRequest) (*LLMResponse, error)
+	// ChatStream works like Chat, onDelta receives content fragments as the model produces them
+	ChatStream(ctx context.Context, req *LLMRequest, onDelta func(content string)) (*LLMResponse, error)
+	// Embed returns one vector per text with the configured embedding model, and the tokens of the texts
+	Embed(ctx context.Context, texts []string) ([][]float32, LLMUsage, error)
+}
+
+// EstimateTokens approximates the token count without a tokenizer:
+// about four latin characters per token, one token per CJK (or other multi-byte) character
+func EstimateTokens(text string) int {
+	ascii := 0
+	other := 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+func NewLLMProvider(cfg *ChatgptConfig) (LLMProvider, error) {
+	switch cfg.Provider {
+	case "", LLMProviderAzure:
+		return NewAzureProvider(cfg)
+	case LLMProviderOpenAI:
+		return NewOpenAIProvider(cfg), nil
+	case LLMProviderFake:
+		return NewFakeProviderFromConfig(cfg.FakeRules)
+	default:
+		return nil, errors.Errorf("unsupported llm provider: %s", cfg.Provider)
+	}
+}
+
+// restRequestBody converts a provider request to the chat completions wire format
+func restRequestBody(req *LLMRequest, temperature float32, presencePenalty float32) azure.RequestBody {
+	body := azure.RequestBody{
+		Messages: lo.Map(req.Messages, func(item *ChatgptMsg, _ int) azure.Message {
+			return azure.Message{
+				Role:    item.Role,
+				Content: item.Content,
+			}
+		}),
+		Tools: lo.Map(req.Tools, func(item LLMTool, _ int) azure.RequestTool {
+			return azure.RequestTool{
+				Type: "function",
+				Function: map[string]any{
+					"name":        item.Name,
+					"description": item.Description,
+					"parameters":  item.Parameters,
+				},
+			}
+		}),
+		Temperature:     lo.ToPtr(temperature),
+		PresencePenalty: lo.ToPtr(presencePenalty),
+	}
+	if req.Temperature != nil {
+		body.Temperature = req.Temperature
+	}
+	if req.PresencePenalty != nil {
+		body.PresencePenalty = req.PresencePenalty
+	}
+	if req.ResponseSchema != nil {
+		body.ResponseFormat = &azure.RequestResponseFormat{
+			Type:       "json_schema",
+			JsonSchema: req.ResponseSchema,
+		}
+	}
+	return body
+}
+
+func restResponse(res *azure.APIResponse) (*LLMResponse, error) {
+	if len(res.Choices) == 0 {
+		return nil, errors.New("not found choices")
+	}
+	ret := &LLMResponse{
+		Content: res.Choices[0].Message.Content,
+		ToolCalls: lo.Map(res.Choices[0].Message.ToolCalls, func(item azure.ResponseTool, _ int) LLMToolCall {
+			return LLMToolCall{
+				ID:        item.Id,
+				Name:      item.Function.Name,
+				Arguments: item.Function.Arguments,
+			}
+		}),
+	}
+	if res.Usage != nil {
+		ret.Usage = LLMUsage{
+			PromptTokens:     res.Usage.PromptTokens,
+			CompletionTokens: res.Usage.CompletionTokens,
+			TotalTokens:      res.Usage.TotalTokens,
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,155 @@
+package extension
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/azure"
+)
+
+// AzureProvider uses the azure sdk for plain chat and function calling, and the restful api for
+// structured output and streaming which the sdk does not support yet
+type AzureProvider struct {
+	cfg        *ChatgptConfig
+	client     *azopenai.Client
+	restClient *azure.ChatAPI
+}
+
+func NewAzureProvider(cfg *ChatgptConfig) (*AzureProvider, error) {
+	options := &azopenai.ClientOptions{}
+	// the sdk refuses to send the key over http, allow it for local endpoints such as azuretest
+	options.InsecureAllowCredentialWithHTTP = strings.HasPrefix(cfg.Endpoint, "http://")
+	client, err := azopenai.NewClientWithKeyCredential(cfg.Endpoint, azcore.NewKeyCredential(cfg.APIKey), options)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create chatgpt client")
+	}
+	return &AzureProvider{
+		cfg:        cfg,
+		client:     client,
+		restClient: azure.NewAzureChatAPI(cfg.EndpointFull, cfg.APIKey, azure.RequestResponseFormat{}, nil),
+	}, nil
+}
+
+func (p *AzureProvider) Name() string {
+	return LLMProviderAzure
+}
+
+func (p *AzureProvider) Chat(ctx context.Context, req *LLMRequest) (*LLMResponse, error) {
+	if req.ResponseSchema != nil {
+		res, err := p.restClient.Complete(ctx, restRequestBody(req, p.cfg.Temperature, p.cfg.PresencePenalty))
+		if err != nil {
+			return nil, err
+		}
+		return restResponse(res)
+	}
+
+	options := azopenai.ChatCompletionsOptions{
+		Messages: lo.Map(req.Messages, func(item *ChatgptMsg, index int) azopenai.ChatRequestMessageClassification {
+			switch item.Role {
+			case "user":
+				return &azopenai.ChatRequestUserMessage{
+					Content: azopenai.NewChatRequestUserMessageContent(item.Content),
+				}
+			case "assistant":
+				return &azopenai.ChatRequestAssistantMessage{
+					Content: to.Ptr(item.Content),
+				}
+			default:
+				return &azopenai.ChatRequestSystemMessage{
+					Content: to.Ptr(item.Content),
+				}
+			}
+		}),
+		DeploymentName:  &p.cfg.Model,
+		Temperature:     lo.ToPtr(p.cfg.Temperature),
+		PresencePenalty: lo.ToPtr(p.cfg.PresencePenalty),
+	}
+	if req.Temperature != nil {
+		options.Temperature = req.Temperature
+	}
+	if req.PresencePenalty != nil {
+		options.PresencePenalty = req.PresencePenalty
+	}
+	if len(req.Tools) > 0 {
+		options.Functions = lo.Map(req.Tools, func(item LLMTool, _ int) azopenai.FunctionDefinition {
+			return azopenai.FunctionDefinition{
+				Name:        to.Ptr(item.Name),
+				Description: to.Ptr(item.Description),
+				Parameters:  item.Parameters,
+			}
+		})
+		options.FunctionCall = &azopenai.ChatCompletionsOptionsFunctionCall{Value: to.Ptr("auto")}
+	}
+
+	res, err := p.client.GetChatCompletions(ctx, options, &azopenai.GetChatCompletionsOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Choices) == 0 {
+		return nil, errors.New("not found choices")
+	}
+
+	ret := &LLMResponse{
+		Content: lo.FromPtr(res.Choices[0].Message.Content),
+	}
+	if fc := res.Choices[0].Message.FunctionCall; fc != nil {
+		ret.ToolCalls = []LLMToolCall{{
+			Name:      lo.FromPtr(fc.Name),
+			Arguments: lo.FromPtr(fc.Arguments),
+		}}
+	}
+	if res.Usage != nil {
+		ret.Usage = LLMUsage{
+			PromptTokens:     int(lo.FromPtr(res.Usage.PromptTokens)),
+			CompletionTokens: int(lo.FromPtr(res.Usage.CompletionTokens)),
+			TotalTokens:      int(lo.FromPtr(res.Usage.TotalTokens)),
+		}
+	}
+	return ret, nil
+}
+
+func (p *AzureProvider) ChatStream(ctx context.Context, req *LLMRequest, onDelta func(content string)) (*LLMResponse, error) {
+	res, err := p.restClient.CompleteStream(ctx, restRequestBody(req, p.cfg.Temperature, p.cfg.PresencePenalty), onDelta)
+	if err != nil {
+		return nil, err
+	}
+	return restResponse(res)
+}
+
+func (p *AzureProvider) Embed(ctx context.Context, texts []string) ([][]float32, LLMUsage, error) {
+	res, err := p.client.GetEmbeddings(ctx, azopenai.EmbeddingsOptions{
+		Input:          texts,
+		DeploymentName: &p.cfg.EmbeddingModel,
+	}, nil)
+	if err != nil {
+		return nil, LLMUsage{}, err
+	}
+	if len(res.Data) != len(texts) {
+		return nil, LLMUsage{}, errors.Errorf("expected %d embeddings, got %d", len(texts), len(res.Data))
+	}
+	vectors := make([][]float32, len(texts))
+	for i, item := range res.Data {
+		index := i
+		if item.Index != nil {
+			index = int(*item.Index)
+		}
+		if index < 0 || index >= len(vectors) {
+			return nil, LLMUsage{}, errors.Errorf("invalid embedding index %d", index)
+		}
+		vectors[index] = item.Embedding
+	}
+	var usage LLMUsage
+	if res.Usage != nil {
+		usage = LLMUsage{
+			PromptTokens: int(lo.FromPtr(res.Usage.PromptTokens)),
+			TotalTokens:  int(lo.FromPtr(res.Usage.TotalTokens)),
+		}
+	}
+	return vectors, usage, nil
+}
@@ -0,0 +1,178 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pkg/errors"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+)
+
+type FakeRule struct {
+	// Pattern is matched against the last user message, nil matches everything
+	Pattern  *regexp.Regexp
+	Response LLMResponse
+}
+
+// FakeProvider answers with scripted responses, the first rule matching the last user message wins.
+// Without a match plain chat echoes the question, structured output returns a general answer echoing it.
+type FakeProvider struct {
+	rules []FakeRule
+
+	lock     sync.Mutex
+	requests []*LLMRequest
+}
+
+func NewFakeProvider(rules ...FakeRule) *FakeProvider {
+	return &FakeProvider{
+		rules: rules,
+	}
+}
+
+func NewFakeProviderFromConfig(cfgRules []config.FakeLLMRule) (*FakeProvider, error) {
+	var rules []FakeRule
+	for _, r := range cfgRules {
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid fake llm rule pattern: %s", r.Pattern)
+		}
+		rule := FakeRule{
+			Pattern: pattern,
+			Response: LLMResponse{
+				Content: r.Content,
+			},
+		}
+		if r.ToolName != "" {
+			rule.Response.ToolCalls = []LLMToolCall{{
+				ID:        "call_" + r.ToolName,
+				Name:      r.ToolName,
+				Arguments: r.ToolArguments,
+			}}
+		}
+		rules = append(rules, rule)
+	}
+	return NewFakeProvider(rules...), nil
+}
+
+func (p *FakeProvider) Name() string {
+	return LLMProviderFake
+}
+
+func (p *FakeProvider) Chat(ctx context.Context, req *LLMRequest) (*LLMResponse, error) {
+	p.lock.Lock()
+	p.requests = append(p.requests, req)
+	p.lock.Unlock()
+
+	question := lastUserContent(req.Messages)
+	for _, rule := range p.rules {
+		if rule.Pattern == nil || rule.Pattern.MatchString(question) {
+			res := rule.Response
+			res.Usage = fakeUsage(req.Messages, res.Content)
+			return &res, nil
+		}
+	}
+
+	content := question
+	if req.ResponseSchema != nil {
+		raw, _ := json.Marshal(map[string]any{
+			"intention":   "",
+			"intent_keys": []string{},
+			"content":     question,
+			"view":        "overview",
+		})
+		content = string(raw)
+	}
+	return &LLMResponse{
+		Content: content,
+		Usage:   fakeUsage(req.Messages, content),
+	}, nil
+}
+
+func (p *FakeProvider) ChatStream(ctx context.Context, req *LLMRequest, onDelta func(content string)) (*LLMResponse, error) {
+	res, err := p.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if onDelta != nil {
+		for _, fragment := range strings.SplitAfter(res.Content, " ") {
+			if fragment != "" {
+				onDelta(fragment)
+			}
+		}
+	}
+	return res, nil
+}
+
+// Embed hashes the words of each text into a small normalized vector, texts sharing words are close
+func (p *FakeProvider) Embed(ctx context.Context, texts []string) ([][]float32, LLMUsage, error) {
+	vectors := make([][]float32, len(texts))
+	var usage LLMUsage
+	for i, text := range texts {
+		vectors[i] = fakeEmbedding(text)
+		usage.PromptTokens += len(strings.Fields(text))
+	}
+	usage.TotalTokens = usage.PromptTokens
+	return vectors, usage, nil
+}
+
+// Requests returns all requests received so far
+func (p *FakeProvider) Requests() []*LLMRequest {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]*LLMRequest{}, p.requests...)
+}
+
+func lastUserContent(msgs []*ChatgptMsg) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == "user" {
+			return msgs[i].Content
+		}
+	}
+	return ""
+}
+
+// fakeUsage approximates token counts with whitespace separated words
+func fakeUsage(msgs []*ChatgptMsg, completion string) LLMUsage {
+	prompt := 0
+	for _, msg := range msgs {
+		prompt += len(strings.Fields(msg.Content))
+	}
+	completionTokens := len(strings.Fields(completion))
+	return LLMUsage{
+		PromptTokens:     prompt,
+		CompletionTokens: completionTokens,
+		TotalTokens:      prompt + completionTokens,
+	}
+}
+
+const fakeEmbeddingDims = 64
+
+func fakeEmbedding(text string) []float32 {
+	vector := make([]float32, fakeEmbeddingDims)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(word))
+		vector[h.Sum32()%fakeEmbeddingDims]++
+	}
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v * v)
+	}
+	if norm == 0 {
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] = float32(float64(vector[i]) / norm)
+	}
+	return vector
+}
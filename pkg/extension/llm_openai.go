@@ -0,0 +1,57 @@
+package extension
+
+import (
+	"context"
+
+	"github.com/wyt-labs/wyt-core/internal/pkg/azure"
+)
+
+// OpenAIProvider talks to any server implementing the openai chat completions api,
+// e.g. self-hosted vLLM, llama.cpp or Ollama
+type OpenAIProvider struct {
+	cfg          *ChatgptConfig
+	api          *azure.ChatAPI
+	embeddingAPI *azure.EmbeddingAPI
+}
+
+func NewOpenAIProvider(cfg *ChatgptConfig) *OpenAIProvider {
+	return &OpenAIProvider{
+		cfg:          cfg,
+		api:          azure.NewOpenAIChatAPI(cfg.Endpoint, cfg.APIKey, cfg.Model),
+		embeddingAPI: azure.NewOpenAIEmbeddingAPI(cfg.Endpoint, cfg.APIKey, cfg.EmbeddingModel),
+	}
+}
+
+func (p *OpenAIProvider) Name() string {
+	return LLMProviderOpenAI
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, req *LLMRequest) (*LLMResponse, error) {
+	res, err := p.api.Complete(ctx, restRequestBody(req, p.cfg.Temperature, p.cfg.PresencePenalty))
+	if err != nil {
+		return nil, err
+	}
+	return restResponse(res)
+}
+
+func (p *OpenAIProvider) ChatStream(ctx context.Context, req *LLMRequest, onDelta func(content string)) (*LLMResponse, error) {
+	res, err := p.api.CompleteStream(ctx, restRequestBody(req, p.cfg.Temperature, p.cfg.PresencePenalty), onDelta)
+	if err != nil {
+		return nil, err
+	}
+	return restResponse(res)
+}
+
+func (p *OpenAIProvider) Embed(ctx context.Context, texts []string) ([][]float32, LLMUsage, error) {
+	vectors, usage, err := p.embeddingAPI.Embed(ctx, texts)
+	if err != nil {
+		return nil, LLMUsage{}, err
+	}
+	if usage == nil {
+		return vectors, LLMUsage{}, nil
+	}
+	return vectors, LLMUsage{
+		PromptTokens: usage.PromptTokens,
+		TotalTokens:  usage.TotalTokens,
+	}, nil
+}
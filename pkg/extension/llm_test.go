@@ -0,0 +1,260 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/azure/azuretest"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+)
+
+func TestFakeProvider_Chat(t *testing.T) {
+	provider, err := NewFakeProviderFromConfig([]config.FakeLLMRule{
+		{Pattern: "(?i)swap", ToolName: "swap", ToolArguments: `{"swap_in_token":"ETH"}`},
+		{Pattern: "(?i)hello", Content: "hi there"},
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name     string
+		req      *LLMRequest
+		content  string
+		toolName string
+	}{
+		{
+			name:     "tool call rule",
+			req:      &LLMRequest{Messages: []*ChatgptMsg{{Role: "user", Content: "swap 1 ETH"}}},
+			toolName: "swap",
+		},
+		{
+			name:    "content rule matches last user message",
+			req:     &LLMRequest{Messages: []*ChatgptMsg{{Role: "user", Content: "swap"}, {Role: "assistant", Content: "ok"}, {Role: "user", Content: "Hello"}}},
+			content: "hi there",
+		},
+		{
+			name:    "plain chat echoes",
+			req:     &LLMRequest{Messages: []*ChatgptMsg{{Role: "user", Content: "what is btc"}}},
+			content: "what is btc",
+		},
+		{
+			name:    "structured output echoes a general answer",
+			req:     &LLMRequest{Messages: []*ChatgptMsg{{Role: "user", Content: "what is btc"}}, ResponseSchema: config.ChatSchemaWithStructureOutputModeEnabled},
+			content: `{"content":"what is btc","intent_keys":[],"intention":"","view":"overview"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := provider.Chat(context.Background(), tt.req)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.content, res.Content)
+			if tt.toolName == "" {
+				assert.Equal(t, 0, len(res.ToolCalls))
+			} else {
+				assert.Equal(t, tt.toolName, res.ToolCalls[0].Name)
+			}
+		})
+	}
+	assert.Equal(t, len(tests), len(provider.Requests()))
+
+	_, err = NewFakeProviderFromConfig([]config.FakeLLMRule{{Pattern: "("}})
+	assert.NotNil(t, err)
+}
+
+func TestOpenAIProvider_Chat(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		_, _ = fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"","tool_calls":[{"id":"c1","type":"function","function":{"name":"top_traders","arguments":"{}"}}]}}],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`)
+	}))
+	defer server.Close()
+
+	cfg := &ChatgptConfig{
+		Endpoint: server.URL + "/v1/",
+		APIKey:   "key",
+		Model:    "llama3",
+	}
+	provider := NewOpenAIProvider(cfg)
+	tools := (&ChatgptDriver{cfg: cfg}).llmTools(context.Background(), "")
+	res, err := provider.Chat(context.Background(), &LLMRequest{
+		Messages:       []*ChatgptMsg{{Role: "user", Content: "top traders"}},
+		Tools:          tools,
+		ResponseSchema: config.ChatSchemaWithStructureOutputModeEnabled,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "llama3", body["model"])
+	assert.Equal(t, len(tools), len(body["tools"].([]any)))
+	assert.Equal(t, "json_schema", body["response_format"].(map[string]any)["type"])
+	assert.Equal(t, []LLMToolCall{{ID: "c1", Name: "top_traders", Arguments: "{}"}}, res.ToolCalls)
+	assert.Equal(t, LLMUsage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}, res.Usage)
+}
+
+func TestChatgptDriver_ChatWithStructuredOutputFake(t *testing.T) {
+	provider := NewFakeProvider(FakeRule{
+		Pattern: regexp.MustCompile("swap"),
+		Response: LLMResponse{ToolCalls: []LLMToolCall{{
+			Name:      "swap",
+			Arguments: `{"swap_in_token":"ETH","swap_out_token":"USDT","source_chain":"BSC"}`,
+		}}},
+	})
+	d, err := NewChatgptDriverWithProvider(&ChatgptConfig{}, provider, base.NewMockBaseComponent(t), nil)
+	assert.Nil(t, err)
+
+	content, fcRet, err := d.ChatWithStructuredOutput([]*ChatgptMsg{{Role: "user", Content: "swap ETH to USDT"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "", content)
+	assert.Equal(t, model.FCSwap, fcRet.FCType)
+	assert.Equal(t, "BSC", fcRet.FCSwapResult.DestChain)
+
+	// no wyt ai backend configured, DocsFaq is served by the provider
+	content, fcRet, err = d.DocsFaq(context.Background(), []*ChatgptMsg{{Role: "user", Content: "what is btc"}}, "")
+	assert.Nil(t, err)
+	assert.Nil(t, fcRet)
+	var ret model.ChatAIAnalyticalResult
+	assert.Nil(t, json.Unmarshal([]byte(content), &ret))
+	assert.Equal(t, "what is btc", ret.Content)
+}
+
+func TestChatgptDriver_WithResponseSchema(t *testing.T) {
+	provider := NewFakeProvider()
+	d, err := NewChatgptDriverWithProvider(&ChatgptConfig{}, provider, base.NewMockBaseComponent(t), nil)
+	assert.Nil(t, err)
+
+	schema := map[string]any{"name": "short-answer", "schema": map[string]any{"type": "object"}}
+	msgs := []*ChatgptMsg{{Role: "user", Content: "what is btc"}}
+	_, _, err = d.ChatWithStructuredOutputForProject(WithResponseSchema(context.Background(), schema), msgs, "", nil)
+	assert.Nil(t, err)
+	_, _, err = d.ChatWithStructuredOutput(msgs)
+	assert.Nil(t, err)
+
+	requests := provider.Requests()
+	assert.Equal(t, schema, requests[0].ResponseSchema)
+	assert.Equal(t, config.ChatSchemaWithStructureOutputModeEnabled, requests[1].ResponseSchema)
+}
+
+func TestChatgptDriver_WithSystemPrompt(t *testing.T) {
+	// the wyt ai backend cannot take the prompt, questions bypassing it are answered by the model
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected wyt ai request %s", r.URL.Path)
+	}))
+	defer backend.Close()
+	baseComponent := base.NewMockBaseComponent(t)
+	baseComponent.Config.AIBackend.AIEnv = "prod"
+	baseComponent.Config.AIBackend.Endpoint = backend.URL
+	provider := NewFakeProvider()
+	d, err := NewChatgptDriverWithProvider(&ChatgptConfig{}, provider, baseComponent, nil)
+	assert.Nil(t, err)
+
+	msgs := []*ChatgptMsg{{Role: "user", Content: "what is btc"}}
+	_, _, err = d.ChatWithStructuredOutput(msgs)
+	assert.Nil(t, err)
+	assert.True(t, d.HasBackend())
+	_, _, err = d.DocsFaq(WithSystemPrompt(WithBypassBackend(context.Background()), "You are terse."), msgs, "")
+	assert.Nil(t, err)
+	schema := map[string]any{"name": "short-answer", "schema": map[string]any{"type": "object"}}
+	_, _, err = d.DocsFaq(WithResponseSchema(WithBypassBackend(context.Background()), schema), msgs, "")
+	assert.Nil(t, err)
+
+	requests := provider.Requests()
+	assert.Equal(t, 3, len(requests))
+	// the built-in prompt by default, replaced by the custom one
+	assert.Equal(t, []*ChatgptMsg{{Role: "system", Content: config.ChatPromptWithStructureOutputModeEnabled}, msgs[0]}, requests[0].Messages)
+	assert.Equal(t, []*ChatgptMsg{{Role: "system", Content: "You are terse."}, msgs[0]}, requests[1].Messages)
+	assert.Equal(t, config.ChatPromptWithStructureOutputModeEnabled, requests[2].Messages[0].Content)
+	assert.Equal(t, schema, requests[2].ResponseSchema)
+}
+
+func TestChatgptDriver_UsageListener(t *testing.T) {
+	d, err := NewChatgptDriverWithProvider(&ChatgptConfig{Model: "gpt-4o", EmbeddingModel: "text-embedding-3-small"}, NewFakeProvider(), base.NewMockBaseComponent(t), nil)
+	assert.Nil(t, err)
+
+	var models []string
+	var usages []LLMUsage
+	ctx := WithUsageListener(context.Background(), func(model string, usage LLMUsage) {
+		models = append(models, model)
+		usages = append(usages, usage)
+	})
+	_, err = d.ChatCompletionsWithContext(ctx, []*ChatgptMsg{{Role: "user", Content: "hello world"}})
+	assert.Nil(t, err)
+	_, _, err = d.ChatWithStructuredOutputForProject(ctx, []*ChatgptMsg{{Role: "user", Content: "what is btc"}}, "", func(string) {})
+	assert.Nil(t, err)
+	_, err = d.Embed(ctx, []string{"bitcoin halving", "ethereum"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o", "text-embedding-3-small"}, models)
+	assert.Equal(t, 2, usages[0].PromptTokens)
+	assert.True(t, usages[1].TotalTokens > usages[1].PromptTokens)
+	assert.Equal(t, LLMUsage{PromptTokens: 3, TotalTokens: 3}, usages[2])
+
+	// calls without listener are not reported
+	_, err = d.ChatCompletions([]*ChatgptMsg{{Role: "user", Content: "hello"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(usages))
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTokens(""))
+	assert.Equal(t, 1, EstimateTokens("abcd"))
+	assert.Equal(t, 2, EstimateTokens("abcde"))
+	assert.Equal(t, 2, EstimateTokens("比特"))
+}
+
+func TestAzureProvider_Mock(t *testing.T) {
+	server := azuretest.NewServer(
+		azuretest.Rule{Pattern: regexp.MustCompile("swap"), ToolCall: &azuretest.ToolCall{Name: "swap", Arguments: `{"swap_in_token":"ETH","source_chain":"Ethereum"}`}},
+		azuretest.Rule{Pattern: regexp.MustCompile("btc"), JSON: map[string]any{"intention": "search", "intent_keys": []string{"BTC"}, "view": "overview"}},
+	)
+	defer server.Close()
+	d, err := NewChatgptDriver(&ChatgptConfig{
+		Endpoint:       server.Endpoint(),
+		EndpointFull:   server.EndpointFull("gpt-4o"),
+		APIKey:         azuretest.APIKey,
+		Model:          "gpt-4o",
+		EmbeddingModel: "text-embedding-3-small",
+	}, base.NewMockBaseComponent(t), nil)
+	assert.Nil(t, err)
+
+	// sdk: embeddings, not recorded as chat requests
+	vectors, err := d.Embed(context.Background(), []string{"bitcoin halving", "ethereum staking"})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]float32{azuretest.Embedding("bitcoin halving"), azuretest.Embedding("ethereum staking")}, vectors)
+
+	// sdk: plain chat and legacy function calling
+	content, err := d.ChatCompletions([]*ChatgptMsg{{Role: "user", Content: "hello"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", content)
+	_, fcRet, err := d.ChatCompletionsWithFuncCall([]*ChatgptMsg{{Role: "user", Content: "swap 1 ETH"}})
+	assert.Nil(t, err)
+	assert.Equal(t, model.FCSwap, fcRet.FCType)
+	assert.Equal(t, "Ethereum", fcRet.FCSwapResult.DestChain)
+
+	// restful api: structured output, tool calls and streaming
+	content, fcRet, err = d.ChatWithStructuredOutput([]*ChatgptMsg{{Role: "user", Content: "what is btc"}})
+	assert.Nil(t, err)
+	assert.Nil(t, fcRet)
+	assert.JSONEq(t, `{"intention":"search","intent_keys":["BTC"],"view":"overview"}`, content)
+	var deltas []string
+	_, fcRet, err = d.ChatWithStructuredOutputForProject(context.Background(), []*ChatgptMsg{{Role: "user", Content: "swap ETH"}}, "", func(content string) {
+		deltas = append(deltas, content)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deltas))
+	assert.Equal(t, model.FCSwap, fcRet.FCType)
+
+	requests := server.Requests()
+	assert.Equal(t, 4, len(requests))
+	assert.Equal(t, "gpt-4o", requests[0].Deployment)
+	assert.Equal(t, 0, len(requests[0].Tools))
+	assert.Equal(t, len(d.llmTools(context.Background(), "")), len(requests[1].Tools))
+	assert.Equal(t, "json_schema", requests[2].ResponseFormat)
+	assert.True(t, requests[3].Stream)
+}
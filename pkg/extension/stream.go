@@ -0,0 +1,160 @@
+package extension
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// structuredOutputContentField is the answer text of config.ChatSchemaWithStructureOutputModeEnabled
+const structuredOutputContentField = "content"
+
+// contentDeltas turns the raw fragments of a structured output into deltas of its top level content string,
+// so clients receive the answer text as it is generated instead of json fragments
+func contentDeltas(onDelta func(content string)) func(fragment string) {
+	var buf strings.Builder
+	emitted := 0
+	return func(fragment string) {
+		buf.WriteString(fragment)
+		content := partialStringField(buf.String(), structuredOutputContentField)
+		if len(content) > emitted {
+			onDelta(content[emitted:])
+			emitted = len(content)
+		}
+	}
+}
+
+// partialStringField decodes the value of the top level string field of a possibly truncated json object,
+// the result only grows as more of the object arrives
+func partialStringField(raw string, field string) string {
+	i := skipSpace(raw, 0)
+	if i >= len(raw) || raw[i] != '{' {
+		return ""
+	}
+	i++
+	for {
+		i = skipSpace(raw, i)
+		if i >= len(raw) {
+			return ""
+		}
+		switch raw[i] {
+		case ',':
+			i++
+			continue
+		case '"':
+		default:
+			return ""
+		}
+		key, next, complete := partialString(raw, i)
+		if !complete {
+			return ""
+		}
+		i = skipSpace(raw, next)
+		if i >= len(raw) || raw[i] != ':' {
+			return ""
+		}
+		i = skipSpace(raw, i+1)
+		if i >= len(raw) {
+			return ""
+		}
+		if key == field {
+			if raw[i] != '"' {
+				return ""
+			}
+			value, _, _ := partialString(raw, i)
+			return value
+		}
+		next, ok := skipValue(raw, i)
+		if !ok {
+			return ""
+		}
+		i = next
+	}
+}
+
+// partialString decodes the string starting at the quote raw[start], a truncated string is decoded up to
+// the last complete escape sequence. next is the index after the closing quote.
+func partialString(raw string, start int) (value string, next int, complete bool) {
+	end := start + 1
+	for end < len(raw) && raw[end] != '"' {
+		if raw[end] != '\\' {
+			end++
+			continue
+		}
+		size := 2
+		if end+1 < len(raw) && raw[end+1] == 'u' {
+			size = 6
+			// a high surrogate is only decodable together with the low one
+			if end+6 <= len(raw) && isHighSurrogate(raw[end+2:end+6]) {
+				size = 12
+			}
+		}
+		if end+size > len(raw) {
+			break
+		}
+		end += size
+	}
+	complete = end < len(raw) && raw[end] == '"'
+	if !complete {
+		// fragments may end inside a multi-byte character
+		for k := end - 1; k > start && k >= end-utf8.UTFMax; k-- {
+			if utf8.RuneStart(raw[k]) {
+				if !utf8.FullRuneInString(raw[k:end]) {
+					end = k
+				}
+				break
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(raw[start:end]+`"`), &value); err != nil {
+		return "", 0, false
+	}
+	return value, end + 1, complete
+}
+
+// skipValue returns the index after the complete json value starting at raw[start]
+func skipValue(raw string, start int) (int, bool) {
+	depth := 0
+	for i := start; i < len(raw); i++ {
+		switch raw[i] {
+		case '"':
+			_, next, complete := partialString(raw, i)
+			if !complete {
+				return 0, false
+			}
+			if depth == 0 {
+				return next, true
+			}
+			i = next - 1
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				// end of the enclosing object right after a literal
+				return i, true
+			}
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		case ',', ' ', '\t', '\n', '\r':
+			if depth == 0 {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func skipSpace(raw string, i int) int {
+	for i < len(raw) && strings.IndexByte(" \t\n\r", raw[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+func isHighSurrogate(hex string) bool {
+	v, err := strconv.ParseUint(hex, 16, 16)
+	return err == nil && v >= 0xd800 && v < 0xdc00
+}
@@ -0,0 +1,33 @@
+package extension
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentDeltas(t *testing.T) {
+	raw := `{"intention":"general","intent_keys":["a\"}",{"b":[1]}],"view":null,"content":"line \"one\"\né 😀 done","fill":""}`
+	// every split point, including ones inside escape sequences
+	for size := 1; size <= 8; size++ {
+		var deltas []string
+		onFragment := contentDeltas(func(content string) {
+			deltas = append(deltas, content)
+		})
+		for i := 0; i < len(raw); i += size {
+			onFragment(raw[i:min(i+size, len(raw))])
+		}
+		assert.Equal(t, "line \"one\"\né 😀 done", strings.Join(deltas, ""), "fragment size %d", size)
+		if size == 1 {
+			assert.Greater(t, len(deltas), 10)
+		}
+	}
+
+	var deltas []string
+	onFragment := contentDeltas(func(content string) {
+		deltas = append(deltas, content)
+	})
+	onFragment(`{"intention":"search","intent_keys":["BTC"],"view":"overview"}`)
+	assert.Empty(t, deltas)
+}
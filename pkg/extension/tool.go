@@ -0,0 +1,238 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+)
+
+// ToolHandler runs a tool called by the model, arguments is the raw json produced by the model
+type ToolHandler func(ctx context.Context, d *ChatgptDriver, arguments string) (*model.FuncCallingRet, error)
+
+// ToolDecoder maps a result already resolved by the wyt ai backend
+type ToolDecoder func(result map[string]any) *model.FuncCallingRet
+
+type ToolRender struct {
+	Intention model.ChatAIAnalyticalIntention
+	Tips      string
+	// IntentKeys shown as project keys, defaults to the intention
+	IntentKeys func(ret *model.FuncCallingRet) []string
+	// Result is the payload stored as the analytical content, defaults to FuncCallingRet.ToolResult
+	Result func(ret *model.FuncCallingRet) any
+	// Attach sets the view of the assistant content, defaults to ChatContentAssistant.Tool
+	Attach func(content *model.ChatContentAssistant, info model.ChatContentAssistantInfo)
+}
+
+type Tool struct {
+	Name string
+	// FCType identifies the result in model.FuncCallingRet, defaults to Name
+	FCType      model.FuncCallingType
+	Description string
+	// Parameters is the json schema of the arguments
+	Parameters map[string]any
+	// Remote tools are executed by the wyt ai backend and only come back resolved, they are not offered to the model
+	Remote  bool
+	Handler ToolHandler
+	Decode  ToolDecoder
+	Render  ToolRender
+}
+
+var toolRegistry = struct {
+	lock    sync.RWMutex
+	ordered []*Tool
+	byName  map[string]*Tool
+	byType  map[model.FuncCallingType]*Tool
+}{
+	byName: map[string]*Tool{},
+	byType: map[model.FuncCallingType]*Tool{},
+}
+
+// RegisterTool adds a tool to the registry, it is meant to be called from init and panics on duplicates
+func RegisterTool(tool *Tool) {
+	if tool.FCType == "" {
+		tool.FCType = tool.Name
+	}
+	toolRegistry.lock.Lock()
+	defer toolRegistry.lock.Unlock()
+	if _, ok := toolRegistry.byName[tool.Name]; ok {
+		panic(fmt.Sprintf("tool %s registered twice", tool.Name))
+	}
+	if _, ok := toolRegistry.byType[tool.FCType]; ok {
+		panic(fmt.Sprintf("tool result type %s registered twice", tool.FCType))
+	}
+	toolRegistry.ordered = append(toolRegistry.ordered, tool)
+	toolRegistry.byName[tool.Name] = tool
+	toolRegistry.byType[tool.FCType] = tool
+}
+
+func LookupTool(name string) (*Tool, bool) {
+	toolRegistry.lock.RLock()
+	defer toolRegistry.lock.RUnlock()
+	tool, ok := toolRegistry.byName[name]
+	return tool, ok
+}
+
+func LookupToolByType(fcType model.FuncCallingType) (*Tool, bool) {
+	toolRegistry.lock.RLock()
+	defer toolRegistry.lock.RUnlock()
+	tool, ok := toolRegistry.byType[fcType]
+	return tool, ok
+}
+
+// RegisteredTools returns all tools in registration order
+func RegisteredTools() []*Tool {
+	toolRegistry.lock.RLock()
+	defer toolRegistry.lock.RUnlock()
+	return append([]*Tool{}, toolRegistry.ordered...)
+}
+
+// ToolEnabled checks the tool policies of the agent (project id), the "*" policy applies to agents without their own
+func ToolEnabled(policies []config.ToolPolicy, projectId string, name string) bool {
+	policy, ok := lo.Find(policies, func(item config.ToolPolicy) bool {
+		return item.ProjectId == projectId
+	})
+	if !ok {
+		policy, ok = lo.Find(policies, func(item config.ToolPolicy) bool {
+			return item.ProjectId == "*"
+		})
+	}
+	if !ok {
+		return true
+	}
+	if len(policy.Enabled) > 0 && !lo.Contains(policy.Enabled, name) {
+		return false
+	}
+	return !lo.Contains(policy.Disabled, name)
+}
+
+type enabledToolsKey struct{}
+
+// WithEnabledTools returns a context whose model calls only offer and run the named tools, on top of the tool
+// policies, an empty names leaves the policies alone. The results of remote tools are checked the same way.
+func WithEnabledTools(ctx context.Context, names []string) context.Context {
+	return context.WithValue(ctx, enabledToolsKey{}, names)
+}
+
+// toolAllowed checks the tool policies and the tools enabled in ctx
+func (d *ChatgptDriver) toolAllowed(ctx context.Context, projectId string, name string) bool {
+	if names, ok := ctx.Value(enabledToolsKey{}).([]string); ok && len(names) != 0 && !lo.Contains(names, name) {
+		return false
+	}
+	return ToolEnabled(d.cfg.ToolPolicies, projectId, name)
+}
+
+// RenderResult fills the assistant message and the analytical result with the tool call result
+func (t *Tool) RenderResult(ret *model.FuncCallingRet, content *model.ChatContentAssistant, analytical *model.ChatAIAnalyticalResult) {
+	intention := t.Render.Intention
+	if intention == "" {
+		intention = t.FCType
+	}
+	keys := []string{intention}
+	if t.Render.IntentKeys != nil {
+		keys = t.Render.IntentKeys(ret)
+	}
+	var result any = ret.ToolResult
+	if t.Render.Result != nil {
+		result = t.Render.Result(ret)
+	}
+
+	analytical.Intention = intention
+	analytical.IntentKeys = keys
+	jsonStr, _ := json.Marshal(result)
+	analytical.Content = string(jsonStr)
+	analytical.View = string(ret.FCType)
+	analytical.Fill = ""
+	analytical.ProjectIDs = []primitive.ObjectID{}
+
+	content.Type = intention
+	content.Fill = ""
+	content.ProjectKeys = keys
+	content.Tips = t.Render.Tips
+	info := model.ChatContentAssistantInfo{
+		ID:             primitive.NewObjectID(),
+		FuncCallingRet: *ret,
+	}
+	if t.Render.Attach != nil {
+		t.Render.Attach(content, info)
+	} else {
+		content.Tool = &model.ChatContentAssistantToolRes{
+			View: t.Name,
+			Tool: info,
+		}
+	}
+}
+
+// decodeInto builds a ToolDecoder storing the resolved result into the field returned by target
+func decodeInto[T any](fcType model.FuncCallingType, target func(ret *model.FuncCallingRet) *T) ToolDecoder {
+	return func(result map[string]any) *model.FuncCallingRet {
+		ret := &model.FuncCallingRet{FCType: fcType}
+		*target(ret) = mapToStruct[T](result)
+		return ret
+	}
+}
+
+// llmTools returns the tools offered to the model for the agent
+func (d *ChatgptDriver) llmTools(ctx context.Context, projectId string) []LLMTool {
+	var tools []LLMTool
+	for _, tool := range RegisteredTools() {
+		if tool.Remote || tool.Handler == nil || !d.toolAllowed(ctx, projectId, tool.Name) {
+			continue
+		}
+		tools = append(tools, LLMTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.Parameters,
+		})
+	}
+	return tools
+}
+
+func (d *ChatgptDriver) handleFunctionCall(ctx context.Context, projectId string, name string, arguments string) (*model.FuncCallingRet, error) {
+	if name == "" {
+		return nil, fmt.Errorf("invalid function call response")
+	}
+	notifyFuncCall(ctx, name, arguments)
+	tool, ok := LookupTool(name)
+	if !ok || tool.Handler == nil {
+		return nil, fmt.Errorf("unknown function: %s", name)
+	}
+	if !d.toolAllowed(ctx, projectId, name) {
+		return nil, toolDisabledError(name, projectId)
+	}
+	ret, err := tool.Handler(ctx, d, arguments)
+	if err != nil {
+		return nil, err
+	}
+	ret.FCType = tool.FCType
+	return ret, nil
+}
+
+func toolDisabledError(name string, projectId string) error {
+	return fmt.Errorf("function %s is disabled for project %s", name, projectId)
+}
+
+// decodeRemoteToolResult maps the result of a function executed by the wyt ai backend
+func decodeRemoteToolResult(name string, result map[string]any) *model.FuncCallingRet {
+	tool, ok := LookupTool(name)
+	if !ok {
+		return &model.FuncCallingRet{
+			RemoteFunctionResult: result,
+		}
+	}
+	if tool.Decode == nil {
+		return &model.FuncCallingRet{
+			FCType:     tool.FCType,
+			ToolResult: result,
+		}
+	}
+	ret := tool.Decode(result)
+	ret.FCType = tool.FCType
+	return ret
+}
@@ -0,0 +1,209 @@
+package extension
+
+import (
+	"context"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+)
+
+// pump.fun data tools, served by the datapuller PumpDataService
+
+func pumpDurationParameters() map[string]any {
+	return map[string]any{
+		"required": []string{},
+		"type":     "object",
+		"properties": map[string]any{
+			"duration": map[string]any{
+				"type":        "number",
+				"description": "The number of consecutive days for which you want to view data.",
+			},
+			"timezone": map[string]any{
+				"type":        "string",
+				"description": "time zone.",
+			},
+		},
+	}
+}
+
+func init() {
+	// pump.fun 每日新创建代币数量以及上raydium的数量
+	RegisterTool(&Tool{
+		Name:        "daily_new_tokens",
+		FCType:      model.FCDailyNewToken,
+		Description: "For several consecutive days, the number of new tokens created daily by pump.fun and the number of tokens listed on Reydium.",
+		Parameters:  pumpDurationParameters(),
+		Handler: func(ctx context.Context, d *ChatgptDriver, arguments string) (*model.FuncCallingRet, error) {
+			ret, err := d.DailyNewTokens(ctx, arguments)
+			if err != nil {
+				return nil, err
+			}
+			return &model.FuncCallingRet{NewTokenResult: *ret}, nil
+		},
+		Decode: decodeInto(model.FCDailyNewToken, func(ret *model.FuncCallingRet) *model.DailyNewTokensFuncCallingResult {
+			return &ret.NewTokenResult
+		}),
+		Render: ToolRender{
+			Intention: model.ChatAIDailyNewToken,
+			Tips:      "here is daily new tokens",
+			Result: func(ret *model.FuncCallingRet) any {
+				return ret.NewTokenResult
+			},
+			Attach: func(content *model.ChatContentAssistant, info model.ChatContentAssistantInfo) {
+				content.DailyNewToken = &model.ChatContentAssistantNewTokenRes{
+					View:     model.ChatContentAssistantNewTokenView,
+					NewToken: info,
+				}
+			},
+		},
+	})
+
+	// pump.fun 每日新创建代币的时间分布，按半小时划分
+	RegisterTool(&Tool{
+		Name:        "token_launched_time_distribution",
+		FCType:      model.FCTokenLaunchedTimeDistribution,
+		Description: "Time distribution of dump.fun new Token creation (by half hour).",
+		Parameters:  pumpDurationParameters(),
+		Handler: func(ctx context.Context, d *ChatgptDriver, arguments string) (*model.FuncCallingRet, error) {
+			ret, err := d.TokenLaunchedTimeDistribution(ctx, arguments)
+			if err != nil {
+				return nil, err
+			}
+			return &model.FuncCallingRet{TokenLTD: *ret}, nil
+		},
+		Decode: decodeInto(model.FCTokenLaunchedTimeDistribution, func(ret *model.FuncCallingRet) *model.TokenLaunchedTimeDtFuncCallingResult {
+			return &ret.TokenLTD
+		}),
+		Render: ToolRender{
+			Intention: model.ChatAITokenLaunchedTimeDistribution,
+			Tips:      "here is token launched time distribution ",
+			Result: func(ret *model.FuncCallingRet) any {
+				return ret.TokenLTD
+			},
+			Attach: func(content *model.ChatContentAssistant, info model.ChatContentAssistantInfo) {
+				content.TokenLaunchedTime = &model.ChatContentAssistantTokenLtDistRes{
+					View:                     model.ChatContentAssistantTokenLTView,
+					LaunchedTimeDistribution: info,
+				}
+			},
+		},
+	})
+
+	// pump.fun 每日token交换（swap）交易统计
+	RegisterTool(&Tool{
+		Name:        "daily_token_swap_counts",
+		FCType:      model.FCDailyTokenSwapCount,
+		Description: "Daily statistics of pump.fun's token exchange (swap) transactions.",
+		Parameters:  pumpDurationParameters(),
+		Handler: func(ctx context.Context, d *ChatgptDriver, arguments string) (*model.FuncCallingRet, error) {
+			ret, err := d.DailyTokenSwapCounts(ctx, arguments)
+			if err != nil {
+				return nil, err
+			}
+			return &model.FuncCallingRet{TokenSwapCount: *ret}, nil
+		},
+		Decode: decodeInto(model.FCDailyTokenSwapCount, func(ret *model.FuncCallingRet) *model.DailyTokenSwapCountsFuncCallingResult {
+			return &ret.TokenSwapCount
+		}),
+		Render: ToolRender{
+			Intention: model.ChatAIDailyTokenSwapCount,
+			Tips:      "Here is daily token swap count",
+			Result: func(ret *model.FuncCallingRet) any {
+				return ret.TokenSwapCount
+			},
+			Attach: func(content *model.ChatContentAssistant, info model.ChatContentAssistantInfo) {
+				content.TokenSwapCount = &model.ChatContentAssistantTokenSwapCountRes{
+					View:           model.ChatContentAssistantTokenSwapCountView,
+					TokenSwapCount: info,
+				}
+			},
+		},
+	})
+
+	// pump.fun top trader
+	RegisterTool(&Tool{
+		Name:        "top_traders",
+		FCType:      model.FCTopTrader,
+		Description: "List of top (high net profit) traders on pump.fun.",
+		Parameters: map[string]any{
+			"required": []string{},
+			"type":     "object",
+			"properties": map[string]any{
+				"duration": map[string]any{
+					"type":        "number",
+					"description": "The number of consecutive days for which you want to view data.",
+				},
+				"winRatio": map[string]any{
+					"type":        "number",
+					"description": "Trader's win rate.",
+				},
+				"timezone": map[string]any{
+					"type":        "string",
+					"description": "time zone.",
+				},
+			},
+		},
+		Handler: func(ctx context.Context, d *ChatgptDriver, arguments string) (*model.FuncCallingRet, error) {
+			ret, err := d.TopTraders(ctx, arguments)
+			if err != nil {
+				return nil, err
+			}
+			return &model.FuncCallingRet{TopTrader: *ret}, nil
+		},
+		Decode: decodeInto(model.FCTopTrader, func(ret *model.FuncCallingRet) *model.TopTradersFuncCallingResult {
+			return &ret.TopTrader
+		}),
+		Render: ToolRender{
+			Intention: model.ChatAITopTrader,
+			Tips:      "Here is top trader",
+			Result: func(ret *model.FuncCallingRet) any {
+				return ret.TopTrader
+			},
+			Attach: func(content *model.ChatContentAssistant, info model.ChatContentAssistantInfo) {
+				content.TopTrader = &model.ChatContentAssistantTopTraderRes{
+					View:      model.ChatContentAssistantTopTrader,
+					TopTrader: info,
+				}
+			},
+		},
+	})
+
+	// pump.fun trader 概览信息
+	RegisterTool(&Tool{
+		Name:        "trader_overview",
+		FCType:      model.FCTraderOverview,
+		Description: "Overview information for pump.fun traders.",
+		Parameters: map[string]any{
+			"required": []string{},
+			"type":     "object",
+			"properties": map[string]any{
+				"address": map[string]any{
+					"type":        "string",
+					"description": "pump.fun trader’s address.",
+				},
+			},
+		},
+		Handler: func(ctx context.Context, d *ChatgptDriver, arguments string) (*model.FuncCallingRet, error) {
+			ret, err := d.TraderOverview(ctx, arguments)
+			if err != nil {
+				return nil, err
+			}
+			return &model.FuncCallingRet{TraderOverview: *ret}, nil
+		},
+		Decode: decodeInto(model.FCTraderOverview, func(ret *model.FuncCallingRet) *model.TraderOverviewFuncCallingResult {
+			return &ret.TraderOverview
+		}),
+		Render: ToolRender{
+			Intention: model.ChatAITraderOverview,
+			Tips:      "Here is trader overview of the past 7 days",
+			Result: func(ret *model.FuncCallingRet) any {
+				return ret.TraderOverview
+			},
+			Attach: func(content *model.ChatContentAssistant, info model.ChatContentAssistantInfo) {
+				content.TraderOverview = &model.ChatContentAssistantTraderOverviewRes{
+					View:           model.ChatContentAssistantTraderOverview,
+					TraderOverview: info,
+				}
+			},
+		},
+	})
+}
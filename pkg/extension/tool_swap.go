@@ -0,0 +1,96 @@
+package extension
+
+import (
+	"context"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+)
+
+const swapTips = "Sure, you can swap here. If it doesn't meet your requirements, you can directly operate in the panel or modify your prompt."
+
+func init() {
+	RegisterTool(&Tool{
+		Name:        "swap",
+		FCType:      model.FCSwap,
+		Description: "Use one crypto token swap for another token",
+		Parameters: map[string]any{
+			"required": []string{},
+			"type":     "object",
+			"properties": map[string]any{
+				"swap_in_token": map[string]any{
+					"type":        "string",
+					"description": "Swap input token symbol.",
+				},
+				"source_chain": map[string]any{
+					"type":        "string",
+					"description": "The chain where the input token is located. Same as the destination chain if no chain specified for source_chain (should not be null in this case!). If user specified source_chain, use it instead!",
+				},
+				"amount_in": map[string]any{
+					"type":        "number",
+					"description": "Swap input token amount.",
+				},
+				"dest_chain": map[string]any{
+					"type":        "string",
+					"description": "The chain where the output token is located. Same as the source chain if no chain specified for dest_chain (should not be null in this case!). If user specified dest_chain, use it instead!",
+				},
+				"swap_out_token": map[string]any{
+					"type":        "string",
+					"description": "Swap output token symbol.",
+				},
+				"dex": map[string]any{
+					"type":        "string",
+					"description": "Swap on which DEX.",
+				},
+			},
+		},
+		Handler: func(ctx context.Context, d *ChatgptDriver, arguments string) (*model.FuncCallingRet, error) {
+			ret, err := d.Swap(ctx, arguments)
+			if err != nil {
+				return nil, err
+			}
+			return &model.FuncCallingRet{FCSwapResult: *ret}, nil
+		},
+		Decode: decodeInto(model.FCSwap, func(ret *model.FuncCallingRet) *model.SwapFuncCallingResult {
+			return &ret.FCSwapResult
+		}),
+		Render: ToolRender{
+			Intention: model.ChatAIAnalyticalIntentionSwap,
+			Tips:      swapTips,
+			IntentKeys: func(ret *model.FuncCallingRet) []string {
+				return []string{ret.FCSwapResult.SwapInToken, ret.FCSwapResult.SwapOutToken}
+			},
+			Result: func(ret *model.FuncCallingRet) any {
+				return ret.FCSwapResult
+			},
+			Attach: func(content *model.ChatContentAssistant, info model.ChatContentAssistantInfo) {
+				content.SwapInfo = &model.ChatContentAssistantSwapRes{
+					View: string(model.FCSwap),
+					Swap: info,
+				}
+			},
+		},
+	})
+
+	// executed by the wyt ai backend for the uniswap agent
+	RegisterTool(&Tool{
+		Name:   "uniswap",
+		FCType: model.FCUniswap,
+		Remote: true,
+		Decode: decodeInto(model.FCUniswap, func(ret *model.FuncCallingRet) *model.UniswapFuncCallingResult {
+			return &ret.FCUniswapResult
+		}),
+		Render: ToolRender{
+			Intention: model.ChatAIAnalyticalIntentionUniswap,
+			Tips:      swapTips,
+			Result: func(ret *model.FuncCallingRet) any {
+				return ret.FCUniswapResult
+			},
+			Attach: func(content *model.ChatContentAssistant, info model.ChatContentAssistantInfo) {
+				content.Uniswap = &model.ChatContentAssistantUniswapRes{
+					View:    model.ChatContentAssistantSwapViewUniswap,
+					Uniswap: info,
+				}
+			},
+		},
+	})
+}
@@ -0,0 +1,145 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wyt-labs/wyt-core/internal/core/model"
+	"github.com/wyt-labs/wyt-core/internal/pkg/base"
+	"github.com/wyt-labs/wyt-core/internal/pkg/config"
+	"github.com/wyt-labs/wyt-core/internal/pkg/entity"
+)
+
+func TestToolEnabled(t *testing.T) {
+	policies := []config.ToolPolicy{
+		{ProjectId: "*", Disabled: []string{"trader_overview"}},
+		{ProjectId: "pump", Enabled: []string{"top_traders", "trader_overview"}},
+		{ProjectId: "dex", Enabled: []string{"swap"}, Disabled: []string{"swap"}},
+	}
+	tests := []struct {
+		projectId string
+		name      string
+		want      bool
+	}{
+		{projectId: "", name: "swap", want: true},
+		{projectId: "", name: "trader_overview", want: false},
+		{projectId: "pump", name: "trader_overview", want: true},
+		{projectId: "pump", name: "swap", want: false},
+		{projectId: "dex", name: "swap", want: false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ToolEnabled(policies, tt.projectId, tt.name), "%s/%s", tt.projectId, tt.name)
+	}
+	assert.True(t, ToolEnabled(nil, "pump", "swap"))
+}
+
+func TestToolRegistry(t *testing.T) {
+	for _, name := range []string{"swap", "uniswap", "daily_new_tokens", "token_launched_time_distribution", "daily_token_swap_counts", "top_traders", "trader_overview"} {
+		tool, ok := LookupTool(name)
+		assert.True(t, ok, name)
+		byType, ok := LookupToolByType(tool.FCType)
+		assert.True(t, ok, name)
+		assert.Equal(t, tool, byType)
+	}
+	assert.Panics(t, func() {
+		RegisterTool(&Tool{Name: "swap"})
+	})
+
+	d := &ChatgptDriver{cfg: &ChatgptConfig{ToolPolicies: []config.ToolPolicy{{ProjectId: "pump", Enabled: []string{"top_traders"}}}}}
+	names := lo.Map(d.llmTools(context.Background(), ""), func(item LLMTool, _ int) string { return item.Name })
+	assert.NotContains(t, names, "uniswap")
+	assert.Contains(t, names, "swap")
+	assert.Equal(t, []LLMTool{{Name: "top_traders", Description: "List of top (high net profit) traders on pump.fun.", Parameters: lo.Must(LookupTool("top_traders")).Parameters}}, d.llmTools(context.Background(), "pump"))
+
+	// the tools enabled for the agent narrow the policies down
+	ctx := WithEnabledTools(context.Background(), []string{"swap", "top_traders", "uniswap"})
+	names = lo.Map(d.llmTools(ctx, ""), func(item LLMTool, _ int) string { return item.Name })
+	assert.ElementsMatch(t, []string{"swap", "top_traders"}, names)
+	assert.Len(t, d.llmTools(WithEnabledTools(context.Background(), nil), ""), len(d.llmTools(context.Background(), "")))
+	_, err := d.handleFunctionCall(ctx, "pump", "swap", "{}")
+	assert.ErrorContains(t, err, "disabled")
+}
+
+func TestChatgptDriver_RemoteToolEnabled(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(entity.Response{
+			ToolResults: []entity.ToolResult{{
+				ToolName: "uniswap",
+				Result:   entity.ToolResultData{Resolved: true, Result: map[string]any{"url": "https://app.uniswap.org"}},
+			}},
+		})
+	}))
+	defer backend.Close()
+	baseComponent := base.NewMockBaseComponent(t)
+	baseComponent.Config.AIBackend.AIEnv = "prod"
+	baseComponent.Config.AIBackend.Endpoint = backend.URL
+	d, err := NewChatgptDriverWithProvider(&ChatgptConfig{}, NewFakeProvider(), baseComponent, nil)
+	assert.Nil(t, err)
+	msgs := []*ChatgptMsg{{Role: "user", Content: "swap on uniswap"}}
+
+	_, fcRet, err := d.DocsFaq(WithEnabledTools(context.Background(), []string{"uniswap"}), msgs, "uni")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://app.uniswap.org", fcRet.FCUniswapResult.Url)
+
+	// the backend runs the tool anyway, its result is refused
+	_, _, err = d.DocsFaq(WithEnabledTools(context.Background(), []string{"swap"}), msgs, "uni")
+	assert.ErrorContains(t, err, "function uniswap is disabled for project uni")
+}
+
+func TestTool_RenderResult(t *testing.T) {
+	swap, _ := LookupTool("swap")
+	ret := &model.FuncCallingRet{
+		FCType:       model.FCSwap,
+		FCSwapResult: model.SwapFuncCallingResult{SwapInToken: "ETH", SwapOutToken: "USDT"},
+	}
+	content := &model.ChatContentAssistant{}
+	var analytical model.ChatAIAnalyticalResult
+	swap.RenderResult(ret, content, &analytical)
+	assert.Equal(t, model.ChatAIAnalyticalIntentionSwap, analytical.Intention)
+	assert.Equal(t, []string{"ETH", "USDT"}, content.ProjectKeys)
+	assert.Equal(t, "swap", content.SwapInfo.View)
+	assert.Equal(t, "ETH", content.SwapInfo.Swap.FuncCallingRet.FCSwapResult.SwapInToken)
+	assert.Nil(t, content.Tool)
+
+	// tools without a dedicated field fall back to the generic tool result
+	generic := &Tool{Name: "gas_price", FCType: "gas_price", Render: ToolRender{Tips: "Here is the gas price"}}
+	ret = &model.FuncCallingRet{FCType: "gas_price", ToolResult: map[string]any{"gwei": 12}}
+	content = &model.ChatContentAssistant{}
+	generic.RenderResult(ret, content, &analytical)
+	assert.Equal(t, "gas_price", analytical.Intention)
+	assert.Equal(t, `{"gwei":12}`, analytical.Content)
+	assert.Equal(t, []string{"gas_price"}, content.ProjectKeys)
+	assert.Equal(t, "gas_price", content.Tool.View)
+}
+
+func TestDecodeRemoteToolResult(t *testing.T) {
+	ret := decodeRemoteToolResult("swap", map[string]any{"swap_in_token": "ETH"})
+	assert.Equal(t, model.FCSwap, ret.FCType)
+	assert.Equal(t, "ETH", ret.FCSwapResult.SwapInToken)
+
+	ret = decodeRemoteToolResult("unknown", map[string]any{"a": "b"})
+	assert.Equal(t, "", ret.FCType)
+	assert.Equal(t, map[string]any{"a": "b"}, ret.RemoteFunctionResult)
+}
+
+func TestChatgptDriver_ToolPolicyRejected(t *testing.T) {
+	provider := NewFakeProvider(FakeRule{
+		Pattern:  regexp.MustCompile("swap"),
+		Response: LLMResponse{ToolCalls: []LLMToolCall{{Name: "swap", Arguments: `{}`}}},
+	})
+	d, err := NewChatgptDriverWithProvider(&ChatgptConfig{
+		ToolPolicies: []config.ToolPolicy{{ProjectId: "pump", Disabled: []string{"swap"}}},
+	}, provider, base.NewMockBaseComponent(t), nil)
+	assert.Nil(t, err)
+
+	_, _, err = d.ChatWithStructuredOutputForProject(context.Background(), []*ChatgptMsg{{Role: "user", Content: "swap ETH"}}, "pump", nil)
+	assert.NotNil(t, err)
+	assert.NotContains(t, lo.Map(provider.Requests()[0].Tools, func(item LLMTool, _ int) string { return item.Name }), "swap")
+}